@@ -17,9 +17,21 @@
 //	  }
 //	}
 //
+// # HTTP Transport
+//
+// By default the server talks to a single client over stdio. To host it as
+// a network service for several clients, serve the MCP streamable HTTP
+// transport at the /mcp endpoint of an address:
+//
+//	gofpdf-mcp -http localhost:8080
+//
+// The HTTP transport has no authentication and the tools can read and write
+// any file the server can, so listen on a loopback address as above, or put
+// the server behind a proxy that authenticates its clients.
+//
 // # Available Tools
 //
-//   - create_pdf: Create PDFs from JSON templates
+//   - create_pdf: Create PDFs from JSON templates or Markdown
 //   - read_pdf: Read PDF metadata
 //   - read_pdf_text: Extract text from PDFs
 //   - merge_pdfs: Merge multiple PDFs
@@ -29,6 +41,11 @@
 //   - flatten_form: Flatten PDF forms
 //   - rotate_pages: Rotate PDF pages
 //   - pdf_info: Get detailed PDF information
+//   - extract_images: Extract images as JPEG or PNG
+//   - sign_pdf: Digitally sign PDFs
+//   - verify_signature: Verify digital signatures
+//   - images_to_pdf: Create PDFs from images
+//   - set_metadata: Set document metadata
 //
 // # Available Resources
 //
@@ -36,9 +53,11 @@
 //   - pdf://metadata?path=... : Get document metadata
 //   - pdf://pages?path=... : Get page information
 //   - pdf://form-fields?path=... : List form fields
+//   - pdf://thumbnail?path=...&page=N : Render a page as PNG
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -46,12 +65,21 @@ import (
 )
 
 func main() {
+	httpAddr := flag.String("http", "", "serve the streamable HTTP transport on this address instead of stdio")
+	flag.Parse()
+
 	server := mcp.NewServer()
 
 	mcp.RegisterDefaultTools(server)
 	mcp.RegisterDefaultResources(server)
 
-	if err := server.Run(); err != nil {
+	var err error
+	if *httpAddr != "" {
+		err = server.RunHTTP(*httpAddr)
+	} else {
+		err = server.Run()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "gofpdf-mcp: %v\n", err)
 		os.Exit(1)
 	}
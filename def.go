@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -624,6 +625,16 @@ type Fpdf struct {
 	userUnderlineThickness float64                  // A custom user underline thickness multiplier.
 	catalogExtra           []string                 // extra lines to add to the catalog dictionary
 	pageAnnots             map[int][]string         // extra annotation strings per page (1-based)
+	rawObjects             []rawObjectType          // extra indirect objects, see NewRawObject
+	rawObjBase             int                      // object number preceding the first raw object
+	rawRefs                *strings.Replacer        // replaces RawObjectRef placeholders on output
+}
+
+// rawObjectType holds an indirect object added by an extension package.
+type rawObjectType struct {
+	body     string // dictionary or other object body
+	stream   []byte // stream data, written after body if isStream is set
+	isStream bool
 }
 
 type encType struct {
@@ -0,0 +1,247 @@
+package doctpl
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/lvillar/gofpdf"
+)
+
+// This file implements the right-to-left support used by elements with
+// "dir": "rtl". PDF content streams place glyphs left to right, so text
+// stored in logical (reading) order has to be rearranged into visual order
+// before it is drawn. The rules below are a simplified form of the Unicode
+// bidirectional algorithm for a right-to-left paragraph: runs of Latin
+// letters and digits keep their order, everything else is reversed and
+// mirrored. Arabic letters are additionally replaced with their contextual
+// presentation forms so that they join; this requires a font that covers
+// the Arabic Presentation Forms blocks.
+
+// renderRTL draws text as right-to-left lines of width w. Lines are wrapped
+// at spaces in logical order and each one is then reordered for display.
+func renderRTL(pdf *gofpdf.Fpdf, w, h float64, text, align string) {
+	for _, line := range wrapWords(pdf, shapeArabic(text), w-2*pdf.GetCellMargin()) {
+		pdf.CellFormat(w, h, visualOrder(line), "", 2, align, false, 0, "")
+	}
+}
+
+// wrapWords breaks text into lines no wider than w, breaking at spaces and
+// at explicit newlines. A word wider than w is kept on a line of its own.
+func wrapWords(pdf *gofpdf.Fpdf, text string, w float64) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line == "" {
+				line = word
+			} else if pdf.GetStringWidth(line+" "+word) <= w {
+				line += " " + word
+			} else {
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// isRTL reports whether r is a strong right-to-left character.
+func isRTL(r rune) bool {
+	switch {
+	case r >= 0x0590 && r <= 0x08FF: // Hebrew, Arabic, Syriac, Thaana, ...
+		return true
+	case r >= 0xFB1D && r <= 0xFDFF: // Hebrew and Arabic presentation forms
+		return true
+	case r >= 0xFE70 && r <= 0xFEFF: // Arabic presentation forms B
+		return true
+	}
+	return false
+}
+
+// isLTR reports whether r starts or continues a left-to-right run.
+func isLTR(r rune) bool {
+	return !isRTL(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// mirrored maps paired punctuation to its counterpart, used for characters
+// drawn in right-to-left runs.
+var mirrored = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+}
+
+// visualOrder converts a single line of a right-to-left paragraph from
+// logical to visual order. Left-to-right runs, such as numbers and Latin
+// words including the spaces and punctuation between them, are kept
+// intact; the line as a whole reads from right to left.
+func visualOrder(line string) string {
+	runes := []rune(line)
+
+	// Mark the runes belonging to left-to-right runs. Neutral characters
+	// join a run only when it continues after them.
+	ltr := make([]bool, len(runes))
+	last := -1 // index of the last LTR rune with no RTL rune since
+	for i, r := range runes {
+		switch {
+		case isRTL(r):
+			last = -1
+		case isLTR(r):
+			start := i
+			if last >= 0 {
+				start = last + 1
+			}
+			for j := start; j <= i; j++ {
+				ltr[j] = true
+			}
+			last = i
+		}
+	}
+
+	out := make([]rune, 0, len(runes))
+	for end := len(runes); end > 0; {
+		start := end - 1
+		for start > 0 && ltr[start-1] == ltr[end-1] {
+			start--
+		}
+		if ltr[start] {
+			out = append(out, runes[start:end]...)
+		} else {
+			for i := end - 1; i >= start; i-- {
+				r := runes[i]
+				if m, ok := mirrored[r]; ok {
+					r = m
+				}
+				out = append(out, r)
+			}
+		}
+		end = start
+	}
+	return string(out)
+}
+
+// arabicForm describes how an Arabic letter joins its neighbours. Its
+// presentation forms are consecutive code points starting at base in the
+// order isolated, final, initial, medial; forms is 1 for letters that never
+// join, 2 for letters that join only to the preceding letter and 4 for
+// letters that join on both sides. A zero base keeps the letter unchanged.
+type arabicForm struct {
+	base  rune
+	forms int
+}
+
+var arabicForms = map[rune]arabicForm{
+	0x0621: {0xFE80, 1}, // hamza
+	0x0622: {0xFE81, 2}, // alef with madda above
+	0x0623: {0xFE83, 2}, // alef with hamza above
+	0x0624: {0xFE85, 2}, // waw with hamza above
+	0x0625: {0xFE87, 2}, // alef with hamza below
+	0x0626: {0xFE89, 4}, // yeh with hamza above
+	0x0627: {0xFE8D, 2}, // alef
+	0x0628: {0xFE8F, 4}, // beh
+	0x0629: {0xFE93, 2}, // teh marbuta
+	0x062A: {0xFE95, 4}, // teh
+	0x062B: {0xFE99, 4}, // theh
+	0x062C: {0xFE9D, 4}, // jeem
+	0x062D: {0xFEA1, 4}, // hah
+	0x062E: {0xFEA5, 4}, // khah
+	0x062F: {0xFEA9, 2}, // dal
+	0x0630: {0xFEAB, 2}, // thal
+	0x0631: {0xFEAD, 2}, // reh
+	0x0632: {0xFEAF, 2}, // zain
+	0x0633: {0xFEB1, 4}, // seen
+	0x0634: {0xFEB5, 4}, // sheen
+	0x0635: {0xFEB9, 4}, // sad
+	0x0636: {0xFEBD, 4}, // dad
+	0x0637: {0xFEC1, 4}, // tah
+	0x0638: {0xFEC5, 4}, // zah
+	0x0639: {0xFEC9, 4}, // ain
+	0x063A: {0xFECD, 4}, // ghain
+	0x0640: {0, 4},      // tatweel
+	0x0641: {0xFED1, 4}, // feh
+	0x0642: {0xFED5, 4}, // qaf
+	0x0643: {0xFED9, 4}, // kaf
+	0x0644: {0xFEDD, 4}, // lam
+	0x0645: {0xFEE1, 4}, // meem
+	0x0646: {0xFEE5, 4}, // noon
+	0x0647: {0xFEE9, 4}, // heh
+	0x0648: {0xFEED, 2}, // waw
+	0x0649: {0xFEEF, 2}, // alef maksura
+	0x064A: {0xFEF1, 4}, // yeh
+}
+
+// lamAlef maps the alef following a lam to the isolated form of the
+// ligature replacing both letters; the final form follows it.
+var lamAlef = map[rune]rune{
+	0x0622: 0xFEF5,
+	0x0623: 0xFEF7,
+	0x0625: 0xFEF9,
+	0x0627: 0xFEFB,
+}
+
+// isTransparent reports whether r is a combining mark that does not affect
+// joining, such as the Arabic short vowels.
+func isTransparent(r rune) bool {
+	return (r >= 0x064B && r <= 0x065F) || r == 0x0670
+}
+
+// neighbour returns the letter next to runes[i] in direction step (-1 or
+// 1), skipping transparent marks, or 0 at the start or end of the text.
+func neighbour(runes []rune, i, step int) rune {
+	for i += step; i >= 0 && i < len(runes); i += step {
+		if !isTransparent(runes[i]) {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+// shapeArabic replaces the Arabic letters of text, in logical order, with
+// the presentation forms matching their position in a word and combines
+// lam followed by alef into a single ligature.
+func shapeArabic(text string) string {
+	runes := []rune(text)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		form, ok := arabicForms[runes[i]]
+		if !ok {
+			out = append(out, runes[i])
+			continue
+		}
+		prev := arabicForms[neighbour(runes, i, -1)]
+		joinsPrev := prev.forms == 4 && form.forms > 1
+
+		if runes[i] == 0x0644 && i+1 < len(runes) {
+			if lig, ok := lamAlef[runes[i+1]]; ok {
+				if joinsPrev {
+					lig++
+				}
+				out = append(out, lig)
+				i++
+				continue
+			}
+		}
+
+		next := arabicForms[neighbour(runes, i, 1)]
+		joinsNext := form.forms == 4 && next.forms > 1
+
+		if form.base == 0 {
+			out = append(out, runes[i])
+			continue
+		}
+		switch {
+		case joinsPrev && joinsNext:
+			out = append(out, form.base+3)
+		case joinsNext:
+			out = append(out, form.base+2)
+		case joinsPrev:
+			out = append(out, form.base+1)
+		default:
+			out = append(out, form.base)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,43 @@
+package doctpl
+
+import "testing"
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"שלום", "םולש"},
+		{"abc def", "abc def"},
+		{"מחיר 42 ש", "ש 42 ריחמ"},
+		{"שלום hello world עולם", "םלוע hello world םולש"},
+		{"(שלום)", "(םולש)"},
+		{"סה\"כ: 1,200.50", "1,200.50 :כ\"הס"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := visualOrder(tt.in); got != tt.want {
+			t.Errorf("visualOrder(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// seen (initial), lam-alef ligature (final), meem (isolated)
+		{"سلام", "ﺳﻼﻡ"},
+		// beh (initial), teh (medial), beh (final)
+		{"ببب", "ﺑﺒﺐ"},
+		// dal only joins the preceding letter, so the noon after it stands alone
+		{"دن", "ﺩﻥ"},
+		// short vowels do not break the join
+		{"بَب", "ﺑَﺐ"},
+		{"abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got := shapeArabic(tt.in); got != tt.want {
+			t.Errorf("shapeArabic(%q) = %U, want %U", tt.in, []rune(got), []rune(tt.want))
+		}
+	}
+}
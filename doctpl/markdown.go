@@ -0,0 +1,456 @@
+package doctpl
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRule      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetext    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdFence     = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	mdListItem  = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])[ \t]+(.*)$`)
+	mdQuote     = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	mdTableRule = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// FromMarkdown converts Markdown text into a Document with a single page,
+// ready to pass to RenderDocument. It understands ATX and setext headings,
+// paragraphs with **bold**, *italic* and `code` spans, nested ordered and
+// unordered lists, fenced and indented code blocks, block quotes, GitHub
+// Flavored Markdown tables and thematic breaks. Links are reduced to their
+// text and raw HTML is kept as is.
+//
+// The text of the first level-1 heading also becomes the document title.
+func FromMarkdown(markdown string) *Document {
+	p := &mdParser{lines: strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")}
+	p.parse()
+	return &Document{Title: p.title, Pages: []Page{{Elements: p.elems}}}
+}
+
+// mdParser turns Markdown lines into elements one block at a time.
+type mdParser struct {
+	lines []string
+	pos   int
+	title string
+	elems []Element
+	para  []string // lines of the paragraph being collected
+}
+
+func (p *mdParser) parse() {
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			p.flushParagraph()
+			p.pos++
+		case len(p.para) > 0 && mdSetext.MatchString(line):
+			level := 2
+			if strings.HasPrefix(trimmed, "=") {
+				level = 1
+			}
+			text := strings.Join(p.para, " ")
+			p.para = nil
+			p.addHeading(level, text)
+			p.pos++
+		case mdRule.MatchString(line):
+			p.flushParagraph()
+			p.elems = append(p.elems, Element{Type: "hr"})
+			p.pos++
+		case mdHeading.MatchString(line):
+			p.flushParagraph()
+			m := mdHeading.FindStringSubmatch(line)
+			p.addHeading(len(m[1]), m[2])
+			p.pos++
+		case mdFence.MatchString(line):
+			p.flushParagraph()
+			p.parseFencedCode()
+		case len(p.para) == 0 && isIndentedCode(line):
+			p.parseIndentedCode()
+		case mdQuote.MatchString(line):
+			p.flushParagraph()
+			p.parseQuote()
+		case mdListItem.MatchString(line):
+			p.flushParagraph()
+			p.parseList()
+		case p.pos+1 < len(p.lines) && strings.Contains(line, "|") && mdTableRule.MatchString(p.lines[p.pos+1]):
+			p.flushParagraph()
+			p.parseTable()
+		default:
+			p.para = append(p.para, line)
+			p.pos++
+		}
+	}
+	p.flushParagraph()
+}
+
+func (p *mdParser) addHeading(level int, text string) {
+	text = plainText(parseInline(strings.TrimSpace(text)))
+	if level == 1 && p.title == "" {
+		p.title = text
+	}
+	p.elems = append(p.elems, Element{Type: "heading", Level: level, Text: text})
+}
+
+// flushParagraph emits the collected paragraph lines, if any. Lines ending
+// in two spaces or a backslash are hard line breaks; other line breaks
+// become spaces.
+func (p *mdParser) flushParagraph() {
+	if len(p.para) == 0 {
+		return
+	}
+	p.elems = append(p.elems, paragraph(p.para))
+	p.para = nil
+}
+
+func paragraph(lines []string) Element {
+	var b strings.Builder
+	for i, line := range lines {
+		hardBreak := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+		line = strings.TrimSpace(line)
+		if hardBreak {
+			line = strings.TrimSuffix(line, "\\")
+		}
+		b.WriteString(line)
+		if i < len(lines)-1 {
+			if hardBreak {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+	}
+	runs := parseInline(b.String())
+	if len(runs) == 1 && runs[0].Style == "" && runs[0].Family == "" {
+		return Element{Type: "paragraph", Text: runs[0].Text}
+	}
+	return Element{Type: "paragraph", Runs: runs}
+}
+
+func codeBlock(lines []string, language string) Element {
+	return Element{Type: "code", Text: strings.Join(lines, "\n"), Language: language}
+}
+
+func (p *mdParser) parseFencedCode() {
+	open := p.lines[p.pos]
+	indent := len(open) - len(strings.TrimLeft(open, " "))
+	fence := mdFence.FindStringSubmatch(open)[1]
+	language, _, _ := strings.Cut(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(open), fence[:1])), " ")
+	p.pos++
+
+	var code []string
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		p.pos++
+		if t := strings.TrimSpace(line); len(t) >= len(fence) && strings.Trim(t, fence[:1]) == "" {
+			break
+		}
+		for i := 0; i < indent && strings.HasPrefix(line, " "); i++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+	p.elems = append(p.elems, codeBlock(code, language))
+}
+
+func isIndentedCode(line string) bool {
+	return strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")
+}
+
+func (p *mdParser) parseIndentedCode() {
+	var code []string
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) != "" && !isIndentedCode(line) {
+			break
+		}
+		if strings.HasPrefix(line, "\t") {
+			line = line[1:]
+		} else {
+			line = strings.TrimPrefix(line, "    ")
+		}
+		code = append(code, line)
+		p.pos++
+	}
+	// Trailing blank lines belong to the surrounding text, not the block
+	for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+		code = code[:len(code)-1]
+	}
+	p.elems = append(p.elems, codeBlock(code, ""))
+}
+
+// parseQuote emits a block quote. Inline styling within it is dropped.
+func (p *mdParser) parseQuote() {
+	var lines []string
+	for p.pos < len(p.lines) {
+		m := mdQuote.FindStringSubmatch(p.lines[p.pos])
+		if m == nil {
+			break
+		}
+		lines = append(lines, m[1])
+		p.pos++
+	}
+	para := paragraph(lines)
+	text := para.Text
+	if len(para.Runs) > 0 {
+		text = plainText(para.Runs)
+	}
+	p.elems = append(p.elems, Element{Type: "blockquote", Text: text})
+}
+
+// parseList collects consecutive list items into a single list element.
+// Items indented further than their predecessor become its sub-items, and
+// unmarked lines indented under an item continue its text. Whether the
+// list is ordered is decided by its first item; a top-level item of the
+// other kind ends the list.
+func (p *mdParser) parseList() {
+	type level struct {
+		indent int
+		items  *[]ListItem
+	}
+	var root []ListItem
+	var stack []level
+	ordered := false
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		m := mdListItem.FindStringSubmatch(line)
+		if m == nil {
+			// A blank line ends the list unless another item follows it
+			if strings.TrimSpace(line) == "" {
+				if p.pos+1 < len(p.lines) && mdListItem.MatchString(p.lines[p.pos+1]) {
+					p.pos++
+					continue
+				}
+				break
+			}
+			if len(stack) == 0 || !isContinuation(line) {
+				break
+			}
+			top := stack[len(stack)-1].items
+			last := &(*top)[len(*top)-1]
+			last.Text += " " + plainText(parseInline(strings.TrimSpace(line)))
+			p.pos++
+			continue
+		}
+		if mdRule.MatchString(line) {
+			break
+		}
+
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+		isOrdered := !strings.ContainsAny(m[2], "-*+")
+		if len(stack) == 0 {
+			ordered = isOrdered
+			stack = append(stack, level{indent: indent, items: &root})
+		} else if indent <= stack[0].indent && isOrdered != ordered {
+			// Switching between bullets and numbers starts a new list
+			break
+		}
+		for len(stack) > 1 && indent < stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		if top := stack[len(stack)-1]; indent > top.indent {
+			// Deeper than the current level: nest under its last item
+			parent := &(*top.items)[len(*top.items)-1]
+			stack = append(stack, level{indent: indent, items: &parent.Items})
+		}
+		items := stack[len(stack)-1].items
+		*items = append(*items, ListItem{Text: plainText(parseInline(strings.TrimSpace(m[3])))})
+		p.pos++
+	}
+	p.elems = append(p.elems, Element{Type: "list", ListItems: root, Ordered: ordered})
+}
+
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
+
+// parseTable emits a GitHub Flavored Markdown table. The header row gives
+// the column titles and the delimiter row their alignment.
+func (p *mdParser) parseTable() {
+	headers := splitTableRow(p.lines[p.pos])
+	rule := splitTableRow(p.lines[p.pos+1])
+	p.pos += 2
+
+	cols := make([]TableColumn, len(headers))
+	for i, h := range headers {
+		cols[i].Header = plainText(parseInline(h))
+		if i < len(rule) {
+			left := strings.HasPrefix(rule[i], ":")
+			right := strings.HasSuffix(rule[i], ":")
+			switch {
+			case left && right:
+				cols[i].Align = "C"
+			case right:
+				cols[i].Align = "R"
+			}
+		}
+	}
+
+	var rows [][]string
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" || !strings.Contains(line, "|") {
+			break
+		}
+		cells := splitTableRow(line)
+		row := make([]string, len(cols))
+		for i := range row {
+			if i < len(cells) {
+				row[i] = plainText(parseInline(cells[i]))
+			}
+		}
+		rows = append(rows, row)
+		p.pos++
+	}
+	p.elems = append(p.elems, Element{Type: "table", Columns: cols, Rows: rows})
+}
+
+// splitTableRow splits a table row into trimmed cells, dropping optional
+// leading and trailing pipes. Escaped pipes (\|) stay within a cell.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseInline splits text into runs at emphasis and code span markers.
+// An opening marker only counts if a matching closing marker follows it;
+// otherwise it is kept as literal text.
+func parseInline(text string) []TextRun {
+	var runs []TextRun
+	var cur strings.Builder
+	bold, italic := false, false
+
+	emit := func(family string) {
+		if cur.Len() == 0 {
+			return
+		}
+		style := ""
+		if bold {
+			style += "B"
+		}
+		if italic {
+			style += "I"
+		}
+		run := TextRun{Text: cur.String(), Style: style, Family: family}
+		cur.Reset()
+		if n := len(runs); n > 0 && runs[n-1].Style == run.Style && runs[n-1].Family == run.Family {
+			runs[n-1].Text += run.Text
+			return
+		}
+		runs = append(runs, run)
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		rest := text[i:]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.ContainsRune("\\`*_[]()#+-.!|>~", rune(text[i+1])):
+			cur.WriteByte(text[i+1])
+			i++
+		case c == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			ticks := rest[:n]
+			end := strings.Index(rest[n:], ticks)
+			if end < 0 {
+				cur.WriteString(ticks)
+				i += n - 1
+				continue
+			}
+			emit("")
+			cur.WriteString(strings.TrimSpace(rest[n : n+end]))
+			emit("Courier")
+			i += n + end + n - 1
+		case (c == '*' || c == '_') && strings.HasPrefix(rest, string([]byte{c, c})):
+			marker := rest[:2]
+			if bold && i > 0 && text[i-1] != ' ' {
+				emit("")
+				bold = false
+				i++
+			} else if !bold && opens(text, i, marker) {
+				emit("")
+				bold = true
+				i++
+			} else {
+				cur.WriteString(marker)
+				i++
+			}
+		case c == '*' || c == '_':
+			if italic && i > 0 && text[i-1] != ' ' {
+				emit("")
+				italic = false
+			} else if !italic && opens(text, i, string(c)) {
+				emit("")
+				italic = true
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '[':
+			// [text](url) keeps only the link text
+			mid := strings.Index(rest, "](")
+			end := -1
+			if mid > 0 {
+				end = strings.IndexByte(rest[mid:], ')')
+			}
+			if end > 0 {
+				cur.WriteString(plainText(parseInline(rest[1:mid])))
+				i += mid + end
+				continue
+			}
+			cur.WriteByte(c)
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	emit("")
+	return runs
+}
+
+// opens reports whether the emphasis marker at text[i] starts a span: it
+// must be followed by a non-space character and closed later in the text.
+// Underscores inside words, as in snake_case, do not open a span.
+func opens(text string, i int, marker string) bool {
+	after := i + len(marker)
+	if after >= len(text) || text[after] == ' ' {
+		return false
+	}
+	if marker[0] == '_' && i > 0 && isWordByte(text[i-1]) {
+		return false
+	}
+	end := strings.Index(text[after:], marker)
+	return end > 0 && text[after+end-1] != ' '
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// plainText joins the text of runs, dropping their styling.
+func plainText(runs []TextRun) string {
+	var b strings.Builder
+	for _, r := range runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package doctpl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+func TestFromMarkdown(t *testing.T) {
+	md := "# Quarterly Report\n" +
+		"\n" +
+		"Revenue grew **12%** this quarter, driven by *new* customers\n" +
+		"and the `export` feature.\n" +
+		"\n" +
+		"## Highlights\n" +
+		"\n" +
+		"- Faster onboarding\n" +
+		"- Two new regions\n" +
+		"  - EU\n" +
+		"  - APAC\n" +
+		"- Lower churn\n" +
+		"\n" +
+		"1. Plan\n" +
+		"2. Ship\n" +
+		"\n" +
+		"---\n" +
+		"\n" +
+		"| Region | Sales |\n" +
+		"|:-------|------:|\n" +
+		"| EU     | 1,200 |\n" +
+		"| APAC   | 950   |\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"hi\")\n" +
+		"```\n" +
+		"\n" +
+		"> Quoted text\n"
+
+	doc := FromMarkdown(md)
+	if doc.Title != "Quarterly Report" {
+		t.Errorf("Title = %q, want Quarterly Report", doc.Title)
+	}
+	if len(doc.Pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(doc.Pages))
+	}
+	elems := doc.Pages[0].Elements
+
+	var types []string
+	for _, e := range elems {
+		types = append(types, e.Type)
+	}
+	wantTypes := []string{"heading", "paragraph", "heading", "list", "list", "hr", "table", "code", "blockquote"}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("element types = %v, want %v", types, wantTypes)
+	}
+
+	if h := elems[2]; h.Level != 2 || h.Text != "Highlights" {
+		t.Errorf("heading = level %d %q, want level 2 Highlights", h.Level, h.Text)
+	}
+
+	wantRuns := []TextRun{
+		{Text: "Revenue grew "},
+		{Text: "12%", Style: "B"},
+		{Text: " this quarter, driven by "},
+		{Text: "new", Style: "I"},
+		{Text: " customers and the "},
+		{Text: "export", Family: "Courier"},
+		{Text: " feature."},
+	}
+	if !reflect.DeepEqual(elems[1].Runs, wantRuns) {
+		t.Errorf("paragraph runs = %+v, want %+v", elems[1].Runs, wantRuns)
+	}
+
+	wantItems := []ListItem{
+		{Text: "Faster onboarding"},
+		{Text: "Two new regions", Items: []ListItem{{Text: "EU"}, {Text: "APAC"}}},
+		{Text: "Lower churn"},
+	}
+	if list := elems[3]; list.Ordered || !reflect.DeepEqual(list.ListItems, wantItems) {
+		t.Errorf("bullet list = ordered %v %+v, want %+v", list.Ordered, list.ListItems, wantItems)
+	}
+	if list := elems[4]; !list.Ordered || len(list.ListItems) != 2 {
+		t.Errorf("numbered list = ordered %v with %d items", list.Ordered, len(list.ListItems))
+	}
+
+	table := elems[6]
+	wantCols := []TableColumn{{Header: "Region"}, {Header: "Sales", Align: "R"}}
+	if !reflect.DeepEqual(table.Columns, wantCols) {
+		t.Errorf("table columns = %+v, want %+v", table.Columns, wantCols)
+	}
+	wantRows := [][]string{{"EU", "1,200"}, {"APAC", "950"}}
+	if !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Errorf("table rows = %v, want %v", table.Rows, wantRows)
+	}
+
+	if code := elems[7]; code.Text != `fmt.Println("hi")` || code.Language != "go" {
+		t.Errorf("code block = %q (%q), want go code", code.Text, code.Language)
+	}
+	if quote := elems[8]; quote.Text != "Quoted text" {
+		t.Errorf("quote = %q, want Quoted text", quote.Text)
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []TextRun
+	}{
+		{"plain text", []TextRun{{Text: "plain text"}}},
+		{"__bold__ and _italic_", []TextRun{{Text: "bold", Style: "B"}, {Text: " and "}, {Text: "italic", Style: "I"}}},
+		{"***both***", []TextRun{{Text: "both", Style: "BI"}}},
+		{"2 * 3 * 4", []TextRun{{Text: "2 * 3 * 4"}}},
+		{"snake_case_name", []TextRun{{Text: "snake_case_name"}}},
+		{`\*literal\*`, []TextRun{{Text: "*literal*"}}},
+		{"see [the docs](https://example.com)", []TextRun{{Text: "see the docs"}}},
+		{"unclosed `tick", []TextRun{{Text: "unclosed `tick"}}},
+	}
+	for _, tt := range tests {
+		if got := parseInline(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseInline(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	md := "# Title\n\nSome **bold** words.\n\n* one\n* two\n\n| A | B |\n|---|---|\n| x | y |\n"
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, FromMarkdown(md)); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := doc.Page(1)
+	text, err := page.ExtractText()
+	if err != nil {
+		t.Fatalf("extracting text: %v", err)
+	}
+	for _, want := range []string{"Title", "bold", "words.", "one", "two", "x", "y"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("rendered text missing %q:\n%s", want, text)
+		}
+	}
+}
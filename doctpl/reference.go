@@ -0,0 +1,163 @@
+package doctpl
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ElementInfo describes a supported element type and the fields it uses.
+type ElementInfo struct {
+	Type        string         `json:"type"`
+	Description string         `json:"description"`
+	Fields      []ElementField `json:"fields"`
+}
+
+// ElementField describes a single field of an element type.
+type ElementField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // JSON type: string, number, boolean, array, object
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description"`
+}
+
+// fieldDoc holds the hand-written part of a field description. Names and
+// types are derived from the Element struct.
+type fieldDoc struct {
+	def, desc string
+}
+
+var elementFieldDocs = map[string]fieldDoc{
+	"Text":         {"", "text content"},
+	"Language":     {"", "language of the code, for reference only"},
+	"Runs":         {"", "inline-styled text {text, style, family, footnote}; overrides text and is set flush left"},
+	"Level":        {"1", "heading level 1-6; for a bookmark, the outline level"},
+	"Title":        {"text", "text of the outline entry"},
+	"Align":        {"L (rtl: R)", "horizontal alignment: L, C, R or J"},
+	"Dir":          {"document dir", "text direction: ltr or rtl; rtl reorders the text and joins Arabic letters"},
+	"Font":         {"document font", "font override {family, style, size}"},
+	"Color":        {"black", "text or line color {r, g, b}"},
+	"LineHeight":   {"0.5 mm per point of font size", "line height in document units"},
+	"Style":        {"", "name of an entry in the document styles map"},
+	"Columns":      {"", "column definitions {header, width, align, headerColspan}; width 0 means auto"},
+	"Rows":         {"", "data rows, each an array of cell strings"},
+	"Cells":        {"", "data rows added after rows, each an array of cells: strings or {text, colspan, align, fillColor, textColor, font}"},
+	"HeaderStyle":  {"blue header, white bold text", "header cell style {fillColor, textColor, font}"},
+	"CellStyle":    {"", "data cell style {fillColor, textColor, font}"},
+	"Src":          {"", "image file path"},
+	"X":            {"current position", "left edge"},
+	"Y":            {"current position", "top edge"},
+	"Width":        {"", "width; 0 keeps the aspect ratio"},
+	"Height":       {"", "height; 0 keeps the aspect ratio"},
+	"Fit":          {"", "scale keeping the aspect ratio: width (span the content width), contain (fit in width x height) or cover (fill width x height, cropped)"},
+	"X1":           {"", "start x"},
+	"Y1":           {"", "start y"},
+	"X2":           {"", "end x"},
+	"Y2":           {"", "end y"},
+	"SpacerHeight": {"10 mm", "vertical space to add"},
+	"LineWidth":    {"0.2 mm (hr: 0.3 mm)", "stroke width"},
+	"Items":        {"", "flat list of item strings"},
+	"ListItems":    {"", "nested items: strings or {text, items}; overrides items"},
+	"Ordered":      {"false", "number items (1., a., i. by level) instead of bullets"},
+	"BulletStr":    {"•", "custom bullet character"},
+	"FillColor":    {"", "fill color {r, g, b}"},
+	"Border":       {"false", "draw the outline"},
+	"RepeatItems":  {"", "data records; each is an object of field values"},
+	"Template":     {"", "element rendered once per record with {{field}} placeholders substituted"},
+	"Kind":         {"note", "callout kind: note, tip, warning or danger"},
+	"AccentColor":  {"depends on kind", "color of the left bar {r, g, b}"},
+	"Elements":     {"", "child elements, rendered in order"},
+	"KeepTogether": {"false", "start a new page first if the element does not fit in the space left"},
+}
+
+// elementTypes lists the supported element types with their Element fields,
+// given by Go field name.
+var elementTypes = []struct {
+	typ, desc string
+	fields    []string
+}{
+	{"heading", "Bold heading with spacing before and after.",
+		[]string{"Text", "Level", "Align", "Dir", "Font", "Color", "LineHeight", "Style"}},
+	{"paragraph", "Block of wrapped body text. \"text\" is an alias.",
+		[]string{"Text", "Runs", "Align", "Dir", "Font", "Color", "LineHeight", "Style"}},
+	{"code", "Monospaced block on a shaded background; line breaks and indentation are kept.",
+		[]string{"Text", "Language", "Font", "Color", "FillColor", "LineHeight", "Style"}},
+	{"blockquote", "Indented quotation with a gray left bar on a tinted background.",
+		[]string{"Text", "Align", "Font", "Color", "FillColor", "AccentColor", "LineHeight", "Style"}},
+	{"callout", "Highlighted note with a colored left bar; the kind picks the colors.",
+		[]string{"Text", "Kind", "Align", "Font", "Color", "FillColor", "AccentColor", "LineHeight", "Style"}},
+	{"table", "Table with a header row and striped data rows.",
+		[]string{"Columns", "Rows", "Cells", "HeaderStyle", "CellStyle"}},
+	{"image", "JPEG, PNG or GIF image placed at a position or in the flow.",
+		[]string{"Src", "X", "Y", "Width", "Height", "Fit"}},
+	{"line", "Straight line between two absolute points.",
+		[]string{"X1", "Y1", "X2", "Y2", "LineWidth", "Color", "Style"}},
+	{"rect", "Rectangle at an absolute position, filled and/or outlined.",
+		[]string{"X", "Y", "Width", "Height", "FillColor", "Border"}},
+	{"spacer", "Vertical whitespace.",
+		[]string{"SpacerHeight"}},
+	{"hr", "Horizontal rule across the content width.",
+		[]string{"LineWidth", "Color", "Style"}},
+	{"list", "Bulleted or numbered list, optionally nested.",
+		[]string{"Items", "ListItems", "Ordered", "BulletStr", "Font", "LineHeight", "Style"}},
+	{"repeat", "Renders a template element once per data record.",
+		[]string{"RepeatItems", "Template"}},
+	{"group", "Keeps its elements on one page, e.g. a heading with its first paragraph.",
+		[]string{"Elements"}},
+	{"bookmark", "Invisible outline entry pointing at the current position; headings add one automatically.",
+		[]string{"Title", "Text", "Level"}},
+}
+
+// ElementReference returns a description of every supported element type
+// with its fields, their JSON types, defaults, and a short description.
+func ElementReference() []ElementInfo {
+	elemType := reflect.TypeOf(Element{})
+	infos := make([]ElementInfo, 0, len(elementTypes))
+	for _, et := range elementTypes {
+		info := ElementInfo{Type: et.typ, Description: et.desc}
+		for _, name := range et.fields {
+			sf, ok := elemType.FieldByName(name)
+			if !ok {
+				continue
+			}
+			doc := elementFieldDocs[name]
+			info.Fields = append(info.Fields, ElementField{
+				Name:        jsonFieldName(sf),
+				Type:        jsonTypeName(sf.Type),
+				Default:     doc.def,
+				Description: doc.desc,
+			})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// jsonFieldName returns the JSON key of an Element field. RepeatItems is
+// encoded by hand under the "items" key.
+func jsonFieldName(sf reflect.StructField) string {
+	if sf.Name == "RepeatItems" {
+		return "items"
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+// jsonTypeName maps a Go type to the JSON type it is encoded as.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonTypeName(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
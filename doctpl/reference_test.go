@@ -0,0 +1,48 @@
+package doctpl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestElementReferenceCoversSchema(t *testing.T) {
+	elemType := reflect.TypeOf(Element{})
+	for _, et := range elementTypes {
+		for _, name := range et.fields {
+			if _, ok := elemType.FieldByName(name); !ok {
+				t.Errorf("%s: Element has no field %s", et.typ, name)
+			}
+			if _, ok := elementFieldDocs[name]; !ok {
+				t.Errorf("%s: field %s is not documented", et.typ, name)
+			}
+		}
+	}
+
+	for _, info := range ElementReference() {
+		doc := Document{Pages: []Page{{Elements: []Element{{Type: info.Type}}}}}
+		var buf bytes.Buffer
+		err := RenderDocument(&buf, &doc)
+		if err != nil && strings.Contains(err.Error(), "unknown element type") {
+			t.Errorf("reference lists type %q that the renderer does not handle", info.Type)
+		}
+	}
+}
+
+func TestElementReferenceTable(t *testing.T) {
+	for _, info := range ElementReference() {
+		if info.Type != "table" {
+			continue
+		}
+		types := make(map[string]string)
+		for _, f := range info.Fields {
+			types[f.Name] = f.Type
+		}
+		if types["columns"] != "array" || types["rows"] != "array" {
+			t.Fatalf("unexpected table fields: %+v", info.Fields)
+		}
+		return
+	}
+	t.Fatal("table element missing from reference")
+}
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	gofpdf "github.com/lvillar/gofpdf"
 	"github.com/lvillar/gofpdf/table"
@@ -19,25 +23,115 @@ func Render(w io.Writer, jsonTemplate []byte) error {
 	return RenderDocument(w, &doc)
 }
 
-// RenderDocument renders a Document struct to a PDF written to w.
+// pageCountAlias is replaced with the total number of pages when the PDF is
+// written.
+const pageCountAlias = "{nb}"
+
+// RenderDocument renders a Document struct to a PDF written to w. An
+// element that fails to render is reported as a *RenderError.
 func RenderDocument(w io.Writer, doc *Document) error {
+	_, err := RenderDocumentWithOptions(w, doc, RenderOptions{})
+	return err
+}
+
+// RenderOptions controls how RenderDocumentWithOptions lays out a document.
+type RenderOptions struct {
+	// TwoPass renders the document twice. The first pass is discarded and
+	// only measures the layout, which the second pass can then rely on:
+	// the {pages} footer placeholder becomes the actual page count rather
+	// than an alias substituted when the file is written. This takes
+	// roughly twice as long as a single pass.
+	TwoPass bool
+}
+
+// Layout describes where the content of a rendered document ended up.
+type Layout struct {
+	Pages    int               // total number of pages
+	Headings []HeadingPosition // in document order
+}
+
+// HeadingPosition records the page a heading was placed on.
+type HeadingPosition struct {
+	Text  string
+	Level int
+	Page  int // 1-based
+}
+
+// RenderError reports an element that failed to render. Errors from
+// elements nested in a group or repeat element are reported against the
+// top-level element containing them.
+type RenderError struct {
+	Page         int    // 1-based page number
+	ElementIndex int    // 0-based index of the element in the page's Elements
+	Type         string // type of the element
+	Err          error  // underlying error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("doctpl: page %d, element %d (%s): %v", e.Page, e.ElementIndex+1, e.Type, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// RenderDocumentWithOptions renders a Document struct to a PDF written to
+// w, as RenderDocument does, and returns its layout.
+func RenderDocumentWithOptions(w io.Writer, doc *Document, opts RenderOptions) (*Layout, error) {
+	var measured *Layout
+	if opts.TwoPass {
+		var err error
+		if measured, err = renderPass(nil, doc, nil); err != nil {
+			return nil, err
+		}
+	}
+	return renderPass(w, doc, measured)
+}
+
+// renderer holds the state of a single rendering pass over doc.
+type renderer struct {
+	doc *Document
+
+	outlineDepth   int      // depth of the last outline entry, -1 before the first
+	layout         *Layout  // layout recorded so far
+	footnotes      []string // notes referenced on the current page
+	footnoteCount  int      // notes numbered so far
+	footnoteHeight float64  // space reserved for footnotes on the current page
+	bottomMargin   float64  // page break margin without footnotes
+	flowBreak      float64  // line feed closing an open line of flowing text, 0 if none
+}
+
+// renderPass lays out doc and writes the PDF to w, or only measures it if w
+// is nil. measured is the layout found by an earlier measurement pass, or
+// nil if there was none.
+func renderPass(w io.Writer, doc *Document, measured *Layout) (*Layout, error) {
+	r := &renderer{doc: doc, outlineDepth: -1, layout: &Layout{}}
+
 	pageSize := doc.PageSize
 	if pageSize == "" {
 		pageSize = "A4"
 	}
 	unit := doc.Unit
-	if unit == "" {
+	switch unit {
+	case "":
 		unit = "mm"
+	case "mm", "cm", "in", "pt":
+	default:
+		return nil, fmt.Errorf("doctpl: unknown unit %q", doc.Unit)
 	}
 
-	pdf := gofpdf.New("P", unit, pageSize, "")
+	docSize, err := parsePageSize(pageSize, unit)
+	if err != nil {
+		return nil, fmt.Errorf("doctpl: %w", err)
+	}
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: unit, Size: docSize})
 
 	// Apply margins
 	if doc.Margin != nil {
 		pdf.SetMargins(doc.Margin.Left, doc.Margin.Top, doc.Margin.Right)
 		pdf.SetAutoPageBreak(true, doc.Margin.Bottom)
 	} else {
-		pdf.SetAutoPageBreak(true, 15)
+		pdf.SetAutoPageBreak(true, mm(pdf, 15))
 	}
 
 	// Set metadata
@@ -51,6 +145,9 @@ func RenderDocument(w io.Writer, doc *Document) error {
 		pdf.SetSubject(doc.Subject, true)
 	}
 
+	_, r.bottomMargin = pdf.GetAutoPageBreak()
+	registerFonts(pdf, doc)
+
 	// Default font
 	defaultFont := Font{Family: "Helvetica", Style: "", Size: 11}
 	if doc.Font != nil {
@@ -63,33 +160,65 @@ func RenderDocument(w io.Writer, doc *Document) error {
 		defaultFont.Style = doc.Font.Style
 	}
 
-	// Set up header/footer callbacks
-	if doc.Header != nil {
-		hdr := *doc.Header
+	// Set up header/footer callbacks. The background is painted first thing
+	// on each page; the watermark is drawn last so it overlays the content.
+	if doc.Header != nil || doc.Background != nil {
 		pdf.SetHeaderFunc(func() {
-			renderHeader(pdf, hdr, defaultFont)
+			if doc.Background != nil {
+				renderBackground(pdf, *doc.Background)
+			}
+			if doc.Header != nil {
+				renderHeader(pdf, *doc.Header, defaultFont)
+			}
 		})
 	}
-	if doc.Footer != nil {
-		ftr := *doc.Footer
-		pdf.SetFooterFunc(func() {
-			renderFooter(pdf, ftr, defaultFont)
-		})
+	// Footnotes are set above the footer of the page referencing them.
+	pages := pageCountAlias
+	if measured != nil {
+		pages = strconv.Itoa(measured.Pages)
 	}
+	if doc.Footer != nil || doc.Watermark != nil {
+		pdf.AliasNbPages(pageCountAlias)
+	}
+	pdf.SetFooterFunc(func() {
+		r.renderFootnotes(pdf, defaultFont)
+		if doc.Footer != nil {
+			renderFooter(pdf, *doc.Footer, defaultFont, pages)
+		}
+		if doc.Watermark != nil {
+			renderWatermark(pdf, *doc.Watermark)
+		}
+	})
 
 	// Render pages
 	for pageIdx, page := range doc.Pages {
-		if page.Size != "" && page.Size != pageSize {
-			pdf.AddPageFormat("P", pdf.GetPageSizeStr(page.Size))
+		orientation := strings.ToUpper(page.Orientation)
+		switch orientation {
+		case "", "P":
+			orientation = "P"
+		case "L":
+		default:
+			return nil, fmt.Errorf("doctpl: page %d: unknown orientation %q", pageIdx+1, page.Orientation)
+		}
+		size := docSize
+		if page.Size != "" {
+			var err error
+			if size, err = parsePageSize(page.Size, unit); err != nil {
+				return nil, fmt.Errorf("doctpl: page %d: %w", pageIdx+1, err)
+			}
+		}
+		if size != docSize || orientation != "P" {
+			pdf.AddPageFormat(orientation, size)
 		} else {
 			pdf.AddPage()
 		}
 
 		pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+		r.flowBreak = 0
 
-		for _, elem := range page.Elements {
-			if err := renderElement(pdf, elem, defaultFont); err != nil {
-				return fmt.Errorf("doctpl: page %d: %w", pageIdx+1, err)
+		for elemIdx, elem := range page.Elements {
+			if err := r.renderElement(pdf, elem, defaultFont); err != nil {
+				return nil, &RenderError{Page: pageIdx + 1, ElementIndex: elemIdx, Type: elem.Type, Err: err}
 			}
 		}
 	}
@@ -100,20 +229,111 @@ func RenderDocument(w io.Writer, doc *Document) error {
 	}
 
 	if pdf.Err() {
-		return fmt.Errorf("doctpl: %w", pdf.Error())
+		return nil, fmt.Errorf("doctpl: %w", pdf.Error())
 	}
+	r.layout.Pages = pdf.PageNo()
 
-	return pdf.Output(w)
+	if w == nil {
+		return r.layout, nil
+	}
+	if err := pdf.Output(w); err != nil {
+		return nil, err
+	}
+	return r.layout, nil
+}
+
+// mm converts a length in millimetres to the document unit. The fixed
+// spacings used by the renderers are given in millimetres, so that they
+// look the same whatever unit the document uses.
+func mm(pdf *gofpdf.Fpdf, v float64) float64 {
+	return pdf.PointToUnitConvert(v * 72 / 25.4)
+}
+
+// pageSizes holds the named page sizes, in points, keyed by lower-case
+// name.
+var pageSizes = map[string]gofpdf.SizeType{
+	"a1":      {Wd: 1683.78, Ht: 2383.94},
+	"a2":      {Wd: 1190.55, Ht: 1683.78},
+	"a3":      {Wd: 841.89, Ht: 1190.55},
+	"a4":      {Wd: 595.28, Ht: 841.89},
+	"a5":      {Wd: 420.94, Ht: 595.28},
+	"a6":      {Wd: 297.64, Ht: 420.94},
+	"letter":  {Wd: 612, Ht: 792},
+	"legal":   {Wd: 612, Ht: 1008},
+	"tabloid": {Wd: 792, Ht: 1224},
 }
 
-func renderElement(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+// unitPoints holds the size of each document unit in points.
+var unitPoints = map[string]float64{"pt": 1, "mm": 72 / 25.4, "cm": 72 / 2.54, "in": 72}
+
+// parsePageSize returns the dimensions, in unit, of a page size: a name
+// from pageSizes such as "A4", in any case, or "WxH" in unit, such as
+// "8.5x11", or followed by a unit of its own, such as "210x297mm".
+func parsePageSize(s, unit string) (gofpdf.SizeType, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if size, ok := pageSizes[name]; ok {
+		return gofpdf.SizeType{Wd: size.Wd / unitPoints[unit], Ht: size.Ht / unitPoints[unit]}, nil
+	}
+
+	scale := 1.0
+	for u, pts := range unitPoints {
+		if rest, ok := strings.CutSuffix(name, u); ok {
+			name, scale = rest, pts/unitPoints[unit]
+			break
+		}
+	}
+	if w, h, ok := strings.Cut(name, "x"); ok {
+		wd, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		ht, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+		if err1 == nil && err2 == nil && wd > 0 && ht > 0 {
+			return gofpdf.SizeType{Wd: wd * scale, Ht: ht * scale}, nil
+		}
+	}
+	return gofpdf.SizeType{}, fmt.Errorf("unknown page size %q (want A1 to A6, Letter, Legal, Tabloid or WxH such as 210x297mm)", s)
+}
+
+// registerFonts adds the TrueType fonts listed in doc.Fonts to pdf.
+func registerFonts(pdf *gofpdf.Fpdf, doc *Document) {
+	for _, f := range doc.Fonts {
+		pdf.AddUTF8Font(f.Family, strings.ToUpper(f.Style), f.File)
+	}
+}
+
+func (r *renderer) renderElement(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+	if elem.Style != "" {
+		style, ok := r.doc.Styles[elem.Style]
+		if !ok {
+			return fmt.Errorf("unknown style %q", elem.Style)
+		}
+		elem = applyStyle(elem, style)
+	}
+	if elem.Dir == "" {
+		elem.Dir = r.doc.Dir
+	}
+
+	// Close the line of flowing text left open by an earlier paragraph
+	if r.flowBreak > 0 && !flows(elem) {
+		pdf.Ln(r.flowBreak)
+		r.flowBreak = 0
+	}
+
+	if elem.KeepTogether || elem.Type == "group" {
+		if err := r.keepTogether(pdf, elem, defaultFont); err != nil {
+			return err
+		}
+	}
+
 	switch elem.Type {
 	case "heading":
-		return renderHeading(pdf, elem, defaultFont)
+		return r.renderHeading(pdf, elem, defaultFont)
 	case "paragraph", "text":
-		return renderParagraph(pdf, elem, defaultFont)
+		return r.renderParagraph(pdf, elem, defaultFont)
+	case "code":
+		renderCode(pdf, elem, defaultFont)
+	case "blockquote", "callout":
+		return renderQuote(pdf, elem, defaultFont)
 	case "table":
-		return renderTable(pdf, elem, defaultFont)
+		return r.renderTable(pdf, elem, defaultFont)
 	case "image":
 		return renderImage(pdf, elem)
 	case "line":
@@ -126,13 +346,58 @@ func renderElement(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 		renderHR(pdf, elem)
 	case "list":
 		renderList(pdf, elem, defaultFont)
+	case "repeat":
+		return r.renderRepeat(pdf, elem, defaultFont)
+	case "group":
+		return r.renderElements(pdf, elem.Elements, defaultFont)
+	case "bookmark":
+		return r.renderBookmark(pdf, elem)
 	default:
 		return fmt.Errorf("unknown element type %q", elem.Type)
 	}
 	return nil
 }
 
-func renderHeading(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+// applyStyle fills the fields of elem that are not set inline from the named
+// style. Font properties are merged individually.
+func applyStyle(elem Element, style Style) Element {
+	if style.Font != nil {
+		font := *style.Font
+		if elem.Font != nil {
+			if elem.Font.Family != "" {
+				font.Family = elem.Font.Family
+			}
+			if elem.Font.Style != "" {
+				font.Style = elem.Font.Style
+			}
+			if elem.Font.Size > 0 {
+				font.Size = elem.Font.Size
+			}
+		}
+		elem.Font = &font
+	}
+	if elem.Color == nil {
+		elem.Color = style.Color
+	}
+	if elem.Align == "" {
+		elem.Align = style.Align
+	}
+	if elem.LineHeight == 0 {
+		elem.LineHeight = style.LineHeight
+	}
+	return elem
+}
+
+// lineHeight returns the line height for text set at the given font size,
+// honouring an explicit element line height.
+func lineHeight(pdf *gofpdf.Fpdf, elem Element, size float64) float64 {
+	if elem.LineHeight > 0 {
+		return elem.LineHeight
+	}
+	return mm(pdf, size*0.5)
+}
+
+func (r *renderer) renderHeading(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 	level := elem.Level
 	if level < 1 {
 		level = 1
@@ -167,22 +432,36 @@ func renderHeading(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 
 	// Add spacing before heading
 	if level <= 2 {
-		pdf.Ln(size * 0.4)
+		pdf.Ln(mm(pdf, size*0.4))
 	} else {
-		pdf.Ln(size * 0.3)
+		pdf.Ln(mm(pdf, size*0.3))
 	}
 
-	align := "L"
-	if elem.Align != "" {
-		align = strings.ToUpper(elem.Align)
+	rtl, err := isRTLElement(elem)
+	if err != nil {
+		return err
 	}
+	align := textAlign(elem, rtl)
 
 	pageW, _ := pdf.GetPageSize()
-	lm, _, rm, _ := pdf.GetMargins()
+	lm, tm, rm, _ := pdf.GetMargins()
 	contentW := pageW - lm - rm
 
-	pdf.MultiCell(contentW, size*0.5, elem.Text, "", align, false)
-	pdf.Ln(size * 0.2)
+	page, top := pdf.PageNo(), pdf.GetY()
+	if rtl {
+		renderRTL(pdf, contentW, lineHeight(pdf, elem, size), elem.Text, align)
+	} else {
+		pdf.MultiCell(contentW, lineHeight(pdf, elem, size), elem.Text, "", align, false)
+	}
+	r.layout.Headings = append(r.layout.Headings, HeadingPosition{Text: elem.Text, Level: level, Page: pdf.PageNo()})
+	if r.doc.Outline == nil || *r.doc.Outline {
+		// The heading moved to the next page if it did not fit
+		if pdf.PageNo() != page {
+			top = tm
+		}
+		r.addBookmark(pdf, elem.Text, level, top)
+	}
+	pdf.Ln(mm(pdf, size*0.2))
 
 	// Reset font and color
 	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
@@ -193,7 +472,59 @@ func renderHeading(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 	return nil
 }
 
-func renderParagraph(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+// renderBookmark adds an outline entry pointing at the current position.
+func (r *renderer) renderBookmark(pdf *gofpdf.Fpdf, elem Element) error {
+	title := elem.Title
+	if title == "" {
+		title = elem.Text
+	}
+	if title == "" {
+		return fmt.Errorf("bookmark has no title")
+	}
+	r.addBookmark(pdf, title, elem.Level, pdf.GetY())
+	return nil
+}
+
+// addBookmark adds an outline entry for position y on the current page.
+// Level 1 is the top of the outline. An entry can be at most one level
+// below the one before it, so deeper levels are raised to fit; a level 3
+// heading directly under a level 1 heading becomes its child.
+func (r *renderer) addBookmark(pdf *gofpdf.Fpdf, title string, level int, y float64) {
+	depth := level - 1
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > r.outlineDepth+1 {
+		depth = r.outlineDepth + 1
+	}
+	r.outlineDepth = depth
+	pdf.Bookmark(title, depth, y)
+}
+
+// isRTLElement reports whether elem's text runs right to left.
+func isRTLElement(elem Element) (bool, error) {
+	switch strings.ToLower(elem.Dir) {
+	case "", "ltr":
+		return false, nil
+	case "rtl":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown dir %q", elem.Dir)
+}
+
+// textAlign returns the alignment of a text element, which defaults to the
+// start of its reading direction.
+func textAlign(elem Element, rtl bool) string {
+	switch {
+	case elem.Align != "":
+		return strings.ToUpper(elem.Align)
+	case rtl:
+		return "R"
+	}
+	return "L"
+}
+
+func (r *renderer) renderParagraph(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 	family := defaultFont.Family
 	style := defaultFont.Style
 	size := defaultFont.Size
@@ -216,17 +547,45 @@ func renderParagraph(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 
 	pdf.SetFont(family, style, size)
 
-	align := "L"
-	if elem.Align != "" {
-		align = strings.ToUpper(elem.Align)
+	rtl, err := isRTLElement(elem)
+	if err != nil {
+		return err
 	}
+	align := textAlign(elem, rtl)
 
 	pageW, _ := pdf.GetPageSize()
 	lm, _, rm, _ := pdf.GetMargins()
 	contentW := pageW - lm - rm
 
-	pdf.MultiCell(contentW, size*0.5, elem.Text, "", align, false)
-	pdf.Ln(size * 0.3)
+	if flows(elem) {
+		runs := elem.Runs
+		if len(runs) == 0 {
+			runs = []TextRun{{Text: elem.Text}}
+		}
+		if r.flowBreak == 0 {
+			pdf.SetX(lm)
+		}
+		lh := lineHeight(pdf, elem, size)
+		if err := r.writeRuns(pdf, runs, family, style, size, lh, defaultFont); err != nil {
+			return err
+		}
+		r.flowBreak = lh + mm(pdf, size*0.3)
+	} else {
+		if rtl {
+			text := elem.Text
+			if len(elem.Runs) > 0 {
+				text = plainText(elem.Runs)
+			}
+			renderRTL(pdf, contentW, lineHeight(pdf, elem, size), text, align)
+		} else if len(elem.Runs) > 0 {
+			if err := r.renderRuns(pdf, elem.Runs, family, style, size, lineHeight(pdf, elem, size), defaultFont); err != nil {
+				return err
+			}
+		} else {
+			pdf.MultiCell(contentW, lineHeight(pdf, elem, size), elem.Text, "", align, false)
+		}
+		pdf.Ln(mm(pdf, size*0.3))
+	}
 
 	// Reset
 	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
@@ -237,7 +596,331 @@ func renderParagraph(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 	return nil
 }
 
-func renderTable(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+// flows reports whether elem is a paragraph written as flowing text.
+func flows(elem Element) bool {
+	return elem.Flow && (elem.Type == "paragraph" || elem.Type == "text") && !strings.EqualFold(elem.Dir, "rtl")
+}
+
+// renderRuns writes text runs in sequence from the left margin, wrapping
+// at the right margin, and moves to the next line.
+func (r *renderer) renderRuns(pdf *gofpdf.Fpdf, runs []TextRun, family, style string, size, lh float64, defaultFont Font) error {
+	lm, _, _, _ := pdf.GetMargins()
+	pdf.SetX(lm)
+	if err := r.writeRuns(pdf, runs, family, style, size, lh, defaultFont); err != nil {
+		return err
+	}
+	pdf.Ln(lh)
+	return nil
+}
+
+// writeRuns writes text runs in sequence from the current position,
+// wrapping at the right margin, and leaves the position after the last
+// one. Runs use the paragraph font unless they set a family or style.
+// Inline images are drawn before the text of their run; footnote
+// references are written after it as superscript numbers.
+func (r *renderer) writeRuns(pdf *gofpdf.Fpdf, runs []TextRun, family, style string, size, lh float64, defaultFont Font) error {
+	for _, run := range runs {
+		if run.Image != "" {
+			if err := writeImage(pdf, run, size, lh); err != nil {
+				return err
+			}
+		}
+		runFamily, runStyle := family, style
+		if run.Family != "" {
+			runFamily = run.Family
+		}
+		if run.Style != "" {
+			runStyle = run.Style
+		}
+		pdf.SetFont(runFamily, runStyle, size)
+		pdf.Write(lh, run.Text)
+		if run.Footnote != "" {
+			n := r.addFootnote(pdf, run.Footnote, defaultFont)
+			pdf.SetFont(runFamily, runStyle, size)
+			pdf.SubWrite(lh, strconv.Itoa(n), size*0.6, size*0.4, 0, "")
+		}
+	}
+	return nil
+}
+
+// writeImage draws the inline image of run at the current position,
+// centered on a line of height lh, and moves past it. It moves to the next
+// line first if the image does not fit before the right margin.
+func writeImage(pdf *gofpdf.Fpdf, run TextRun, size, lh float64) error {
+	info := pdf.RegisterImageOptions(run.Image, gofpdf.ImageOptions{})
+	if pdf.Err() {
+		return fmt.Errorf("image %q: %w", run.Image, pdf.Error())
+	}
+	h := run.ImageHeight
+	if h <= 0 {
+		h = pdf.PointToUnitConvert(size)
+	}
+	iw, ih := info.Extent()
+	w := h * iw / ih
+
+	pageW, _ := pdf.GetPageSize()
+	lm, _, rm, _ := pdf.GetMargins()
+	if x := pdf.GetX(); x > lm && x+w > pageW-rm {
+		pdf.Ln(lh)
+	}
+	// An empty cell takes up the space, breaking the page if needed
+	pdf.CellFormat(w, lh, "", "", 0, "", false, 0, "")
+	pdf.ImageOptions(run.Image, pdf.GetX()-w, pdf.GetY()+(lh-h)/2, w, h, false, gofpdf.ImageOptions{}, 0, "")
+	return nil
+}
+
+// footnoteSize is the font size of footnote text, in points.
+const footnoteSize = 8.0
+
+// addFootnote queues a note for the bottom of the current page and returns
+// its number. The page break margin grows by the height of the note so
+// that the text above stops short of it.
+func (r *renderer) addFootnote(pdf *gofpdf.Fpdf, text string, defaultFont Font) int {
+	pageW, _ := pdf.GetPageSize()
+	lm, _, rm, _ := pdf.GetMargins()
+
+	if len(r.footnotes) == 0 {
+		r.footnoteHeight = mm(pdf, 3) // separator rule and the gap above it
+	}
+	r.footnoteCount++
+	r.footnotes = append(r.footnotes, text)
+
+	pdf.SetFont(defaultFont.Family, "", footnoteSize)
+	lines := pdf.SplitLines([]byte(footnoteText(r.footnoteCount, text)), pageW-lm-rm)
+	r.footnoteHeight += float64(len(lines)) * mm(pdf, footnoteSize*0.5)
+
+	auto, _ := pdf.GetAutoPageBreak()
+	pdf.SetAutoPageBreak(auto, r.bottomMargin+r.footnoteHeight)
+	return r.footnoteCount
+}
+
+// footnoteText returns note n as printed at the foot of the page.
+func footnoteText(n int, text string) string {
+	return strconv.Itoa(n) + "  " + text
+}
+
+// renderFootnotes draws the notes referenced on the current page below a
+// short rule, above the bottom margin, and resets the page break margin
+// for the next page. It runs from the footer callback.
+func (r *renderer) renderFootnotes(pdf *gofpdf.Fpdf, defaultFont Font) {
+	if len(r.footnotes) == 0 {
+		return
+	}
+	pageW, pageH := pdf.GetPageSize()
+	lm, _, rm, _ := pdf.GetMargins()
+	contentW := pageW - lm - rm
+
+	// Start in the reserved space, or below the last line if a reference
+	// near the bottom of the page pushed the text into it.
+	y := pageH - r.bottomMargin - r.footnoteHeight
+	if y < pdf.GetY() {
+		y = pdf.GetY()
+	}
+	y += mm(pdf, 1.5)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(mm(pdf, 0.2))
+	pdf.Line(lm, y, lm+contentW/3, y)
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont(defaultFont.Family, "", footnoteSize)
+	pdf.SetY(y + mm(pdf, 1.5))
+	first := r.footnoteCount - len(r.footnotes) + 1
+	for i, text := range r.footnotes {
+		pdf.MultiCell(contentW, mm(pdf, footnoteSize*0.5), footnoteText(first+i, text), "", "L", false)
+	}
+
+	r.footnotes = nil
+	r.footnoteHeight = 0
+	pdf.SetAutoPageBreak(true, r.bottomMargin)
+}
+
+// codePadding is the space between a code block's background edge and its
+// text, in millimetres.
+const codePadding = 3
+
+// renderCode draws elem.Text line by line in Courier on a shaded
+// background. Lines are never reflowed; those wider than the content area
+// are broken at the last character that fits. Tabs expand to four spaces.
+func renderCode(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) {
+	family, style, size := "Courier", "", 9.0
+	if elem.Font != nil {
+		if elem.Font.Family != "" {
+			family = elem.Font.Family
+		}
+		style = elem.Font.Style
+		if elem.Font.Size > 0 {
+			size = elem.Font.Size
+		}
+	}
+	fill := Color{R: 245, G: 245, B: 245}
+	if elem.FillColor != nil {
+		fill = *elem.FillColor
+	}
+
+	pageW, _ := pdf.GetPageSize()
+	lm, _, rm, _ := pdf.GetMargins()
+	contentW := pageW - lm - rm
+	lh := lineHeight(pdf, elem, size)
+	pad := mm(pdf, codePadding)
+
+	pdf.SetFont(family, style, size)
+	pdf.SetFillColor(fill.R, fill.G, fill.B)
+	if elem.Color != nil {
+		pdf.SetTextColor(elem.Color.R, elem.Color.G, elem.Color.B)
+	}
+	cellMargin := pdf.GetCellMargin()
+	pdf.SetCellMargin(pad)
+
+	var lines []string
+	text := strings.ReplaceAll(strings.TrimRight(elem.Text, "\n"), "\t", "    ")
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, breakLine(pdf, line, contentW-2*pad)...)
+	}
+
+	pdf.SetX(lm)
+	pdf.CellFormat(contentW, pad, "", "", 1, "", true, 0, "")
+	for _, line := range lines {
+		pdf.SetX(lm)
+		pdf.CellFormat(contentW, lh, line, "", 1, "L", true, 0, "")
+	}
+	pdf.SetX(lm)
+	pdf.CellFormat(contentW, pad, "", "", 1, "", true, 0, "")
+	pdf.Ln(mm(pdf, size*0.3))
+
+	pdf.SetCellMargin(cellMargin)
+	pdf.SetFillColor(0, 0, 0)
+	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+	if elem.Color != nil {
+		pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+// breakLine splits line into pieces no wider than w in the current font,
+// breaking between characters. A character wider than w makes a piece of
+// its own.
+func breakLine(pdf *gofpdf.Fpdf, line string, w float64) []string {
+	var pieces []string
+	for utf8.RuneCountInString(line) > 1 && pdf.GetStringWidth(line) > w {
+		runes := []rune(line)
+		n := len(runes) - 1
+		for n > 1 && pdf.GetStringWidth(string(runes[:n])) > w {
+			n--
+		}
+		pieces = append(pieces, string(runes[:n]))
+		line = string(runes[n:])
+	}
+	return append(pieces, line)
+}
+
+// quoteColors holds the accent bar and background colors of a blockquote
+// or callout kind.
+type quoteColors struct {
+	accent, fill Color
+}
+
+var (
+	blockquoteColors = quoteColors{Color{180, 180, 180}, Color{248, 248, 248}}
+	calloutColors    = map[string]quoteColors{
+		"note":    {Color{41, 128, 185}, Color{232, 242, 250}},
+		"tip":     {Color{39, 174, 96}, Color{233, 247, 239}},
+		"warning": {Color{230, 126, 34}, Color{253, 242, 232}},
+		"danger":  {Color{192, 57, 43}, Color{250, 234, 232}},
+	}
+)
+
+// quoteBarWidth is the width of the accent bar drawn left of a blockquote
+// or callout, in millimetres.
+const quoteBarWidth = 1.2
+
+// renderQuote draws a blockquote or callout: wrapped text inset from the
+// left margin, on a tinted background with an accent bar down its left
+// side. Each line is drawn with its slice of bar and background so that
+// long blocks break across pages cleanly.
+func renderQuote(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+	colors := blockquoteColors
+	if elem.Type == "callout" {
+		kind := strings.ToLower(elem.Kind)
+		if kind == "" {
+			kind = "note"
+		}
+		c, ok := calloutColors[kind]
+		if !ok {
+			return fmt.Errorf("unknown callout kind %q", elem.Kind)
+		}
+		colors = c
+	}
+	if elem.AccentColor != nil {
+		colors.accent = *elem.AccentColor
+	}
+	if elem.FillColor != nil {
+		colors.fill = *elem.FillColor
+	}
+
+	family, style, size := defaultFont.Family, defaultFont.Style, defaultFont.Size
+	if elem.Type == "blockquote" {
+		style = "I"
+	}
+	if elem.Font != nil {
+		if elem.Font.Family != "" {
+			family = elem.Font.Family
+		}
+		if elem.Font.Style != "" {
+			style = elem.Font.Style
+		}
+		if elem.Font.Size > 0 {
+			size = elem.Font.Size
+		}
+	}
+	text := Color{80, 80, 80}
+	if elem.Color != nil {
+		text = *elem.Color
+	}
+	align := "L"
+	if elem.Align != "" {
+		align = strings.ToUpper(elem.Align)
+	}
+
+	pageW, pageH := pdf.GetPageSize()
+	lm, _, rm, bm := pdf.GetMargins()
+	contentW := pageW - lm - rm
+	bar, pad := mm(pdf, quoteBarWidth), mm(pdf, codePadding)
+	textX := lm + bar
+	textW := contentW - bar
+	lh := lineHeight(pdf, elem, size)
+
+	pdf.SetFont(family, style, size)
+	pdf.SetTextColor(text.R, text.G, text.B)
+	cellMargin := pdf.GetCellMargin()
+	pdf.SetCellMargin(pad)
+
+	// row draws one horizontal slice of the block, h high, starting a new
+	// page first if it would cross the bottom margin
+	row := func(h float64, line string) {
+		if pdf.GetY()+h > pageH-bm {
+			pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pageW, Ht: pageH})
+		}
+		y := pdf.GetY()
+		pdf.SetFillColor(colors.accent.R, colors.accent.G, colors.accent.B)
+		pdf.Rect(lm, y, bar, h, "F")
+		pdf.SetFillColor(colors.fill.R, colors.fill.G, colors.fill.B)
+		pdf.SetX(textX)
+		pdf.CellFormat(textW, h, line, "", 1, align, true, 0, "")
+	}
+	row(pad, "")
+	for _, line := range pdf.SplitLines([]byte(elem.Text), textW) {
+		row(lh, string(line))
+	}
+	row(pad, "")
+	pdf.Ln(mm(pdf, size*0.3))
+
+	pdf.SetCellMargin(cellMargin)
+	pdf.SetFillColor(0, 0, 0)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+	return nil
+}
+
+func (r *renderer) renderTable(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 	t := table.New(pdf)
 
 	// Set up columns
@@ -251,69 +934,136 @@ func renderTable(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
 		}
 		t.SetColumns(cols...)
 
-		// Add header row
+		// Add header row, skipping the columns a spanning header covers
 		hr := t.AddHeaderRow()
-		for _, c := range elem.Columns {
-			hr.AddCell(c.Header)
-		}
-
-		// Style the header
-		headerStyle := table.CellStyle{
-			FillColor: &table.RGBColor{R: 63, G: 81, B: 181},
-			TextColor: &table.RGBColor{R: 255, G: 255, B: 255},
-			Font:      &table.FontSpec{Family: defaultFont.Family, Style: "B", Size: defaultFont.Size},
-		}
-		if elem.HeaderStyle != nil {
-			if elem.HeaderStyle.FillColor != nil {
-				headerStyle.FillColor = &table.RGBColor{
-					R: elem.HeaderStyle.FillColor.R,
-					G: elem.HeaderStyle.FillColor.G,
-					B: elem.HeaderStyle.FillColor.B,
-				}
-			}
-			if elem.HeaderStyle.TextColor != nil {
-				headerStyle.TextColor = &table.RGBColor{
-					R: elem.HeaderStyle.TextColor.R,
-					G: elem.HeaderStyle.TextColor.G,
-					B: elem.HeaderStyle.TextColor.B,
-				}
-			}
-			if elem.HeaderStyle.Font != nil {
-				if elem.HeaderStyle.Font.Family != "" {
-					headerStyle.Font.Family = elem.HeaderStyle.Font.Family
-				}
-				if elem.HeaderStyle.Font.Style != "" {
-					headerStyle.Font.Style = elem.HeaderStyle.Font.Style
-				}
-				if elem.HeaderStyle.Font.Size > 0 {
-					headerStyle.Font.Size = elem.HeaderStyle.Font.Size
-				}
+		for i := 0; i < len(elem.Columns); i++ {
+			c := elem.Columns[i]
+			cell := hr.AddCell(c.Header)
+			if c.HeaderColspan > 1 {
+				cell.SetColspan(c.HeaderColspan)
+				i += c.HeaderColspan - 1
 			}
 		}
-
-		t.SetStyle(table.TableStyle{
-			CellPadding: table.UniformPadding(2),
-			HeaderStyle: &headerStyle,
-			AlternateRows: &table.AlternateStyle{
-				Even: table.CellStyle{
-					FillColor: &table.RGBColor{R: 245, G: 245, B: 245},
-				},
-			},
-		})
 	}
+	style := tableStyle(r.doc.TableStyle, elem, defaultFont, mm(pdf, 2))
+	t.SetStyle(style)
+	// The table's default minimum row height is in the document unit
+	t.SetMinRowHeight(mm(pdf, 5))
 
 	// Add data rows
 	for _, row := range elem.Rows {
-		r := t.AddRow()
+		tr := t.AddRow()
 		for _, cell := range row {
-			r.AddCell(cell)
+			tr.AddCell(cell)
+		}
+	}
+	for _, row := range elem.Cells {
+		tr := t.AddRow()
+		for _, cell := range row {
+			c := tr.AddCell(cell.Text)
+			if cell.Colspan > 1 {
+				c.SetColspan(cell.Colspan)
+			}
+			if cell.Align != "" || cell.FillColor != nil || cell.TextColor != nil || cell.Font != nil {
+				c.SetStyle(cellStyle(cell, *style.AlternateRows.Odd.Font, defaultFont))
+			}
 		}
 	}
 
-	pdf.Ln(2)
+	pdf.Ln(mm(pdf, 2))
 	return t.Render()
 }
 
+// tableStyle builds the style of a table element from the built-in
+// defaults, the document table style, and the element's own header and
+// cell styles, in increasing order of precedence. padding is the default
+// cell padding in the document unit.
+func tableStyle(docStyle *TableStyle, elem Element, defaultFont Font, padding float64) table.TableStyle {
+	headerStyle := table.CellStyle{
+		FillColor: &table.RGBColor{R: 63, G: 81, B: 181},
+		TextColor: &table.RGBColor{R: 255, G: 255, B: 255},
+		Font:      &table.FontSpec{Family: defaultFont.Family, Style: "B", Size: defaultFont.Size},
+	}
+	cellStyle := table.CellStyle{
+		Font: &table.FontSpec{Family: defaultFont.Family, Style: defaultFont.Style, Size: defaultFont.Size},
+	}
+	stripe := &table.RGBColor{R: 245, G: 245, B: 245}
+	style := table.TableStyle{CellPadding: table.UniformPadding(padding)}
+
+	if docStyle != nil {
+		mergeCellStyle(&headerStyle, docStyle.HeaderStyle, defaultFont)
+		mergeCellStyle(&cellStyle, docStyle.CellStyle, defaultFont)
+		if docStyle.StripeColor != nil {
+			stripe = tableColor(docStyle.StripeColor)
+		}
+		if docStyle.Padding > 0 {
+			style.CellPadding = table.UniformPadding(docStyle.Padding)
+		}
+		if docStyle.BorderColor != nil || docStyle.BorderWidth > 0 {
+			style.Border = &table.BorderStyle{Width: docStyle.BorderWidth}
+			if docStyle.BorderColor != nil {
+				style.Border.Color = *tableColor(docStyle.BorderColor)
+			}
+		}
+	}
+	mergeCellStyle(&headerStyle, elem.HeaderStyle, defaultFont)
+	mergeCellStyle(&cellStyle, elem.CellStyle, defaultFont)
+
+	even := cellStyle
+	if even.FillColor == nil {
+		even.FillColor = stripe
+	}
+	style.HeaderStyle = &headerStyle
+	style.AlternateRows = &table.AlternateStyle{Even: even, Odd: cellStyle}
+	return style
+}
+
+// cellStyle returns the style overrides of a data cell. A font override
+// starts from base, the table's data cell font.
+func cellStyle(cell TableCell, base table.FontSpec, defaultFont Font) table.CellStyle {
+	cs := table.CellStyle{Align: strings.ToUpper(cell.Align)}
+	if cell.Font != nil {
+		cs.Font = &base
+	}
+	mergeCellStyle(&cs, &CellStyle{FillColor: cell.FillColor, TextColor: cell.TextColor, Font: cell.Font}, defaultFont)
+	return cs
+}
+
+// mergeCellStyle applies the fields set in src to dst. Font fields are
+// merged one by one, starting from the document font if dst has none.
+func mergeCellStyle(dst *table.CellStyle, src *CellStyle, defaultFont Font) {
+	if src == nil {
+		return
+	}
+	if src.FillColor != nil {
+		dst.FillColor = tableColor(src.FillColor)
+	}
+	if src.TextColor != nil {
+		dst.TextColor = tableColor(src.TextColor)
+	}
+	if src.Font != nil {
+		font := table.FontSpec{Family: defaultFont.Family, Style: defaultFont.Style, Size: defaultFont.Size}
+		if dst.Font != nil {
+			font = *dst.Font
+		}
+		if src.Font.Family != "" {
+			font.Family = src.Font.Family
+		}
+		if src.Font.Style != "" {
+			font.Style = src.Font.Style
+		}
+		if src.Font.Size > 0 {
+			font.Size = src.Font.Size
+		}
+		dst.Font = &font
+	}
+}
+
+// tableColor converts a schema color to a table color.
+func tableColor(c *Color) *table.RGBColor {
+	return &table.RGBColor{R: c.R, G: c.G, B: c.B}
+}
+
 func renderImage(pdf *gofpdf.Fpdf, elem Element) error {
 	if elem.Src == "" {
 		return fmt.Errorf("image element requires 'src' field")
@@ -321,20 +1071,86 @@ func renderImage(pdf *gofpdf.Fpdf, elem Element) error {
 
 	x := elem.X
 	y := elem.Y
-	w := elem.Width
-	h := elem.Height
+	inFlow := x == 0 && y == 0
 
 	// If no position specified, use flow position
-	if x == 0 && y == 0 {
+	if inFlow {
 		x = pdf.GetX()
 		y = pdf.GetY()
 	}
 
-	pdf.Image(elem.Src, x, y, w, h, false, "", 0, "")
+	info := pdf.RegisterImageOptions(elem.Src, gofpdf.ImageOptions{})
+	if pdf.Err() {
+		return fmt.Errorf("image %q: %w", elem.Src, pdf.Error())
+	}
+	iw, ih := info.Extent()
+
+	pageW, pageH := pdf.GetPageSize()
+	_, tm, rm, bm := pdf.GetMargins()
+
+	// The image, w by h, is centred in a box that it takes up in the
+	// layout. Only cover lets the image overflow the box.
+	fit := strings.ToLower(elem.Fit)
+	var w, h, boxW, boxH float64
+	switch fit {
+	case "":
+		w, h = elem.Width, elem.Height
+		switch {
+		case w == 0 && h == 0:
+			w, h = iw, ih
+		case w == 0:
+			w = h * iw / ih
+		case h == 0:
+			h = w * ih / iw
+		}
+		boxW, boxH = w, h
+	case "width":
+		w = pageW - rm - x
+		h = w * ih / iw
+		boxW, boxH = w, h
+	case "contain", "cover":
+		boxW, boxH = elem.Width, elem.Height
+		if boxW == 0 {
+			boxW = pageW - rm - x
+		}
+		if boxH == 0 {
+			if fit == "cover" {
+				return fmt.Errorf("image fit \"cover\" requires a height")
+			}
+			boxH = pageH - tm - bm
+		}
+		scale := math.Min(boxW/iw, boxH/ih)
+		if fit == "cover" {
+			scale = math.Max(boxW/iw, boxH/ih)
+		}
+		w, h = iw*scale, ih*scale
+		if elem.Height == 0 {
+			boxH = h
+		}
+	default:
+		return fmt.Errorf("unknown image fit %q", elem.Fit)
+	}
+
+	// Images in the flow start a new page if they do not fit in the space
+	// left, unless they are taller than a page.
+	if inFlow && y+boxH > pageH-bm && boxH <= pageH-tm-bm {
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pageW, Ht: pageH})
+		x, y = pdf.GetX(), pdf.GetY()
+	}
+
+	if fit == "cover" {
+		pdf.ClipRect(x, y, boxW, boxH, false)
+	}
+	// A covering image may start left of the page edge
+	opts := gofpdf.ImageOptions{AllowNegativePosition: true}
+	pdf.ImageOptions(elem.Src, x+(boxW-w)/2, y+(boxH-h)/2, w, h, false, opts, 0, "")
+	if fit == "cover" {
+		pdf.ClipEnd()
+	}
 
 	// Advance Y if using flow
-	if elem.Y == 0 && h > 0 {
-		pdf.SetY(y + h + 2)
+	if elem.Y == 0 {
+		pdf.SetY(y + boxH + mm(pdf, 2))
 	}
 
 	return nil
@@ -352,7 +1168,7 @@ func renderLine(pdf *gofpdf.Fpdf, elem Element) {
 		pdf.SetDrawColor(0, 0, 0)
 	}
 	if elem.LineWidth > 0 {
-		pdf.SetLineWidth(0.2)
+		pdf.SetLineWidth(mm(pdf, 0.2))
 	}
 }
 
@@ -381,7 +1197,7 @@ func renderRect(pdf *gofpdf.Fpdf, elem Element) {
 func renderSpacer(pdf *gofpdf.Fpdf, elem Element) {
 	h := elem.SpacerHeight
 	if h == 0 {
-		h = 10
+		h = mm(pdf, 10)
 	}
 	pdf.Ln(h)
 }
@@ -390,12 +1206,12 @@ func renderHR(pdf *gofpdf.Fpdf, elem Element) {
 	pageW, _ := pdf.GetPageSize()
 	lm, _, rm, _ := pdf.GetMargins()
 
-	pdf.Ln(3)
+	pdf.Ln(mm(pdf, 3))
 	y := pdf.GetY()
 
 	lw := elem.LineWidth
 	if lw == 0 {
-		lw = 0.3
+		lw = mm(pdf, 0.3)
 	}
 	pdf.SetLineWidth(lw)
 
@@ -407,8 +1223,8 @@ func renderHR(pdf *gofpdf.Fpdf, elem Element) {
 
 	pdf.Line(lm, y, pageW-rm, y)
 	pdf.SetDrawColor(0, 0, 0)
-	pdf.SetLineWidth(0.2)
-	pdf.Ln(3)
+	pdf.SetLineWidth(mm(pdf, 0.2))
+	pdf.Ln(mm(pdf, 3))
 }
 
 func renderList(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) {
@@ -430,28 +1246,285 @@ func renderList(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) {
 
 	pdf.SetFont(family, style, size)
 
+	items := elem.ListItems
+	if len(items) == 0 {
+		items = make([]ListItem, len(elem.Items))
+		for i, text := range elem.Items {
+			items[i] = ListItem{Text: text}
+		}
+	}
+	renderListItems(pdf, items, elem, size, 0)
+
+	pdf.Ln(mm(pdf, 2))
+	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+}
+
+// listBullets holds the default bullet glyph for each nesting level; deeper
+// levels cycle through the same glyphs.
+var listBullets = []string{"\u2022", "-", "\u00b7"}
+
+// renderListItems renders items at the given nesting level, recursing into
+// sub-items with a deeper indentation.
+func renderListItems(pdf *gofpdf.Fpdf, items []ListItem, elem Element, size float64, level int) {
 	pageW, _ := pdf.GetPageSize()
 	lm, _, rm, _ := pdf.GetMargins()
-	contentW := pageW - lm - rm - 10 // indent for bullet
+	step := mm(pdf, 5)
+	indent := step + float64(level)*step
+	contentW := pageW - lm - rm - indent - step
+
+	for i, item := range items {
+		prefix := listBullets[level%len(listBullets)] + " "
+		if elem.BulletStr != "" {
+			prefix = elem.BulletStr + " "
+		}
+		if elem.Ordered {
+			prefix = listMarker(i+1, level) + " "
+		}
+
+		pdf.SetX(lm + indent)
+		pdf.MultiCell(contentW, lineHeight(pdf, elem, size), prefix+item.Text, "", "L", false)
+		pdf.Ln(mm(pdf, 1))
 
-	bullet := "\u2022 " // default bullet
-	if elem.BulletStr != "" {
-		bullet = elem.BulletStr + " "
+		if len(item.Items) > 0 {
+			renderListItems(pdf, item.Items, elem, size, level+1)
+		}
 	}
+}
 
-	for i, item := range elem.Items {
-		prefix := bullet
-		if elem.Ordered {
-			prefix = fmt.Sprintf("%d. ", i+1)
+// listMarker returns the ordered-list marker for the n-th item (1-based) at
+// the given nesting level: decimal, then lower-case letters, then lower-case
+// roman numerals, repeating for deeper levels.
+func listMarker(n, level int) string {
+	switch level % 3 {
+	case 1:
+		return alphaNumeral(n) + "."
+	case 2:
+		return strings.ToLower(romanNumeral(n)) + "."
+	}
+	return fmt.Sprintf("%d.", n)
+}
+
+// alphaNumeral converts n (1-based) to a, b, ..., z, aa, ab, ...
+func alphaNumeral(n int) string {
+	var b []byte
+	for n > 0 {
+		n--
+		b = append([]byte{byte('a' + n%26)}, b...)
+		n /= 26
+	}
+	return string(b)
+}
+
+// romanNumeral converts n to upper-case roman numerals. Values outside
+// 1-3999 are returned as decimal.
+func romanNumeral(n int) string {
+	if n <= 0 || n >= 4000 {
+		return fmt.Sprintf("%d", n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var sb strings.Builder
+	for i, v := range values {
+		for n >= v {
+			sb.WriteString(symbols[i])
+			n -= v
 		}
+	}
+	return sb.String()
+}
+
+// placeholderRe matches {{field}} placeholders in repeat templates.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
 
-		pdf.SetX(lm + 5)
-		pdf.MultiCell(contentW, size*0.5, prefix+item, "", "L", false)
-		pdf.Ln(1)
+func (r *renderer) renderRepeat(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+	if elem.Template == nil {
+		return fmt.Errorf("repeat element requires 'template' field")
+	}
+	if elem.Template.Type == "repeat" {
+		return fmt.Errorf("repeat template cannot itself be a repeat element")
 	}
 
-	pdf.Ln(2)
-	pdf.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+	// Round-trip the template through JSON so placeholders are substituted
+	// in every string, including table rows and list items.
+	tpl, err := json.Marshal(elem.Template)
+	if err != nil {
+		return fmt.Errorf("repeat: encoding template: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(tpl, &generic); err != nil {
+		return fmt.Errorf("repeat: decoding template: %w", err)
+	}
+
+	for i, item := range elem.RepeatItems {
+		data, err := json.Marshal(substitutePlaceholders(generic, item))
+		if err != nil {
+			return fmt.Errorf("repeat item %d: %w", i+1, err)
+		}
+		var child Element
+		if err := json.Unmarshal(data, &child); err != nil {
+			return fmt.Errorf("repeat item %d: %w", i+1, err)
+		}
+		if err := r.renderElement(pdf, child, defaultFont); err != nil {
+			return fmt.Errorf("repeat item %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *renderer) renderElements(pdf *gofpdf.Fpdf, elems []Element, defaultFont Font) error {
+	for _, elem := range elems {
+		if err := r.renderElement(pdf, elem, defaultFont); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepTogether starts a new page if elem, a group or an element marked
+// keepTogether, would not fit in the space left on the current one. Blocks
+// taller than a page are left to break normally.
+func (r *renderer) keepTogether(pdf *gofpdf.Fpdf, elem Element, defaultFont Font) error {
+	elems := elem.Elements
+	if elem.Type != "group" {
+		elem.KeepTogether = false
+		elems = []Element{elem}
+	}
+	h, err := r.measureHeight(pdf, elems, defaultFont)
+	if err != nil {
+		return err
+	}
+
+	pageW, pageH := pdf.GetPageSize()
+	_, top, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+h <= pageH-bottom || h > pageH-top-bottom {
+		return nil
+	}
+	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pageW, Ht: pageH})
+	return nil
+}
+
+// measureHeight returns the height elems take up when rendered at the
+// current page width. They are laid out with renderElement on a scratch
+// document whose page is too tall to break, so the measurement matches the
+// real rendering exactly.
+func (r *renderer) measureHeight(pdf *gofpdf.Fpdf, elems []Element, defaultFont Font) (float64, error) {
+	unit := r.doc.Unit
+	if unit == "" {
+		unit = "mm"
+	}
+	pageW, _ := pdf.GetPageSize()
+	left, top, right, _ := pdf.GetMargins()
+
+	scratch := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: unit,
+		Size:    gofpdf.SizeType{Wd: pageW, Ht: 1e6},
+	})
+	scratch.SetMargins(left, top, right)
+	scratch.SetAutoPageBreak(false, 0)
+	scratch.SetCellMargin(pdf.GetCellMargin())
+	registerFonts(scratch, r.doc)
+	scratch.AddPage()
+	scratch.SetFont(defaultFont.Family, defaultFont.Style, defaultFont.Size)
+
+	// Bookmarks, headings and footnotes on the scratch document must not
+	// affect those of the real one.
+	depth, headings := r.outlineDepth, len(r.layout.Headings)
+	notes, noteCount, noteHeight := len(r.footnotes), r.footnoteCount, r.footnoteHeight
+	defer func() {
+		r.outlineDepth = depth
+		r.layout.Headings = r.layout.Headings[:headings]
+		r.footnotes = r.footnotes[:notes]
+		r.footnoteCount, r.footnoteHeight = noteCount, noteHeight
+	}()
+
+	// A line of flowing text left open on the real document is continued
+	// there, so the scratch document starts on a fresh line.
+	flowBreak := r.flowBreak
+	r.flowBreak = 0
+	defer func() { r.flowBreak = flowBreak }()
+
+	start := scratch.GetY()
+	if err := r.renderElements(scratch, elems, defaultFont); err != nil {
+		return 0, err
+	}
+	if scratch.Err() {
+		return 0, scratch.Error()
+	}
+	return scratch.GetY() + r.flowBreak - start, nil
+}
+
+// substitutePlaceholders returns a copy of v, a decoded JSON value, with
+// {{field}} placeholders in all strings replaced by the values in item.
+// Placeholders naming missing fields are replaced by an empty string.
+func substitutePlaceholders(v interface{}, item map[string]interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return placeholderRe.ReplaceAllStringFunc(val, func(m string) string {
+			field, ok := item[placeholderRe.FindStringSubmatch(m)[1]]
+			if !ok || field == nil {
+				return ""
+			}
+			return fmt.Sprint(field)
+		})
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = substitutePlaceholders(e, item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			out[k] = substitutePlaceholders(e, item)
+		}
+		return out
+	}
+	return v
+}
+
+func renderBackground(pdf *gofpdf.Fpdf, c Color) {
+	pageW, pageH := pdf.GetPageSize()
+	pdf.SetFillColor(c.R, c.G, c.B)
+	pdf.Rect(0, 0, pageW, pageH, "F")
+}
+
+// renderWatermark draws the watermark text rotated about the page centre,
+// in the same way as pageops text watermarks.
+func renderWatermark(pdf *gofpdf.Fpdf, wm Watermark) {
+	if wm.Text == "" {
+		return
+	}
+	if wm.FontSize == 0 {
+		wm.FontSize = 60
+	}
+	if wm.Opacity == 0 {
+		wm.Opacity = 0.3
+	}
+	if wm.Angle == 0 {
+		wm.Angle = 45
+	}
+	color := Color{R: 200, G: 200, B: 200}
+	if wm.Color != nil {
+		color = *wm.Color
+	}
+
+	pdf.SetFont("Helvetica", "B", wm.FontSize)
+	pdf.SetTextColor(color.R, color.G, color.B)
+	pdf.SetAlpha(wm.Opacity, "Normal")
+
+	pageW, pageH := pdf.GetPageSize()
+	_, fontH := pdf.GetFontSize()
+	textW := pdf.GetStringWidth(wm.Text)
+	cx := pageW / 2
+	cy := pageH / 2
+
+	pdf.TransformBegin()
+	pdf.TransformRotate(wm.Angle, cx, cy)
+	pdf.Text(cx-textW/2, cy+fontH/3, wm.Text)
+	pdf.TransformEnd()
+
+	pdf.SetAlpha(1.0, "Normal")
+	pdf.SetTextColor(0, 0, 0)
 }
 
 func renderHeader(pdf *gofpdf.Fpdf, hdr Header, defaultFont Font) {
@@ -486,16 +1559,18 @@ func renderHeader(pdf *gofpdf.Fpdf, hdr Header, defaultFont Font) {
 		align = strings.ToUpper(hdr.Align)
 	}
 
-	pdf.SetY(5)
-	pdf.CellFormat(contentW, 10, hdr.Text, "", 0, align, false, 0, "")
-	pdf.Ln(5)
+	pdf.SetY(mm(pdf, 5))
+	pdf.CellFormat(contentW, mm(pdf, 10), hdr.Text, "", 0, align, false, 0, "")
+	pdf.Ln(mm(pdf, 5))
 
 	if hdr.Color != nil {
 		pdf.SetTextColor(0, 0, 0)
 	}
 }
 
-func renderFooter(pdf *gofpdf.Fpdf, ftr Footer, defaultFont Font) {
+// renderFooter draws the footer of the current page. pages is the text
+// substituted for the {pages} placeholder.
+func renderFooter(pdf *gofpdf.Fpdf, ftr Footer, defaultFont Font, pages string) {
 	family := defaultFont.Family
 	style := ""
 	size := 8.0
@@ -529,15 +1604,15 @@ func renderFooter(pdf *gofpdf.Fpdf, ftr Footer, defaultFont Font) {
 		align = strings.ToUpper(ftr.Align)
 	}
 
-	// Replace placeholders
+	// Replace placeholders. Without a measurement pass the page count is
+	// not known until the document is complete, and pages is the alias
+	// set up in renderPass.
 	text := ftr.Text
 	text = strings.ReplaceAll(text, "{page}", fmt.Sprintf("%d", pdf.PageNo()))
-	// {pages} requires AliasNbPages which is set at generation time
-	// We use a simple format here
-	text = strings.ReplaceAll(text, "{pages}", "{nb}")
+	text = strings.ReplaceAll(text, "{pages}", pages)
 
-	pdf.SetY(-15)
-	pdf.CellFormat(contentW, 10, text, "", 0, align, false, 0, "")
+	pdf.SetY(-mm(pdf, 15))
+	pdf.CellFormat(contentW, mm(pdf, 10), text, "", 0, align, false, 0, "")
 
 	pdf.SetTextColor(0, 0, 0)
 }
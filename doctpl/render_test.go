@@ -3,8 +3,20 @@ package doctpl
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+
+	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/reader"
+	"github.com/lvillar/gofpdf/table"
 )
 
 func TestRenderMinimalDocument(t *testing.T) {
@@ -89,6 +101,241 @@ func TestRenderWithTable(t *testing.T) {
 	}
 }
 
+func TestRenderTableColspan(t *testing.T) {
+	jsonTemplate := `{
+		"pages": [{
+			"elements": [
+				{"type": "table",
+				 "columns": [
+					{"header": "Item", "width": 80},
+					{"header": "Qty", "width": 30},
+					{"header": "Price", "width": 40, "align": "R"}
+				 ],
+				 "rows": [["Widget", "1", "$10.00"]],
+				 "cells": [
+					["Widget", "2", "$10.00"],
+					[{"text": "Subtotal", "colspan": 2, "align": "R", "fillColor": {"r": 255, "g": 255, "b": 0}}, "$20.00"]
+				 ]}
+			]
+		}]
+	}`
+
+	var doc Document
+	if err := json.Unmarshal([]byte(jsonTemplate), &doc); err != nil {
+		t.Fatalf("decoding template: %v", err)
+	}
+	rows := doc.Pages[0].Elements[0].Cells
+	if rows[0][1] != (TableCell{Text: "2"}) {
+		t.Errorf("plain cell = %+v", rows[0][1])
+	}
+	if c := rows[1][0]; c.Text != "Subtotal" || c.Colspan != 2 || c.Align != "R" || c.FillColor == nil {
+		t.Errorf("object cell = %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+	// The spanning cell is filled across the first two columns, 110mm
+	if !bytes.Contains(content, []byte("1.000 1.000 0.000 rg")) {
+		t.Error("cell fill color not found")
+	}
+	if !regexp.MustCompile(` 311\.8\d -\d+\.\d+ re `).Match(content) {
+		t.Errorf("no 110mm wide cell found in content:\n%s", content)
+	}
+
+	// Plain cells encode back to strings
+	data, err := json.Marshal(rows[0])
+	if err != nil || string(data) != `["Widget","2","$10.00"]` {
+		t.Errorf("encoded row = %s, %v", data, err)
+	}
+}
+
+func TestRenderTableCellStyle(t *testing.T) {
+	doc := Document{
+		Pages: []Page{{
+			Elements: []Element{{
+				Type:    "table",
+				Columns: []TableColumn{{Header: "Item"}, {Header: "Amount", Align: "R"}},
+				Rows:    [][]string{{"Widget", "$10.00"}},
+				Cells: [][]TableCell{
+					{{Text: "Late fee"}, {Text: "$5.00", TextColor: &Color{R: 255}, Font: &Font{Style: "B"}}},
+				},
+			}},
+		}},
+	}
+
+	cell := doc.Pages[0].Elements[0].Cells[0][1]
+	cs := cellStyle(cell, table.FontSpec{Family: "Helvetica", Size: 9}, Font{Family: "Helvetica", Size: 10})
+	if cs.Font == nil || cs.Font.Family != "Helvetica" || cs.Font.Style != "B" || cs.Font.Size != 9 {
+		t.Errorf("cell font = %+v, want bold Helvetica 9", cs.Font)
+	}
+	if cs.Align != "" || cs.FillColor != nil {
+		t.Errorf("unset fields overridden: %+v", cs)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+	if !regexp.MustCompile(`1\.000 0\.000 0\.000 rg BT [\d. ]+Td \(\$5\.00\)Tj`).Match(content) {
+		t.Errorf("cell text color not applied:\n%s", content)
+	}
+}
+
+func TestRenderRTL(t *testing.T) {
+	doc := Document{
+		Fonts: []FontFile{
+			{Family: "DejaVu", File: "../font/DejaVuSansCondensed.ttf"},
+			{Family: "DejaVu", Style: "B", File: "../font/DejaVuSansCondensed-Bold.ttf"},
+		},
+		Pages: []Page{{
+			Elements: []Element{
+				{Type: "paragraph", Text: "Total", Dir: "rtl"},
+				{Type: "heading", Text: "فاتورة رقم 42", Dir: "rtl", Font: &Font{Family: "DejaVu"}, KeepTogether: true},
+				{Type: "paragraph", Text: "Total", Dir: "rtl", Align: "L"},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	// Right-aligned by default; an explicit align still applies
+	matches := regexp.MustCompile(`BT ([\d.]+) [\d.]+ Td \(Total\)Tj`).FindAllSubmatch(content, -1)
+	if len(matches) != 2 {
+		t.Fatalf("found %d Total cells, want 2:\n%s", len(matches), content)
+	}
+	if x, _ := strconv.ParseFloat(string(matches[0][1]), 64); x < 500 {
+		t.Errorf("rtl paragraph starts at x=%.2f, want right-aligned", x)
+	}
+	if x, _ := strconv.ParseFloat(string(matches[1][1]), 64); x > 100 {
+		t.Errorf("left-aligned rtl paragraph starts at x=%.2f", x)
+	}
+
+	doc.Pages[0].Elements = []Element{{Type: "paragraph", Text: "x", Dir: "sideways"}}
+	if err := RenderDocument(&buf, &doc); err == nil || !strings.Contains(err.Error(), "sideways") {
+		t.Errorf("unknown dir: got error %v", err)
+	}
+}
+
+func TestRenderDocumentDir(t *testing.T) {
+	doc := Document{
+		Dir: "rtl",
+		Pages: []Page{{
+			Elements: []Element{
+				{Type: "paragraph", Text: "Total"},
+				{Type: "group", Elements: []Element{{Type: "paragraph", Text: "Total"}}},
+				{Type: "paragraph", Text: "Total", Dir: "ltr"},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	// Elements inherit the document direction unless they set their own
+	matches := regexp.MustCompile(`BT ([\d.]+) [\d.]+ Td \(Total\)Tj`).FindAllSubmatch(content, -1)
+	if len(matches) != 3 {
+		t.Fatalf("found %d Total cells, want 3:\n%s", len(matches), content)
+	}
+	for i, rtl := range []bool{true, true, false} {
+		x, _ := strconv.ParseFloat(string(matches[i][1]), 64)
+		if rtl != (x > 500) {
+			t.Errorf("paragraph %d starts at x=%.2f, want rtl=%v", i+1, x, rtl)
+		}
+	}
+}
+
+func TestRenderOutline(t *testing.T) {
+	doc := Document{
+		Pages: []Page{
+			{Elements: []Element{
+				{Type: "heading", Text: "Introduction", Level: 1},
+				{Type: "heading", Text: "Scope", Level: 3},
+				{Type: "bookmark", Title: "Glossary", Level: 2},
+			}},
+			{Elements: []Element{
+				{Type: "heading", Text: "Usage", Level: 1},
+			}},
+		},
+	}
+
+	outline := func(doc *Document) []*reader.OutlineItem {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := RenderDocument(&buf, doc); err != nil {
+			t.Fatalf("RenderDocument failed: %v", err)
+		}
+		parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		items, err := parsed.Outlines()
+		if err != nil {
+			t.Fatalf("reading outline: %v", err)
+		}
+		return items
+	}
+
+	// The level 3 heading has no level 2 parent, so it nests one level down
+	items := outline(&doc)
+	if len(items) != 2 || items[0].Title != "Introduction" || items[1].Title != "Usage" || items[1].Page != 2 {
+		t.Fatalf("top-level entries = %+v", items)
+	}
+	children := items[0].Children
+	if len(children) != 2 || children[0].Title != "Scope" || children[1].Title != "Glossary" {
+		t.Errorf("children of Introduction = %+v", children)
+	}
+
+	off := false
+	doc.Outline = &off
+	items = outline(&doc)
+	if len(items) != 1 || items[0].Title != "Glossary" {
+		t.Errorf("outline without headings = %+v", items)
+	}
+}
+
 func TestRenderWithList(t *testing.T) {
 	doc := Document{
 		Pages: []Page{{
@@ -117,6 +364,222 @@ func TestRenderWithList(t *testing.T) {
 	}
 }
 
+func TestRenderNestedList(t *testing.T) {
+	jsonTemplate := `{
+		"pages": [{
+			"elements": [
+				{"type": "list", "ordered": true, "listItems": [
+					"Introduction",
+					{"text": "Methods", "items": [
+						"Sampling",
+						{"text": "Analysis", "items": ["Regression", "Clustering"]}
+					]},
+					"Results"
+				]}
+			]
+		}]
+	}`
+
+	var doc Document
+	if err := json.Unmarshal([]byte(jsonTemplate), &doc); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	items := doc.Pages[0].Elements[0].ListItems
+	if len(items) != 3 {
+		t.Fatalf("expected 3 top-level items, got %d", len(items))
+	}
+	if items[1].Text != "Methods" || len(items[1].Items) != 2 {
+		t.Fatalf("unexpected second item: %+v", items[1])
+	}
+	if got := items[1].Items[1].Items[0].Text; got != "Regression" {
+		t.Fatalf("expected third-level item %q, got %q", "Regression", got)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("expected non-empty PDF output")
+	}
+}
+
+func TestListMarker(t *testing.T) {
+	tests := []struct {
+		n, level int
+		want     string
+	}{
+		{1, 0, "1."},
+		{12, 0, "12."},
+		{1, 1, "a."},
+		{27, 1, "aa."},
+		{4, 2, "iv."},
+		{2, 3, "2."},
+	}
+	for _, tt := range tests {
+		if got := listMarker(tt.n, tt.level); got != tt.want {
+			t.Errorf("listMarker(%d, %d) = %q, want %q", tt.n, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestRenderRepeat(t *testing.T) {
+	jsonTemplate := `{
+		"pages": [{
+			"elements": [
+				{"type": "repeat",
+				 "items": [
+					{"sku": "WDG-001", "qty": 10},
+					{"sku": "WDG-002", "qty": 5}
+				 ],
+				 "template": {"type": "paragraph", "text": "{{sku}} x {{ qty }}"}}
+			]
+		}]
+	}`
+
+	var doc Document
+	if err := json.Unmarshal([]byte(jsonTemplate), &doc); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	elem := doc.Pages[0].Elements[0]
+	if len(elem.RepeatItems) != 2 {
+		t.Fatalf("expected 2 repeat items, got %d", len(elem.RepeatItems))
+	}
+
+	got := substitutePlaceholders(elem.Template.Text, elem.RepeatItems[1])
+	if got != "WDG-002 x 5" {
+		t.Fatalf("unexpected substitution: %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+
+	// The repeat element must survive a JSON round trip.
+	data, err := json.Marshal(elem)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var elem2 Element
+	if err := json.Unmarshal(data, &elem2); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(elem2.RepeatItems) != 2 || elem2.Template == nil {
+		t.Fatalf("repeat element lost in round trip: %s", data)
+	}
+}
+
+func TestRenderRepeatMissingTemplate(t *testing.T) {
+	doc := Document{
+		Pages: []Page{{
+			Elements: []Element{
+				{Type: "repeat", RepeatItems: []map[string]interface{}{{"a": 1}}},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err == nil {
+		t.Fatal("expected error for repeat without template")
+	}
+}
+
+func TestRenderNamedStyles(t *testing.T) {
+	jsonTemplate := `{
+		"styles": {
+			"h1": {"font": {"family": "Times", "style": "B", "size": 22}, "color": {"r": 0, "g": 51, "b": 102}},
+			"body": {"font": {"size": 10}, "align": "J", "lineHeight": 6}
+		},
+		"pages": [{
+			"elements": [
+				{"type": "heading", "text": "Report", "style": "h1"},
+				{"type": "paragraph", "text": "Styled body text.", "style": "body"},
+				{"type": "paragraph", "text": "Override.", "style": "body", "align": "R", "font": {"style": "I"}},
+				{"type": "paragraph", "text": "Inline only.", "font": {"size": 9}}
+			]
+		}]
+	}`
+
+	var buf bytes.Buffer
+	if err := Render(&buf, []byte(jsonTemplate)); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	style := Style{Font: &Font{Family: "Times", Size: 10}, Align: "J", LineHeight: 6}
+	elem := applyStyle(Element{Align: "R", Font: &Font{Style: "I"}}, style)
+	if elem.Align != "R" {
+		t.Errorf("Align = %q, want inline %q", elem.Align, "R")
+	}
+	if elem.Font.Family != "Times" || elem.Font.Style != "I" || elem.Font.Size != 10 {
+		t.Errorf("unexpected merged font: %+v", *elem.Font)
+	}
+	if elem.LineHeight != 6 {
+		t.Errorf("LineHeight = %v, want 6", elem.LineHeight)
+	}
+}
+
+func TestRenderUnknownStyle(t *testing.T) {
+	doc := Document{
+		Pages: []Page{{
+			Elements: []Element{
+				{Type: "paragraph", Text: "x", Style: "missing"},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	err := RenderDocument(&buf, &doc)
+	if err == nil || !strings.Contains(err.Error(), "unknown style") {
+		t.Fatalf("expected unknown style error, got %v", err)
+	}
+}
+
+func TestRenderDocumentTableStyle(t *testing.T) {
+	jsonTemplate := `{
+		"tableStyle": {
+			"headerStyle": {"fillColor": {"r": 0, "g": 128, "b": 0}},
+			"stripeColor": {"r": 230, "g": 255, "b": 230}
+		},
+		"pages": [{
+			"elements": [
+				{"type": "table", "columns": [{"header": "Name"}, {"header": "Qty"}],
+				 "rows": [["Widget", "10"], ["Gadget", "5"]]},
+				{"type": "table", "columns": [{"header": "Own"}],
+				 "headerStyle": {"fillColor": {"r": 255, "g": 0, "b": 0}},
+				 "rows": [["x"]]}
+			]
+		}]
+	}`
+
+	var buf bytes.Buffer
+	if err := Render(&buf, []byte(jsonTemplate)); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := doc.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	for name, op := range map[string]string{
+		"document header fill": "0.000 0.502 0.000 rg",
+		"document stripe fill": "0.902 1.000 0.902 rg",
+		"element header fill":  "1.000 0.000 0.000 rg",
+	} {
+		if !bytes.Contains(content, []byte(op)) {
+			t.Errorf("%s %q not found in content", name, op)
+		}
+	}
+	if bytes.Contains(content, []byte("0.247 0.318 0.710 rg")) {
+		t.Error("built-in blue header used despite document table style")
+	}
+}
+
 func TestRenderWithSpacer(t *testing.T) {
 	doc := Document{
 		Pages: []Page{{
@@ -158,6 +621,138 @@ func TestRenderMultiplePages(t *testing.T) {
 	}
 }
 
+func TestRenderPageOrientation(t *testing.T) {
+	doc := Document{
+		Pages: []Page{
+			{Elements: []Element{{Type: "paragraph", Text: "Portrait"}}},
+			{Orientation: "L", Elements: []Element{{Type: "paragraph", Text: "Landscape"}}},
+			{Size: "A5", Orientation: "L", Elements: []Element{{Type: "paragraph", Text: "Small"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	want := [][2]int{{595, 842}, {842, 595}, {595, 421}}
+	for n, page := range parsed.Pages() {
+		w, h := page.MediaBox.Width(), page.MediaBox.Height()
+		if int(w+0.5) != want[n-1][0] || int(h+0.5) != want[n-1][1] {
+			t.Errorf("page %d is %.0fx%.0f, want %dx%d", n, w, h, want[n-1][0], want[n-1][1])
+		}
+	}
+
+	doc.Pages[1].Orientation = "sideways"
+	if err := RenderDocument(&buf, &doc); err == nil {
+		t.Error("expected an error for an unknown orientation")
+	}
+}
+
+func TestRenderUnits(t *testing.T) {
+	elements := []Element{
+		{Type: "heading", Text: "Units", Level: 1},
+		{Type: "paragraph", Text: "The same layout in any unit."},
+		{Type: "list", Items: []string{"one", "two"}},
+		{Type: "hr"},
+		{Type: "spacer"},
+		{Type: "code", Text: "x := 1"},
+		{Type: "callout", Text: "Note"},
+		{Type: "table", Columns: []TableColumn{{Header: "A"}}, Rows: [][]string{{"1"}}},
+	}
+	render := func(unit string, perMM float64) []byte {
+		t.Helper()
+		doc := Document{
+			Unit:   unit,
+			Margin: &Margin{Top: 20 * perMM, Right: 20 * perMM, Bottom: 20 * perMM, Left: 20 * perMM},
+			Footer: &Footer{Text: "Page {page}"},
+			Pages:  []Page{{Elements: elements}},
+		}
+		var buf bytes.Buffer
+		if err := RenderDocument(&buf, &doc); err != nil {
+			t.Fatalf("RenderDocument(%s) failed: %v", unit, err)
+		}
+		parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		page, _ := parsed.Page(1)
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("content stream: %v", err)
+		}
+		return content
+	}
+
+	want := render("mm", 1)
+	for unit, perMM := range map[string]float64{"cm": 0.1, "in": 1 / 25.4, "pt": 72 / 25.4} {
+		if got := render(unit, perMM); !bytes.Equal(got, want) {
+			t.Errorf("%s layout differs from mm:\n%s\nwant:\n%s", unit, got, want)
+		}
+	}
+
+	// Custom page sizes are given in the document unit
+	doc := Document{Unit: "in", PageSize: "8.5x11", Pages: []Page{{}, {Size: "5 x 3"}}}
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	want2 := [][2]int{{612, 792}, {360, 216}}
+	for n, page := range parsed.Pages() {
+		w, h := page.MediaBox.Width(), page.MediaBox.Height()
+		if int(w+0.5) != want2[n-1][0] || int(h+0.5) != want2[n-1][1] {
+			t.Errorf("page %d is %.0fx%.0f, want %dx%d", n, w, h, want2[n-1][0], want2[n-1][1])
+		}
+	}
+
+	doc.Unit = "furlong"
+	if err := RenderDocument(&buf, &doc); err == nil || !strings.Contains(err.Error(), "furlong") {
+		t.Errorf("unknown unit: got error %v", err)
+	}
+}
+
+func TestRenderPageSizes(t *testing.T) {
+	// Named sizes in any case, and custom sizes with a unit of their own
+	doc := Document{Unit: "in", PageSize: "letter", Pages: []Page{{}, {Size: "Tabloid"}, {Size: "210x297mm"}, {Size: "100 x 50 pt"}}}
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	want := [][2]int{{612, 792}, {792, 1224}, {595, 842}, {100, 50}}
+	for n, page := range parsed.Pages() {
+		w, h := page.MediaBox.Width(), page.MediaBox.Height()
+		if int(w+0.5) != want[n-1][0] || int(h+0.5) != want[n-1][1] {
+			t.Errorf("page %d is %.0fx%.0f, want %dx%d", n, w, h, want[n-1][0], want[n-1][1])
+		}
+	}
+
+	for _, tt := range []struct {
+		doc  Document
+		want string
+	}{
+		{Document{PageSize: "A$"}, `unknown page size "A$"`},
+		{Document{PageSize: "0x297mm"}, `unknown page size "0x297mm"`},
+		{Document{Pages: []Page{{}, {Size: "A44"}}}, `page 2: unknown page size "A44"`},
+		{Document{Pages: []Page{{Size: "210x297furlong"}}}, `page 1: unknown page size`},
+	} {
+		if err := RenderDocument(&buf, &tt.doc); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got error %v, want one containing %q", err, tt.want)
+		}
+	}
+}
+
 func TestRenderWithHeaderFooter(t *testing.T) {
 	doc := Document{
 		Title: "Report",
@@ -186,6 +781,501 @@ func TestRenderWithHeaderFooter(t *testing.T) {
 	}
 }
 
+func TestRenderFooterPageCount(t *testing.T) {
+	doc := Document{
+		Footer: &Footer{Text: "Page {page} of {pages}"},
+		Pages: []Page{
+			{Elements: []Element{{Type: "paragraph", Text: "One"}}},
+			{Elements: []Element{{Type: "paragraph", Text: "Two"}}},
+			{Elements: []Element{{Type: "paragraph", Text: "Three"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(2)
+	text, err := page.ExtractText()
+	if err != nil {
+		t.Fatalf("extracting text: %v", err)
+	}
+	if !strings.Contains(text, "Page 2 of 3") {
+		t.Errorf("footer not resolved, page text:\n%s", text)
+	}
+	if strings.Contains(text, "{nb}") || strings.Contains(text, "{pages}") {
+		t.Errorf("unresolved placeholder in page text:\n%s", text)
+	}
+}
+
+func TestRenderTwoPass(t *testing.T) {
+	doc := Document{
+		Footer: &Footer{Text: "{pages}", Align: "R"},
+		Pages: []Page{
+			{Elements: []Element{
+				{Type: "heading", Text: "Intro", Level: 1},
+				{Type: "group", KeepTogether: true, Elements: []Element{{Type: "heading", Text: "Grouped", Level: 2}}},
+			}},
+			{Elements: []Element{{Type: "heading", Text: "Details", Level: 2}}},
+		},
+	}
+
+	footerX := func(opts RenderOptions) (float64, *Layout) {
+		t.Helper()
+		var buf bytes.Buffer
+		layout, err := RenderDocumentWithOptions(&buf, &doc, opts)
+		if err != nil {
+			t.Fatalf("RenderDocumentWithOptions(%+v) failed: %v", opts, err)
+		}
+		parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		page, _ := parsed.Page(1)
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("content stream: %v", err)
+		}
+		m := regexp.MustCompile(`BT ([\d.]+) [\d.]+ Td \(2\)Tj`).FindSubmatch(content)
+		if m == nil {
+			t.Fatalf("page count not found in footer:\n%s", content)
+		}
+		x, _ := strconv.ParseFloat(string(m[1]), 64)
+		return x, layout
+	}
+
+	onePassX, layout := footerX(RenderOptions{})
+	want := &Layout{Pages: 2, Headings: []HeadingPosition{
+		{Text: "Intro", Level: 1, Page: 1},
+		{Text: "Grouped", Level: 2, Page: 1},
+		{Text: "Details", Level: 2, Page: 2},
+	}}
+	if !reflect.DeepEqual(layout, want) {
+		t.Errorf("layout = %+v, want %+v", layout, want)
+	}
+
+	// In a single pass the footer is aligned using the width of the page
+	// count alias; with the measured count the text is flush right.
+	twoPassX, layout := footerX(RenderOptions{TwoPass: true})
+	if !reflect.DeepEqual(layout, want) {
+		t.Errorf("two-pass layout = %+v, want %+v", layout, want)
+	}
+	if twoPassX <= onePassX {
+		t.Errorf("two-pass footer at x=%.2f, single pass at %.2f; want it further right", twoPassX, onePassX)
+	}
+}
+
+func TestRenderConcurrently(t *testing.T) {
+	doc := Document{Pages: []Page{{Elements: []Element{
+		{Type: "heading", Text: "Intro", Level: 1},
+		{Type: "paragraph", Runs: []TextRun{{Text: "A cited claim", Footnote: "A source."}}},
+		{Type: "group", Elements: []Element{{Type: "heading", Text: "Grouped", Level: 3}}},
+	}}}}
+	want, err := RenderDocumentWithOptions(io.Discard, &doc, RenderOptions{})
+	if err != nil {
+		t.Fatalf("RenderDocumentWithOptions failed: %v", err)
+	}
+
+	// The render state belongs to each call, so one document can be
+	// rendered from several goroutines at once.
+	layouts := make([]*Layout, 8)
+	errs := make([]error, len(layouts))
+	var wg sync.WaitGroup
+	for i := range layouts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			layouts[i], errs[i] = RenderDocumentWithOptions(io.Discard, &doc, RenderOptions{TwoPass: true})
+		}(i)
+	}
+	wg.Wait()
+	for i, layout := range layouts {
+		if errs[i] != nil {
+			t.Fatalf("render %d failed: %v", i, errs[i])
+		}
+		if !reflect.DeepEqual(layout, want) {
+			t.Errorf("render %d: layout = %+v, want %+v", i, layout, want)
+		}
+	}
+}
+
+func TestRenderFootnotes(t *testing.T) {
+	elems := []Element{{Type: "paragraph", Runs: []TextRun{
+		{Text: "A cited claim", Footnote: "First source."},
+		{Text: " and another.", Footnote: "Second source."},
+	}}}
+	for i := 0; i < 40; i++ {
+		elems = append(elems, Element{Type: "paragraph", Text: fmt.Sprintf("Line %d", i)})
+	}
+	elems = append(elems, Element{Type: "paragraph", Runs: []TextRun{{Text: "Later", Footnote: "Third source."}}})
+	doc := Document{Footer: &Footer{Text: "Page {page}"}, Pages: []Page{{Elements: elems}}}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if parsed.NumPages() != 2 {
+		t.Fatalf("got %d pages, want 2", parsed.NumPages())
+	}
+
+	td := regexp.MustCompile(`BT [\d.]+ ([\d.]+) Td \(([^)]*)\)Tj`)
+	for n, want := range map[int][]string{1: {"1  First source.", "2  Second source."}, 2: {"3  Third source."}} {
+		page, _ := parsed.Page(n)
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("content stream: %v", err)
+		}
+		texts := make(map[string]float64)
+		lowestLine := 1000.0
+		for _, m := range td.FindAllSubmatch(content, -1) {
+			y, _ := strconv.ParseFloat(string(m[1]), 64)
+			texts[string(m[2])] = y
+			if strings.HasPrefix(string(m[2]), "Line") && y < lowestLine {
+				lowestLine = y
+			}
+		}
+		for i, note := range want {
+			y, ok := texts[note]
+			if !ok {
+				t.Errorf("page %d: footnote %q not found:\n%s", n, note, content)
+				continue
+			}
+			footer := texts[fmt.Sprintf("Page %d", n)]
+			if i == 0 && (y >= lowestLine || y <= footer) {
+				t.Errorf("page %d: note at y=%.2f, want between footer (%.2f) and text (%.2f)", n, y, footer, lowestLine)
+			}
+		}
+		if n == 1 && !bytes.Contains(content, []byte("(1)Tj")) {
+			t.Errorf("page 1: footnote marker not found")
+		}
+	}
+}
+
+func TestRenderFlow(t *testing.T) {
+	doc := Document{Pages: []Page{{Elements: []Element{
+		{Type: "paragraph", Text: "Plain and", Flow: true},
+		{Type: "paragraph", Text: "red", Flow: true, Color: &Color{R: 255}},
+		{Type: "paragraph", Flow: true, Runs: []TextRun{
+			{Text: "text with an "},
+			{Image: "../image/logo.png", ImageHeight: 4, Text: " icon"},
+		}},
+		{Type: "paragraph", Text: "Next block"},
+	}}}}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	type pos struct{ x, y float64 }
+	texts := make(map[string]pos)
+	td := regexp.MustCompile(`BT ([\d.]+) ([\d.]+) Td \(([^)]*)\)Tj`)
+	for _, m := range td.FindAllSubmatch(content, -1) {
+		x, _ := strconv.ParseFloat(string(m[1]), 64)
+		y, _ := strconv.ParseFloat(string(m[2]), 64)
+		texts[string(m[3])] = pos{x, y}
+	}
+	line := []string{"Plain and", "red", "text with an ", " icon"}
+	for i, s := range line {
+		p, ok := texts[s]
+		if !ok {
+			t.Fatalf("text %q not found:\n%s", s, content)
+		}
+		if first := texts[line[0]]; p.y != first.y {
+			t.Errorf("%q at y=%.2f, want on the line of %q at y=%.2f", s, p.y, line[0], first.y)
+		}
+		if i > 0 && p.x <= texts[line[i-1]].x {
+			t.Errorf("%q at x=%.2f, want after %q", s, p.x, line[i-1])
+		}
+	}
+
+	// The icon, 4 mm high, sits between the text before and after it
+	cm := regexp.MustCompile(`q ([\d.]+) 0 0 ([\d.]+) ([\d.]+) ([\d.]+) cm /I`).FindSubmatch(content)
+	if cm == nil {
+		t.Fatalf("inline image not found:\n%s", content)
+	}
+	h, _ := strconv.ParseFloat(string(cm[2]), 64)
+	x, _ := strconv.ParseFloat(string(cm[3]), 64)
+	if math.Abs(h-4*72/25.4) > 0.01 {
+		t.Errorf("image height %.2f, want 4 mm", h)
+	}
+	if x <= texts["text with an "].x || x >= texts[" icon"].x {
+		t.Errorf("image at x=%.2f, want between the surrounding text", x)
+	}
+
+	next := texts["Next block"]
+	if next.y >= texts[line[0]].y || next.x != texts[line[0]].x {
+		t.Errorf("block paragraph at (%.2f, %.2f), want on a new line below the flowing text", next.x, next.y)
+	}
+}
+
+func TestRenderImageFit(t *testing.T) {
+	const src = "../image/logo.png" // 104 x 71 px
+	tests := []struct {
+		elem       Element
+		w, h, x, y float64 // placement in points; y is the top edge
+		clipped    bool
+	}{
+		// 20mm margins leave a 170mm (481.89pt) column on A4
+		{Element{Type: "image", Src: src, Fit: "width"}, 481.89, 481.89 * 71 / 104, 56.69, 56.69, false},
+		{Element{Type: "image", Src: src, Fit: "contain", Width: 100, Height: 100}, 283.46, 283.46 * 71 / 104, 56.69, 56.69 + (283.46-283.46*71/104)/2, false},
+		{Element{Type: "image", Src: src, Fit: "cover", Width: 100, Height: 100}, 283.46 * 104 / 71, 283.46, 56.69 - (283.46*104/71-283.46)/2, 56.69, true},
+		{Element{Type: "image", Src: src, Width: 50}, 141.73, 141.73 * 71 / 104, 56.69, 56.69, false},
+	}
+	cm := regexp.MustCompile(`q ([\d.]+) 0 0 ([\d.]+) (-?[\d.]+) (-?[\d.]+) cm /I`)
+	near := func(a, b float64) bool { return a-b < 0.05 && b-a < 0.05 }
+
+	for _, tt := range tests {
+		doc := Document{
+			Margin: &Margin{Top: 20, Right: 20, Bottom: 20, Left: 20},
+			Pages:  []Page{{Elements: []Element{tt.elem, {Type: "paragraph", Text: "After"}}}},
+		}
+		var buf bytes.Buffer
+		if err := RenderDocument(&buf, &doc); err != nil {
+			t.Fatalf("fit %q: RenderDocument failed: %v", tt.elem.Fit, err)
+		}
+		parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		page, _ := parsed.Page(1)
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("content stream: %v", err)
+		}
+		m := cm.FindSubmatch(content)
+		if m == nil {
+			t.Fatalf("fit %q: image not found:\n%s", tt.elem.Fit, content)
+		}
+		var v [4]float64
+		for i := range v {
+			v[i], _ = strconv.ParseFloat(string(m[i+1]), 64)
+		}
+		top := 841.89 - v[3] - v[1]
+		if !near(v[0], tt.w) || !near(v[1], tt.h) || !near(v[2], tt.x) || !near(top, tt.y) {
+			t.Errorf("fit %q: image %.2fx%.2f at (%.2f, %.2f), want %.2fx%.2f at (%.2f, %.2f)",
+				tt.elem.Fit, v[0], v[1], v[2], top, tt.w, tt.h, tt.x, tt.y)
+		}
+		if clipped := bytes.Contains(content, []byte(" re W n")); clipped != tt.clipped {
+			t.Errorf("fit %q: clipped = %v", tt.elem.Fit, clipped)
+		}
+
+		// The following text starts below the image's box
+		boxH := tt.h
+		if tt.elem.Height > 0 {
+			boxH = 283.46
+		}
+		after := regexp.MustCompile(`BT [\d.]+ ([\d.]+) Td \(After\)Tj`).FindSubmatch(content)
+		if y, _ := strconv.ParseFloat(string(after[1]), 64); 841.89-y < 56.69+boxH {
+			t.Errorf("fit %q: text at %.2f overlaps the image", tt.elem.Fit, 841.89-y)
+		}
+	}
+
+	doc := Document{Pages: []Page{{Elements: []Element{{Type: "image", Src: src, Fit: "stretch"}}}}}
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err == nil || !strings.Contains(err.Error(), "stretch") {
+		t.Errorf("unknown fit: got error %v", err)
+	}
+	doc.Pages[0].Elements[0].Fit = "cover"
+	if err := RenderDocument(&buf, &doc); err == nil {
+		t.Error("cover without a height: expected an error")
+	}
+}
+
+func TestRenderCode(t *testing.T) {
+	code := "func add(a, b int) int {\n\treturn a + b\n}\n\n// " + strings.Repeat("x", 200)
+	doc := Document{Pages: []Page{{Elements: []Element{
+		{Type: "code", Language: "go", Text: code},
+	}}}}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	if !bytes.Contains(content, []byte("(    return a + b)Tj")) {
+		t.Error("indentation of the second line not kept")
+	}
+	if !bytes.Contains(content, []byte("0.961 g")) {
+		t.Error("background fill not found")
+	}
+	// The long comment line is broken rather than run off the page
+	if n := bytes.Count(content, []byte("xxxxxxxxxx")); n < 2 {
+		t.Errorf("long line drawn on %d lines, want it broken", n)
+	}
+}
+
+func TestBreakLineWideGlyph(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Courier", "", 72)
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"W", []string{"W"}},
+		{"WW", []string{"W", "W"}},
+		{"WWW", []string{"W", "W", "W"}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := breakLine(pdf, tt.line, 1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("breakLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// A code block in a column narrower than one character still renders
+	doc := Document{Margin: &Margin{Left: 100, Right: 100}, Pages: []Page{{Elements: []Element{
+		{Type: "code", Text: "ab", Font: &Font{Size: 200}},
+	}}}}
+	if err := RenderDocument(io.Discard, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+}
+
+func TestRenderQuoteAndCallout(t *testing.T) {
+	doc := Document{Pages: []Page{{Elements: []Element{
+		{Type: "blockquote", Text: "Simplicity is prerequisite for reliability."},
+		{Type: "callout", Kind: "warning", Text: "Back up your data first."},
+		{Type: "callout", Text: "Custom colors.", AccentColor: &Color{R: 255}, FillColor: &Color{R: 255, G: 255}},
+	}}}}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := parsed.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("content stream: %v", err)
+	}
+
+	for name, op := range map[string]string{
+		"blockquote bar": "0.706 g",
+		"warning bar":    "0.902 0.494 0.133 rg",
+		"warning fill":   "0.992 0.949 0.910 rg",
+		"custom bar":     "1.000 0.000 0.000 rg",
+		"custom fill":    "1.000 1.000 0.000 rg",
+		"callout text":   "(Back up your data first.)Tj",
+		"quote text":     "(Simplicity is prerequisite for reliability.)Tj",
+	} {
+		if !bytes.Contains(content, []byte(op)) {
+			t.Errorf("%s %q not found in content", name, op)
+		}
+	}
+
+	doc.Pages[0].Elements = []Element{{Type: "callout", Kind: "gossip", Text: "?"}}
+	if err := RenderDocument(&buf, &doc); err == nil {
+		t.Error("expected an error for an unknown callout kind")
+	}
+}
+
+func TestRenderGroupKeepsTogether(t *testing.T) {
+	body := strings.Repeat("Body text that belongs with its heading. ", 30)
+	pageOf := func(doc Document, needle string) int {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := RenderDocument(&buf, &doc); err != nil {
+			t.Fatalf("RenderDocument failed: %v", err)
+		}
+		parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		for n, page := range parsed.Pages() {
+			text, _ := page.ExtractText()
+			if strings.Contains(text, needle) {
+				return n
+			}
+		}
+		t.Fatalf("%q not found", needle)
+		return 0
+	}
+	filler := Element{Type: "spacer", SpacerHeight: 250}
+	heading := Element{Type: "heading", Text: "Results", Level: 2}
+	paragraph := Element{Type: "paragraph", Text: body}
+
+	loose := Document{Pages: []Page{{Elements: []Element{filler, heading, paragraph}}}}
+	if n := pageOf(loose, "Results"); n != 1 {
+		t.Fatalf("without a group the heading should stay on page 1, got page %d", n)
+	}
+
+	grouped := Document{Pages: []Page{{Elements: []Element{
+		filler,
+		{Type: "group", Elements: []Element{heading, paragraph}},
+	}}}}
+	if n := pageOf(grouped, "Results"); n != 2 {
+		t.Errorf("grouped heading on page %d, want 2", n)
+	}
+
+	table := Element{
+		Type:         "table",
+		KeepTogether: true,
+		Columns:      []TableColumn{{Header: "Quarter"}, {Header: "Total"}},
+		Rows:         [][]string{{"Q1", "10"}, {"Q2", "20"}, {"Q3", "30"}, {"Q4", "40"}},
+	}
+	kept := Document{Pages: []Page{{Elements: []Element{filler, table}}}}
+	if n := pageOf(kept, "Quarter"); n != 2 {
+		t.Errorf("keepTogether table starts on page %d, want 2", n)
+	}
+}
+
+func TestRenderBackgroundAndWatermark(t *testing.T) {
+	doc := Document{
+		Background: &Color{R: 250, G: 248, B: 240},
+		Watermark:  &Watermark{Text: "DRAFT", Opacity: 0.2},
+		Footer:     &Footer{Text: "Page {page}"},
+		Pages: []Page{
+			{Elements: []Element{{Type: "paragraph", Text: "First page."}}},
+			{Elements: []Element{{Type: "paragraph", Text: "Second page."}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderDocument(&buf, &doc); err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+
+	parsed, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	for num, page := range parsed.Pages() {
+		if !page.UsesTransparency() {
+			t.Errorf("page %d: expected semi-transparent watermark", num)
+		}
+	}
+}
+
 func TestRenderWithCustomFont(t *testing.T) {
 	doc := Document{
 		Font: &Font{Family: "Courier", Size: 12},
@@ -270,11 +1360,13 @@ func TestRenderInvalidJSON(t *testing.T) {
 
 func TestRenderUnknownElementType(t *testing.T) {
 	doc := Document{
-		Pages: []Page{{
-			Elements: []Element{
+		Pages: []Page{
+			{Elements: []Element{{Type: "paragraph", Text: "Fine"}}},
+			{Elements: []Element{
+				{Type: "paragraph", Text: "Fine"},
 				{Type: "nonexistent"},
-			},
-		}},
+			}},
+		},
 	}
 
 	var buf bytes.Buffer
@@ -285,6 +1377,16 @@ func TestRenderUnknownElementType(t *testing.T) {
 	if !strings.Contains(err.Error(), "unknown element type") {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	var renderErr *RenderError
+	if !errors.As(err, &renderErr) {
+		t.Fatalf("error %v is not a *RenderError", err)
+	}
+	if renderErr.Page != 2 || renderErr.ElementIndex != 1 || renderErr.Type != "nonexistent" {
+		t.Errorf("RenderError = %+v, want page 2, element index 1, type nonexistent", renderErr)
+	}
+	if want := "doctpl: page 2, element 2 (nonexistent): "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("error %q, want prefix %q", err, want)
+	}
 }
 
 func TestRenderEmptyPages(t *testing.T) {
@@ -2,7 +2,9 @@
 //
 // It allows defining PDF documents using a declarative JSON schema that is easy
 // for both humans and LLMs to generate. The schema supports text, headings,
-// paragraphs, tables, images, lines, rectangles, and spacers.
+// paragraphs, tables, images, lines, rectangles, spacers, lists, "repeat"
+// elements that render a template once per item of a data array, and
+// "group" elements that keep their contents on one page.
 //
 // Example JSON:
 //
@@ -18,18 +20,61 @@
 //	}
 package doctpl
 
+import "encoding/json"
+
 // Document is the top-level template that describes an entire PDF.
 type Document struct {
-	Title    string   `json:"title,omitempty"`
-	Author   string   `json:"author,omitempty"`
-	Subject  string   `json:"subject,omitempty"`
-	PageSize string   `json:"pageSize,omitempty"` // A4, Letter, Legal (default: A4)
-	Unit     string   `json:"unit,omitempty"`     // mm, cm, in, pt (default: mm)
-	Margin   *Margin  `json:"margin,omitempty"`
-	Font     *Font    `json:"font,omitempty"` // default font for the document
-	Pages    []Page   `json:"pages"`
-	Header   *Header  `json:"header,omitempty"` // repeated on every page
-	Footer   *Footer  `json:"footer,omitempty"` // repeated on every page
+	Title    string  `json:"title,omitempty"`
+	Author   string  `json:"author,omitempty"`
+	Subject  string  `json:"subject,omitempty"`
+	PageSize string  `json:"pageSize,omitempty"` // A1-A6, Letter, Legal, Tabloid or "WxH" in Unit or with a unit suffix, e.g. "8.5x11", "210x297mm" (default: A4)
+	Unit     string  `json:"unit,omitempty"`     // mm, cm, in, pt (default: mm); applies to all lengths
+	Margin   *Margin `json:"margin,omitempty"`
+	Font     *Font   `json:"font,omitempty"` // default font for the document
+	Pages    []Page  `json:"pages"`
+	Header   *Header `json:"header,omitempty"` // repeated on every page
+	Footer   *Footer `json:"footer,omitempty"` // repeated on every page
+
+	Background *Color     `json:"background,omitempty"` // page background color
+	Watermark  *Watermark `json:"watermark,omitempty"`  // drawn over every page
+
+	// Outline lists every heading in the PDF outline, the navigation tree
+	// shown in a viewer's sidebar, nested by heading level (default: true).
+	// Bookmark elements are added regardless.
+	Outline *bool `json:"outline,omitempty"`
+
+	// Dir is the default text direction of headings and paragraphs, "ltr"
+	// (default) or "rtl". Elements setting their own Dir override it.
+	Dir string `json:"dir,omitempty"`
+
+	// Fonts registers TrueType fonts that elements can then select by
+	// family, such as a font covering Arabic or Hebrew for "dir": "rtl".
+	Fonts []FontFile `json:"fonts,omitempty"`
+
+	// Styles holds named styles that elements reference by name.
+	Styles map[string]Style `json:"styles,omitempty"`
+
+	// TableStyle is the default appearance of tables. Element headerStyle
+	// and cellStyle settings take precedence over it.
+	TableStyle *TableStyle `json:"tableStyle,omitempty"`
+}
+
+// Watermark is text drawn diagonally across the centre of every page.
+type Watermark struct {
+	Text     string  `json:"text"`
+	FontSize float64 `json:"fontSize,omitempty"` // in points (default: 60)
+	Color    *Color  `json:"color,omitempty"`    // default: light gray
+	Opacity  float64 `json:"opacity,omitempty"`  // 0.0 to 1.0 (default: 0.3)
+	Angle    float64 `json:"angle,omitempty"`    // rotation in degrees (default: 45)
+}
+
+// Style is a named set of text properties. An element referencing a style
+// uses its values for any field the element does not set itself.
+type Style struct {
+	Font       *Font   `json:"font,omitempty"`
+	Color      *Color  `json:"color,omitempty"`
+	Align      string  `json:"align,omitempty"`
+	LineHeight float64 `json:"lineHeight,omitempty"`
 }
 
 // Margin defines page margins.
@@ -47,6 +92,13 @@ type Font struct {
 	Size   float64 `json:"size"`
 }
 
+// FontFile is a TrueType font file registered under a family and style.
+type FontFile struct {
+	Family string `json:"family"`
+	Style  string `json:"style,omitempty"` // "" (regular), "B", "I", "BI"
+	File   string `json:"file"`            // path to the .ttf file
+}
+
 // Color is an RGB color.
 type Color struct {
 	R int `json:"r"`
@@ -56,27 +108,59 @@ type Color struct {
 
 // Page represents a single page of the document.
 type Page struct {
-	Size     string    `json:"size,omitempty"` // override document page size
-	Elements []Element `json:"elements"`
+	Size        string    `json:"size,omitempty"`        // override document page size, named or "WxH"
+	Orientation string    `json:"orientation,omitempty"` // P (portrait) or L (landscape) (default: P)
+	Elements    []Element `json:"elements"`
 }
 
 // Element is a single visual element within a page.
 // The Type field determines which other fields are relevant.
 type Element struct {
-	Type string `json:"type"` // heading, paragraph, table, image, line, rect, spacer, list, hr
+	Type string `json:"type"` // heading, paragraph, code, blockquote, callout, table, image, line, rect, spacer, list, hr, repeat, group, bookmark
 
-	// Text content (heading, paragraph)
+	// Text content (heading, paragraph, code, blockquote, callout)
 	Text  string `json:"text,omitempty"`
-	Level int    `json:"level,omitempty"` // heading level 1-6
+	Level int    `json:"level,omitempty"` // heading level 1-6; outline level of a bookmark
 	Align string `json:"align,omitempty"` // L, C, R (default: L)
 
+	// Dir is the text direction of a heading or paragraph: "ltr" or "rtl"
+	// (default: the document's Dir). Right-to-left text is reordered for
+	// display, Arabic letters are joined, and the default alignment
+	// becomes R. Inline runs are drawn as plain text in this mode.
+	Dir string `json:"dir,omitempty"`
+
+	// Language names the language of a code element's text. It is
+	// informational and does not affect rendering.
+	Language string `json:"language,omitempty"`
+
+	// Runs holds paragraph text with inline styling, such as a bold word
+	// within a sentence. It takes precedence over Text; runs are written
+	// one after another and wrapped flush left.
+	Runs []TextRun `json:"runs,omitempty"`
+
+	// Flow writes a paragraph as flowing text that continues the line left
+	// open by the flowing paragraph before it, rather than as a block on
+	// lines of its own, so that differently colored or sized text can share
+	// a line. It is set flush left and wraps at the right margin. The next
+	// element that does not flow starts on a new line. Right-to-left
+	// paragraphs do not flow.
+	Flow bool `json:"flow,omitempty"`
+
 	// Font override for this element
-	Font  *Font  `json:"font,omitempty"`
-	Color *Color `json:"color,omitempty"`
+	Font       *Font   `json:"font,omitempty"`
+	Color      *Color  `json:"color,omitempty"`
+	LineHeight float64 `json:"lineHeight,omitempty"` // heading, paragraph, list
+
+	// Style names an entry of Document.Styles; inline fields override it.
+	Style string `json:"style,omitempty"`
 
 	// Table
-	Columns     []TableColumn `json:"columns,omitempty"`
-	Rows        [][]string    `json:"rows,omitempty"`
+	Columns []TableColumn `json:"columns,omitempty"`
+	Rows    [][]string    `json:"rows,omitempty"`
+
+	// Cells holds data rows whose cells can span columns or have their own
+	// style. They are added after Rows.
+	Cells       [][]TableCell `json:"cells,omitempty"`
 	HeaderStyle *CellStyle    `json:"headerStyle,omitempty"`
 	CellStyle   *CellStyle    `json:"cellStyle,omitempty"`
 
@@ -87,6 +171,12 @@ type Element struct {
 	Width  float64 `json:"width,omitempty"`
 	Height float64 `json:"height,omitempty"`
 
+	// Fit scales an image keeping its aspect ratio: "width" spans the
+	// content width; "contain" fits inside Width by Height and "cover"
+	// fills it, cropping the overflow. Width defaults to the content width
+	// and Height, for contain, to the page's content height.
+	Fit string `json:"fit,omitempty"`
+
 	// Line
 	X1 float64 `json:"x1,omitempty"`
 	Y1 float64 `json:"y1,omitempty"`
@@ -98,13 +188,121 @@ type Element struct {
 	LineWidth    float64 `json:"lineWidth,omitempty"`
 
 	// List
-	Items     []string `json:"items,omitempty"`
-	Ordered   bool     `json:"ordered,omitempty"`
-	BulletStr string   `json:"bullet,omitempty"` // custom bullet character
+	Items     []string   `json:"items,omitempty"`
+	ListItems []ListItem `json:"listItems,omitempty"` // nested items; takes precedence over Items
+	Ordered   bool       `json:"ordered,omitempty"`
+	BulletStr string     `json:"bullet,omitempty"` // custom bullet character
+
+	// Background (rect, code, blockquote, callout)
+	FillColor *Color `json:"fillColor,omitempty"`
+	Border    bool   `json:"border,omitempty"`
+
+	// Blockquote / callout: Kind selects the callout colors (note, tip,
+	// warning or danger) and AccentColor overrides the left bar color.
+	Kind        string `json:"kind,omitempty"`
+	AccentColor *Color `json:"accentColor,omitempty"`
+
+	// Group: Elements are rendered in order and kept on one page, moving to
+	// the next page first if they would not fit in the space left.
+	Elements []Element `json:"elements,omitempty"`
+
+	// KeepTogether moves an element to the next page if it would not fit in
+	// the space left, so that a table, say, is not split. It has no effect on
+	// elements taller than a page.
+	KeepTogether bool `json:"keepTogether,omitempty"`
+
+	// Repeat: Template is rendered once per entry of RepeatItems, with
+	// {{field}} placeholders in its text replaced by the entry's values.
+	// RepeatItems is read from and written to the "items" JSON key when
+	// Type is "repeat".
+	RepeatItems []map[string]interface{} `json:"-"`
+	Template    *Element                 `json:"template,omitempty"`
+
+	// Bookmark: Title is the text of an outline entry pointing at the
+	// current position. It defaults to Text.
+	Title string `json:"title,omitempty"`
+}
+
+// UnmarshalJSON decodes an element. The "items" key holds list entries for
+// list elements and data records for repeat elements.
+func (e *Element) UnmarshalJSON(data []byte) error {
+	type element Element
+	var aux struct {
+		element
+		Items json.RawMessage `json:"items,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*e = Element(aux.element)
+	if len(aux.Items) == 0 {
+		return nil
+	}
+	if e.Type == "repeat" {
+		return json.Unmarshal(aux.Items, &e.RepeatItems)
+	}
+	return json.Unmarshal(aux.Items, &e.Items)
+}
+
+// MarshalJSON encodes an element, writing RepeatItems under the "items" key
+// for repeat elements.
+func (e Element) MarshalJSON() ([]byte, error) {
+	type element Element
+	if e.Type != "repeat" {
+		return json.Marshal(element(e))
+	}
+	return json.Marshal(struct {
+		element
+		Items []map[string]interface{} `json:"items,omitempty"`
+	}{element(e), e.RepeatItems})
+}
+
+// TextRun is a piece of paragraph text set in its own font style.
+//
+// A run with a Footnote is followed by a superscript note number; the note
+// text is printed with that number at the bottom of the page, above the
+// footer. Notes are numbered through the whole document.
+type TextRun struct {
+	Text     string `json:"text"`
+	Style    string `json:"style,omitempty"`    // "" (regular), "B", "I", "BI"; "U" adds an underline
+	Family   string `json:"family,omitempty"`   // font family override, e.g. Courier for inline code
+	Footnote string `json:"footnote,omitempty"` // text of a footnote referenced after the run
 
-	// Background (rect)
-	FillColor *Color  `json:"fillColor,omitempty"`
-	Border    bool    `json:"border,omitempty"`
+	// Image draws an image file inline before the run's text, such as an
+	// icon within a sentence, centered on the line. ImageHeight defaults
+	// to the font size; the width keeps the image's aspect ratio.
+	Image       string  `json:"image,omitempty"`
+	ImageHeight float64 `json:"imageHeight,omitempty"`
+}
+
+// ListItem is a single entry of a list element. Sub-items are rendered one
+// indentation level deeper. In JSON an item may be given either as a plain
+// string or as an object:
+//
+//	"listItems": [
+//	  "Fruit",
+//	  {"text": "Vegetables", "items": ["Carrots", {"text": "Greens", "items": ["Kale"]}]}
+//	]
+type ListItem struct {
+	Text  string     `json:"text"`
+	Items []ListItem `json:"items,omitempty"`
+}
+
+// UnmarshalJSON accepts either a plain string or an object with text and
+// items fields.
+func (li *ListItem) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*li = ListItem{Text: text}
+		return nil
+	}
+	type listItem ListItem
+	var item listItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	*li = ListItem(item)
+	return nil
 }
 
 // TableColumn defines a column in a table element.
@@ -112,6 +310,55 @@ type TableColumn struct {
 	Header string  `json:"header"`
 	Width  float64 `json:"width,omitempty"` // 0 = auto
 	Align  string  `json:"align,omitempty"` // L, C, R
+
+	// HeaderColspan makes the header span this and the following columns,
+	// whose own headers are then not drawn.
+	HeaderColspan int `json:"headerColspan,omitempty"`
+}
+
+// TableCell is a cell of a table element's Cells. In JSON a cell may be
+// given either as a plain string or as an object:
+//
+//	"cells": [
+//	  ["Widget", "2", "$10.00"],
+//	  [{"text": "Subtotal", "colspan": 2, "align": "R"}, "$20.00"]
+//	]
+//
+// The styling fields of an object cell take precedence over the table's
+// cell style and row striping; font fields are overridden one by one.
+type TableCell struct {
+	Text      string `json:"text"`
+	Colspan   int    `json:"colspan,omitempty"` // number of columns spanned (default: 1)
+	Align     string `json:"align,omitempty"`   // overrides the column alignment
+	FillColor *Color `json:"fillColor,omitempty"`
+	TextColor *Color `json:"textColor,omitempty"`
+	Font      *Font  `json:"font,omitempty"`
+}
+
+// UnmarshalJSON accepts either a plain string or an object with text,
+// colspan and styling fields.
+func (c *TableCell) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = TableCell{Text: text}
+		return nil
+	}
+	type tableCell TableCell
+	var cell tableCell
+	if err := json.Unmarshal(data, &cell); err != nil {
+		return err
+	}
+	*c = TableCell(cell)
+	return nil
+}
+
+// MarshalJSON encodes a cell holding only text as a plain string.
+func (c TableCell) MarshalJSON() ([]byte, error) {
+	if c == (TableCell{Text: c.Text}) {
+		return json.Marshal(c.Text)
+	}
+	type tableCell TableCell
+	return json.Marshal(tableCell(c))
 }
 
 // CellStyle defines styling for table cells.
@@ -121,6 +368,16 @@ type CellStyle struct {
 	Font      *Font  `json:"font,omitempty"`
 }
 
+// TableStyle defines the appearance of tables.
+type TableStyle struct {
+	HeaderStyle *CellStyle `json:"headerStyle,omitempty"` // default: blue fill, white bold text
+	CellStyle   *CellStyle `json:"cellStyle,omitempty"`   // data cells
+	StripeColor *Color     `json:"stripeColor,omitempty"` // fill of every other data row (default: light gray)
+	BorderColor *Color     `json:"borderColor,omitempty"` // default: black
+	BorderWidth float64    `json:"borderWidth,omitempty"`
+	Padding     float64    `json:"padding,omitempty"` // cell padding (default: 2 mm)
+}
+
 // Header defines content repeated at the top of every page.
 type Header struct {
 	Text  string `json:"text,omitempty"`
@@ -131,8 +388,8 @@ type Header struct {
 
 // Footer defines content repeated at the bottom of every page.
 type Footer struct {
-	Text   string `json:"text,omitempty"` // supports {page} and {pages} placeholders
-	Align  string `json:"align,omitempty"`
-	Font   *Font  `json:"font,omitempty"`
-	Color  *Color `json:"color,omitempty"`
+	Text  string `json:"text,omitempty"` // supports {page} and {pages} placeholders
+	Align string `json:"align,omitempty"`
+	Font  *Font  `json:"font,omitempty"`
+	Color *Color `json:"color,omitempty"`
 }
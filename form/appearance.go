@@ -0,0 +1,113 @@
+package form
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+var appearanceFontSizeRe = regexp.MustCompile(`(\d*\.?\d+)\s+Tf`)
+
+// helvResources is the resource dictionary of generated appearance streams,
+// matching the /Helv font of the AcroForm default resources.
+const helvResources = "/Resources <</Font <</Helv <</Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding>>>>>>"
+
+// textAppearance returns the dictionary entries and content stream of the
+// normal appearance of a w×h point text field showing value in Helvetica.
+// A fontSize of 0 selects a size that fits the field height. Multi-line
+// fields are drawn from the top, one line per newline in value; other
+// fields are drawn on a single, vertically centered line. Lines are aligned
+// according to quadding, as in the /Q entry: 0 left, 1 centered, 2 right.
+// frame holds operators drawn first, such as the field background.
+func textAppearance(value string, w, h, fontSize float64, multiLine bool, quadding int, frame string) (string, []byte) {
+	if fontSize <= 0 {
+		fontSize = min(12, h*0.6)
+		if multiLine {
+			fontSize = 12
+		}
+	}
+	leading := fontSize * 1.15
+
+	lines := []string{value}
+	// Center the line between the baseline and the cap height
+	ty := (h-fontSize*0.72)/2 + 0.5
+	if multiLine {
+		lines = strings.Split(value, "\n")
+		ty = h - 2 - fontSize*0.9
+	}
+
+	var b strings.Builder
+	b.WriteString(frame)
+	fmt.Fprintf(&b, "/Tx BMC\nq\n1 1 %.2f %.2f re W n\nBT\n/Helv %.2f Tf 0 g\n", w-2, h-2, fontSize)
+	for i, line := range lines {
+		text := winAnsiText(line)
+		tx := 2.0
+		switch quadding {
+		case 1:
+			tx = (w - helveticaWidth(text)*fontSize) / 2
+		case 2:
+			tx = w - 2 - helveticaWidth(text)*fontSize
+		}
+		fmt.Fprintf(&b, "1 0 0 1 %.2f %.2f Tm\n(%s) Tj\n", tx, ty-float64(i)*leading, escapePDFString(text))
+	}
+	b.WriteString("ET\nQ\nEMC")
+
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f] %s", w, h, helvResources)
+	return dict, []byte(b.String())
+}
+
+var (
+	helvOnce    sync.Once
+	helvMetrics *gofpdf.Fpdf
+)
+
+// helveticaWidth returns the width of WinAnsiEncoding text in Helvetica,
+// in units of the font size.
+func helveticaWidth(text string) float64 {
+	helvOnce.Do(func() {
+		helvMetrics = gofpdf.New("P", "pt", "A4", "")
+		helvMetrics.SetFont("Helvetica", "", 12)
+	})
+	return float64(helvMetrics.GetStringSymbolWidth(text)) / 1000
+}
+
+// fontSizeFromDA returns the font size of a default appearance string such
+// as "/Helv 12 Tf 0 g", or 0 if it has none.
+func fontSizeFromDA(da string) float64 {
+	m := appearanceFontSizeRe.FindStringSubmatch(da)
+	if m == nil {
+		return 0
+	}
+	size, _ := strconv.ParseFloat(m[1], 64)
+	return size
+}
+
+// winAnsiSpecial maps the characters of WinAnsiEncoding outside Latin-1 to
+// their codes.
+var winAnsiSpecial = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsiText converts UTF-8 text to WinAnsiEncoding for display with a
+// standard font. Characters that the encoding lacks become '?'.
+func winAnsiText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch c, ok := winAnsiSpecial[r]; {
+		case ok:
+			b.WriteByte(c)
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
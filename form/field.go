@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/reader"
 )
 
 // FieldType specifies the type of form field.
@@ -20,22 +21,58 @@ const (
 	TypeCheckbox                  // checkbox (on/off)
 	TypeDropdown                  // dropdown/combo box
 	TypeButton                    // push button
+	TypeRadio                     // radio button group (one choice of several)
+	TypeListBox                   // scrolling list of options
+	TypeSignature                 // digital signature placeholder
 )
 
+// RGBColor is a color with red, green and blue components from 0 to 255.
+type RGBColor struct {
+	R, G, B int
+}
+
+// components returns the color as PDF color components from 0 to 1.
+func (c RGBColor) components() string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// RadioOption defines one button of a radio group.
+type RadioOption struct {
+	Value string  // export value of the button, selected by Field.Value
+	X, Y  float64 // position in user units
+	Size  float64 // width and height in user units
+}
+
 // Field defines a form field to be added to a PDF page.
 type Field struct {
-	Name     string    // field name (must be unique within the form)
-	Type     FieldType // field type
-	Page     int       // page number (1-based)
-	X, Y     float64   // position in user units
-	W, H     float64   // width and height in user units
-	Value    string    // default value
-	Options  []string  // options for dropdown/radio fields
-	FontSize float64   // font size for text display (default: 12)
-	MaxLen   int       // maximum text length (0 = unlimited)
-	ReadOnly bool      // whether the field is read-only
-	Required bool      // whether the field is required
-	MultiLine bool     // for text fields: allow multi-line input
+	Name      string    // field name (must be unique within the form)
+	Type      FieldType // field type
+	Page      int       // page number (1-based)
+	X, Y      float64   // position in user units
+	W, H      float64   // width and height in user units
+	Value     string    // default value, written as both /V and /DV
+	Options   []string  // options for dropdown/radio fields
+	FontSize  float64   // font size for text display (default: 12)
+	MaxLen    int       // maximum text length (0 = unlimited)
+	ReadOnly  bool      // whether the field is read-only
+	Required  bool      // whether the field is required
+	MultiLine bool      // for text fields: allow multi-line input
+	Alignment string    // text alignment: "L" (default), "C" or "R"
+	Tooltip   string    // tooltip and accessible description of the field
+	// MultiSelect allows several options of a list box to be selected.
+	// Values holds the initially selected options; Value may be used
+	// instead for a single selection.
+	MultiSelect bool
+	Values      []string
+	// BorderColor and BackgroundColor color the widget box; nil leaves the
+	// border or background undrawn. BorderWidth is in points and defaults
+	// to 1 if a border color is set.
+	BorderColor     *RGBColor
+	BackgroundColor *RGBColor
+	BorderWidth     float64
+	// RadioButtons holds the buttons of a radio group; Options lists their
+	// values.
+	RadioButtons []RadioOption
 }
 
 // FormBuilder manages the creation of interactive form fields on a PDF.
@@ -71,6 +108,20 @@ func (fb *FormBuilder) AddCheckbox(name string, page int, x, y, size float64) *F
 	})
 }
 
+// AddRadioGroup adds a group of radio buttons sharing the field name, of
+// which at most one can be selected. Use SetValue with an option's value to
+// select it initially.
+func (fb *FormBuilder) AddRadioGroup(name string, page int, options []RadioOption) *Field {
+	values := make([]string, len(options))
+	for i, opt := range options {
+		values[i] = opt.Value
+	}
+	return fb.addField(Field{
+		Name: name, Type: TypeRadio, Page: page,
+		Options: values, RadioButtons: options,
+	})
+}
+
 // AddDropdown adds a dropdown/combo box field to the form.
 func (fb *FormBuilder) AddDropdown(name string, page int, x, y, w, h float64, options []string) *Field {
 	return fb.addField(Field{
@@ -79,6 +130,15 @@ func (fb *FormBuilder) AddDropdown(name string, page int, x, y, w, h float64, op
 	})
 }
 
+// AddListBox adds a scrolling list box field to the form. Use SetMultiSelect
+// to let the user select several options.
+func (fb *FormBuilder) AddListBox(name string, page int, x, y, w, h float64, options []string) *Field {
+	return fb.addField(Field{
+		Name: name, Type: TypeListBox, Page: page,
+		X: x, Y: y, W: w, H: h, Options: options, FontSize: 12,
+	})
+}
+
 // AddButton adds a push button field to the form.
 func (fb *FormBuilder) AddButton(name string, page int, x, y, w, h float64, label string) *Field {
 	return fb.addField(Field{
@@ -87,6 +147,16 @@ func (fb *FormBuilder) AddButton(name string, page int, x, y, w, h float64, labe
 	})
 }
 
+// AddSignatureField adds an empty signature field to the form, a box in
+// which a digital signature can later be placed, for example by the sign
+// package. The field has no value until the document is signed.
+func (fb *FormBuilder) AddSignatureField(name string, page int, x, y, w, h float64) *Field {
+	return fb.addField(Field{
+		Name: name, Type: TypeSignature, Page: page,
+		X: x, Y: y, W: w, H: h,
+	})
+}
+
 // SetValue sets the default value for a field. Returns the field for chaining.
 func (f *Field) SetValue(v string) *Field {
 	f.Value = v
@@ -117,6 +187,50 @@ func (f *Field) SetMultiLine(multiLine bool) *Field {
 	return f
 }
 
+// SetMultiSelect allows several options of a list box to be selected.
+func (f *Field) SetMultiSelect(multiSelect bool) *Field {
+	f.MultiSelect = multiSelect
+	return f
+}
+
+// SetValues sets the initially selected options of a multi-select list box.
+func (f *Field) SetValues(values ...string) *Field {
+	f.Values = values
+	return f
+}
+
+// SetBorderColor draws a border of the given color around the field.
+func (f *Field) SetBorderColor(r, g, b int) *Field {
+	f.BorderColor = &RGBColor{r, g, b}
+	return f
+}
+
+// SetBackgroundColor fills the field box with the given color.
+func (f *Field) SetBackgroundColor(r, g, b int) *Field {
+	f.BackgroundColor = &RGBColor{r, g, b}
+	return f
+}
+
+// SetBorderWidth sets the width of the field border in points.
+func (f *Field) SetBorderWidth(w float64) *Field {
+	f.BorderWidth = w
+	return f
+}
+
+// SetAlignment sets the alignment of text and dropdown values: "L", "C"
+// or "R".
+func (f *Field) SetAlignment(align string) *Field {
+	f.Alignment = align
+	return f
+}
+
+// SetTooltip sets the text shown when hovering over the field, which screen
+// readers also use to describe it.
+func (f *Field) SetTooltip(tooltip string) *Field {
+	f.Tooltip = tooltip
+	return f
+}
+
 // Build generates the AcroForm structure and injects it into the PDF.
 // This must be called after all pages have been added but before Output().
 func (fb *FormBuilder) Build() error {
@@ -130,7 +244,15 @@ func (fb *FormBuilder) Build() error {
 	var fieldRefs []string
 
 	for i, f := range fb.fields {
-		annot, fieldRef := buildFieldAnnotation(f, i, k)
+		if f.Type == TypeRadio {
+			fieldRefs = append(fieldRefs, fb.buildRadioGroup(f, k))
+			continue
+		}
+		if f.Type == TypeSignature {
+			fieldRefs = append(fieldRefs, fb.buildSignatureField(f, i, k))
+			continue
+		}
+		annot, fieldRef := buildFieldAnnotation(f, i, k, fb.appearance(f, k))
 		fb.pdf.AddPageAnnotation(f.Page, annot)
 		fieldRefs = append(fieldRefs, fieldRef)
 	}
@@ -143,8 +265,28 @@ func (fb *FormBuilder) Build() error {
 	return fb.pdf.Error()
 }
 
+// appearance returns the /AP entry of a field's widget, or "" if the field
+// has no generated appearance. Text fields with a value, border or
+// background get a normal appearance showing them, so that viewers which
+// ignore /NeedAppearances display them.
+func (fb *FormBuilder) appearance(f Field, k float64) string {
+	if f.Type != TypeText {
+		return ""
+	}
+	w, h := f.W*k, f.H*k
+	frame := f.frameAppearance(w, h)
+	if f.Value == "" && frame == "" {
+		return ""
+	}
+	dict, content := textAppearance(f.Value, w, h, f.FontSize, f.MultiLine, quadding(f.Alignment), frame)
+	id := fb.pdf.NewRawObject()
+	fb.pdf.SetRawStreamObject(id, dict, content)
+	return fmt.Sprintf("/AP <</N %s>>", fb.pdf.RawObjectRef(id))
+}
+
 // buildFieldAnnotation constructs the PDF annotation string for a field.
-func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef string) {
+// ap is the field's /AP entry, if any.
+func buildFieldAnnotation(f Field, index int, k float64, ap string) (annot string, fieldRef string) {
 	// Convert user units to points
 	x := f.X * k
 	y := f.Y * k
@@ -164,6 +306,12 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 	// We use the annotation directly in /Fields
 	fieldRef = fmt.Sprintf("<</Type /Annot /Subtype /Widget /T (%s) /Rect [%.2f %.2f %.2f %.2f]",
 		escapePDFString(f.Name), x, y, x+w, y+h)
+	if f.Tooltip != "" {
+		fieldRef += fmt.Sprintf(" /TU (%s)", escapePDFString(f.Tooltip))
+	}
+	if f.Alignment != "" && (f.Type == TypeText || f.Type == TypeDropdown || f.Type == TypeListBox) {
+		fieldRef += fmt.Sprintf(" /Q %d", quadding(f.Alignment))
+	}
 
 	switch f.Type {
 	case TypeText:
@@ -172,7 +320,7 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 			fieldRef += fmt.Sprintf(" /DA (/Helv %.1f Tf 0 g)", f.FontSize)
 		}
 		if f.Value != "" {
-			fieldRef += fmt.Sprintf(" /V (%s)", escapePDFString(f.Value))
+			fieldRef += fmt.Sprintf(" /V (%[1]s) /DV (%[1]s)", escapePDFString(f.Value))
 		}
 		if f.MaxLen > 0 {
 			fieldRef += fmt.Sprintf(" /MaxLen %d", f.MaxLen)
@@ -184,7 +332,7 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 	case TypeCheckbox:
 		fieldRef += " /FT /Btn"
 		if f.Value == "Yes" || f.Value == "true" || f.Value == "on" {
-			fieldRef += " /V /Yes /AS /Yes"
+			fieldRef += " /V /Yes /AS /Yes /DV /Yes"
 		} else {
 			fieldRef += " /V /Off /AS /Off"
 		}
@@ -200,7 +348,49 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 			fieldRef += fmt.Sprintf(" /Opt [%s]", strings.Join(opts, " "))
 		}
 		if f.Value != "" {
-			fieldRef += fmt.Sprintf(" /V (%s)", escapePDFString(f.Value))
+			fieldRef += fmt.Sprintf(" /V (%[1]s) /DV (%[1]s)", escapePDFString(f.Value))
+		}
+		if f.FontSize > 0 {
+			fieldRef += fmt.Sprintf(" /DA (/Helv %.1f Tf 0 g)", f.FontSize)
+		}
+
+	case TypeListBox:
+		fieldRef += " /FT /Ch"
+		if f.MultiSelect {
+			ff |= 1 << 21 // Bit 22: MultiSelect
+		}
+		if len(f.Options) > 0 {
+			opts := make([]string, len(f.Options))
+			for i, opt := range f.Options {
+				opts[i] = fmt.Sprintf("(%s)", escapePDFString(opt))
+			}
+			fieldRef += fmt.Sprintf(" /Opt [%s]", strings.Join(opts, " "))
+		}
+		selected := f.Values
+		if len(selected) == 0 && f.Value != "" {
+			selected = []string{f.Value}
+		}
+		if len(selected) == 1 {
+			fieldRef += fmt.Sprintf(" /V (%[1]s) /DV (%[1]s)", escapePDFString(selected[0]))
+		} else if len(selected) > 1 {
+			vals := make([]string, len(selected))
+			for i, v := range selected {
+				vals[i] = fmt.Sprintf("(%s)", escapePDFString(v))
+			}
+			fieldRef += fmt.Sprintf(" /V [%[1]s] /DV [%[1]s]", strings.Join(vals, " "))
+		}
+		// /I lists the indices of the selected options in ascending order
+		var indices []string
+		for i, opt := range f.Options {
+			for _, v := range selected {
+				if v == opt {
+					indices = append(indices, fmt.Sprint(i))
+					break
+				}
+			}
+		}
+		if len(indices) > 0 {
+			fieldRef += fmt.Sprintf(" /I [%s]", strings.Join(indices, " "))
 		}
 		if f.FontSize > 0 {
 			fieldRef += fmt.Sprintf(" /DA (/Helv %.1f Tf 0 g)", f.FontSize)
@@ -209,14 +399,29 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 	case TypeButton:
 		fieldRef += " /FT /Btn"
 		ff |= 1 << 16 // Bit 17: Pushbutton
-		if f.Value != "" {
-			fieldRef += fmt.Sprintf(" /MK <</CA (%s)>>", escapePDFString(f.Value))
-		}
+
+	case TypeSignature:
+		fieldRef += " /FT /Sig /F 4"
+	}
+
+	// Appearance characteristics: colors and the push button caption
+	mk := f.colorEntries()
+	if f.Type == TypeButton && f.Value != "" {
+		mk = append(mk, fmt.Sprintf("/CA (%s)", escapePDFString(f.Value)))
+	}
+	if len(mk) > 0 {
+		fieldRef += fmt.Sprintf(" /MK <<%s>>", strings.Join(mk, " "))
+	}
+	if bs := f.borderStyle(); bs != "" {
+		fieldRef += " " + bs
 	}
 
 	if ff != 0 {
 		fieldRef += fmt.Sprintf(" /Ff %d", ff)
 	}
+	if ap != "" {
+		fieldRef += " " + ap
+	}
 
 	fieldRef += ">>"
 
@@ -225,6 +430,172 @@ func buildFieldAnnotation(f Field, index int, k float64) (annot string, fieldRef
 	return annot, fieldRef
 }
 
+// buildSignatureField adds a signature field as an indirect object, so that
+// a signer can later set its /V by redefining that single object, and
+// returns the reference to it for the AcroForm /Fields array.
+func (fb *FormBuilder) buildSignatureField(f Field, index int, k float64) string {
+	_, fieldRef := buildFieldAnnotation(f, index, k, "")
+	id := fb.pdf.NewRawObject()
+	fb.pdf.SetRawObject(id, fieldRef)
+	fb.pdf.AddPageAnnotation(f.Page, fb.pdf.RawObjectRef(id)+" ")
+	return fb.pdf.RawObjectRef(id)
+}
+
+// buildRadioGroup adds a radio group as a parent field object with a widget
+// annotation kid per button, and returns the reference to the parent for the
+// AcroForm /Fields array. Each widget has an appearance for its on state,
+// named after the button's value, and for the /Off state.
+func (fb *FormBuilder) buildRadioGroup(f Field, k float64) string {
+	ff := 1<<15 | 1<<14 // Bit 16: Radio, bit 15: NoToggleToOff
+	if f.ReadOnly {
+		ff |= 1
+	}
+	if f.Required {
+		ff |= 2
+	}
+
+	selected := "Off"
+	for _, opt := range f.RadioButtons {
+		if opt.Value == f.Value && opt.Value != "" {
+			selected = opt.Value
+		}
+	}
+
+	var style string
+	if mk := f.colorEntries(); len(mk) > 0 {
+		style += fmt.Sprintf(" /MK <<%s>>", strings.Join(mk, " "))
+	}
+	if bs := f.borderStyle(); bs != "" {
+		style += " " + bs
+	}
+
+	parent := fb.pdf.NewRawObject()
+	kids := make([]string, len(f.RadioButtons))
+	for i, opt := range f.RadioButtons {
+		x, y, size := opt.X*k, opt.Y*k, opt.Size*k
+
+		on := fb.pdf.NewRawObject()
+		fb.pdf.SetRawStreamObject(on, radioAppearanceDict(size), []byte(radioAppearance(size, true)))
+		off := fb.pdf.NewRawObject()
+		fb.pdf.SetRawStreamObject(off, radioAppearanceDict(size), []byte(radioAppearance(size, false)))
+
+		state := "Off"
+		if opt.Value == selected {
+			state = selected
+		}
+		kid := fb.pdf.NewRawObject()
+		fb.pdf.SetRawObject(kid, fmt.Sprintf(
+			"<</Type /Annot /Subtype /Widget /Parent %s /Rect [%.2f %.2f %.2f %.2f] /F 4%s /AS %s /AP <</N <<%s %s /Off %s>>>>>>",
+			fb.pdf.RawObjectRef(parent), x, y, x+size, y+size, style, reader.Name(state).Encode(),
+			reader.Name(opt.Value).Encode(), fb.pdf.RawObjectRef(on), fb.pdf.RawObjectRef(off)))
+		fb.pdf.AddPageAnnotation(f.Page, fb.pdf.RawObjectRef(kid)+" ")
+		kids[i] = fb.pdf.RawObjectRef(kid)
+	}
+
+	tooltip := ""
+	if f.Tooltip != "" {
+		tooltip = fmt.Sprintf(" /TU (%s)", escapePDFString(f.Tooltip))
+	}
+	value := " /V " + reader.Name(selected).Encode()
+	if selected != "Off" {
+		value += " /DV " + reader.Name(selected).Encode()
+	}
+	fb.pdf.SetRawObject(parent, fmt.Sprintf("<</FT /Btn /T (%s)%s /Ff %d%s /Kids [%s]>>",
+		escapePDFString(f.Name), tooltip, ff, value, strings.Join(kids, " ")))
+	return fb.pdf.RawObjectRef(parent)
+}
+
+// radioAppearanceDict returns the form XObject dictionary entries of a
+// radio button appearance of the given size in points.
+func radioAppearanceDict(size float64) string {
+	return fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 0 %.2f %.2f]", size, size)
+}
+
+// radioAppearance returns the content stream of a radio button appearance:
+// a circle, with a dot in its center if on is true.
+func radioAppearance(size float64, on bool) string {
+	c := size / 2
+	s := "0 G 1 w " + circlePath(c, c, c-0.5) + " S"
+	if on {
+		s += " 0 g " + circlePath(c, c, c/2) + " f"
+	}
+	return s
+}
+
+// circlePath returns the path operators of a circle approximated by four
+// Bézier curves.
+func circlePath(cx, cy, r float64) string {
+	const kappa = 0.5523
+	d := r * kappa
+	return fmt.Sprintf("%.2f %.2f m %.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c",
+		cx+r, cy,
+		cx+r, cy+d, cx+d, cy+r, cx, cy+r,
+		cx-d, cy+r, cx-r, cy+d, cx-r, cy,
+		cx-r, cy-d, cx-d, cy-r, cx, cy-r,
+		cx+d, cy-r, cx+r, cy-d, cx+r, cy)
+}
+
+// colorEntries returns the /BC and /BG entries of the field's appearance
+// characteristics dictionary (/MK).
+func (f Field) colorEntries() []string {
+	var entries []string
+	if f.BorderColor != nil {
+		entries = append(entries, fmt.Sprintf("/BC [%s]", f.BorderColor.components()))
+	}
+	if f.BackgroundColor != nil {
+		entries = append(entries, fmt.Sprintf("/BG [%s]", f.BackgroundColor.components()))
+	}
+	return entries
+}
+
+// borderWidth returns the width of the field border in points, or 0 if
+// the field has no border.
+func (f Field) borderWidth() float64 {
+	if f.BorderColor == nil {
+		return 0
+	}
+	if f.BorderWidth > 0 {
+		return f.BorderWidth
+	}
+	return 1
+}
+
+// borderStyle returns the /BS border style entry of a field with a border.
+func (f Field) borderStyle() string {
+	bw := f.borderWidth()
+	if bw == 0 {
+		return ""
+	}
+	return fmt.Sprintf("/BS <</W %.2f /S /S>>", bw)
+}
+
+// frameAppearance returns the content stream operators drawing the
+// background and border of a w×h point field.
+func (f Field) frameAppearance(w, h float64) string {
+	var b strings.Builder
+	if f.BackgroundColor != nil {
+		fmt.Fprintf(&b, "%s rg 0 0 %.2f %.2f re f\n", f.BackgroundColor.components(), w, h)
+	}
+	if bw := f.borderWidth(); bw > 0 {
+		fmt.Fprintf(&b, "%s RG %.2f w %.2f %.2f %.2f %.2f re S\n",
+			f.BorderColor.components(), bw, bw/2, bw/2, w-bw, h-bw)
+	}
+	return b.String()
+}
+
+// quadding returns the /Q value for an alignment: 0 for left, 1 for
+// centered and 2 for right.
+func quadding(align string) int {
+	switch align {
+	case "C":
+		return 1
+	case "R":
+		return 2
+	}
+	return 0
+}
+
 // escapePDFString escapes special characters in a PDF string.
 func escapePDFString(s string) string {
 	s = strings.ReplaceAll(s, `\`, `\\`)
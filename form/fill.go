@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 
 	"github.com/lvillar/gofpdf/reader"
@@ -13,14 +14,21 @@ import (
 
 var (
 	fillValueStringRe = regexp.MustCompile(`/V\s*\([^)]*\)`)
-	fillValueNameRe   = regexp.MustCompile(`/V\s+/[A-Za-z]+(\s+/AS\s+/[A-Za-z]+)?`)
+	fillValueNameRe   = regexp.MustCompile(`/V\s*/[^\s/<>\[\]()]+`)
+	fillStateRe       = regexp.MustCompile(`\s*/AS\s*/[^\s/<>\[\]()]+`)
 	fillObjPatternRe  = regexp.MustCompile(`(?m)^(\d+)\s+(\d+)\s+obj\b`)
+	fillAppearanceRe  = regexp.MustCompile(`/AP\s*<<`)
+	fillSizeRe        = regexp.MustCompile(`/Size\s+\d+`)
 )
 
 // Fill reads a PDF from input, fills form fields with the provided values,
 // and writes the result to output. Field names are matched case-sensitively.
 //
-// After modifying field values, the xref table is rebuilt to ensure validity.
+// Text fields get a new appearance stream showing the value, so that it is
+// displayed by viewers that ignore /NeedAppearances. After modifying field
+// values, the xref table is rebuilt to ensure validity. Fields stored in
+// object streams (PDF 1.5+) are instead written in an incremental update
+// that re-emits their object streams.
 func Fill(input io.ReadSeeker, output io.Writer, values map[string]string) error {
 	if len(values) == 0 {
 		if _, err := input.Seek(0, io.SeekStart); err != nil {
@@ -65,9 +73,36 @@ func Fill(input io.ReadSeeker, output io.Writer, values map[string]string) error
 	modified := make([]byte, len(data))
 	copy(modified, data)
 
-	for name, value := range values {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		return fmt.Errorf("form: reading cross-reference: %w", err)
+	}
+	targets := make([]*reader.FormField, len(names))
+	for i, name := range names {
+		targets[i] = fieldMap[name]
+	}
+	if objectStreamFields(summary, targets) {
+		modified, err := fillObjectStreams(doc, data, summary, fieldMap, values, names)
+		if err != nil {
+			return err
+		}
+		_, err = output.Write(modified)
+		return err
+	}
+
+	for _, name := range names {
 		field := fieldMap[name]
-		modified = setFieldValue(modified, field, value)
+		var ap string
+		if field.Type == "Tx" {
+			modified, ap = addTextAppearance(modified, field, values[name])
+		}
+		modified = setFieldValue(modified, field, values[name], ap)
 	}
 
 	// Rebuild xref table to account for any byte offset changes
@@ -106,45 +141,78 @@ func flattenFields(fields []*reader.FormField) []*reader.FormField {
 	return result
 }
 
-// setFieldValue modifies the raw PDF bytes to set a field's /V entry.
-// Updates all occurrences (field appears in /Annots and /AcroForm /Fields).
-// May change total data length; caller must rebuild xref after.
-func setFieldValue(data []byte, field *reader.FormField, value string) []byte {
-	escapedName := escapePDFString(field.Name)
-	pattern := []byte(fmt.Sprintf("/T (%s)", escapedName))
-	altPattern := []byte(fmt.Sprintf("/T(%s)", escapedName))
+// addTextAppearance adds a stream object with the normal appearance of a
+// text field showing value, and returns the /AP entry referring to it. It
+// returns data unchanged and "" if the field has no widget rectangle or the
+// object cannot be added.
+func addTextAppearance(data []byte, field *reader.FormField, value string) ([]byte, string) {
+	body, ok := textAppearanceObject(field, value)
+	if !ok {
+		return data, ""
+	}
+	data, num, ok := appendObject(data, body)
+	if !ok {
+		return data, ""
+	}
+	return data, fmt.Sprintf("/AP <</N %d 0 R>>", num)
+}
 
-	// Process up to 10 occurrences (field dict duplicated in Annots + Fields)
-	for pass := 0; pass < 10; pass++ {
-		idx := bytes.Index(data, pattern)
-		if idx < 0 {
-			idx = bytes.Index(data, altPattern)
-		}
-		if idx < 0 {
-			break
-		}
+// textAppearanceObject returns the body of a stream object with the normal
+// appearance of a text field showing value. It reports false if the field
+// has no widget rectangle.
+func textAppearanceObject(field *reader.FormField, value string) (string, bool) {
+	w, h := field.Rect.Width(), field.Rect.Height()
+	if w <= 0 || h <= 0 {
+		return "", false
+	}
+	multiLine := field.Flags&(1<<12) != 0
+	dict, content := textAppearance(value, w, h, fontSizeFromDA(field.DefaultAppearance), multiLine, field.Quadding, "")
+	return fmt.Sprintf("<<%s /Length %d>>\nstream\n%s\nendstream", dict, len(content), content), true
+}
 
-		dictStart := findDictStart(data, idx)
-		dictEnd := findDictEnd(data, idx)
-		if dictStart < 0 || dictEnd < 0 {
-			break
+// appendObject inserts a new object with the given body before the last
+// xref table and returns its number, one above the highest object number
+// in use. It reports false if the file has no xref table. The caller must
+// rebuild the xref afterwards.
+func appendObject(data []byte, body string) ([]byte, int, bool) {
+	xrefIdx := bytes.LastIndex(data, []byte("\nxref\n"))
+	if xrefIdx < 0 {
+		return data, 0, false
+	}
+	maxObj := 0
+	for _, m := range fillObjPatternRe.FindAllSubmatch(data, -1) {
+		if num, _ := strconv.Atoi(string(m[1])); num > maxObj {
+			maxObj = num
 		}
+	}
 
-		fieldDict := make([]byte, dictEnd+2-dictStart)
-		copy(fieldDict, data[dictStart:dictEnd+2])
+	obj := fmt.Sprintf("%d 0 obj\n%s\nendobj\n", maxObj+1, body)
+	result := make([]byte, 0, len(data)+len(obj))
+	result = append(result, data[:xrefIdx+1]...)
+	result = append(result, obj...)
+	result = append(result, data[xrefIdx+1:]...)
+	return result, maxObj + 1, true
+}
 
-		var newValueStr string
-		switch field.Type {
-		case "Btn":
-			if value == "true" || value == "Yes" || value == "on" {
-				newValueStr = "/V /Yes /AS /Yes"
-			} else {
-				newValueStr = "/V /Off /AS /Off"
-			}
-		default:
-			newValueStr = fmt.Sprintf("/V (%s)", escapePDFString(value))
-		}
+// setFieldValue modifies the raw PDF bytes to set a field's /V entry and,
+// if ap is not empty, replace its /AP entry with ap.
+// Updates all occurrences (field appears in /Annots and /AcroForm /Fields).
+// May change total data length; caller must rebuild xref after.
+func setFieldValue(data []byte, field *reader.FormField, value, ap string) []byte {
+	var newValueStr string
+	switch field.Type {
+	case "Btn":
+		state := reader.Name(checkboxState(field, value)).Encode()
+		newValueStr = fmt.Sprintf("/V %s /AS %s", state, state)
+	default:
+		newValueStr = fmt.Sprintf("/V (%s)", escapePDFString(value))
+	}
 
+	return editFieldDicts(data, field, func(fieldDict []byte) []byte {
+		if field.Type == "Btn" {
+			// The new value carries the appearance state
+			fieldDict = fillStateRe.ReplaceAll(fieldDict, nil)
+		}
 		var newDict []byte
 		replaced := false
 
@@ -172,20 +240,86 @@ func setFieldValue(data []byte, field *reader.FormField, value string) []byte {
 			newDict = append(newDict, '>', '>')
 		}
 
-		if bytes.Equal(fieldDict, newDict) {
+		if ap != "" {
+			newDict = setAppearance(newDict, ap)
+		}
+		return newDict
+	})
+}
+
+// checkboxState returns the appearance state that shows value in a
+// checkbox: its on state, read from the widget's appearance dictionary, for
+// "true", "Yes", "on" or the on state itself, and "Off" otherwise.
+func checkboxState(field *reader.FormField, value string) string {
+	on := field.OnState
+	if on == "" {
+		on = "Yes"
+	}
+	switch value {
+	case on, "true", "Yes", "on":
+		return on
+	}
+	return "Off"
+}
+
+// editFieldDicts replaces each occurrence of a field's dictionary in the raw
+// PDF bytes with the result of edit, which receives a copy of it. The field
+// dictionary is found by its /T entry.
+func editFieldDicts(data []byte, field *reader.FormField, edit func(fieldDict []byte) []byte) []byte {
+	escapedName := escapePDFString(field.Name)
+	pattern := []byte(fmt.Sprintf("/T (%s)", escapedName))
+	altPattern := []byte(fmt.Sprintf("/T(%s)", escapedName))
+
+	// Process up to 10 occurrences (field dict duplicated in Annots + Fields)
+	from := 0
+	for pass := 0; pass < 10; pass++ {
+		idx := bytes.Index(data[from:], pattern)
+		if alt := bytes.Index(data[from:], altPattern); alt >= 0 && (idx < 0 || alt < idx) {
+			idx = alt
+		}
+		if idx < 0 {
+			break
+		}
+		idx += from
+
+		dictStart := findDictStart(data, idx)
+		dictEnd := findDictEnd(data, idx)
+		if dictStart < 0 || dictEnd < 0 {
 			break
 		}
 
+		fieldDict := make([]byte, dictEnd+2-dictStart)
+		copy(fieldDict, data[dictStart:dictEnd+2])
+		newDict := edit(fieldDict)
+
 		result := make([]byte, 0, len(data)-len(fieldDict)+len(newDict))
 		result = append(result, data[:dictStart]...)
 		result = append(result, newDict...)
 		result = append(result, data[dictEnd+2:]...)
 		data = result
+		from = dictStart + len(newDict)
 	}
 
 	return data
 }
 
+// setAppearance replaces the /AP entry of a dictionary with ap, or adds it
+// if the dictionary has none.
+func setAppearance(dict []byte, ap string) []byte {
+	result := make([]byte, 0, len(dict)+len(ap)+1)
+	if loc := fillAppearanceRe.FindIndex(dict); loc != nil {
+		if end := findDictEnd(dict, loc[1]-2); end >= 0 {
+			result = append(result, dict[:loc[0]]...)
+			result = append(result, ap...)
+			return append(result, dict[end+2:]...)
+		}
+	}
+	result = append(result, dict[:len(dict)-2]...)
+	result = append(result, ' ')
+	result = append(result, ap...)
+	return append(result, '>', '>')
+}
+
 // rebuildXref scans the PDF body for object definitions and rebuilds the
 // xref table with correct offsets. This handles byte-level modifications
 // that shift object positions.
@@ -235,6 +369,7 @@ func rebuildXref(data []byte) []byte {
 		return data
 	}
 	trailerDict := bytes.TrimSpace(data[trailerAbsIdx+7 : trailerAbsIdx+startxrefIdx])
+	trailerDict = fillSizeRe.ReplaceAll(trailerDict, []byte(fmt.Sprintf("/Size %d", maxObj+1)))
 
 	// Body = everything up to and including the newline before "xref"
 	body := data[:xrefIdx+1]
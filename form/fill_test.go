@@ -2,6 +2,10 @@ package form_test
 
 import (
 	"bytes"
+	"compress/zlib"
+	"fmt"
+	"regexp"
+	"strconv"
 	"testing"
 
 	gofpdf "github.com/lvillar/gofpdf"
@@ -215,3 +219,356 @@ func TestFillThenFlatten(t *testing.T) {
 	t.Logf("Fill+Flatten: original=%d, filled=%d, flattened=%d bytes",
 		len(pdfData), filled.Len(), flattened.Len())
 }
+
+func TestFillGeneratesAppearance(t *testing.T) {
+	pdfData := generateFilledFormPDF(t)
+
+	var output bytes.Buffer
+	err := form.Fill(bytes.NewReader(pdfData), &output, map[string]string{
+		"name": "John Doe",
+	})
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	result := output.Bytes()
+
+	m := regexp.MustCompile(`/T \(name\)[^>]*/AP <</N (\d+) 0 R>>`).FindAllSubmatch(result, -1)
+	if len(m) != 2 {
+		t.Fatalf("expected /AP on both copies of the field, got %d", len(m))
+	}
+	if !bytes.Equal(m[0][1], m[1][1]) {
+		t.Errorf("field copies refer to different appearances: %s and %s", m[0][1], m[1][1])
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("reading filled PDF: %v", err)
+	}
+	num, _ := strconv.Atoi(string(m[0][1]))
+	obj, err := doc.ResolveReference(reader.Reference{Number: num})
+	if err != nil {
+		t.Fatalf("resolving appearance: %v", err)
+	}
+	ap, ok := obj.(reader.Stream)
+	if !ok {
+		t.Fatalf("appearance is %T, want a stream", obj)
+	}
+	if ap.Dict.GetName("Subtype") != "Form" {
+		t.Errorf("appearance subtype %q, want Form", ap.Dict.GetName("Subtype"))
+	}
+	if !bytes.Contains(ap.Data, []byte("(John Doe) Tj")) {
+		t.Errorf("appearance does not show the value: %q", ap.Data)
+	}
+}
+
+// objectStreamFormPDF returns a PDF 1.5 file whose catalog, page and form
+// field are stored in a compressed object stream, indexed by a
+// cross-reference stream.
+func objectStreamFormPDF(t *testing.T) []byte {
+	t.Helper()
+	objects := []string{
+		"<</Type /Catalog /Pages 2 0 R /AcroForm 5 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Annots [4 0 R]>>",
+		"<</FT /Tx /T (name) /Subtype /Widget /Rect [10 10 110 30] /DA (/Helv 12 Tf 0 g) /P 3 0 R>>",
+		"<</Fields [4 0 R] /DA (/Helv 0 Tf 0 g)>>",
+	}
+	var header, body bytes.Buffer
+	for i, obj := range objects {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	zw.Write(header.Bytes())
+	zw.Write(body.Bytes())
+	zw.Close()
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n")
+	stmOffset := buf.Len()
+	fmt.Fprintf(&buf, "6 0 obj\n<</Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d>>\nstream\n", len(objects), header.Len(), content.Len())
+	buf.Write(content.Bytes())
+	buf.WriteString("\nendstream\nendobj\n")
+
+	xrefOffset := buf.Len()
+	rows := []byte{0, 0, 0, 255, 255}
+	for i := range objects {
+		rows = append(rows, 2, 0, 6, 0, byte(i))
+	}
+	rows = append(rows, 1, byte(stmOffset>>8), byte(stmOffset), 0, 0)
+	rows = append(rows, 1, byte(xrefOffset>>8), byte(xrefOffset), 0, 0)
+	fmt.Fprintf(&buf, "7 0 obj\n<</Type /XRef /Size 8 /W [1 2 2] /Root 1 0 R /Length %d>>\nstream\n", len(rows))
+	buf.Write(rows)
+	fmt.Fprintf(&buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return buf.Bytes()
+}
+
+func TestFillObjectStreamFields(t *testing.T) {
+	pdfData := objectStreamFormPDF(t)
+
+	var output bytes.Buffer
+	err := form.Fill(bytes.NewReader(pdfData), &output, map[string]string{
+		"name": "Jane Roe",
+	})
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+	result := output.Bytes()
+	if !bytes.HasPrefix(result, pdfData) {
+		t.Error("expected the original bytes to be kept by an incremental update")
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("reading filled PDF: %v", err)
+	}
+	if doc.NumPages() != 1 {
+		t.Errorf("expected 1 page, got %d", doc.NumPages())
+	}
+	field, err := doc.FormField("name")
+	if err != nil {
+		t.Fatalf("FormField: %v", err)
+	}
+	if field.Value != "Jane Roe" {
+		t.Errorf("field value %q, want %q", field.Value, "Jane Roe")
+	}
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		t.Fatalf("XRefSummary: %v", err)
+	}
+	if entries := summary[4]; entries[len(entries)-1].Type != "compressed" {
+		t.Errorf("field object entry %q, want it kept in an object stream", entries[len(entries)-1].Type)
+	}
+
+	raw, err := doc.RawObject(4)
+	if err != nil {
+		t.Fatalf("RawObject: %v", err)
+	}
+	m := regexp.MustCompile(`/AP <</N (\d+) 0 R>>`).FindSubmatch(raw)
+	if m == nil {
+		t.Fatalf("expected an appearance on the field: %s", raw)
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	obj, err := doc.ResolveReference(reader.Reference{Number: num})
+	if err != nil {
+		t.Fatalf("resolving appearance: %v", err)
+	}
+	ap, ok := obj.(reader.Stream)
+	if !ok {
+		t.Fatalf("appearance is %T, want a stream", obj)
+	}
+	if !bytes.Contains(ap.Data, []byte("(Jane Roe) Tj")) {
+		t.Errorf("appearance does not show the value: %q", ap.Data)
+	}
+}
+
+// classicPDF returns a PDF file holding the given objects, numbered from 1,
+// with a cross-reference table and object 1 as the catalog.
+func classicPDF(objects []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<</Size %d /Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+func TestFillCheckboxOnState(t *testing.T) {
+	pdfData := classicPDF([]string{
+		"<</Type /Catalog /Pages 2 0 R /AcroForm 5 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Annots [4 0 R]>>",
+		"<</FT /Btn /T (agree) /Subtype /Widget /Rect [10 10 20 20] /V /Off /AS /Off /AP <</N <</On 6 0 R /Off 7 0 R>>>> /P 3 0 R>>",
+		"<</Fields [4 0 R]>>",
+		"<</Type /XObject /Subtype /Form /BBox [0 0 10 10] /Length 20>>\nstream\n0 0 10 10 re f\n\nendstream",
+		"<</Type /XObject /Subtype /Form /BBox [0 0 10 10] /Length 0>>\nstream\n\nendstream",
+	})
+
+	fill := func(data []byte, value string) []byte {
+		t.Helper()
+		var output bytes.Buffer
+		if err := form.Fill(bytes.NewReader(data), &output, map[string]string{"agree": value}); err != nil {
+			t.Fatalf("Fill %q: %v", value, err)
+		}
+		return output.Bytes()
+	}
+	check := func(data []byte, want string) {
+		t.Helper()
+		doc, err := reader.ReadFrom(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("reading filled PDF: %v", err)
+		}
+		field, err := doc.FormField("agree")
+		if err != nil {
+			t.Fatalf("FormField: %v", err)
+		}
+		if field.OnState != "On" {
+			t.Errorf("on state %q, want On", field.OnState)
+		}
+		if field.Value != want {
+			t.Errorf("value %q, want %q", field.Value, want)
+		}
+		if n := bytes.Count(data, []byte("/AS")); n != 1 {
+			t.Errorf("expected a single /AS entry, got %d", n)
+		}
+		if !bytes.Contains(data, []byte("/AS /"+want)) {
+			t.Errorf("expected appearance state /%s", want)
+		}
+	}
+
+	checked := fill(pdfData, "Yes")
+	check(checked, "On")
+	check(fill(checked, "Off"), "Off")
+	check(fill(pdfData, "On"), "On")
+}
+
+func TestResetForm(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("name", 1, 40, 5, 80, 10).SetValue("Your name")
+	fb.AddTextField("email", 1, 40, 20, 80, 10)
+	fb.AddCheckbox("agree", 1, 40, 35, 5)
+	fb.AddCheckbox("newsletter", 1, 40, 45, 5).SetValue("Yes")
+	fb.AddDropdown("country", 1, 40, 55, 80, 8, []string{"USA", "Canada", "Mexico"}).SetValue("USA")
+	fb.AddListBox("size", 1, 40, 70, 80, 20, []string{"Small", "Large"})
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build form: %v", err)
+	}
+	var blank bytes.Buffer
+	if err := pdf.Output(&blank); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	var filled bytes.Buffer
+	err := form.Fill(bytes.NewReader(blank.Bytes()), &filled, map[string]string{
+		"name":       "Jane Smith",
+		"email":      "jane@example.com",
+		"agree":      "Yes",
+		"newsletter": "Off",
+		"country":    "Canada",
+		"size":       "Large",
+	})
+	if err != nil {
+		t.Fatalf("Fill: %v", err)
+	}
+
+	var reset bytes.Buffer
+	if err := form.Reset(bytes.NewReader(filled.Bytes()), &reset); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	result := reset.Bytes()
+
+	doc, err := reader.ReadFrom(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("reading reset PDF: %v", err)
+	}
+
+	// Text fields show their default again.
+	for name, text := range map[string]string{"name": "(Your name) Tj", "email": "() Tj"} {
+		m := regexp.MustCompile(`/T \(` + name + `\)[^>]*/AP <</N (\d+) 0 R>>`).FindSubmatch(result)
+		if m == nil {
+			t.Fatalf("field %s has no appearance", name)
+		}
+		num, _ := strconv.Atoi(string(m[1]))
+		obj, err := doc.ResolveReference(reader.Reference{Number: num})
+		if err != nil {
+			t.Fatalf("resolving appearance of %s: %v", name, err)
+		}
+		if ap, ok := obj.(reader.Stream); !ok || !bytes.Contains(ap.Data, []byte(text)) {
+			t.Errorf("field %s: expected appearance showing %s", name, text)
+		}
+	}
+
+	want := map[string]string{
+		"name":       "Your name",
+		"email":      "",
+		"agree":      "Off",
+		"newsletter": "Yes",
+		"country":    "USA",
+		"size":       "",
+	}
+	for name, value := range want {
+		field, err := doc.FormField(name)
+		if err != nil || field == nil {
+			t.Fatalf("field %s: %v", name, err)
+		}
+		if field.Value != value {
+			t.Errorf("field %s: expected %q after reset, got %q", name, value, field.Value)
+		}
+	}
+}
+
+func TestFlattenFields(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("signer", 1, 40, 5, 80, 10).SetValue("Jane Smith")
+	fb.AddTextField("date", 1, 40, 20, 80, 10)
+	fb.AddRadioGroup("plan", 1, []form.RadioOption{
+		{Value: "Basic", X: 40, Y: 35, Size: 5},
+		{Value: "Pro", X: 40, Y: 45, Size: 5},
+	}).SetValue("Pro")
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build form: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := form.FlattenFields(bytes.NewReader(buf.Bytes()), &output, []string{"signer", "plan"}); err != nil {
+		t.Fatalf("FlattenFields: %v", err)
+	}
+	result := output.Bytes()
+	if len(result) != buf.Len() {
+		t.Errorf("expected byte offsets to be preserved, got %d vs %d bytes", len(result), buf.Len())
+	}
+	if !bytes.Contains(result, []byte("/AcroForm")) {
+		t.Fatal("expected /AcroForm to be kept for the remaining field")
+	}
+	// Only the two copies of the date field remain widgets.
+	if n := bytes.Count(result, []byte("/Subtype /Widget")); n != 2 {
+		t.Errorf("expected 2 widgets left, got %d", n)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("reading flattened PDF: %v", err)
+	}
+	fields, err := doc.FormFields()
+	if err != nil {
+		t.Fatalf("form fields: %v", err)
+	}
+	if len(fields) != 1 || fields[0].Name != "date" {
+		t.Errorf("expected only the date field to remain, got %d fields", len(fields))
+	}
+
+	// Flattening every field removes the AcroForm.
+	output.Reset()
+	if err := form.FlattenFields(bytes.NewReader(buf.Bytes()), &output, []string{"signer", "date", "plan"}); err != nil {
+		t.Fatalf("FlattenFields all: %v", err)
+	}
+	if bytes.Contains(output.Bytes(), []byte("/AcroForm")) {
+		t.Error("expected /AcroForm to be removed when all fields are flattened")
+	}
+
+	if err := form.FlattenFields(bytes.NewReader(buf.Bytes()), &output, []string{"missing"}); err == nil {
+		t.Error("expected error for a non-existent field")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/lvillar/gofpdf/reader"
 )
@@ -16,6 +18,8 @@ var (
 	flattenSubtypeRe     = regexp.MustCompile(`/Subtype\s+/Widget`)
 	flattenAppearanceRe  = regexp.MustCompile(`/DA\s*\([^)]*\)`)
 	flattenNeedAppRe     = regexp.MustCompile(`/NeedAppearances\s+(true|false)`)
+	flattenFieldsArrayRe = regexp.MustCompile(`/Fields\s*\[`)
+	flattenFieldNameRe   = regexp.MustCompile(`/T\s*\(([^)]*)\)`)
 )
 
 // Flatten reads a PDF with form fields and converts all field widgets into
@@ -77,6 +81,187 @@ func FlattenFile(inputPath, outputPath string) error {
 	return Flatten(input, out)
 }
 
+// FlattenFields reads a PDF with form fields and flattens only the fields
+// with the given fully qualified names, leaving the other fields
+// interactive. The flattened fields are removed from the AcroForm /Fields
+// array; the AcroForm itself is only removed if no fields remain.
+//
+// Like Flatten, it uses space-replacement to preserve byte offsets and xref
+// table validity.
+func FlattenFields(input io.ReadSeeker, output io.Writer, names []string) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("form: reading input: %w", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("form: parsing PDF: %w", err)
+	}
+
+	fields, err := doc.FormFields()
+	if err != nil {
+		return fmt.Errorf("form: reading form fields: %w", err)
+	}
+
+	// Unnamed kid widgets share their parent's full name; keep the parent
+	fieldMap := make(map[string]*reader.FormField)
+	for _, f := range flattenFields(fields) {
+		if _, ok := fieldMap[f.FullName]; !ok {
+			fieldMap[f.FullName] = f
+		}
+	}
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		if _, ok := fieldMap[name]; !ok {
+			return fmt.Errorf("form: field %q not found in PDF", name)
+		}
+		selected[name] = true
+	}
+
+	modified := make([]byte, len(data))
+	copy(modified, data)
+
+	// Remove the top-level fields from /Fields first, so that the widget
+	// markers blanked below are those of the page annotations.
+	remaining := 0
+	removed := make(map[string]bool)
+	refs := make(map[int]bool)
+	for _, f := range fields {
+		if !selected[f.FullName] {
+			remaining++
+			continue
+		}
+		removed[escapePDFString(f.Name)] = true
+		if f.ObjNum != 0 {
+			refs[f.ObjNum] = true
+		}
+	}
+	if remaining == 0 {
+		blankAcroForm(modified)
+	} else {
+		blankFieldEntries(modified, removed, refs)
+	}
+
+	for _, name := range names {
+		field := fieldMap[name]
+		blankFieldMarkers(modified, field)
+		// Widgets of a field with kids, such as the buttons of a radio
+		// group, are separate objects without a name
+		for _, kid := range flattenFields(field.Kids) {
+			if kid.Name == "" && kid.ObjNum != 0 {
+				blankObjectMarkers(modified, kid.ObjNum)
+			}
+		}
+	}
+
+	_, err = io.Copy(output, bytes.NewReader(modified))
+	return err
+}
+
+// FlattenFieldsFile reads a PDF from inputPath, flattens the named form
+// fields, and writes to outputPath.
+func FlattenFieldsFile(inputPath, outputPath string, names []string) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("form: opening %s: %w", inputPath, err)
+	}
+	defer input.Close()
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("form: creating %s: %w", outputPath, err)
+	}
+	defer out.Close()
+
+	return FlattenFields(input, out, names)
+}
+
+// blankFieldEntries replaces the entries of the AcroForm /Fields array that
+// belong to the given fields with spaces: inline field dictionaries whose
+// escaped /T is in names and references to the objects in refs.
+func blankFieldEntries(data []byte, names map[string]bool, refs map[int]bool) {
+	acroStart := bytes.Index(data, []byte("/AcroForm"))
+	if acroStart < 0 {
+		return
+	}
+
+	// The AcroForm dictionary is inline in the catalog or a separate object
+	start := acroStart
+	pos := acroStart + len("/AcroForm")
+	if loc := flattenObjRefRe.FindIndex(data[pos:]); loc != nil && len(bytes.TrimSpace(data[pos:pos+loc[0]])) == 0 {
+		num, _ := strconv.Atoi(strings.Fields(string(data[pos+loc[0] : pos+loc[1]]))[0])
+		objRe := regexp.MustCompile(fmt.Sprintf(`(?m)^%d\s+\d+\s+obj\b`, num))
+		objLoc := objRe.FindIndex(data)
+		if objLoc == nil {
+			return
+		}
+		start = objLoc[1]
+	}
+
+	loc := flattenFieldsArrayRe.FindIndex(data[start:])
+	if loc == nil {
+		return
+	}
+	i := start + loc[1]
+	for i < len(data) {
+		switch c := data[i]; {
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+			i++
+		case c == ']':
+			return
+		case c == '<' && i+1 < len(data) && data[i+1] == '<':
+			end := findDictEnd(data, i)
+			if end < 0 {
+				return
+			}
+			entry := data[i : end+2]
+			if m := flattenFieldNameRe.FindSubmatch(entry); m != nil && names[string(m[1])] {
+				blankBytes(entry)
+			}
+			i = end + 2
+		default:
+			ref := flattenObjRefRe.FindIndex(data[i:])
+			if ref == nil || ref[0] != 0 {
+				return
+			}
+			entry := data[i : i+ref[1]]
+			num, _ := strconv.Atoi(strings.Fields(string(entry))[0])
+			if refs[num] {
+				blankBytes(entry)
+			}
+			i += ref[1]
+		}
+	}
+}
+
+// blankObjectMarkers blanks the interactive markers of the widget
+// annotation stored as object num.
+func blankObjectMarkers(data []byte, num int) {
+	objRe := regexp.MustCompile(fmt.Sprintf(`(?m)^%d\s+\d+\s+obj\b`, num))
+	loc := objRe.FindIndex(data)
+	if loc == nil {
+		return
+	}
+	dictStart := bytes.Index(data[loc[1]:], []byte("<<"))
+	if dictStart < 0 {
+		return
+	}
+	dictStart += loc[1]
+	dictEnd := findDictEnd(data, dictStart)
+	if dictEnd < 0 {
+		return
+	}
+	blankMatches(data[dictStart:dictEnd+2], flattenSubtypeRe)
+}
+
+// blankBytes replaces data with spaces.
+func blankBytes(data []byte) {
+	for i := range data {
+		data[i] = ' '
+	}
+}
+
 // blankAcroForm replaces the /AcroForm entry in the catalog with spaces
 // (same byte length) to preserve xref offsets.
 func blankAcroForm(data []byte) {
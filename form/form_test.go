@@ -2,6 +2,11 @@ package form_test
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	gofpdf "github.com/lvillar/gofpdf"
@@ -183,3 +188,276 @@ func TestReadOnlyField(t *testing.T) {
 	}
 	t.Logf("Read-only field PDF: %d bytes", buf.Len())
 }
+
+func TestRadioGroupCreation(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.Text(10, 10, "How did you hear about us?")
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddRadioGroup("source", 1, []form.RadioOption{
+		{Value: "Web", X: 10, Y: 20, Size: 5},
+		{Value: "Friend", X: 10, Y: 30, Size: 5},
+		{Value: "Other", X: 10, Y: 40, Size: 5},
+	}).SetValue("Friend")
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	field, err := doc.FormField("source")
+	if err != nil {
+		t.Fatalf("form field: %v", err)
+	}
+	if field == nil {
+		t.Fatal("radio group not found")
+	}
+	if field.Type != "Btn" || !field.IsRadio() {
+		t.Errorf("expected a radio button field, got type %q flags %d", field.Type, field.Flags)
+	}
+	if field.Value != "Friend" {
+		t.Errorf("expected value Friend, got %q", field.Value)
+	}
+	if got := strings.Join(field.Options, ","); got != "Web,Friend,Other" {
+		t.Errorf("expected options Web,Friend,Other, got %s", got)
+	}
+	if len(field.Kids) != 3 {
+		t.Fatalf("expected 3 widgets, got %d", len(field.Kids))
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("/AS /Friend")); n != 1 {
+		t.Errorf("expected one widget in the on state, got %d", n)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("/AS /Off")); n != 2 {
+		t.Errorf("expected two widgets in the off state, got %d", n)
+	}
+}
+
+func TestTextFieldAppearance(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("city", 1, 40, 5, 80, 10).SetValue("Zürich")
+	fb.AddTextField("empty", 1, 40, 20, 80, 10)
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if n := bytes.Count(out, []byte("/AP <</N ")); n != 2 {
+		t.Errorf("expected an appearance on both copies of the filled field only, got %d", n)
+	}
+	if !bytes.Contains(out, []byte("(Z\xfcrich) Tj")) {
+		t.Error("expected the value drawn in WinAnsiEncoding in the appearance stream")
+	}
+	if _, err := reader.ReadFrom(bytes.NewReader(out)); err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+}
+
+func TestFieldAlignmentAndTooltip(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("total", 1, 100, 100, 200, 20).
+		SetValue("1,234.00").
+		SetAlignment("R").
+		SetTooltip("Amount due (EUR)")
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if !bytes.Contains(out, []byte(`/TU (Amount due \(EUR\))`)) {
+		t.Error("expected escaped /TU tooltip in PDF output")
+	}
+
+	// The value ends 2pt before the right edge of the 200pt wide field.
+	m := regexp.MustCompile(`1 0 0 1 ([\d.]+) [\d.]+ Tm\n\(1,234.00\) Tj`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("value not found in appearance stream")
+	}
+	x, _ := strconv.ParseFloat(string(m[1]), 64)
+	pdf.SetFont("Helvetica", "", 12)
+	if want := 200 - 2 - pdf.GetStringWidth("1,234.00"); math.Abs(x-want) > 0.01 {
+		t.Errorf("value starts at %.2f, want %.2f", x, want)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	field, err := doc.FormField("total")
+	if err != nil || field == nil {
+		t.Fatalf("form field: %v", err)
+	}
+	if field.Quadding != 2 {
+		t.Errorf("expected /Q 2, got %d", field.Quadding)
+	}
+	if field.Tooltip != "Amount due (EUR)" {
+		t.Errorf("expected tooltip, got %q", field.Tooltip)
+	}
+}
+
+func TestListBoxCreation(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddListBox("toppings", 1, 40, 5, 60, 30, []string{"Cheese", "Ham", "Olives", "Pineapple"}).
+		SetMultiSelect(true).
+		SetValues("Cheese", "Olives")
+	fb.AddListBox("size", 1, 40, 40, 60, 20, []string{"Small", "Large"}).SetValue("Large")
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if !bytes.Contains(out, []byte("/I [0 2]")) {
+		t.Error("expected selected indices /I [0 2] in PDF output")
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	toppings, err := doc.FormField("toppings")
+	if err != nil || toppings == nil {
+		t.Fatalf("toppings field: %v", err)
+	}
+	if toppings.Type != "Ch" || !toppings.IsMultiSelect() || toppings.Flags&(1<<17) != 0 {
+		t.Errorf("expected a multi-select list box, got type %q flags %d", toppings.Type, toppings.Flags)
+	}
+	if got := strings.Join(toppings.Values, ","); got != "Cheese,Olives" {
+		t.Errorf("expected values Cheese,Olives, got %s", got)
+	}
+	if toppings.Value != "Cheese" {
+		t.Errorf("expected first value Cheese, got %q", toppings.Value)
+	}
+	if len(toppings.Options) != 4 {
+		t.Errorf("expected 4 options, got %d", len(toppings.Options))
+	}
+
+	size, err := doc.FormField("size")
+	if err != nil || size == nil {
+		t.Fatalf("size field: %v", err)
+	}
+	if size.IsMultiSelect() || size.Value != "Large" || size.Values != nil {
+		t.Errorf("expected single selection Large, got %q %v", size.Value, size.Values)
+	}
+}
+
+func TestFieldBorderAndBackground(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("name", 1, 40, 5, 80, 10).
+		SetBorderColor(128, 128, 128).
+		SetBackgroundColor(240, 240, 240).
+		SetBorderWidth(0.5)
+	fb.AddTextField("plain", 1, 40, 20, 80, 10)
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if !bytes.Contains(out, []byte("/MK <</BC [0.502 0.502 0.502] /BG [0.941 0.941 0.941]>> /BS <</W 0.50 /S /S>>")) {
+		t.Error("expected /MK colors and /BS border style in PDF output")
+	}
+	// Both copies of the styled field carry the colors; the plain field none.
+	if n := bytes.Count(out, []byte("/MK")); n != 2 {
+		t.Errorf("expected /MK on the styled field only, got %d", n)
+	}
+	// The appearance stream draws the box so it shows without /NeedAppearances.
+	if !bytes.Contains(out, []byte("0.941 0.941 0.941 rg 0 0 226.77 28.35 re f")) {
+		t.Error("expected the background in the appearance stream")
+	}
+	if !bytes.Contains(out, []byte("0.502 0.502 0.502 RG 0.50 w")) {
+		t.Error("expected the border in the appearance stream")
+	}
+}
+
+func TestSignatureFieldCreation(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("name", 1, 40, 5, 80, 10)
+	fb.AddSignatureField("approval", 1, 40, 30, 80, 20).SetTooltip("Sign here")
+
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if n := bytes.Count(out, []byte("/FT /Sig")); n != 1 {
+		t.Errorf("expected a single signature field dictionary, got %d", n)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	sig, err := doc.FormField("approval")
+	if err != nil || sig == nil {
+		t.Fatalf("approval field: %v", err)
+	}
+	if sig.Type != "Sig" {
+		t.Errorf("expected type Sig, got %q", sig.Type)
+	}
+	if sig.Value != "" {
+		t.Errorf("expected an unsigned field, got value %q", sig.Value)
+	}
+	if sig.ObjNum == 0 {
+		t.Error("expected the signature field to be an indirect object")
+	}
+	if sig.Tooltip != "Sign here" {
+		t.Errorf("expected tooltip %q, got %q", "Sign here", sig.Tooltip)
+	}
+
+	annotRe := regexp.MustCompile(fmt.Sprintf(`/Annots \[[^\n]*\b%d 0 R`, sig.ObjNum))
+	if !annotRe.Match(out) {
+		t.Errorf("expected page annotations to refer to object %d", sig.ObjNum)
+	}
+}
@@ -0,0 +1,250 @@
+package form
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// objectStreamFields reports whether any of the given fields is stored in a
+// compressed object stream, where setFieldValue cannot find it by searching
+// the raw file bytes.
+func objectStreamFields(summary map[int][]reader.XRefEntry, fields []*reader.FormField) bool {
+	for _, field := range fields {
+		if entries := summary[field.ObjNum]; len(entries) > 0 && entries[len(entries)-1].Type == "compressed" {
+			return true
+		}
+	}
+	return false
+}
+
+// fillObjectStreams fills the fields of a PDF 1.5+ document whose field
+// dictionaries live in object streams. Each object stream holding one of the
+// fields is decompressed, its field dictionaries are edited and it is
+// re-emitted, together with any new appearance streams, in an incremental
+// update ending with a cross-reference stream. Fields stored as regular
+// objects are rewritten in the same update.
+func fillObjectStreams(doc *reader.Document, data []byte, summary map[int][]reader.XRefEntry, fieldMap map[string]*reader.FormField, values map[string]string, names []string) ([]byte, error) {
+	trailer := doc.Trailer()
+	if _, ok := trailer["Encrypt"]; ok {
+		return nil, fmt.Errorf("form: filling encrypted documents with object streams is not supported")
+	}
+	prev, err := reader.StartXRef(data)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", err)
+	}
+
+	current := make(map[int]reader.XRefEntry, len(summary))
+	nextObj := 1
+	if size, ok := trailer.GetInt("Size"); ok {
+		nextObj = int(size)
+	}
+	for num, entries := range summary {
+		current[num] = entries[len(entries)-1]
+		nextObj = max(nextObj, num+1)
+	}
+
+	// Collect the objects to rewrite: every object of the object streams
+	// that hold a field, and fields stored as regular objects.
+	streams := make(map[int][]int)
+	var plain []int
+	for _, name := range names {
+		num := fieldMap[name].ObjNum
+		entry, ok := current[num]
+		switch {
+		case !ok || num == 0:
+		case entry.Type == "compressed":
+			streams[entry.StreamObj] = nil
+		case entry.Type == "in-use" && !slices.Contains(plain, num):
+			plain = append(plain, num)
+		}
+	}
+	for num, entry := range current {
+		if entry.Type != "compressed" {
+			continue
+		}
+		if _, ok := streams[entry.StreamObj]; ok {
+			streams[entry.StreamObj] = append(streams[entry.StreamObj], num)
+		}
+	}
+
+	var text bytes.Buffer
+	for _, members := range streams {
+		sort.Slice(members, func(i, j int) bool { return current[members[i]].Index < current[members[j]].Index })
+		for _, num := range members {
+			if err := writeRawObject(&text, doc, num, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, num := range plain {
+		if err := writeRawObject(&text, doc, num, current[num].Generation); err != nil {
+			return nil, err
+		}
+	}
+
+	// Edit the field dictionaries. Appearance streams cannot be stored in
+	// object streams and become regular objects.
+	edited := text.Bytes()
+	var appearances []string
+	for _, name := range names {
+		field := fieldMap[name]
+		var ap string
+		if field.Type == "Tx" {
+			if body, ok := textAppearanceObject(field, values[name]); ok {
+				ap = fmt.Sprintf("/AP <</N %d 0 R>>", nextObj+len(appearances))
+				appearances = append(appearances, body)
+			}
+		}
+		edited = setFieldValue(edited, field, values[name], ap)
+	}
+	objects := splitObjects(edited)
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	if len(data) > 0 && data[len(data)-1] != '\n' && data[len(data)-1] != '\r' {
+		buf.WriteByte('\n')
+	}
+
+	entries := make(map[int]xrefStreamEntry)
+	streamNums := make([]int, 0, len(streams))
+	for num := range streams {
+		streamNums = append(streamNums, num)
+	}
+	sort.Ints(streamNums)
+	for _, streamNum := range streamNums {
+		members := streams[streamNum]
+		content, first, err := objectStreamContent(members, objects)
+		if err != nil {
+			return nil, err
+		}
+		gen := current[streamNum].Generation
+		entries[streamNum] = xrefStreamEntry{1, buf.Len(), gen}
+		fmt.Fprintf(&buf, "%d %d obj\n<</Type /ObjStm /N %d /First %d /Filter /FlateDecode /Length %d>>\nstream\n", streamNum, gen, len(members), first, len(content))
+		buf.Write(content)
+		buf.WriteString("\nendstream\nendobj\n")
+		for i, num := range members {
+			entries[num] = xrefStreamEntry{2, streamNum, i}
+		}
+	}
+	for _, num := range plain {
+		gen := current[num].Generation
+		entries[num] = xrefStreamEntry{1, buf.Len(), gen}
+		fmt.Fprintf(&buf, "%d %d obj\n%s\nendobj\n", num, gen, objects[num])
+	}
+	for _, body := range appearances {
+		entries[nextObj] = xrefStreamEntry{1, buf.Len(), 0}
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", nextObj, body)
+		nextObj++
+	}
+
+	writeXRefStream(&buf, trailer, entries, nextObj, prev)
+	return buf.Bytes(), nil
+}
+
+// writeRawObject writes the object with the given number, taken from the
+// current revision of doc, as an indirect object definition.
+func writeRawObject(buf *bytes.Buffer, doc *reader.Document, num, gen int) error {
+	raw, err := doc.RawObject(num)
+	if err != nil {
+		return fmt.Errorf("form: reading object %d: %w", num, err)
+	}
+	fmt.Fprintf(buf, "%d %d obj\n%s\nendobj\n", num, gen, raw)
+	return nil
+}
+
+// splitObjects returns the body of each indirect object definition in data
+// by object number.
+func splitObjects(data []byte) map[int][]byte {
+	objects := make(map[int][]byte)
+	matches := fillObjPatternRe.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		body := bytes.TrimSpace(data[m[1]:end])
+		objects[num] = bytes.TrimSpace(bytes.TrimSuffix(body, []byte("endobj")))
+	}
+	return objects
+}
+
+// objectStreamContent returns the compressed data of an object stream
+// holding the given objects in order, and the offset of the first object
+// in the decompressed data.
+func objectStreamContent(members []int, objects map[int][]byte) ([]byte, int, error) {
+	var header, body bytes.Buffer
+	for _, num := range members {
+		obj, ok := objects[num]
+		if !ok {
+			return nil, 0, fmt.Errorf("form: object %d lost while editing", num)
+		}
+		fmt.Fprintf(&header, "%d %d ", num, body.Len())
+		body.Write(obj)
+		body.WriteByte('\n')
+	}
+	header.WriteByte('\n')
+	first := header.Len()
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(header.Bytes())
+	zw.Write(body.Bytes())
+	if err := zw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("form: compressing object stream: %w", err)
+	}
+	return compressed.Bytes(), first, nil
+}
+
+// xrefStreamEntry is a row of a cross-reference stream: type 1 entries hold
+// a byte offset and generation number, type 2 entries an object stream
+// number and index.
+type xrefStreamEntry struct {
+	typ            int
+	field2, field3 int
+}
+
+// writeXRefStream writes a cross-reference stream object numbered num
+// listing entries, with /Prev pointing at the previous section and the
+// root, info and ID of trailer, followed by startxref.
+func writeXRefStream(buf *bytes.Buffer, trailer reader.Dict, entries map[int]xrefStreamEntry, num int, prev int64) {
+	offset := buf.Len()
+	entries[num] = xrefStreamEntry{1, offset, 0}
+
+	nums := make([]int, 0, len(entries))
+	for n := range entries {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	var index bytes.Buffer
+	var rows []byte
+	for i, n := range nums {
+		if i > 0 {
+			index.WriteByte(' ')
+		}
+		fmt.Fprintf(&index, "%d 1", n)
+		e := entries[n]
+		rows = append(rows, byte(e.typ))
+		rows = binary.BigEndian.AppendUint32(rows, uint32(e.field2))
+		rows = binary.BigEndian.AppendUint16(rows, uint16(e.field3))
+	}
+
+	fmt.Fprintf(buf, "%d 0 obj\n<</Type /XRef /Size %d /W [1 4 2] /Index [%s] /Prev %d", num, num+1, index.String(), prev)
+	for _, key := range []reader.Name{"Root", "Info", "ID"} {
+		if v, ok := trailer[key]; ok {
+			fmt.Fprintf(buf, " %s ", key.Encode())
+			reader.WriteObject(buf, v)
+		}
+	}
+	fmt.Fprintf(buf, " /Length %d>>\nstream\n", len(rows))
+	buf.Write(rows)
+	fmt.Fprintf(buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", offset)
+}
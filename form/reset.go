@@ -0,0 +1,126 @@
+package form
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+var resetValueRe = regexp.MustCompile(`\s*/V\s*(\([^)]*\)|\[[^\]]*\]|/[^\s/<>\[\]()]+)`)
+
+// Reset reads a PDF with form fields, sets every field back to its default
+// value (/DV) and writes the result to output. Fields without a default are
+// cleared: text and choice fields lose their value and checkboxes and radio
+// groups are turned off. Push buttons and signature fields are left as they
+// are.
+//
+// After modifying field values, the xref table is rebuilt to ensure validity.
+func Reset(input io.ReadSeeker, output io.Writer) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("form: reading input: %w", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("form: parsing PDF: %w", err)
+	}
+
+	fields, err := doc.FormFields()
+	if err != nil {
+		return fmt.Errorf("form: reading form fields: %w", err)
+	}
+
+	if len(fields) == 0 {
+		_, err = io.Copy(output, bytes.NewReader(data))
+		return err
+	}
+
+	modified := make([]byte, len(data))
+	copy(modified, data)
+
+	for _, field := range flattenFields(fields) {
+		if field.Name == "" || field.Type == "" {
+			continue
+		}
+		modified = resetField(modified, field)
+	}
+
+	// Rebuild xref table to account for any byte offset changes
+	modified = rebuildXref(modified)
+
+	_, err = io.Copy(output, bytes.NewReader(modified))
+	return err
+}
+
+// ResetFile reads a PDF from inputPath, resets its form fields to their
+// defaults, and writes to outputPath.
+func ResetFile(inputPath, outputPath string) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("form: opening %s: %w", inputPath, err)
+	}
+	defer input.Close()
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("form: creating %s: %w", outputPath, err)
+	}
+	defer out.Close()
+
+	return Reset(input, out)
+}
+
+// resetField sets a field back to its default value in the raw PDF bytes.
+// Text fields get a new appearance stream for the default, so the old value
+// is no longer displayed.
+func resetField(data []byte, field *reader.FormField) []byte {
+	switch field.Type {
+	case "Tx":
+		if field.Value == "" && field.Default == "" {
+			return data
+		}
+		var ap string
+		data, ap = addTextAppearance(data, field, field.Default)
+		if field.Default != "" {
+			return setFieldValue(data, field, field.Default, ap)
+		}
+		return removeFieldValue(data, field, ap)
+
+	case "Sig":
+		return data
+
+	case "Btn":
+		if field.Flags&(1<<16) != 0 { // push button
+			return data
+		}
+		value := field.Default
+		if value == "" {
+			value = "Off"
+		}
+		return setFieldValue(data, field, value, "")
+
+	default:
+		if field.Default != "" {
+			return setFieldValue(data, field, field.Default, "")
+		}
+		return removeFieldValue(data, field, "")
+	}
+}
+
+// removeFieldValue removes a field's /V entry from the raw PDF bytes and,
+// if ap is not empty, replaces its /AP entry with ap. Like setFieldValue it
+// updates all occurrences of the field dictionary.
+func removeFieldValue(data []byte, field *reader.FormField, ap string) []byte {
+	return editFieldDicts(data, field, func(fieldDict []byte) []byte {
+		newDict := resetValueRe.ReplaceAll(fieldDict, nil)
+		if ap != "" {
+			newDict = setAppearance(newDict, ap)
+		}
+		return newDict
+	})
+}
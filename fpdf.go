@@ -2138,6 +2138,33 @@ func (f *Fpdf) GetFontSize() (ptSize, unitSize float64) {
 	return f.fontSizePt, f.fontSize
 }
 
+// GetFontFamily returns the family of the current font in lower case, as
+// set by SetFont().
+func (f *Fpdf) GetFontFamily() string {
+	return f.fontFamily
+}
+
+// GetFontStyle returns the style of the current font as accepted by
+// SetFont(), for example "B" or "BIU". Together with GetFontFamily() and
+// GetFontSize() it allows the current font to be restored later.
+func (f *Fpdf) GetFontStyle() string {
+	styleStr := f.fontStyle
+	if f.underline {
+		styleStr += "U"
+	}
+	if f.strikeout {
+		styleStr += "S"
+	}
+	return styleStr
+}
+
+// IsCurrentFontUTF8 reports whether the current font was added with
+// AddUTF8Font() or AddUTF8FontFromBytes(). Text in such a font is written as
+// UTF-8; text in the core fonts is written in cp1252.
+func (f *Fpdf) IsCurrentFontUTF8() bool {
+	return f.isCurrentUTF8
+}
+
 // AddLink creates a new internal link and returns its identifier. An internal
 // link is a clickable area which directs to another place within the document.
 // The identifier can then be passed to Cell(), Write(), Image() or Link(). The
@@ -3489,6 +3516,74 @@ func (f *Fpdf) AddPageAnnotation(page int, annot string) {
 	f.pageAnnots[page] = append(f.pageAnnots[page], annot)
 }
 
+// NewRawObject reserves an indirect object that is written to the document
+// on output, and returns its identifier. Its body is set with SetRawObject or
+// SetRawStreamObject and other objects refer to it with RawObjectRef, so
+// objects may refer to each other in any order. This is used by extension
+// packages (e.g., form) for objects that must be indirect, such as field
+// hierarchies and appearance streams.
+func (f *Fpdf) NewRawObject() int {
+	f.rawObjects = append(f.rawObjects, rawObjectType{body: "null"})
+	return len(f.rawObjects)
+}
+
+// SetRawObject sets the body of the raw object id, such as a dictionary
+// written as "<</Type /Annot ...>>".
+func (f *Fpdf) SetRawObject(id int, body string) {
+	if id < 1 || id > len(f.rawObjects) {
+		f.err = fmt.Errorf("invalid raw object identifier %d", id)
+		return
+	}
+	f.rawObjects[id-1] = rawObjectType{body: body}
+}
+
+// SetRawStreamObject makes the raw object id a stream with the given
+// dictionary entries and data. The /Length entry is added on output and the
+// data is encrypted along with the rest of the document if protection is
+// enabled.
+func (f *Fpdf) SetRawStreamObject(id int, dict string, data []byte) {
+	if id < 1 || id > len(f.rawObjects) {
+		f.err = fmt.Errorf("invalid raw object identifier %d", id)
+		return
+	}
+	f.rawObjects[id-1] = rawObjectType{body: dict, stream: data, isStream: true}
+}
+
+// RawObjectRef returns a placeholder for a reference to the raw object id.
+// It may be used in strings passed to SetRawObject, SetRawStreamObject,
+// AddPageAnnotation and AddCatalogEntry, and is replaced with the indirect
+// reference "n 0 R" on output.
+func (f *Fpdf) RawObjectRef(id int) string {
+	return sprintf("{{rawobj:%d}}", id)
+}
+
+// resolveRawRefs replaces the RawObjectRef placeholders in s.
+func (f *Fpdf) resolveRawRefs(s string) string {
+	if f.rawRefs == nil {
+		return s
+	}
+	return f.rawRefs.Replace(s)
+}
+
+// putRawObjects writes the objects added with NewRawObject. Their numbers
+// follow the page objects, as reserved by enddoc.
+func (f *Fpdf) putRawObjects() {
+	if f.n != f.rawObjBase {
+		f.err = fmt.Errorf("raw objects start at object %d, expected %d", f.n+1, f.rawObjBase+1)
+		return
+	}
+	for _, obj := range f.rawObjects {
+		f.newobj()
+		if obj.isStream {
+			f.outf("<<%s /Length %d>>", f.resolveRawRefs(obj.body), len(obj.stream))
+			f.putstream(obj.stream)
+		} else {
+			f.out(f.resolveRawRefs(obj.body))
+		}
+		f.out("endobj")
+	}
+}
+
 // GetScaleFactor returns the scale factor (points per user unit).
 func (f *Fpdf) GetScaleFactor() float64 {
 	return f.k
@@ -3952,7 +4047,7 @@ func (f *Fpdf) putpages() {
 			f.putAttachmentAnnotationLinks(&annots, n)
 			// Extra annotations (form widgets, etc.)
 			for _, extra := range extraAnnots {
-				annots.printf(extra)
+				annots.printf(f.resolveRawRefs(extra))
 			}
 			annots.printf("]")
 			f.out(annots.String())
@@ -4719,7 +4814,7 @@ func (f *Fpdf) putcatalog() {
 	f.out(">>")
 	// Extra catalog entries (e.g., AcroForm from form package)
 	for _, extra := range f.catalogExtra {
-		f.out(extra)
+		f.out(f.resolveRawRefs(extra))
 	}
 }
 
@@ -4812,7 +4907,17 @@ func (f *Fpdf) enddoc() {
 	// Embedded files
 	f.putAttachments()
 	f.putAnnotationsAttachments()
+	// Raw objects are numbered after the page and page content objects
+	f.rawObjBase = f.n + 2*f.page
+	if len(f.rawObjects) > 0 {
+		pairs := make([]string, 0, 2*len(f.rawObjects))
+		for id := 1; id <= len(f.rawObjects); id++ {
+			pairs = append(pairs, f.RawObjectRef(id), sprintf("%d 0 R", f.rawObjBase+id))
+		}
+		f.rawRefs = strings.NewReplacer(pairs...)
+	}
 	f.putpages()
+	f.putRawObjects()
 	f.putresources()
 	if f.err != nil {
 		return
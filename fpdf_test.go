@@ -34,6 +34,7 @@ import (
 	"github.com/lvillar/gofpdf"
 	"github.com/lvillar/gofpdf/internal/example"
 	"github.com/lvillar/gofpdf/internal/files"
+	"github.com/lvillar/gofpdf/reader"
 )
 
 func init() {
@@ -2852,6 +2853,200 @@ func TestMultiCellUnsupportedChar(t *testing.T) {
 	pdf.OutputFileAndClose(fileStr)
 }
 
+func TestGetFont(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Arial", "ub", 11)
+	if got := pdf.GetFontFamily(); got != "helvetica" {
+		t.Errorf("GetFontFamily() = %q, want %q", got, "helvetica")
+	}
+	if got := pdf.GetFontStyle(); got != "BU" {
+		t.Errorf("GetFontStyle() = %q, want %q", got, "BU")
+	}
+
+	family, style := pdf.GetFontFamily(), pdf.GetFontStyle()
+	pdf.SetFont("Times", "I", 9)
+	pdf.SetFont(family, style, 11)
+	if got := pdf.GetFontFamily() + " " + pdf.GetFontStyle(); got != "helvetica BU" {
+		t.Errorf("restored font = %q, want %q", got, "helvetica BU")
+	}
+}
+
+func TestNewDocumentWithProtection(t *testing.T) {
+	pdf := gofpdf.NewDocument(
+		gofpdf.WithPageSize(gofpdf.PageSizeA4),
+		gofpdf.WithProtection(gofpdf.CnProtectPrint|gofpdf.CnProtectCopy, "u", "o"),
+	)
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Encrypt")) {
+		t.Error("expected an encrypted document")
+	}
+
+	pdf = gofpdf.NewDocument(gofpdf.WithProtection(1, "u", "o"))
+	if pdf.Error() == nil {
+		t.Error("expected an error for invalid protection flags")
+	}
+}
+
+func TestNewDocumentWithMargins(t *testing.T) {
+	// Without options the layout matches New
+	def := gofpdf.NewDocument()
+	ref := gofpdf.New("P", "mm", "A4", "")
+	l1, t1, r1, b1 := def.GetMargins()
+	l2, t2, r2, b2 := ref.GetMargins()
+	if l1 != l2 || t1 != t2 || r1 != r2 || b1 != b2 {
+		t.Errorf("default margins = %v %v %v %v, want %v %v %v %v", l1, t1, r1, b1, l2, t2, r2, b2)
+	}
+	auto1, m1 := def.GetAutoPageBreak()
+	auto2, m2 := ref.GetAutoPageBreak()
+	if auto1 != auto2 || m1 != m2 {
+		t.Errorf("default page break = %v %v, want %v %v", auto1, m1, auto2, m2)
+	}
+
+	pdf := gofpdf.NewDocument(
+		gofpdf.WithMargins(15, 20, 25),
+		gofpdf.WithAutoPageBreak(false, 30),
+	)
+	if left, top, right, _ := pdf.GetMargins(); left != 15 || top != 20 || right != 25 {
+		t.Errorf("margins = %v %v %v, want 15 20 25", left, top, right)
+	}
+	if auto, bottom := pdf.GetAutoPageBreak(); auto || bottom != 30 {
+		t.Errorf("page break = %v %v, want false 30", auto, bottom)
+	}
+}
+
+func TestNewDocumentWithMetadata(t *testing.T) {
+	pdf := gofpdf.NewDocument(gofpdf.WithMetadata(gofpdf.Metadata{
+		Title:    "Café menu",
+		Author:   "Zoë",
+		Keywords: "menu prices",
+	}))
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	meta := doc.Metadata()
+	if meta["Title"] != "Café menu" || meta["Author"] != "Zoë" || meta["Keywords"] != "menu prices" {
+		t.Errorf("metadata = %v", meta)
+	}
+	if _, ok := meta["Subject"]; ok {
+		t.Errorf("unexpected subject %q", meta["Subject"])
+	}
+}
+
+func TestRawObjects(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.AddPage()
+
+	// The dictionary refers to the stream before its body is set
+	dict := pdf.NewRawObject()
+	data := pdf.NewRawObject()
+	link := pdf.NewRawObject()
+	pdf.SetRawObject(dict, "<</Type /Example /Data "+pdf.RawObjectRef(data)+">>")
+	pdf.SetRawStreamObject(data, "/Type /ExampleData", []byte("raw stream data"))
+	pdf.SetRawObject(link, "<</Type /Annot /Subtype /Link /Rect [10 10 50 50] /A <</S /URI /URI (https://example.com)>>>>")
+	pdf.AddCatalogEntry("/Example " + pdf.RawObjectRef(dict))
+	pdf.AddPageAnnotation(2, pdf.RawObjectRef(link))
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("{{rawobj:")) {
+		t.Error("output contains an unresolved raw object placeholder")
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	catalog, err := doc.Catalog()
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	ref, ok := catalog["Example"].(reader.Reference)
+	if !ok {
+		t.Fatalf("catalog /Example = %v, want a reference", catalog["Example"])
+	}
+	obj, err := doc.ResolveReference(ref)
+	if err != nil {
+		t.Fatalf("resolving /Example: %v", err)
+	}
+	example, ok := obj.(reader.Dict)
+	if !ok || example.GetName("Type") != "Example" {
+		t.Fatalf("/Example = %v, want the raw dictionary", obj)
+	}
+	ref, ok = example["Data"].(reader.Reference)
+	if !ok {
+		t.Fatalf("/Data = %v, want a reference", example["Data"])
+	}
+	obj, err = doc.ResolveReference(ref)
+	if err != nil {
+		t.Fatalf("resolving /Data: %v", err)
+	}
+	stream, ok := obj.(reader.Stream)
+	if !ok {
+		t.Fatalf("/Data = %v, want a stream", obj)
+	}
+	if got, err := stream.Decode(); err != nil || string(got) != "raw stream data" {
+		t.Errorf("stream data = %q, %v", got, err)
+	}
+
+	page, err := doc.Page(2)
+	if err != nil {
+		t.Fatalf("page 2: %v", err)
+	}
+	links, err := page.Links()
+	if err != nil {
+		t.Fatalf("links: %v", err)
+	}
+	if len(links) != 1 || links[0].URL != "https://example.com" {
+		t.Errorf("page 2 links = %+v, want the raw link annotation", links)
+	}
+}
+
+func TestRawObjectsProtected(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtection(gofpdf.CnProtectPrint, "", "owner")
+	pdf.AddPage()
+	id := pdf.NewRawObject()
+	pdf.SetRawStreamObject(id, "", []byte("secret raw stream data"))
+	pdf.AddCatalogEntry("/Example " + pdf.RawObjectRef(id))
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret raw stream data")) {
+		t.Error("raw stream data written unencrypted")
+	}
+}
+
+func TestRawObjectInvalidID(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.NewRawObject()
+	pdf.SetRawObject(2, "<<>>")
+	if pdf.Error() == nil {
+		t.Error("expected an error for an unknown raw object identifier")
+	}
+
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.SetRawStreamObject(0, "", nil)
+	if pdf.Error() == nil {
+		t.Error("expected an error for raw object identifier 0")
+	}
+}
+
 // ExampleFpdf_SetTextRenderingMode demonstrates embedding files in PDFs,
 // at the top-level.
 func ExampleFpdf_SetAttachments() {
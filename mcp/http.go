@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxMessageSize bounds the size of a request body, like the line buffer of
+// the stdio transport.
+const maxMessageSize = 10 * 1024 * 1024
+
+// Timeouts of the server started by RunHTTP. There is no write timeout, as
+// a tool call such as merging large files may take a while to answer.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = time.Minute
+	idleTimeout       = 2 * time.Minute
+)
+
+// RunHTTP serves the MCP streamable HTTP transport on addr, at the /mcp
+// endpoint, until the listener fails. It is an alternative to Run for
+// hosting the server as a network service shared by several clients.
+//
+// The transport has no authentication, and the tools read and write files
+// with the permissions of the server process. addr should therefore be a
+// loopback address such as "localhost:8080", unless the server sits behind
+// a proxy that authenticates its clients.
+func (s *Server) RunHTTP(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", s.Handler())
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	return srv.ListenAndServe()
+}
+
+// Handler returns an http.Handler implementing the MCP streamable HTTP
+// transport, for mounting the server at an endpoint of an existing HTTP
+// server.
+//
+// Clients POST JSON-RPC messages, or batches of them, to the endpoint.
+// Responses are sent as a server-sent event stream if the client accepts
+// text/event-stream, and as a JSON body otherwise. Notifications get an
+// empty 202 Accepted response. The server is stateless: it issues no
+// session IDs and does not offer a stream for server-initiated messages, so
+// GET requests are rejected. Requests from a browser page of another
+// origin are forbidden, to protect local servers from DNS rebinding.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading request: %v", err), http.StatusBadRequest)
+		return
+	}
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+
+	var messages []jsonrpcRequest
+	if batch {
+		err = json.Unmarshal(body, &messages)
+	} else {
+		var req jsonrpcRequest
+		err = json.Unmarshal(body, &req)
+		messages = append(messages, req)
+	}
+	if err != nil || len(messages) == 0 {
+		if err == nil {
+			err = fmt.Errorf("empty batch")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		s.connection(w).sendError(nil, -32700, "Parse error", err.Error())
+		return
+	}
+
+	// Client responses and notifications need no reply
+	hasRequests := slices.ContainsFunc(messages, func(msg jsonrpcRequest) bool {
+		return msg.ID != nil && msg.Method != ""
+	})
+	stream := hasRequests && strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	var buf bytes.Buffer
+	conn := s.connection(&buf)
+	// A plain JSON response has no room for progress notifications
+	conn.noNotify = true
+	if stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		conn = s.connection(&sseWriter{w: w})
+	}
+	for _, msg := range messages {
+		switch {
+		case msg.Method == "":
+		case msg.ID == nil:
+			s.connection(io.Discard).handleRequest(msg)
+		default:
+			conn.handleRequest(msg)
+		}
+	}
+	if !hasRequests {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if stream {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !batch {
+		w.Write(buf.Bytes())
+		return
+	}
+	// Each response is a line; a batch gets them as a JSON array
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	w.Write([]byte("["))
+	w.Write(bytes.Join(lines, []byte(",")))
+	w.Write([]byte("]\n"))
+}
+
+// connection returns a server sharing s's tools and resources that writes
+// its messages to out, so that requests received over HTTP are answered on
+// their own response.
+func (s *Server) connection(out io.Writer) *Server {
+	return &Server{
+		tools:     s.tools,
+		resources: s.resources,
+		output:    out,
+	}
+}
+
+// sseWriter sends each message written to it, a line of JSON, as a
+// server-sent event.
+type sseWriter struct {
+	w http.ResponseWriter
+}
+
+func (sw *sseWriter) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(sw.w, "event: message\ndata: %s\n\n", bytes.TrimSpace(p)); err != nil {
+		return 0, err
+	}
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return len(p), nil
+}
@@ -1,10 +1,16 @@
 package mcp
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"image/png"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/lvillar/gofpdf/doctpl"
 	"github.com/lvillar/gofpdf/reader"
 )
 
@@ -42,6 +48,22 @@ func RegisterDefaultResources(s *Server) {
 		MIMEType:    "application/json",
 		Handler:     handleFormFieldsResource,
 	})
+
+	s.AddResource(Resource{
+		URI:         "pdf://thumbnail",
+		Name:        "PDF Page Thumbnail",
+		Description: "Render a page of a PDF as a PNG image, with text shown as gray bars. Pass the file path, the page number (default 1), and optionally the image width in pixels (default 300) as query parameters: pdf://thumbnail?path=/path/to/file.pdf&page=2&width=600",
+		MIMEType:    "image/png",
+		Handler:     handleThumbnailResource,
+	})
+
+	s.AddResource(Resource{
+		URI:         "doctpl://elements",
+		Name:        "Template Element Reference",
+		Description: "Reference of every element type supported by the create_pdf template, with each field's name, JSON type, default, and description.",
+		MIMEType:    "application/json",
+		Handler:     handleElementsResource,
+	})
 }
 
 func extractPathFromURI(uri string) string {
@@ -168,6 +190,9 @@ func handleFormFieldsResource(uri string) ([]ResourceContent, error) {
 		if len(f.Options) > 0 {
 			fi["options"] = f.Options
 		}
+		if len(f.Values) > 0 {
+			fi["values"] = f.Values
+		}
 		fieldInfos = append(fieldInfos, fi)
 	}
 
@@ -183,3 +208,72 @@ func handleFormFieldsResource(uri string) ([]ResourceContent, error) {
 		Text:     string(jsonBytes),
 	}}, nil
 }
+
+func handleThumbnailResource(uri string) ([]ResourceContent, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parsing URI: %w", err)
+	}
+	query := u.Query()
+	path := query.Get("path")
+	if path == "" {
+		return nil, fmt.Errorf("missing 'path' parameter in URI")
+	}
+	pageNum, width := 1, 300
+	if v := query.Get("page"); v != "" {
+		if pageNum, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid 'page' parameter %q", v)
+		}
+	}
+	if v := query.Get("width"); v != "" {
+		if width, err = strconv.Atoi(v); err != nil || width <= 0 || width > 4000 {
+			return nil, fmt.Errorf("invalid 'width' parameter %q (must be 1 to 4000)", v)
+		}
+	}
+
+	doc, err := reader.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening PDF: %w", err)
+	}
+	page, err := doc.Page(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scale the visible page width, which is the height of rotated pages
+	box := page.MediaBox
+	if page.CropBox != nil {
+		box = *page.CropBox
+	}
+	pageWidth := box.Width()
+	if page.Rotate%180 != 0 {
+		pageWidth = box.Height()
+	}
+	img, err := page.Render(float64(width) / pageWidth)
+	if err != nil {
+		return nil, fmt.Errorf("rendering page %d: %w", pageNum, err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encoding PNG: %w", err)
+	}
+	return []ResourceContent{{
+		URI:      uri,
+		MIMEType: "image/png",
+		Blob:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}}, nil
+}
+
+func handleElementsResource(uri string) ([]ResourceContent, error) {
+	info := map[string]interface{}{
+		"elements": doctpl.ElementReference(),
+	}
+
+	jsonBytes, _ := json.MarshalIndent(info, "", "  ")
+	return []ResourceContent{{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(jsonBytes),
+	}}, nil
+}
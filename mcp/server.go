@@ -1,8 +1,9 @@
 // Package mcp implements a Model Context Protocol (MCP) server that exposes
 // gofpdf's PDF capabilities as tools and resources for AI assistants.
 //
-// The server communicates via JSON-RPC 2.0 over stdio and implements the
-// MCP specification (2024-11-05) for tools and resources.
+// The server communicates via JSON-RPC 2.0 over stdio, or over HTTP with
+// Server.RunHTTP, and implements the MCP specification (2024-11-05) for
+// tools and resources.
 //
 // # Usage with Claude Desktop
 //
@@ -23,29 +24,48 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 )
 
-// Server is an MCP server that handles JSON-RPC 2.0 messages over stdio.
+// Server is an MCP server that handles JSON-RPC 2.0 messages over stdio or
+// HTTP.
 type Server struct {
 	tools     map[string]Tool
 	resources map[string]Resource
 	input     io.Reader
 	output    io.Writer
 	mu        sync.Mutex
+
+	// noNotify drops notifications, for connections that can only carry
+	// responses
+	noNotify bool
 }
 
-// Tool defines an MCP tool that can be called by the client.
+// Tool defines an MCP tool that can be called by the client. Either
+// Handler or, for long-running tools that report progress,
+// ProgressHandler must be set.
 type Tool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
-	Handler     ToolHandler            `json:"-"`
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description"`
+	InputSchema     map[string]interface{} `json:"inputSchema"`
+	Handler         ToolHandler            `json:"-"`
+	ProgressHandler ProgressToolHandler    `json:"-"`
 }
 
 // ToolHandler is a function that executes a tool with the given arguments.
 type ToolHandler func(args map[string]interface{}) (ToolResult, error)
 
+// ProgressToolHandler is a ToolHandler that can report its progress while it
+// runs by calling progress.
+type ProgressToolHandler func(args map[string]interface{}, progress ProgressFunc) (ToolResult, error)
+
+// ProgressFunc reports that a tool has completed progress units of work out
+// of total. If the client asked for progress by sending a progressToken
+// with its call, each report is sent as a notifications/progress message;
+// otherwise it is ignored.
+type ProgressFunc func(progress, total float64)
+
 // ToolResult is the result returned by a tool execution.
 type ToolResult struct {
 	Content []ContentBlock `json:"content"`
@@ -95,6 +115,12 @@ type jsonrpcResponse struct {
 	Error   *jsonrpcError    `json:"error,omitempty"`
 }
 
+type jsonrpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 type jsonrpcError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
@@ -207,6 +233,9 @@ func (s *Server) handleToolsCall(req jsonrpcRequest) {
 	var params struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
+		Meta      struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"_meta"`
 	}
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		s.sendError(req.ID, -32602, "Invalid params", err.Error())
@@ -219,7 +248,13 @@ func (s *Server) handleToolsCall(req jsonrpcRequest) {
 		return
 	}
 
-	result, err := tool.Handler(params.Arguments)
+	var result ToolResult
+	var err error
+	if tool.ProgressHandler != nil {
+		result, err = tool.ProgressHandler(params.Arguments, s.progressFunc(params.Meta.ProgressToken))
+	} else {
+		result, err = tool.Handler(params.Arguments)
+	}
 	if err != nil {
 		s.sendResult(req.ID, ToolResult{
 			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
@@ -231,6 +266,25 @@ func (s *Server) handleToolsCall(req jsonrpcRequest) {
 	s.sendResult(req.ID, result)
 }
 
+// progressFunc returns a ProgressFunc sending progress notifications
+// tagged with token, or one doing nothing if token is empty.
+func (s *Server) progressFunc(token json.RawMessage) ProgressFunc {
+	if len(token) == 0 || string(token) == "null" || s.noNotify {
+		return func(progress, total float64) {}
+	}
+	return func(progress, total float64) {
+		s.send(jsonrpcNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/progress",
+			Params: map[string]interface{}{
+				"progressToken": token,
+				"progress":      progress,
+				"total":         total,
+			},
+		})
+	}
+}
+
 func (s *Server) handleResourcesList(req jsonrpcRequest) {
 	resources := make([]map[string]interface{}, 0, len(s.resources))
 	for _, r := range s.resources {
@@ -258,7 +312,12 @@ func (s *Server) handleResourcesRead(req jsonrpcRequest) {
 		return
 	}
 
+	// Resources taking parameters are registered without their query
 	resource, ok := s.resources[params.URI]
+	if !ok {
+		base, _, _ := strings.Cut(params.URI, "?")
+		resource, ok = s.resources[base]
+	}
 	if !ok {
 		s.sendError(req.ID, -32602, "Unknown resource", params.URI)
 		return
@@ -293,11 +352,12 @@ func (s *Server) sendError(id *json.RawMessage, code int, message string, data i
 	})
 }
 
-func (s *Server) send(resp jsonrpcResponse) {
+// send writes a response or notification as a line of JSON.
+func (s *Server) send(msg interface{}) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := json.Marshal(resp)
+	data, err := json.Marshal(msg)
 	if err != nil {
 		return
 	}
@@ -2,9 +2,32 @@ package mcp
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/reader"
 )
 
 func sendRequest(t *testing.T, s *Server, method string, id int, params interface{}) jsonrpcResponse {
@@ -134,11 +157,62 @@ func TestServerResourcesList(t *testing.T) {
 		t.Fatal("resources is not an array")
 	}
 
-	if len(resources) != 4 {
-		t.Fatalf("expected 4 resources, got %d", len(resources))
+	if len(resources) != 6 {
+		t.Fatalf("expected 6 resources, got %d", len(resources))
 	}
 }
 
+func TestServerElementsResource(t *testing.T) {
+	s := NewServerWithIO(nil, nil)
+	RegisterDefaultResources(s)
+
+	resp := sendRequest(t, s, "resources/read", 8, map[string]interface{}{
+		"uri": "doctpl://elements",
+	})
+
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error.Message)
+	}
+
+	resultBytes, _ := json.Marshal(resp.Result)
+	var result struct {
+		Contents []ResourceContent `json:"contents"`
+	}
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		t.Fatalf("unmarshaling result: %v", err)
+	}
+	if len(result.Contents) != 1 {
+		t.Fatalf("expected 1 content, got %d", len(result.Contents))
+	}
+
+	var ref struct {
+		Elements []struct {
+			Type   string `json:"type"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &ref); err != nil {
+		t.Fatalf("unmarshaling reference: %v", err)
+	}
+
+	for _, elem := range ref.Elements {
+		if elem.Type != "table" {
+			continue
+		}
+		fields := make(map[string]bool)
+		for _, f := range elem.Fields {
+			fields[f.Name] = true
+		}
+		if !fields["columns"] || !fields["rows"] {
+			t.Fatalf("table element missing columns/rows: %+v", elem.Fields)
+		}
+		return
+	}
+	t.Fatal("table element not listed")
+}
+
 func TestServerPing(t *testing.T) {
 	s := NewServerWithIO(nil, nil)
 
@@ -220,6 +294,45 @@ func TestServerCreatePDFTool(t *testing.T) {
 	}
 }
 
+func TestCreatePDFToolMarkdown(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "notes.pdf")
+	_, err := handleCreatePDF(map[string]interface{}{
+		"markdown":   "# Meeting Notes\n\n- **Budget** approved\n- Launch in May\n\n| Owner | Task |\n|---|---|\n| Ana | Slides |\n",
+		"outputPath": outputPath,
+	})
+	if err != nil {
+		t.Fatalf("create_pdf: %v", err)
+	}
+
+	doc, err := reader.Open(outputPath)
+	if err != nil {
+		t.Fatalf("opening output: %v", err)
+	}
+	if title := doc.Metadata()["Title"]; title != "Meeting Notes" {
+		t.Errorf("title = %q, want Meeting Notes", title)
+	}
+	page, _ := doc.Page(1)
+	text, err := page.ExtractText()
+	if err != nil {
+		t.Fatalf("extracting text: %v", err)
+	}
+	for _, want := range []string{"Budget approved", "Launch in May", "Slides"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+
+	if _, err := handleCreatePDF(map[string]interface{}{}); err == nil {
+		t.Error("expected an error without template or markdown")
+	}
+	if _, err := handleCreatePDF(map[string]interface{}{
+		"template": map[string]interface{}{"pages": []interface{}{}},
+		"markdown": "# Both",
+	}); err == nil {
+		t.Error("expected an error with both template and markdown")
+	}
+}
+
 func TestServerMultipleRequests(t *testing.T) {
 	// Test that the server can handle multiple requests in sequence
 	requests := []string{
@@ -288,3 +401,455 @@ func TestToolAddTool(t *testing.T) {
 		t.Fatalf("unexpected result: %s", string(resultBytes))
 	}
 }
+
+func TestServerProgressNotifications(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("in%d.pdf", i))
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.AddPage()
+		if err := pdf.OutputFileAndClose(path); err != nil {
+			t.Fatalf("generating PDF: %v", err)
+		}
+		inputs = append(inputs, path)
+	}
+	inputsJSON, _ := json.Marshal(inputs)
+	outputJSON, _ := json.Marshal(filepath.Join(dir, "merged.pdf"))
+
+	call := func(meta string) []string {
+		t.Helper()
+		input := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"merge_pdfs","arguments":{"inputPaths":%s,"outputPath":%s}%s}}`+"\n",
+			inputsJSON, outputJSON, meta)
+		var output bytes.Buffer
+		s := NewServerWithIO(strings.NewReader(input), &output)
+		RegisterDefaultTools(s)
+		s.Run()
+		return strings.Split(strings.TrimSpace(output.String()), "\n")
+	}
+
+	lines := call(`,"_meta":{"progressToken":"merge-1"}`)
+	if len(lines) != 4 {
+		t.Fatalf("expected 3 notifications and a response, got %d lines:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, line := range lines[:3] {
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				ProgressToken string  `json:"progressToken"`
+				Progress      float64 `json:"progress"`
+				Total         float64 `json:"total"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("notification %d: %v", i, err)
+		}
+		if msg.Method != "notifications/progress" || msg.Params.ProgressToken != "merge-1" ||
+			msg.Params.Progress != float64(i+1) || msg.Params.Total != 3 {
+			t.Errorf("notification %d = %s", i, line)
+		}
+	}
+	var resp jsonrpcResponse
+	if err := json.Unmarshal([]byte(lines[3]), &resp); err != nil || resp.Error != nil {
+		t.Fatalf("unexpected final message: %s", lines[3])
+	}
+
+	// Without a progress token only the response is sent
+	if lines := call(""); len(lines) != 1 {
+		t.Errorf("expected only the response, got:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestExtractImagesTool(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 40, 30))
+	var jpg, pngData bytes.Buffer
+	if err := jpeg.Encode(&jpg, gray, nil); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.RegisterImageOptionsReader("photo", gofpdf.ImageOptions{ImageType: "JPG"}, &jpg)
+	pdf.RegisterImageOptionsReader("chart", gofpdf.ImageOptions{ImageType: "PNG"}, &pngData)
+	pdf.AddPage()
+	pdf.ImageOptions("photo", 10, 10, 80, 60, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.AddPage()
+	pdf.ImageOptions("chart", 10, 10, 80, 40, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.ImageOptions("photo", 10, 100, 80, 60, false, gofpdf.ImageOptions{}, 0, "")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "images.pdf")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	result, err := handleExtractImages(map[string]interface{}{"path": path, "outputDir": dir})
+	if err != nil {
+		t.Fatalf("extract_images: %v", err)
+	}
+	var summary struct {
+		Count  int
+		Images []struct {
+			Pages  []int
+			Width  int
+			Height int
+			Format string
+			File   string
+		}
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &summary); err != nil {
+		t.Fatalf("unmarshaling summary: %v", err)
+	}
+	if summary.Count != 2 || len(summary.Images) != 2 {
+		t.Fatalf("expected 2 images, got %s", result.Content[0].Text)
+	}
+	photo, chart := summary.Images[0], summary.Images[1]
+	if photo.Format != "jpeg" || photo.Width != 40 || photo.Height != 30 || len(photo.Pages) != 2 {
+		t.Errorf("unexpected JPEG image: %+v", photo)
+	}
+	if chart.Format != "png" || chart.Width != 20 || chart.Height != 10 {
+		t.Errorf("unexpected PNG image: %+v", chart)
+	}
+	for _, img := range summary.Images {
+		f, err := os.Open(img.File)
+		if err != nil {
+			t.Fatalf("opening %s: %v", img.File, err)
+		}
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || format != img.Format || cfg.Width != img.Width {
+			t.Errorf("%s: decoded %s %dx%d (%v), want %s %dx%d", img.File, format, cfg.Width, cfg.Height, err, img.Format, img.Width, img.Height)
+		}
+	}
+
+	// Without an output directory the images are returned inline
+	result, err = handleExtractImages(map[string]interface{}{"path": path, "pages": []interface{}{float64(2)}})
+	if err != nil {
+		t.Fatalf("extract_images: %v", err)
+	}
+	if len(result.Content) != 3 || result.Content[1].Type != "image" || result.Content[1].Data == "" {
+		t.Errorf("expected a summary and 2 inline images, got %+v", result.Content)
+	}
+}
+
+func TestSignAndVerifyTools(t *testing.T) {
+	dir := t.TempDir()
+	writeCert := func(name string) (certPath, keyPath string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("generating key: %v", err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatalf("creating certificate: %v", err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("marshaling key: %v", err)
+		}
+		certPath = filepath.Join(dir, name+".crt")
+		keyPath = filepath.Join(dir, name+".key")
+		os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+		os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+		return certPath, keyPath
+	}
+	certPath, keyPath := writeCert("Alice")
+	otherCertPath, _ := writeCert("Bob")
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.Cell(40, 10, "Contract")
+	path := filepath.Join(dir, "contract.pdf")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	signedPath := filepath.Join(dir, "signed.pdf")
+	_, err := handleSignPDF(map[string]interface{}{
+		"path":       path,
+		"certPath":   certPath,
+		"keyPath":    keyPath,
+		"reason":     "Approval",
+		"location":   "Madrid",
+		"outputPath": signedPath,
+	})
+	if err != nil {
+		t.Fatalf("sign_pdf: %v", err)
+	}
+
+	verify := func(args map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result, err := handleVerifySignature(args)
+		if err != nil {
+			t.Fatalf("verify_signature: %v", err)
+		}
+		var summary struct {
+			Signatures []map[string]interface{}
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &summary); err != nil {
+			t.Fatalf("unmarshaling result: %v", err)
+		}
+		if len(summary.Signatures) != 1 {
+			t.Fatalf("expected 1 signature, got %s", result.Content[0].Text)
+		}
+		return summary.Signatures[0]
+	}
+
+	sig := verify(map[string]interface{}{"path": signedPath})
+	if sig["valid"] != true || sig["signer"] != "Alice" || sig["reason"] != "Approval" || sig["location"] != "Madrid" || sig["signedAt"] == nil {
+		t.Errorf("unexpected signature: %v", sig)
+	}
+	if sig := verify(map[string]interface{}{"path": signedPath, "certPath": certPath}); sig["valid"] != true {
+		t.Errorf("signature not valid with the signer certificate: %v", sig)
+	}
+	if sig := verify(map[string]interface{}{"path": signedPath, "certPath": otherCertPath}); sig["valid"] != false {
+		t.Errorf("signature valid with another certificate: %v", sig)
+	}
+}
+
+func TestImagesToPDFTool(t *testing.T) {
+	dir := t.TempDir()
+	var wide, tall bytes.Buffer
+	if err := png.Encode(&wide, image.NewRGBA(image.Rect(0, 0, 400, 100))); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+	if err := jpeg.Encode(&tall, image.NewGray(image.Rect(0, 0, 100, 200)), nil); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+	widePath := filepath.Join(dir, "wide.png")
+	if err := os.WriteFile(widePath, wide.Bytes(), 0644); err != nil {
+		t.Fatalf("writing image: %v", err)
+	}
+
+	outputPath := filepath.Join(dir, "images.pdf")
+	_, err := handleImagesToPDF(map[string]interface{}{
+		"images": []interface{}{
+			widePath,
+			"data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(tall.Bytes()),
+		},
+		"pageSize":   "A4",
+		"margin":     float64(0),
+		"outputPath": outputPath,
+	})
+	if err != nil {
+		t.Fatalf("images_to_pdf: %v", err)
+	}
+
+	doc, err := reader.Open(outputPath)
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if doc.NumPages() != 2 {
+		t.Fatalf("expected 2 pages, got %d", doc.NumPages())
+	}
+	// A4 is 595.28 x 841.89 pt; the wide image gets a landscape page
+	want := []struct{ pageW, imgW, imgH float64 }{
+		{841.89, 841.89, 210.47},
+		{595.28, 420.94, 841.89},
+	}
+	for n, page := range doc.Pages() {
+		w := want[n-1]
+		if got := page.MediaBox.Width(); math.Abs(got-w.pageW) > 0.01 {
+			t.Errorf("page %d width = %g, want %g", n, got, w.pageW)
+		}
+		// gofpdf shares the resources of all pages, so the image drawn on
+		// the page is listed first and the other one last
+		images, err := page.Images()
+		if err != nil || len(images) == 0 {
+			t.Fatalf("page %d: images = %v, %v", n, images, err)
+		}
+		if img := images[0]; math.Abs(img.DrawnWidth-w.imgW) > 0.01 || math.Abs(img.DrawnHeight-w.imgH) > 0.01 {
+			t.Errorf("page %d image drawn at %gx%g, want %gx%g", n, img.DrawnWidth, img.DrawnHeight, w.imgW, w.imgH)
+		}
+	}
+
+	if _, err := handleImagesToPDF(map[string]interface{}{"images": []interface{}{"not an image"}}); err == nil {
+		t.Error("expected an error for invalid image data")
+	}
+}
+
+func TestServerHTTP(t *testing.T) {
+	s := NewServer()
+	RegisterDefaultTools(s)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	post := func(body, accept string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		return resp
+	}
+
+	// A single request gets a JSON response
+	resp := post(`{"jsonrpc":"2.0","id":1,"method":"ping"}`, "application/json")
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var rpc jsonrpcResponse
+	if err := json.Unmarshal(data, &rpc); err != nil || rpc.Error != nil || string(*rpc.ID) != "1" {
+		t.Errorf("ping: unexpected response %q (%v)", data, err)
+	}
+
+	// A batch gets a JSON array, without replies to notifications
+	resp = post(`[{"jsonrpc":"2.0","method":"initialized"},{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"tools/list"}]`, "application/json")
+	data, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var batch []jsonrpcResponse
+	if err := json.Unmarshal(data, &batch); err != nil || len(batch) != 2 {
+		t.Errorf("batch: unexpected response %q (%v)", data, err)
+	}
+
+	// Clients accepting event streams get each response as an event
+	resp = post(`{"jsonrpc":"2.0","id":4,"method":"tools/list"}`, "application/json, text/event-stream")
+	data, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("stream: Content-Type = %q", ct)
+	}
+	event, ok := strings.CutPrefix(string(data), "event: message\ndata: ")
+	if !ok || !strings.HasSuffix(event, "\n\n") {
+		t.Fatalf("stream: unexpected response %q", data)
+	}
+	if err := json.Unmarshal([]byte(event), &rpc); err != nil || rpc.Error != nil || !strings.Contains(event, "create_pdf") {
+		t.Errorf("stream: unexpected event %q (%v)", event, err)
+	}
+
+	// Notifications alone are accepted without a body
+	resp = post(`{"jsonrpc":"2.0","method":"initialized"}`, "application/json, text/event-stream")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification: status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	resp = post(`{not json`, "application/json")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid JSON: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":5,"method":"ping"}`))
+	req.Header.Set("Origin", "http://evil.example")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("foreign origin: status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestServerThumbnailResource(t *testing.T) {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddPage()
+	pdf.AddPage()
+	pdf.SetFillColor(255, 0, 0)
+	pdf.Rect(0, 0, 595.28, 841.89, "F")
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	s := NewServerWithIO(nil, nil)
+	RegisterDefaultResources(s)
+	resp := sendRequest(t, s, "resources/read", 9, map[string]interface{}{
+		"uri": "pdf://thumbnail?path=" + url.QueryEscape(path) + "&page=2&width=120",
+	})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v (%v)", resp.Error.Message, resp.Error.Data)
+	}
+
+	resultBytes, _ := json.Marshal(resp.Result)
+	var result struct {
+		Contents []ResourceContent `json:"contents"`
+	}
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		t.Fatalf("unmarshaling result: %v", err)
+	}
+	if len(result.Contents) != 1 || result.Contents[0].MIMEType != "image/png" {
+		t.Fatalf("unexpected contents: %+v", result.Contents)
+	}
+	data, err := base64.StdEncoding.DecodeString(result.Contents[0].Blob)
+	if err != nil {
+		t.Fatalf("decoding blob: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 120 || b.Dy() != 170 {
+		t.Errorf("thumbnail size = %dx%d, want 120x170", b.Dx(), b.Dy())
+	}
+	if r, g, _, _ := img.At(60, 85).RGBA(); r>>8 != 255 || g>>8 != 0 {
+		t.Errorf("thumbnail center = %v, want red", img.At(60, 85))
+	}
+
+	resp = sendRequest(t, s, "resources/read", 10, map[string]interface{}{
+		"uri": "pdf://thumbnail?path=" + url.QueryEscape(path) + "&page=3",
+	})
+	if resp.Error == nil {
+		t.Error("expected an error for a page out of range")
+	}
+}
+
+func TestSetMetadataTool(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.pdf")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Draft", false)
+	pdf.AddPage()
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	outputPath := filepath.Join(dir, "out.pdf")
+	_, err := handleSetMetadata(map[string]interface{}{
+		"path":       path,
+		"outputPath": outputPath,
+		"fields":     map[string]interface{}{"author": "Acme Corp", "keywords": "report, 2026"},
+	})
+	if err != nil {
+		t.Fatalf("set_metadata: %v", err)
+	}
+	doc, err := reader.Open(outputPath)
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	meta := doc.Metadata()
+	if meta["Author"] != "Acme Corp" || meta["Keywords"] != "report, 2026" || meta["Title"] != "Draft" {
+		t.Errorf("metadata = %v", meta)
+	}
+
+	_, err = handleSetMetadata(map[string]interface{}{
+		"path":       path,
+		"outputPath": outputPath,
+		"fields":     map[string]interface{}{"color": "red"},
+	})
+	if err == nil {
+		t.Error("expected an error for an unsupported field")
+	}
+}
@@ -2,16 +2,27 @@ package mcp
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"image"
+	_ "image/gif" // formats recognized by image.DecodeConfig
+	_ "image/jpeg"
+	"image/png"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	gofpdf "github.com/lvillar/gofpdf"
 	"github.com/lvillar/gofpdf/doctpl"
 	"github.com/lvillar/gofpdf/form"
 	"github.com/lvillar/gofpdf/pageops"
 	"github.com/lvillar/gofpdf/reader"
+	"github.com/lvillar/gofpdf/sign"
 )
 
 // RegisterDefaultTools adds all built-in PDF tools to the server.
@@ -26,12 +37,17 @@ func RegisterDefaultTools(s *Server) {
 	s.AddTool(flattenFormTool())
 	s.AddTool(rotatePDFTool())
 	s.AddTool(pdfInfoTool())
+	s.AddTool(extractImagesTool())
+	s.AddTool(signPDFTool())
+	s.AddTool(verifySignatureTool())
+	s.AddTool(imagesToPDFTool())
+	s.AddTool(setMetadataTool())
 }
 
 func createPDFTool() Tool {
 	return Tool{
 		Name:        "create_pdf",
-		Description: "Create a PDF document from a JSON template. The template supports headings, paragraphs, tables, images, lists, horizontal rules, and spacers. Returns the PDF as base64.",
+		Description: "Create a PDF document from a JSON template or from Markdown. The template supports headings, paragraphs, tables, images, lists, horizontal rules, and spacers. Markdown headings, paragraphs with bold, italic and code spans, lists, code blocks, block quotes, GFM tables and --- rules are converted to the same elements. Pass exactly one of template or markdown. Returns the PDF as base64.",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -39,31 +55,43 @@ func createPDFTool() Tool {
 					"type":        "object",
 					"description": "JSON document template with title, pageSize, pages, and elements",
 				},
+				"markdown": map[string]interface{}{
+					"type":        "string",
+					"description": "Markdown text to render instead of a template. The first level-1 heading becomes the document title.",
+				},
 				"outputPath": map[string]interface{}{
 					"type":        "string",
 					"description": "Optional file path to save the PDF. If omitted, returns base64.",
 				},
 			},
-			"required": []string{"template"},
 		},
 		Handler: handleCreatePDF,
 	}
 }
 
 func handleCreatePDF(args map[string]interface{}) (ToolResult, error) {
-	templateData, ok := args["template"]
-	if !ok {
-		return ToolResult{}, fmt.Errorf("missing 'template' argument")
-	}
-
-	jsonBytes, err := json.Marshal(templateData)
-	if err != nil {
-		return ToolResult{}, fmt.Errorf("encoding template: %w", err)
+	templateData, hasTemplate := args["template"]
+	markdown, hasMarkdown := args["markdown"].(string)
+	if hasTemplate && hasMarkdown {
+		return ToolResult{}, fmt.Errorf("pass either 'template' or 'markdown', not both")
 	}
 
 	var buf bytes.Buffer
-	if err := doctpl.Render(&buf, jsonBytes); err != nil {
-		return ToolResult{}, fmt.Errorf("rendering PDF: %w", err)
+	switch {
+	case hasMarkdown:
+		if err := doctpl.RenderDocument(&buf, doctpl.FromMarkdown(markdown)); err != nil {
+			return ToolResult{}, fmt.Errorf("rendering PDF: %w", err)
+		}
+	case hasTemplate:
+		jsonBytes, err := json.Marshal(templateData)
+		if err != nil {
+			return ToolResult{}, fmt.Errorf("encoding template: %w", err)
+		}
+		if err := doctpl.Render(&buf, jsonBytes); err != nil {
+			return ToolResult{}, fmt.Errorf("rendering PDF: %w", err)
+		}
+	default:
+		return ToolResult{}, fmt.Errorf("missing 'template' or 'markdown' argument")
 	}
 
 	// Save to file if outputPath specified
@@ -214,11 +242,11 @@ func mergePDFsTool() Tool {
 			},
 			"required": []string{"inputPaths", "outputPath"},
 		},
-		Handler: handleMergePDFs,
+		ProgressHandler: handleMergePDFs,
 	}
 }
 
-func handleMergePDFs(args map[string]interface{}) (ToolResult, error) {
+func handleMergePDFs(args map[string]interface{}, progress ProgressFunc) (ToolResult, error) {
 	pathsRaw, ok := args["inputPaths"].([]interface{})
 	if !ok {
 		return ToolResult{}, fmt.Errorf("missing 'inputPaths' argument")
@@ -233,7 +261,11 @@ func handleMergePDFs(args map[string]interface{}) (ToolResult, error) {
 		paths[i], _ = p.(string)
 	}
 
-	if err := pageops.MergeFiles(outputPath, paths...); err != nil {
+	// Progress is reported once per input file
+	opts := pageops.MergeOptions{Progress: func(done, total int) {
+		progress(float64(done), float64(total))
+	}}
+	if err := pageops.MergeFilesWithOptions(outputPath, opts, paths...); err != nil {
 		return ToolResult{}, fmt.Errorf("merging: %w", err)
 	}
 
@@ -279,11 +311,11 @@ func addWatermarkTool() Tool {
 			},
 			"required": []string{"inputPath", "outputPath", "text"},
 		},
-		Handler: handleAddWatermark,
+		ProgressHandler: handleAddWatermark,
 	}
 }
 
-func handleAddWatermark(args map[string]interface{}) (ToolResult, error) {
+func handleAddWatermark(args map[string]interface{}, progress ProgressFunc) (ToolResult, error) {
 	inputPath, _ := args["inputPath"].(string)
 	outputPath, _ := args["outputPath"].(string)
 	text, _ := args["text"].(string)
@@ -302,6 +334,9 @@ func handleAddWatermark(args map[string]interface{}) (ToolResult, error) {
 	if angle, ok := args["angle"].(float64); ok {
 		wm.Angle = angle
 	}
+	wm.Progress = func(done, total int) {
+		progress(float64(done), float64(total))
+	}
 
 	if err := pageops.AddTextWatermarkToFile(inputPath, outputPath, wm); err != nil {
 		return ToolResult{}, err
@@ -613,6 +648,565 @@ func handlePDFInfo(args map[string]interface{}) (ToolResult, error) {
 	}, nil
 }
 
+func extractImagesTool() Tool {
+	return Tool{
+		Name:        "extract_images",
+		Description: "Extract the images of a PDF. JPEG images are saved as they are, other images as PNG. Returns a JSON summary with each image's page, size, and format, and the images as base64 if no output directory is given.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the PDF file",
+				},
+				"pages": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "number"},
+					"description": "Page numbers to extract images from (1-based). Omit for all pages.",
+				},
+				"outputDir": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional directory to save the images to, as page<N>_<name>.jpg or .png. If omitted, returns them as base64.",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Handler: handleExtractImages,
+	}
+}
+
+func handleExtractImages(args map[string]interface{}) (ToolResult, error) {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ToolResult{}, fmt.Errorf("missing 'path' argument")
+	}
+	outputDir, _ := args["outputDir"].(string)
+
+	doc, err := reader.Open(path)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("opening PDF: %w", err)
+	}
+
+	pageSet := make(map[int]bool)
+	if pagesArg, ok := args["pages"].([]interface{}); ok {
+		for _, p := range pagesArg {
+			if num, ok := p.(float64); ok {
+				pageSet[int(num)] = true
+			}
+		}
+	}
+
+	// Images shared by several pages are extracted once
+	extracted := make(map[reader.Reference]map[string]interface{})
+	var blocks []ContentBlock
+	images := make([]map[string]interface{}, 0)
+	for pageNum, page := range doc.Pages() {
+		if len(pageSet) > 0 && !pageSet[pageNum] {
+			continue
+		}
+		pageImages, err := page.Images()
+		if err != nil {
+			return ToolResult{}, fmt.Errorf("reading images of page %d: %w", pageNum, err)
+		}
+
+		for _, img := range pageImages {
+			if info, ok := extracted[img.Ref]; ok {
+				info["pages"] = append(info["pages"].([]int), pageNum)
+				continue
+			}
+			info := map[string]interface{}{
+				"pages":            []int{pageNum},
+				"name":             img.Name,
+				"width":            img.Width,
+				"height":           img.Height,
+				"colorSpace":       img.ColorSpace,
+				"bitsPerComponent": img.BitsPerComponent,
+			}
+			extracted[img.Ref] = info
+			images = append(images, info)
+
+			data, format, mimeType, err := encodeImage(img)
+			if err != nil {
+				info["error"] = err.Error()
+				continue
+			}
+			info["format"] = format
+			info["bytes"] = len(data)
+
+			if outputDir == "" {
+				blocks = append(blocks, ContentBlock{
+					Type:     "image",
+					MIMEType: mimeType,
+					Data:     base64.StdEncoding.EncodeToString(data),
+				})
+				continue
+			}
+			ext := format
+			if ext == "jpeg" {
+				ext = "jpg"
+			}
+			file := filepath.Join(outputDir, fmt.Sprintf("page%d_%s.%s", pageNum, img.Name, ext))
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				return ToolResult{}, fmt.Errorf("writing file: %w", err)
+			}
+			info["file"] = file
+		}
+	}
+
+	summary := map[string]interface{}{
+		"count":  len(images),
+		"images": images,
+	}
+	jsonBytes, _ := json.MarshalIndent(summary, "", "  ")
+	return ToolResult{
+		Content: append([]ContentBlock{{Type: "text", Text: string(jsonBytes)}}, blocks...),
+	}, nil
+}
+
+// encodeImage returns the data of an image as a file: JPEG images as they
+// are stored, others decoded and encoded as PNG.
+func encodeImage(img reader.ImageInfo) (data []byte, format, mimeType string, err error) {
+	if _, single := img.Stream.Dict["Filter"].(reader.Name); single && img.Filter == "DCTDecode" {
+		return img.Stream.Data, "jpeg", "image/jpeg", nil
+	}
+	decoded, err := img.Decode()
+	if err != nil {
+		return nil, "", "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "png", "image/png", nil
+}
+
+func signPDFTool() Tool {
+	return Tool{
+		Name:        "sign_pdf",
+		Description: "Digitally sign a PDF with a certificate and private key in PEM format. The signature is added as an incremental update, keeping earlier signatures valid.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the PDF to sign",
+				},
+				"certPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the signer certificate in PEM format, optionally followed by its chain",
+				},
+				"keyPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the unencrypted private key in PEM format (PKCS#8, PKCS#1 or SEC 1)",
+				},
+				"reason": map[string]interface{}{
+					"type":        "string",
+					"description": "Reason for signing",
+				},
+				"location": map[string]interface{}{
+					"type":        "string",
+					"description": "Signing location",
+				},
+				"outputPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Path for the signed PDF",
+				},
+			},
+			"required": []string{"path", "certPath", "keyPath", "outputPath"},
+		},
+		Handler: handleSignPDF,
+	}
+}
+
+func handleSignPDF(args map[string]interface{}) (ToolResult, error) {
+	path, _ := args["path"].(string)
+	certPath, _ := args["certPath"].(string)
+	keyPath, _ := args["keyPath"].(string)
+	outputPath, _ := args["outputPath"].(string)
+	reason, _ := args["reason"].(string)
+	location, _ := args["location"].(string)
+
+	if path == "" || certPath == "" || keyPath == "" || outputPath == "" {
+		return ToolResult{}, fmt.Errorf("path, certPath, keyPath, and outputPath are required")
+	}
+
+	certs, err := loadCertificates(certPath)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	key, err := loadPrivateKey(keyPath)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	input, err := os.Open(path)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("opening PDF: %w", err)
+	}
+	defer input.Close()
+
+	var buf bytes.Buffer
+	err = sign.Sign(input, &buf, sign.Options{
+		Certificate: certs[0],
+		PrivateKey:  key,
+		CertChain:   certs[1:],
+		Reason:      reason,
+		Location:    location,
+	})
+	if err != nil {
+		return ToolResult{}, err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return ToolResult{}, fmt.Errorf("writing file: %w", err)
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{
+			Type: "text",
+			Text: fmt.Sprintf("PDF signed by %s: %s -> %s", certs[0].Subject.CommonName, path, outputPath),
+		}},
+	}, nil
+}
+
+func verifySignatureTool() Tool {
+	return Tool{
+		Name:        "verify_signature",
+		Description: "Verify the digital signatures of a PDF. Returns each signature's signer, reason, location, signing time, and validity as JSON.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the signed PDF",
+				},
+				"certPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional path to the expected signer certificate in PEM format. If omitted, signatures are checked against the certificates they embed, which proves integrity but not identity.",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Handler: handleVerifySignature,
+	}
+}
+
+func handleVerifySignature(args map[string]interface{}) (ToolResult, error) {
+	path, ok := args["path"].(string)
+	if !ok {
+		return ToolResult{}, fmt.Errorf("missing 'path' argument")
+	}
+	certPath, _ := args["certPath"].(string)
+
+	input, err := os.Open(path)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("opening PDF: %w", err)
+	}
+	defer input.Close()
+
+	var sigs []sign.SignatureInfo
+	if certPath != "" {
+		certs, err := loadCertificates(certPath)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		sigs, err = sign.VerifyWithCertificate(input, certs[0].PublicKey)
+		if err != nil {
+			return ToolResult{}, err
+		}
+	} else if sigs, err = sign.Verify(input); err != nil {
+		return ToolResult{}, err
+	}
+
+	signatures := make([]map[string]interface{}, 0, len(sigs))
+	for _, sig := range sigs {
+		info := map[string]interface{}{
+			"signer":   sig.SignerName,
+			"reason":   sig.Reason,
+			"location": sig.Location,
+			"valid":    sig.Valid,
+		}
+		if !sig.SignedAt.IsZero() {
+			info["signedAt"] = sig.SignedAt.Format(time.RFC3339)
+		}
+		if !sig.Timestamp.IsZero() {
+			info["timestamp"] = sig.Timestamp.Format(time.RFC3339)
+		}
+		if len(sig.Errors) > 0 {
+			errs := make([]string, len(sig.Errors))
+			for i, e := range sig.Errors {
+				errs[i] = e.Error()
+			}
+			info["errors"] = errs
+		}
+		signatures = append(signatures, info)
+	}
+
+	result := map[string]interface{}{
+		"count":      len(signatures),
+		"signatures": signatures,
+	}
+	jsonBytes, _ := json.MarshalIndent(result, "", "  ")
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: string(jsonBytes)}},
+	}, nil
+}
+
+func imagesToPDFTool() Tool {
+	return Tool{
+		Name:        "images_to_pdf",
+		Description: "Create a PDF from JPEG, PNG, or GIF images, one per page, each scaled to fit the page with its aspect ratio preserved and centered. Pages are turned to landscape for landscape images.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"images": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Images in page order, each a file path or base64 data (optionally as a data: URI)",
+				},
+				"pageSize": map[string]interface{}{
+					"type":        "string",
+					"description": "Page size: A4 (default), Letter, Legal, A3, A5, ...",
+				},
+				"margin": map[string]interface{}{
+					"type":        "number",
+					"description": "Page margin around each image in millimeters (default 10)",
+				},
+				"outputPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional file path to save the PDF. If omitted, returns base64-encoded PDF.",
+				},
+			},
+			"required": []string{"images"},
+		},
+		Handler: handleImagesToPDF,
+	}
+}
+
+func handleImagesToPDF(args map[string]interface{}) (ToolResult, error) {
+	imagesRaw, _ := args["images"].([]interface{})
+	if len(imagesRaw) == 0 {
+		return ToolResult{}, fmt.Errorf("at least one image is required")
+	}
+	pageSize, _ := args["pageSize"].(string)
+	if pageSize == "" {
+		pageSize = "A4"
+	}
+	margin := 10.0
+	if m, ok := args["margin"].(float64); ok {
+		margin = m
+	}
+
+	pdf := gofpdf.New("P", "mm", pageSize, "")
+	size := pdf.GetPageSizeStr(pageSize)
+	if size.Wd <= 2*margin || size.Ht <= 2*margin {
+		return ToolResult{}, fmt.Errorf("margin %g mm leaves no room on %s pages", margin, pageSize)
+	}
+
+	for i, raw := range imagesRaw {
+		src, _ := raw.(string)
+		data, err := loadImageData(src)
+		if err != nil {
+			return ToolResult{}, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return ToolResult{}, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		opt := gofpdf.ImageOptions{ImageType: format}
+		name := fmt.Sprintf("image%d", i+1)
+		pdf.RegisterImageOptionsReader(name, opt, bytes.NewReader(data))
+
+		pageW, pageH := size.Wd, size.Ht
+		if (cfg.Width > cfg.Height) != (pageW > pageH) {
+			pageW, pageH = pageH, pageW
+		}
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pageW, Ht: pageH})
+
+		scale := min((pageW-2*margin)/float64(cfg.Width), (pageH-2*margin)/float64(cfg.Height))
+		w, h := float64(cfg.Width)*scale, float64(cfg.Height)*scale
+		pdf.ImageOptions(name, (pageW-w)/2, (pageH-h)/2, w, h, false, opt, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return ToolResult{}, fmt.Errorf("rendering PDF: %w", err)
+	}
+
+	if outputPath, ok := args["outputPath"].(string); ok && outputPath != "" {
+		if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+			return ToolResult{}, fmt.Errorf("writing file: %w", err)
+		}
+		return ToolResult{
+			Content: []ContentBlock{{
+				Type: "text",
+				Text: fmt.Sprintf("PDF created from %d images: %s (%d bytes)", len(imagesRaw), outputPath, buf.Len()),
+			}},
+		}, nil
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return ToolResult{
+		Content: []ContentBlock{{
+			Type: "text",
+			Text: fmt.Sprintf("PDF created from %d images (%d bytes). Base64 data:\n%s", len(imagesRaw), buf.Len(), encoded),
+		}},
+	}, nil
+}
+
+func setMetadataTool() Tool {
+	return Tool{
+		Name:        "set_metadata",
+		Description: "Set the title, author, subject, or keywords of an existing PDF. Fields that are not given are kept; an empty string removes a field.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to the input PDF",
+				},
+				"outputPath": map[string]interface{}{
+					"type":        "string",
+					"description": "Path for the output PDF",
+				},
+				"fields": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":    map[string]interface{}{"type": "string"},
+						"author":   map[string]interface{}{"type": "string"},
+						"subject":  map[string]interface{}{"type": "string"},
+						"keywords": map[string]interface{}{"type": "string"},
+					},
+					"description": "Metadata fields to set",
+				},
+			},
+			"required": []string{"path", "outputPath", "fields"},
+		},
+		Handler: handleSetMetadata,
+	}
+}
+
+func handleSetMetadata(args map[string]interface{}) (ToolResult, error) {
+	path, _ := args["path"].(string)
+	outputPath, _ := args["outputPath"].(string)
+	fieldsRaw, _ := args["fields"].(map[string]interface{})
+
+	if path == "" || outputPath == "" || fieldsRaw == nil {
+		return ToolResult{}, fmt.Errorf("path, outputPath, and fields are required")
+	}
+
+	keys := map[string]string{
+		"title":    "Title",
+		"author":   "Author",
+		"subject":  "Subject",
+		"keywords": "Keywords",
+	}
+	fields := make(map[string]string)
+	for name, v := range fieldsRaw {
+		key, ok := keys[name]
+		if !ok {
+			return ToolResult{}, fmt.Errorf("unsupported field %q (must be title, author, subject, or keywords)", name)
+		}
+		value, ok := v.(string)
+		if !ok {
+			return ToolResult{}, fmt.Errorf("field %q must be a string", name)
+		}
+		fields[key] = value
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.SetMetadata(&buf, path, fields); err != nil {
+		return ToolResult{}, err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return ToolResult{}, fmt.Errorf("writing file: %w", err)
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{
+			Type: "text",
+			Text: fmt.Sprintf("Metadata updated (%d fields): %s -> %s", len(fields), path, outputPath),
+		}},
+	}, nil
+}
+
+// loadImageData returns the contents of an image given as a file path,
+// base64 data or a base64 data: URI.
+func loadImageData(src string) ([]byte, error) {
+	if src == "" {
+		return nil, fmt.Errorf("empty image")
+	}
+	if strings.HasPrefix(src, "data:") {
+		if i := strings.Index(src, ";base64,"); i >= 0 {
+			src = src[i+len(";base64,"):]
+		}
+		return base64.StdEncoding.DecodeString(src)
+	}
+	if data, err := os.ReadFile(src); err == nil {
+		return data, nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading %s: %w", src, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return nil, fmt.Errorf("%.40q is neither a file nor base64 data", src)
+	}
+	return data, nil
+}
+
+// loadCertificates reads the PEM certificates of a file, the signer
+// certificate first.
+func loadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %w", err)
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate %s: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found in %s", path)
+	}
+	return certs, nil
+}
+
+// loadPrivateKey reads the first PEM private key of a file.
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading private key: %w", err)
+	}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		var key interface{}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing private key %s: %w", path, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T in %s", key, path)
+		}
+		return signer, nil
+	}
+	return nil, fmt.Errorf("no PEM private key found in %s", path)
+}
+
 // flattenFormFields recursively collects all form fields.
 func flattenFormFields(fields []*reader.FormField) []*reader.FormField {
 	var result []*reader.FormField
@@ -9,6 +9,25 @@ type documentConfig struct {
 	size        string
 	fontDir     string
 	pageSize    SizeType
+	protection  *protectionConfig
+	metadata    Metadata
+	margins     *marginConfig
+	pageBreak   *pageBreakConfig
+}
+
+type marginConfig struct {
+	left, top, right float64
+}
+
+type pageBreakConfig struct {
+	enabled      bool
+	bottomMargin float64
+}
+
+type protectionConfig struct {
+	permFlags int
+	userPass  string
+	ownerPass string
 }
 
 // WithOrientation sets the default page orientation.
@@ -49,6 +68,56 @@ func WithFontDir(dir string) Option {
 	}
 }
 
+// WithProtection encrypts the document as SetProtection does. permFlags is
+// a combination of CnProtectPrint, CnProtectModify, CnProtectCopy and
+// CnProtectAnnotForms; any other bit sets an error on the new document.
+func WithProtection(permFlags int, userPass, ownerPass string) Option {
+	return func(c *documentConfig) {
+		c.protection = &protectionConfig{
+			permFlags: permFlags,
+			userPass:  userPass,
+			ownerPass: ownerPass,
+		}
+	}
+}
+
+// WithMargins sets the left, top and right page margins in the configured
+// unit, as SetMargins does. A negative right margin is set to the left
+// one. By default all three are 1 cm.
+func WithMargins(left, top, right float64) Option {
+	return func(c *documentConfig) {
+		c.margins = &marginConfig{left: left, top: top, right: right}
+	}
+}
+
+// WithAutoPageBreak turns automatic page breaking on or off, as
+// SetAutoPageBreak does. bottomMargin, in the configured unit, is the
+// distance from the bottom of the page that triggers a break. By default
+// breaking is on with a 2 cm margin.
+func WithAutoPageBreak(enabled bool, bottomMargin float64) Option {
+	return func(c *documentConfig) {
+		c.pageBreak = &pageBreakConfig{enabled: enabled, bottomMargin: bottomMargin}
+	}
+}
+
+// Metadata holds the document information set by WithMetadata. Empty
+// fields are left unset.
+type Metadata struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string // space-delimited, for example "invoice August"
+	Creator  string
+}
+
+// WithMetadata sets the document information dictionary entries. The
+// strings are UTF-8 encoded, so non-ASCII text is written correctly.
+func WithMetadata(meta Metadata) Option {
+	return func(c *documentConfig) {
+		c.metadata = meta
+	}
+}
+
 // NewDocument creates a new PDF document using functional options.
 // If no options are specified, defaults to portrait A4 with millimeter units.
 //
@@ -58,6 +127,10 @@ func WithFontDir(dir string) Option {
 //	    gofpdf.WithPageSize(gofpdf.PageSizeA4),
 //	    gofpdf.WithOrientation(gofpdf.OrientationPortrait),
 //	    gofpdf.WithUnit(gofpdf.UnitMillimeter),
+//	    gofpdf.WithMargins(15, 20, 15),
+//	    gofpdf.WithAutoPageBreak(true, 20),
+//	    gofpdf.WithMetadata(gofpdf.Metadata{Title: "Report", Author: "Jane Doe"}),
+//	    gofpdf.WithProtection(gofpdf.CnProtectPrint, "user", "owner"),
 //	)
 func NewDocument(opts ...Option) *Fpdf {
 	cfg := &documentConfig{
@@ -69,5 +142,34 @@ func NewDocument(opts ...Option) *Fpdf {
 	for _, opt := range opts {
 		opt(cfg)
 	}
-	return fpdfNew(cfg.orientation, cfg.unit, cfg.size, cfg.fontDir, cfg.pageSize)
+	f := fpdfNew(cfg.orientation, cfg.unit, cfg.size, cfg.fontDir, cfg.pageSize)
+	if m := cfg.margins; m != nil {
+		f.SetMargins(m.left, m.top, m.right)
+	}
+	if b := cfg.pageBreak; b != nil {
+		f.SetAutoPageBreak(b.enabled, b.bottomMargin)
+	}
+	for _, entry := range []struct {
+		value string
+		set   func(string, bool)
+	}{
+		{cfg.metadata.Title, f.SetTitle},
+		{cfg.metadata.Author, f.SetAuthor},
+		{cfg.metadata.Subject, f.SetSubject},
+		{cfg.metadata.Keywords, f.SetKeywords},
+		{cfg.metadata.Creator, f.SetCreator},
+	} {
+		if entry.value != "" {
+			entry.set(entry.value, true)
+		}
+	}
+	if p := cfg.protection; p != nil {
+		const validFlags = CnProtectPrint | CnProtectModify | CnProtectCopy | CnProtectAnnotForms
+		if p.permFlags&^validFlags != 0 {
+			f.SetErrorf("invalid protection flags %d: only CnProtectPrint, CnProtectModify, CnProtectCopy and CnProtectAnnotForms are allowed", p.permFlags)
+		} else {
+			f.SetProtection(byte(p.permFlags), p.userPass, p.ownerPass)
+		}
+	}
+	return f
 }
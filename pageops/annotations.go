@@ -0,0 +1,299 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// annotCopier copies objects of a source document into a merged one. Every
+// indirect object is copied once and given a new number; references to
+// source pages become references to the pages they were merged into.
+type annotCopier struct {
+	src     *reader.Document
+	pages   map[int]reader.Reference // source page object number to merged page
+	copied  map[reader.Reference]reader.Reference
+	direct  map[string]reader.Reference        // direct objects made indirect, by content
+	objects map[reader.Reference]reader.Object // objects of the update
+	next    *int                               // next free object number
+}
+
+// indirect copies obj like copy and returns a reference to the copy. A
+// direct object becomes a new indirect object, shared by all direct objects
+// with the same content: writers such as gofpdf's form builder repeat each
+// field dictionary in the page's /Annots and the form's /Fields, which must
+// refer to the same object in the merged form.
+func (c *annotCopier) indirect(obj reader.Object) (reader.Reference, error) {
+	if ref, ok := obj.(reader.Reference); ok {
+		copied, err := c.copy(ref)
+		if err != nil {
+			return reader.Reference{}, err
+		}
+		if copied, ok := copied.(reader.Reference); ok {
+			return copied, nil
+		}
+		return reader.Reference{}, fmt.Errorf("object %d cannot be copied", ref.Number)
+	}
+
+	var buf bytes.Buffer
+	reader.WriteObject(&buf, obj)
+	if ref, ok := c.direct[buf.String()]; ok {
+		return ref, nil
+	}
+	copied, err := c.copy(obj)
+	if err != nil {
+		return reader.Reference{}, err
+	}
+	ref := reader.Reference{Number: *c.next}
+	*c.next++
+	c.objects[ref] = copied
+	c.direct[buf.String()] = ref
+	return ref, nil
+}
+
+// copy returns obj with every indirect object it refers to copied.
+func (c *annotCopier) copy(obj reader.Object) (reader.Object, error) {
+	switch v := obj.(type) {
+	case reader.Reference:
+		if page, ok := c.pages[v.Number]; ok {
+			return page, nil
+		}
+		if ref, ok := c.copied[v]; ok {
+			return ref, nil
+		}
+		resolved, err := c.src.ResolveReference(v)
+		if err != nil {
+			return nil, err
+		}
+		// Never pull in the source's page tree or catalog
+		if d, ok := resolved.(reader.Dict); ok {
+			switch d.GetName("Type") {
+			case "Catalog", "Pages", "Page":
+				return reader.Null{}, nil
+			}
+		}
+		ref := reader.Reference{Number: *c.next}
+		*c.next++
+		c.copied[v] = ref
+		value, err := c.copy(resolved)
+		if err != nil {
+			return nil, err
+		}
+		c.objects[ref] = value
+		return ref, nil
+	case reader.Array:
+		a := make(reader.Array, len(v))
+		for i, item := range v {
+			copied, err := c.copy(item)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = copied
+		}
+		return a, nil
+	case reader.Dict:
+		d := make(reader.Dict, len(v))
+		for k, item := range v {
+			copied, err := c.copy(item)
+			if err != nil {
+				return nil, err
+			}
+			d[k] = copied
+		}
+		return d, nil
+	case reader.Stream:
+		dict, err := c.copy(v.Dict)
+		if err != nil {
+			return nil, err
+		}
+		return reader.Stream{Dict: dict.(reader.Dict), Data: v.Data}, nil
+	}
+	return obj, nil
+}
+
+// resolveDict returns obj as a dictionary of the source, resolving a
+// reference.
+func (c *annotCopier) resolveDict(obj reader.Object) reader.Dict {
+	d, _ := resolveIn(c.src, obj).(reader.Dict)
+	return d
+}
+
+// resolveArray returns obj as an array of the source, resolving a
+// reference.
+func (c *annotCopier) resolveArray(obj reader.Object) reader.Array {
+	a, _ := resolveIn(c.src, obj).(reader.Array)
+	return a
+}
+
+// resolveIn resolves obj in doc if it is a reference, returning nil if it
+// cannot be resolved.
+func resolveIn(doc *reader.Document, obj reader.Object) reader.Object {
+	if ref, ok := obj.(reader.Reference); ok {
+		resolved, err := doc.ResolveReference(ref)
+		if err != nil {
+			return nil
+		}
+		return resolved
+	}
+	return obj
+}
+
+// writeMergedAnnotations writes the merged document data followed by an
+// incremental update that adds the link and widget annotations of the
+// source documents to the pages they were merged into, and an /AcroForm
+// combining the fields of all sources. Top-level fields of a later source
+// whose name is already taken are renamed so that they stay independent.
+// The data is written unchanged if the sources have neither.
+func writeMergedAnnotations(w io.Writer, data []byte, inputPaths []string) error {
+	merged, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: merge: reading merged document: %w", err)
+	}
+	trailer := merged.Trailer()
+	size, _ := trailer.GetInt("Size")
+	next := int(size)
+	objects := make(map[reader.Reference]reader.Object)
+
+	pageAnnots := make(map[int]reader.Array) // by merged page number
+	var fields reader.Array
+	acroForm := reader.Dict{}
+	taken := make(map[string]bool)
+	offset := 0
+
+	for n, inputPath := range inputPaths {
+		src, err := reader.Open(inputPath)
+		if err != nil {
+			return fmt.Errorf("pageops: merging %s: %w", inputPath, err)
+		}
+		c := &annotCopier{
+			src:     src,
+			pages:   make(map[int]reader.Reference),
+			copied:  make(map[reader.Reference]reader.Reference),
+			direct:  make(map[string]reader.Reference),
+			objects: objects,
+			next:    &next,
+		}
+		for i, page := range src.Pages() {
+			out, err := merged.Page(offset + i)
+			if err != nil {
+				return fmt.Errorf("pageops: merge: %w", err)
+			}
+			c.pages[page.ObjNum] = reader.Reference{Number: out.ObjNum}
+		}
+
+		for i, page := range src.Pages() {
+			pageDict := c.resolveDict(reader.Reference{Number: page.ObjNum})
+			for _, item := range c.resolveArray(pageDict["Annots"]) {
+				var copied reader.Object
+				var err error
+				switch c.resolveDict(item).GetName("Subtype") {
+				case "Link":
+					copied, err = c.copy(item)
+				case "Widget":
+					copied, err = c.indirect(item)
+				default:
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("pageops: merging annotations of %s: %w", inputPath, err)
+				}
+				pageAnnots[offset+i] = append(pageAnnots[offset+i], copied)
+			}
+		}
+
+		catalog, err := src.Catalog()
+		if err != nil {
+			return fmt.Errorf("pageops: merging %s: %w", inputPath, err)
+		}
+		form := c.resolveDict(catalog["AcroForm"])
+		for _, item := range c.resolveArray(form["Fields"]) {
+			ref, err := c.indirect(item)
+			if err != nil {
+				return fmt.Errorf("pageops: merging fields of %s: %w", inputPath, err)
+			}
+			field, _ := objects[ref].(reader.Dict)
+			if name := field.GetString("T"); name != "" {
+				if taken[name] {
+					field["T"] = reader.String{Value: []byte(name + "_" + strconv.Itoa(n+1))}
+				}
+				taken[field.GetString("T")] = true
+			}
+			fields = append(fields, ref)
+		}
+
+		// Form-wide defaults come from the first form; resource fonts are
+		// collected from all of them.
+		for _, key := range []reader.Name{"DA", "Q", "NeedAppearances"} {
+			if _, ok := acroForm[key]; !ok && form[key] != nil {
+				acroForm[key] = form[key]
+			}
+		}
+		if fonts := c.resolveDict(c.resolveDict(form["DR"])["Font"]); len(fonts) > 0 {
+			dr, _ := acroForm["DR"].(reader.Dict)
+			if dr == nil {
+				dr = reader.Dict{"Font": reader.Dict{}}
+				acroForm["DR"] = dr
+			}
+			all := dr["Font"].(reader.Dict)
+			for name, font := range fonts {
+				if _, ok := all[name]; ok {
+					continue
+				}
+				copied, err := c.copy(font)
+				if err != nil {
+					return fmt.Errorf("pageops: merging fields of %s: %w", inputPath, err)
+				}
+				all[name] = copied
+			}
+		}
+
+		offset += src.NumPages()
+	}
+
+	if len(pageAnnots) == 0 && len(fields) == 0 {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("pageops: writing output: %w", err)
+		}
+		return nil
+	}
+
+	for num, annots := range pageAnnots {
+		page, err := merged.Page(num)
+		if err != nil {
+			return fmt.Errorf("pageops: merge: %w", err)
+		}
+		ref := reader.Reference{Number: page.ObjNum}
+		obj, err := merged.ResolveReference(ref)
+		if err != nil {
+			return fmt.Errorf("pageops: merge: %w", err)
+		}
+		dict := maps.Clone(obj.(reader.Dict))
+		existing, _ := resolveIn(merged, dict["Annots"]).(reader.Array)
+		dict["Annots"] = append(existing, annots...)
+		objects[ref] = dict
+	}
+
+	if len(fields) > 0 {
+		rootRef, ok := trailer["Root"].(reader.Reference)
+		if !ok {
+			return fmt.Errorf("pageops: merge: document catalog is not an indirect object")
+		}
+		catalog, err := merged.Catalog()
+		if err != nil {
+			return fmt.Errorf("pageops: merge: %w", err)
+		}
+		acroForm["Fields"] = fields
+		catalog = maps.Clone(catalog)
+		catalog["AcroForm"] = acroForm
+		objects[rootRef] = catalog
+	}
+
+	if err := merged.AppendUpdate(w, objects); err != nil {
+		return fmt.Errorf("pageops: merge: %w", err)
+	}
+	return nil
+}
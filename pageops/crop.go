@@ -0,0 +1,76 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// Rectangle is a box on a page in PDF coordinates: points from the
+// bottom-left corner of the unrotated page.
+type Rectangle = reader.Rectangle
+
+// CropPages sets the visible area of the given pages (all pages if pages is
+// nil) to box, for example to trim the margins or a scanner border off
+// scanned pages, and writes the result to w. The box must lie within each
+// page's media box.
+//
+// The pages get box as their /CropBox in an incremental update, so viewers
+// and printers show only that area while the page content is kept intact
+// and the crop can be undone. Encrypted documents are not supported.
+func CropPages(w io.Writer, inputPath string, box Rectangle, pages []int) error {
+	if box.Width() <= 0 || box.Height() <= 0 {
+		return fmt.Errorf("pageops: crop box [%g %g %g %g] is empty", box.LLX, box.LLY, box.URX, box.URY)
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	if _, ok := doc.Trailer()["Encrypt"]; ok {
+		return fmt.Errorf("pageops: crop: encrypted documents are not supported")
+	}
+	if err := checkPages(pages, doc.NumPages()); err != nil {
+		return err
+	}
+
+	cropPages := buildPageSet(pages, doc.NumPages())
+	cropBox := reader.Array{reader.Real(box.LLX), reader.Real(box.LLY), reader.Real(box.URX), reader.Real(box.URY)}
+	objects := make(map[reader.Reference]reader.Object)
+	for n, page := range doc.Pages() {
+		if !cropPages[n] {
+			continue
+		}
+		mb := page.MediaBox
+		if box.LLX < mb.LLX || box.LLY < mb.LLY || box.URX > mb.URX || box.URY > mb.URY {
+			return fmt.Errorf("pageops: crop box [%g %g %g %g] exceeds the media box [%g %g %g %g] of page %d",
+				box.LLX, box.LLY, box.URX, box.URY, mb.LLX, mb.LLY, mb.URX, mb.URY, n)
+		}
+
+		ref := reader.Reference{Number: page.ObjNum}
+		obj, err := doc.ResolveReference(ref)
+		if err != nil {
+			return fmt.Errorf("pageops: crop: page %d: %w", n, err)
+		}
+		dict, ok := obj.(reader.Dict)
+		if !ok {
+			return fmt.Errorf("pageops: crop: page %d is not a dictionary", n)
+		}
+		dict = maps.Clone(dict)
+		dict["CropBox"] = cropBox
+		objects[ref] = dict
+	}
+
+	if err := doc.AppendUpdate(w, objects); err != nil {
+		return fmt.Errorf("pageops: crop: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package pageops
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sort"
+
+	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// Encrypt writes a password-protected copy of a PDF to w, encrypted with
+// RC4 and a 128-bit key. userPass is needed to open the document (it may be
+// empty to let anyone open it) and ownerPass gives full access regardless
+// of perms. An empty ownerPass is replaced with a random value, as
+// gofpdf.Fpdf.SetProtection does.
+//
+// perms combines the gofpdf.CnProtectPrint, CnProtectModify, CnProtectCopy
+// and CnProtectAnnotForms flags to allow the corresponding actions to users
+// who open the document with userPass. Like SetProtection's flags, they are
+// advisory.
+//
+// The document is rewritten like Optimize does, without the objects that
+// cannot be reached from its catalog or info dictionary. Documents that are
+// already encrypted are not supported.
+func Encrypt(w io.Writer, inputPath, userPass, ownerPass string, perms int) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	trailer := doc.Trailer()
+	if _, ok := trailer["Encrypt"]; ok {
+		return fmt.Errorf("pageops: encrypt: document is already encrypted")
+	}
+	if ownerPass == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return fmt.Errorf("pageops: encrypt: %w", err)
+		}
+		ownerPass = hex.EncodeToString(random)
+	}
+
+	objects, err := reachableObjects(doc)
+	if err != nil {
+		return fmt.Errorf("pageops: encrypt: %w", err)
+	}
+	nums, remap := renumberObjects(objects)
+
+	// The key depends on the file ID; documents without one get a new one
+	var id reader.String
+	ids, _ := trailer["ID"].(reader.Array)
+	if len(ids) > 0 {
+		id, _ = ids[0].(reader.String)
+	}
+	if len(id.Value) == 0 {
+		sum := md5.Sum(data)
+		id = reader.String{Value: sum[:], IsHex: true}
+		ids = reader.Array{id, id}
+	}
+	enc := reader.NewEncryptor(userPass, ownerPass, permissionFlags(perms), id.Value)
+
+	out := make([]reader.Object, len(nums), len(nums)+1)
+	for i, num := range nums {
+		out[i] = enc.EncryptObject(remapRefs(objects[num], remap), i+1, 0)
+	}
+	out = append(out, enc.Dict())
+
+	updated := remapRefs(newTrailer(trailer, 0), remap).(reader.Dict)
+	updated["ID"] = ids
+	updated["Encrypt"] = reader.Reference{Number: len(out)}
+
+	// RC4 with keys longer than 40 bits needs PDF 1.4
+	version := doc.Version
+	if version < "1.4" {
+		version = "1.4"
+	}
+	return writeNewFile(w, version, out, updated)
+}
+
+// permissionFlags returns the /P value for revision 3 of the standard
+// security handler allowing the actions given by gofpdf's CnProtect flags.
+func permissionFlags(perms int) int32 {
+	const reserved = -0xf40 // bits 7-8 and 13-32 are set
+	p := int32(reserved) | int32(perms&(gofpdf.CnProtectPrint|gofpdf.CnProtectModify|gofpdf.CnProtectCopy|gofpdf.CnProtectAnnotForms))
+
+	// Revision 3 adds finer-grained flags, which follow the basic ones;
+	// extracting text for accessibility is always allowed.
+	p |= 1 << 9
+	if perms&gofpdf.CnProtectAnnotForms != 0 {
+		p |= 1 << 8 // filling in forms
+	}
+	if perms&gofpdf.CnProtectModify != 0 {
+		p |= 1 << 10 // assembling the document
+	}
+	if perms&gofpdf.CnProtectPrint != 0 {
+		p |= 1 << 11 // high-quality printing
+	}
+	return p
+}
+
+// Decrypt writes an unencrypted copy of an encrypted PDF to outputPath,
+// opening it with password, which may be the user or the owner password.
+// The document is rewritten like Optimize does, without the objects that
+// cannot be reached from its catalog or info dictionary.
+func Decrypt(inputPath, password, outputPath string) error {
+	doc, err := reader.OpenWithPassword(inputPath, password)
+	if err != nil {
+		return fmt.Errorf("pageops: decrypting %s: %w", inputPath, err)
+	}
+
+	// Strings and streams are decrypted as the reader resolves them
+	objects, err := reachableObjects(doc)
+	if err != nil {
+		return fmt.Errorf("pageops: decrypt: %w", err)
+	}
+	nums, remap := renumberObjects(objects)
+	out := make([]reader.Object, len(nums))
+	for i, num := range nums {
+		out[i] = remapRefs(objects[num], remap)
+	}
+	trailer := maps.Clone(doc.Trailer())
+	delete(trailer, "Encrypt")
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: creating %s: %w", outputPath, err)
+	}
+	err = writeNewFile(f, doc.Version, out, remapRefs(trailer, remap).(reader.Dict))
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("pageops: writing %s: %w", outputPath, cerr)
+	}
+	return err
+}
+
+// renumberObjects returns the numbers of objects in ascending order, and a
+// function mapping references to them to their position in that order,
+// counting from 1.
+func renumberObjects(objects map[int]reader.Object) ([]int, func(reader.Reference) reader.Reference) {
+	nums := make([]int, 0, len(objects))
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	renumber := make(map[int]int, len(nums))
+	for i, num := range nums {
+		renumber[num] = i + 1
+	}
+	return nums, func(ref reader.Reference) reader.Reference {
+		return reader.Reference{Number: renumber[ref.Number]}
+	}
+}
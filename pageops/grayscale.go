@@ -0,0 +1,519 @@
+package pageops
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// ToGrayscale converts a PDF to shades of gray, for example for printing on
+// a black-and-white printer, and writes the result to w.
+//
+// The RGB and CMYK colors set in the content streams of the pages, and of
+// the form XObjects they draw, are replaced by their luminance, and the
+// rewritten streams are compressed with Flate. RGB images are converted to
+// DeviceGray when they are JPEG images or 8-bit images stored without a
+// filter or with Flate. Other images, inline images, shadings and colors in
+// other color spaces (ICC-based, indexed, separations) are left as they
+// are. Only the rewritten streams are replaced, as RecompressImages does.
+// Encrypted documents are not supported.
+func ToGrayscale(w io.Writer, inputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	if _, ok := doc.Trailer()["Encrypt"]; ok {
+		return fmt.Errorf("pageops: grayscale: encrypted documents are not supported")
+	}
+
+	g := &grayConverter{
+		doc:      doc,
+		replaced: make(map[reader.Reference]reader.Stream),
+		seen:     make(map[reader.Reference]bool),
+	}
+	for _, page := range doc.Pages() {
+		obj, err := doc.ResolveReference(reader.Reference{Number: page.ObjNum})
+		if err != nil {
+			return fmt.Errorf("pageops: grayscale: %w", err)
+		}
+		pageDict, _ := obj.(reader.Dict)
+		contents := pageDict["Contents"]
+		if ref, ok := contents.(reader.Reference); ok {
+			if resolved, err := doc.ResolveReference(ref); err == nil {
+				if _, isArray := resolved.(reader.Array); isArray {
+					contents = resolved
+				}
+			}
+		}
+		refs, _ := contents.(reader.Array)
+		if ref, ok := contents.(reader.Reference); ok {
+			refs = reader.Array{ref}
+		}
+		for _, item := range refs {
+			if ref, ok := item.(reader.Reference); ok {
+				if err := g.content(ref); err != nil {
+					return fmt.Errorf("pageops: grayscale: page %d: %w", page.Number, err)
+				}
+			}
+		}
+		if err := g.resources(page.Resources); err != nil {
+			return fmt.Errorf("pageops: grayscale: page %d: %w", page.Number, err)
+		}
+	}
+
+	if len(g.replaced) == 0 {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("pageops: writing output: %w", err)
+		}
+		return nil
+	}
+	return writeReplacedObjects(w, doc, data, g.replaced)
+}
+
+// grayConverter collects the grayscale replacements of the streams of a
+// document.
+type grayConverter struct {
+	doc      *reader.Document
+	replaced map[reader.Reference]reader.Stream
+	seen     map[reader.Reference]bool
+}
+
+// stream resolves ref to a stream that has not been visited yet.
+func (g *grayConverter) stream(ref reader.Reference) (reader.Stream, bool) {
+	if g.seen[ref] {
+		return reader.Stream{}, false
+	}
+	g.seen[ref] = true
+	obj, err := g.doc.ResolveReference(ref)
+	if err != nil {
+		return reader.Stream{}, false
+	}
+	s, ok := obj.(reader.Stream)
+	return s, ok
+}
+
+// content converts the colors of the content stream ref.
+func (g *grayConverter) content(ref reader.Reference) error {
+	s, ok := g.stream(ref)
+	if !ok {
+		return nil
+	}
+	if _, ok := s.Dict["DecodeParms"]; ok {
+		return nil
+	}
+	data, err := s.Decode()
+	if err != nil {
+		return err
+	}
+	g.replaced[ref] = flateStream(s.Dict, grayContent(data))
+	return nil
+}
+
+// resources converts the images and form XObjects of a resource
+// dictionary.
+func (g *grayConverter) resources(res reader.Dict) error {
+	xobjects, _ := resolveIn(g.doc, res["XObject"]).(reader.Dict)
+	for _, item := range xobjects {
+		ref, ok := item.(reader.Reference)
+		if !ok {
+			continue
+		}
+		s, ok := g.stream(ref)
+		if !ok {
+			continue
+		}
+		switch s.Dict.GetName("Subtype") {
+		case "Image":
+			if gray, ok := grayImage(s); ok {
+				g.replaced[ref] = gray
+			}
+		case "Form":
+			if _, ok := s.Dict["DecodeParms"]; ok {
+				continue
+			}
+			data, err := s.Decode()
+			if err != nil {
+				return err
+			}
+			g.replaced[ref] = flateStream(s.Dict, grayContent(data))
+			formRes, _ := resolveIn(g.doc, s.Dict["Resources"]).(reader.Dict)
+			if err := g.resources(formRes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flateStream returns a stream with the given dictionary holding data
+// compressed with Flate.
+func flateStream(dict reader.Dict, data []byte) reader.Stream {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+
+	d := make(reader.Dict, len(dict))
+	for k, v := range dict {
+		d[k] = v
+	}
+	delete(d, "DecodeParms")
+	d["Filter"] = reader.Name("FlateDecode")
+	return reader.Stream{Dict: d, Data: buf.Bytes()}
+}
+
+// grayImage converts an RGB image to DeviceGray. It reports false if the
+// image is not an RGB JPEG or 8-bit Flate or unfiltered image.
+func grayImage(s reader.Stream) (reader.Stream, bool) {
+	if s.Dict.GetName("ColorSpace") != "DeviceRGB" {
+		return reader.Stream{}, false
+	}
+	if _, ok := s.Dict["Decode"]; ok {
+		return reader.Stream{}, false
+	}
+	width, _ := s.Dict.GetInt("Width")
+	height, _ := s.Dict.GetInt("Height")
+
+	dict := make(reader.Dict, len(s.Dict))
+	for k, v := range s.Dict {
+		dict[k] = v
+	}
+	dict["ColorSpace"] = reader.Name("DeviceGray")
+
+	filter, _ := s.Dict["Filter"].(reader.Name)
+	switch {
+	case filter == "DCTDecode":
+		src, err := jpeg.Decode(bytes.NewReader(s.Data))
+		if err != nil {
+			return reader.Stream{}, false
+		}
+		b := src.Bounds()
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, downsample(src, b.Dx(), b.Dy(), true), &jpeg.Options{Quality: 90}); err != nil {
+			return reader.Stream{}, false
+		}
+		delete(dict, "DecodeParms")
+		dict["BitsPerComponent"] = reader.Integer(8)
+		return reader.Stream{Dict: dict, Data: buf.Bytes()}, true
+
+	case s.Dict["Filter"] == nil || filter == "FlateDecode":
+		if bpc, _ := s.Dict.GetInt("BitsPerComponent"); bpc != 8 || width <= 0 || height <= 0 {
+			return reader.Stream{}, false
+		}
+		data, err := s.Decode()
+		if err != nil {
+			return reader.Stream{}, false
+		}
+		parms, _ := s.Dict["DecodeParms"].(reader.Dict)
+		if predictor, _ := parms.GetInt("Predictor"); predictor >= 10 {
+			data, err = unpredictPNG(data, 3, int(width))
+			if err != nil {
+				return reader.Stream{}, false
+			}
+		} else if predictor > 1 {
+			return reader.Stream{}, false
+		}
+		n := int(width * height)
+		if len(data) < 3*n {
+			return reader.Stream{}, false
+		}
+		gray := make([]byte, n)
+		for i := range gray {
+			r, g, b := float64(data[3*i]), float64(data[3*i+1]), float64(data[3*i+2])
+			gray[i] = byte(math.Round(luminance(r, g, b)))
+		}
+		return flateStream(dict, gray), true
+	}
+	return reader.Stream{}, false
+}
+
+// unpredictPNG reverses the PNG predictors of 8-bit image rows of the
+// given number of color components and pixel width.
+func unpredictPNG(data []byte, colors, width int) ([]byte, error) {
+	stride := colors * width
+	if len(data)%(stride+1) != 0 {
+		return nil, fmt.Errorf("predicted data is not a whole number of rows")
+	}
+	out := make([]byte, 0, len(data)/(stride+1)*stride)
+	prev := make([]byte, stride)
+	for pos := 0; pos < len(data); pos += stride + 1 {
+		filter, row := data[pos], data[pos+1:pos+1+stride]
+		cur := make([]byte, stride)
+		for i := range row {
+			var left, upLeft byte
+			if i >= colors {
+				left, upLeft = cur[i-colors], prev[i-colors]
+			}
+			up := prev[i]
+			switch filter {
+			case 0:
+				cur[i] = row[i]
+			case 1:
+				cur[i] = row[i] + left
+			case 2:
+				cur[i] = row[i] + up
+			case 3:
+				cur[i] = row[i] + byte((int(left)+int(up))/2)
+			case 4:
+				cur[i] = row[i] + paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("unknown PNG filter %d", filter)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of PNG.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// luminance returns the gray level of an RGB color, with components on any
+// common scale, using the ITU-R BT.601 weights.
+func luminance(r, g, b float64) float64 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+// cmykLuminance returns the gray level of a CMYK color.
+func cmykLuminance(c, m, y, k float64) float64 {
+	return luminance((1-c)*(1-k), (1-m)*(1-k), (1-y)*(1-k))
+}
+
+// grayContent rewrites the RGB and CMYK color operators of a content
+// stream as gray ones. rg/RG and k/K become g/G, and /DeviceRGB or
+// /DeviceCMYK selected with cs/CS becomes /DeviceGray, with the colors set
+// by sc/scn/SC/SCN in those spaces converted. Everything else is copied
+// unchanged.
+func grayContent(data []byte) []byte {
+	type operand struct {
+		start int
+		num   float64
+		isNum bool
+		name  string
+	}
+	type spaces struct{ fill, stroke int } // number of components, 0 if not converted
+
+	var out bytes.Buffer
+	var operands []operand
+	var state spaces
+	var stack []spaces
+	copied := 0
+
+	// replace writes repl in place of the last n operands and the operator
+	// ending at end, if they are all numbers.
+	replace := func(n, end int, repl func(nums []float64) string) {
+		if len(operands) < n {
+			return
+		}
+		ops := operands[len(operands)-n:]
+		nums := make([]float64, n)
+		for i, op := range ops {
+			if !op.isNum {
+				return
+			}
+			nums[i] = op.num
+		}
+		out.Write(data[copied:ops[0].start])
+		out.WriteString(repl(nums))
+		copied = end
+	}
+	gray := func(v float64) string {
+		return strconv.FormatFloat(math.Round(min(max(v, 0), 1)*1000)/1000, 'f', -1, 64)
+	}
+	fromRGB := func(op string) func([]float64) string {
+		return func(n []float64) string { return gray(luminance(n[0], n[1], n[2])) + " " + op }
+	}
+	fromCMYK := func(op string) func([]float64) string {
+		return func(n []float64) string { return gray(cmykLuminance(n[0], n[1], n[2], n[3])) + " " + op }
+	}
+	components := map[string]int{"DeviceRGB": 3, "DeviceCMYK": 4}
+
+	i := 0
+	for i < len(data) {
+		b := data[i]
+		switch {
+		case b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0:
+			i++
+			continue
+		case b == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+			continue
+		case b == '(':
+			operands = append(operands, operand{start: i})
+			i = skipContentString(data, i)
+			continue
+		case b == '<' || b == '[':
+			operands = append(operands, operand{start: i})
+			i = skipContentGroup(data, i)
+			continue
+		case b == '/':
+			start := i
+			i++
+			for i < len(data) && isContentRegular(data[i]) {
+				i++
+			}
+			operands = append(operands, operand{start: start, name: string(data[start+1 : i])})
+			continue
+		case !isContentRegular(b):
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(data) && isContentRegular(data[i]) {
+			i++
+		}
+		tok := string(data[start:i])
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			operands = append(operands, operand{start: start, num: f, isNum: true})
+			continue
+		}
+
+		switch tok {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "rg", "RG":
+			replace(3, i, fromRGB(map[string]string{"rg": "g", "RG": "G"}[tok]))
+		case "k", "K":
+			replace(4, i, fromCMYK(map[string]string{"k": "g", "K": "G"}[tok]))
+		case "cs", "CS":
+			n := 0
+			if len(operands) > 0 {
+				op := operands[len(operands)-1]
+				if n = components[op.name]; n > 0 {
+					out.Write(data[copied:op.start])
+					out.WriteString("/DeviceGray " + tok)
+					copied = i
+				}
+			}
+			if tok == "cs" {
+				state.fill = n
+			} else {
+				state.stroke = n
+			}
+		case "sc", "scn", "SC", "SCN":
+			n := state.fill
+			if tok == "SC" || tok == "SCN" {
+				n = state.stroke
+			}
+			switch n {
+			case 3:
+				replace(3, i, fromRGB(tok))
+			case 4:
+				replace(4, i, fromCMYK(tok))
+			}
+		case "BI":
+			i = skipContentInlineImage(data, i)
+		}
+		operands = operands[:0]
+	}
+	out.Write(data[copied:])
+	return out.Bytes()
+}
+
+// isContentRegular reports whether b is a regular character, neither white
+// space nor a delimiter.
+func isContentRegular(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// skipContentString returns the offset just past the literal string
+// starting at pos.
+func skipContentString(data []byte, pos int) int {
+	depth := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case '\\':
+			pos++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		}
+		pos++
+	}
+	return pos
+}
+
+// skipContentGroup returns the offset just past the array, dictionary or
+// hexadecimal string starting at pos.
+func skipContentGroup(data []byte, pos int) int {
+	depth := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case '(':
+			pos = skipContentString(data, pos)
+			continue
+		case '[', '<':
+			depth++
+		case ']', '>':
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		}
+		pos++
+	}
+	return pos
+}
+
+// skipContentInlineImage returns the offset just past the EI operator
+// ending the inline image whose BI operator ends at pos.
+func skipContentInlineImage(data []byte, pos int) int {
+	idx := bytes.Index(data[pos:], []byte("ID"))
+	if idx < 0 {
+		return len(data)
+	}
+	pos += idx + 2
+	for pos+2 < len(data) {
+		if data[pos+1] == 'E' && data[pos+2] == 'I' && !isContentRegular(data[pos]) &&
+			(pos+3 >= len(data) || !isContentRegular(data[pos+3])) {
+			return pos + 3
+		}
+		pos++
+	}
+	return len(data)
+}
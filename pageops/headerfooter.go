@@ -0,0 +1,106 @@
+package pageops
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// HeaderFooterOptions defines the text stamped at the top and bottom of
+// every page. Each slot may contain the placeholders {page} (the page
+// number), {pages} (the page count) and {date}; empty slots are skipped.
+type HeaderFooterOptions struct {
+	HeaderLeft, HeaderCenter, HeaderRight string
+	FooterLeft, FooterCenter, FooterRight string
+
+	FontSize   float64   // font size in points (default: 9)
+	Color      RGBColor  // text color (default: black)
+	Margin     float64   // margin from page edges in points (default: 30)
+	Date       time.Time // value of {date} (default: now)
+	DateFormat string    // time layout of {date} (default: "2006-01-02")
+}
+
+// AddHeaderFooter stamps header and footer text on all pages of a PDF.
+func AddHeaderFooter(w io.Writer, inputPath string, opts HeaderFooterOptions) error {
+	pdf, err := buildHeaderFooterPDF(inputPath, opts)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// AddHeaderFooterToFile stamps header and footer text and saves to a file.
+func AddHeaderFooterToFile(inputPath, outputPath string, opts HeaderFooterOptions) error {
+	pdf, err := buildHeaderFooterPDF(inputPath, opts)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+func buildHeaderFooterPDF(inputPath string, opts HeaderFooterOptions) (*gofpdf.Fpdf, error) {
+	if opts.FontSize == 0 {
+		opts.FontSize = 9
+	}
+	if opts.Margin == 0 {
+		opts.Margin = 30
+	}
+	if opts.Date.IsZero() {
+		opts.Date = time.Now()
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "2006-01-02"
+	}
+
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, imp := newBasePDF()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	slots := []struct {
+		pos  Position
+		text string
+	}{
+		{TopLeft, opts.HeaderLeft},
+		{TopCenter, opts.HeaderCenter},
+		{TopRight, opts.HeaderRight},
+		{BottomLeft, opts.FooterLeft},
+		{BottomCenter, opts.FooterCenter},
+		{BottomRight, opts.FooterRight},
+	}
+	replacer := func(page int) *strings.Replacer {
+		return strings.NewReplacer(
+			"{page}", strconv.Itoa(page),
+			"{pages}", strconv.Itoa(pageCount),
+			"{date}", opts.Date.Format(opts.DateFormat),
+		)
+	}
+
+	for i := 1; i <= pageCount; i++ {
+		pw, ph := addImportedPage(pdf, imp, inputPath, i)
+
+		pdf.SetFont("Helvetica", "", opts.FontSize)
+		pdf.SetTextColor(opts.Color.R, opts.Color.G, opts.Color.B)
+		r := replacer(i)
+		for _, slot := range slots {
+			if slot.text == "" {
+				continue
+			}
+			text := tr(r.Replace(slot.text))
+			textW := pdf.GetStringWidth(text)
+			x, y := calculatePosition(slot.pos, pw, ph, textW, opts.FontSize, opts.Margin)
+			pdf.Text(x, y, text)
+		}
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: header and footer: %w", pdf.Error())
+	}
+	return pdf, nil
+}
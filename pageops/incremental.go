@@ -0,0 +1,195 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// writeReplacedObjects writes the original file data with the given stream
+// objects replaced in place, followed by a cross-reference section listing
+// every object at its new offset. The bytes of all other objects are left
+// untouched; only the sections written by earlier revisions become unused.
+// This lets a replacement make the file smaller, which an incremental
+// update cannot. It falls back to an incremental update if the document
+// has objects stored in object streams, whose entries a cross-reference
+// table cannot express.
+func writeReplacedObjects(w io.Writer, doc *reader.Document, data []byte, objects map[reader.Reference]reader.Stream) error {
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		return fmt.Errorf("pageops: reading cross-reference: %w", err)
+	}
+
+	// Current entry of each object.
+	current := make(map[int]reader.XRefEntry, len(summary))
+	for num, entries := range summary {
+		entry := entries[len(entries)-1]
+		if entry.Type == "compressed" {
+			updates := make(map[reader.Reference]reader.Object, len(objects))
+			for ref, s := range objects {
+				updates[ref] = s
+			}
+			if err := doc.AppendUpdate(w, updates); err != nil {
+				return fmt.Errorf("pageops: %w", err)
+			}
+			return nil
+		}
+		if entry.Type == "in-use" {
+			current[num] = entry
+		}
+	}
+
+	// Locate the byte span of each replaced object.
+	type span struct {
+		start, end int
+		body       []byte
+	}
+	var spans []span
+	for ref, s := range objects {
+		entry, ok := current[ref.Number]
+		if !ok {
+			return fmt.Errorf("pageops: object %d is not in use", ref.Number)
+		}
+		orig, err := doc.ResolveReference(ref)
+		if err != nil {
+			return fmt.Errorf("pageops: object %d: %w", ref.Number, err)
+		}
+		origStream, ok := orig.(reader.Stream)
+		if !ok {
+			return fmt.Errorf("pageops: object %d is not a stream", ref.Number)
+		}
+		end, err := streamObjectEnd(data, int(entry.Offset), len(origStream.Data))
+		if err != nil {
+			return fmt.Errorf("pageops: object %d: %w", ref.Number, err)
+		}
+		var body bytes.Buffer
+		fmt.Fprintf(&body, "%d %d obj\n", ref.Number, ref.Generation)
+		reader.WriteObject(&body, s)
+		body.WriteString("\nendobj")
+		spans = append(spans, span{int(entry.Offset), end, body.Bytes()})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	// newOffset maps an offset in data to the matching offset in the output.
+	newOffset := func(off int64) int64 {
+		shifted := off
+		for _, sp := range spans {
+			if int64(sp.end) <= off {
+				shifted += int64(len(sp.body) - (sp.end - sp.start))
+			}
+		}
+		return shifted
+	}
+
+	var buf bytes.Buffer
+	pos := 0
+	for _, sp := range spans {
+		buf.Write(data[pos:sp.start])
+		buf.Write(sp.body)
+		pos = sp.end
+	}
+	buf.Write(data[pos:])
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+
+	size, _ := doc.Trailer().GetInt("Size")
+	for num := range current {
+		size = max(size, int64(num)+1)
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for num := 1; num < int(size); num++ {
+		entry, ok := current[num]
+		if !ok {
+			buf.WriteString("0000000000 65535 f \n")
+			continue
+		}
+		fmt.Fprintf(&buf, "%010d %05d n \n", newOffset(entry.Offset), entry.Generation)
+	}
+
+	buf.WriteString("trailer\n")
+	reader.WriteObject(&buf, newTrailer(doc.Trailer(), size))
+	fmt.Fprintf(&buf, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("pageops: writing output: %w", err)
+	}
+	return nil
+}
+
+// writeNewFile writes a complete PDF file with the given objects, object
+// i+1 being objects[i], and a trailer keeping the root, info, ID and
+// encryption of trailer. version defaults to 1.4.
+func writeNewFile(w io.Writer, version string, objects []reader.Object, trailer reader.Dict) error {
+	if version == "" {
+		version = "1.4"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%%PDF-%s\n%%\xe2\xe3\xcf\xd3\n", version)
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		reader.WriteObject(&buf, obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	size := len(objects) + 1
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	buf.WriteString("trailer\n")
+	reader.WriteObject(&buf, newTrailer(trailer, int64(size)))
+	fmt.Fprintf(&buf, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("pageops: writing output: %w", err)
+	}
+	return nil
+}
+
+// streamObjectEnd returns the offset just past the endobj keyword of the
+// stream object starting at start whose data is dataLen bytes long.
+func streamObjectEnd(data []byte, start, dataLen int) (int, error) {
+	if start < 0 || start >= len(data) {
+		return 0, fmt.Errorf("offset %d out of bounds", start)
+	}
+	idx := bytes.Index(data[start:], []byte("stream"))
+	if idx < 0 {
+		return 0, fmt.Errorf("stream keyword not found")
+	}
+	pos := start + idx + len("stream")
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+	pos += dataLen
+	if pos > len(data) {
+		return 0, fmt.Errorf("stream data out of bounds")
+	}
+	idx = bytes.Index(data[pos:], []byte("endobj"))
+	if idx < 0 {
+		return 0, fmt.Errorf("endobj keyword not found")
+	}
+	return pos + idx + len("endobj"), nil
+}
+
+// newTrailer returns a trailer dictionary for a new cross-reference section
+// that keeps the document root, info, ID and encryption of trailer.
+func newTrailer(trailer reader.Dict, size int64) reader.Dict {
+	d := reader.Dict{"Size": reader.Integer(size)}
+	for _, key := range []reader.Name{"Root", "Info", "ID", "Encrypt"} {
+		if v, ok := trailer[key]; ok {
+			d[key] = v
+		}
+	}
+	return d
+}
@@ -0,0 +1,99 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// InsertPages inserts all pages of the insert document after page afterPage
+// of the base document and writes the result to w. An afterPage of 0
+// inserts the pages before the first page; the base page count appends
+// them at the end.
+func InsertPages(w io.Writer, basePath string, insert io.Reader, afterPage int) error {
+	pdf, err := buildInsertedPDF(basePath, insert, afterPage)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// InsertPagesToFile inserts the pages of insert after page afterPage of the
+// base document and saves the result to a file.
+func InsertPagesToFile(basePath string, insert io.Reader, afterPage int, outputPath string) error {
+	pdf, err := buildInsertedPDF(basePath, insert, afterPage)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+// InsertPagesFromFile inserts all pages of the PDF file insertPath after page
+// afterPage of the base document and writes the result to w, like
+// InsertPages.
+func InsertPagesFromFile(w io.Writer, basePath, insertPath string, afterPage int) error {
+	f, err := os.Open(insertPath)
+	if err != nil {
+		return fmt.Errorf("pageops: opening %s: %w", insertPath, err)
+	}
+	defer f.Close()
+	return InsertPages(w, basePath, f, afterPage)
+}
+
+func buildInsertedPDF(basePath string, insert io.Reader, afterPage int) (*gofpdf.Fpdf, error) {
+	baseCount, err := getPageCount(basePath)
+	if err != nil {
+		return nil, err
+	}
+	if afterPage < 0 || afterPage > baseCount {
+		return nil, fmt.Errorf("pageops: insert position %d out of range [0, %d]", afterPage, baseCount)
+	}
+
+	data, err := io.ReadAll(insert)
+	if err != nil {
+		return nil, fmt.Errorf("pageops: reading inserted document: %w", err)
+	}
+	insertCount, err := getPageCountFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("pageops: reading inserted document: %w", err)
+	}
+
+	// Template names are only unique within one importer, and only while
+	// each source is imported in one run, so all base pages are imported
+	// before the inserted ones
+	pdf, imp := newBasePDF()
+	type page struct {
+		tplID int
+		w, h  float64
+	}
+	basePages := make([]page, baseCount)
+	for i := range basePages {
+		p := &basePages[i]
+		p.tplID, p.w, p.h = importPage(pdf, imp, basePath, i+1)
+	}
+	insertPages := make([]page, insertCount)
+	rs := io.ReadSeeker(bytes.NewReader(data))
+	for i := range insertPages {
+		p := &insertPages[i]
+		p.tplID = imp.ImportPageFromStream(pdf, &rs, i+1, "/MediaBox")
+		p.w, p.h = importedPageSize(imp, i+1)
+	}
+
+	pages := append(append(append([]page{}, basePages[:afterPage]...), insertPages...), basePages[afterPage:]...)
+	for _, p := range pages {
+		if p.w == 0 || p.h == 0 {
+			p.w = defaultPageWidth
+			p.h = defaultPageHeight
+		}
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: p.w, Ht: p.h})
+		imp.UseImportedTemplate(pdf, p.tplID, 0, 0, p.w, p.h)
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: insert: %w", pdf.Error())
+	}
+	return pdf, nil
+}
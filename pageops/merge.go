@@ -1,49 +1,127 @@
 package pageops
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"os"
 
 	gofpdf "github.com/lvillar/gofpdf"
-	"github.com/lvillar/gofpdf/contrib/gofpdi"
+	"github.com/lvillar/gofpdf/reader"
 )
 
+// MergeOptions controls how PDFs are merged. Title and Author override the
+// document information taken from the first input when not empty.
+type MergeOptions struct {
+	Title  string
+	Author string
+
+	// PreserveAnnotations keeps the link annotations and form fields of the
+	// inputs, which are otherwise lost because pages are imported as
+	// templates. It adds an incremental update copying them onto the merged
+	// pages and combining the fields into one form; fields of later inputs
+	// whose name is already taken get a "_<n>" suffix, n being the 1-based
+	// position of the input.
+	PreserveAnnotations bool
+
+	// Progress, if set, is called after the pages of each input have been
+	// added, with the number of inputs done so far and the total.
+	Progress func(done, total int)
+}
+
 // MergeFiles combines multiple PDF files into a single output file.
 // Pages are added in order: all pages from the first file, then all from the second, etc.
+// The document information (title, author, subject, keywords, creator and
+// producer) is carried over from the first file.
 func MergeFiles(outputPath string, inputPaths ...string) error {
-	pdf, err := buildMergedPDF(inputPaths)
+	return MergeFilesWithOptions(outputPath, MergeOptions{}, inputPaths...)
+}
+
+// Merge combines multiple PDF files and writes the result to w.
+func Merge(w io.Writer, inputPaths ...string) error {
+	return MergeWithOptions(w, MergeOptions{}, inputPaths...)
+}
+
+// MergeFilesWithOptions combines multiple PDF files into a single output
+// file like MergeFiles, with the given options.
+func MergeFilesWithOptions(outputPath string, opts MergeOptions, inputPaths ...string) error {
+	if opts.PreserveAnnotations {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("pageops: creating %s: %w", outputPath, err)
+		}
+		defer f.Close()
+		return MergeWithOptions(f, opts, inputPaths...)
+	}
+	pdf, err := buildMergedPDF(inputPaths, opts)
 	if err != nil {
 		return err
 	}
 	return writePDFToFile(pdf, outputPath)
 }
 
-// Merge combines multiple PDF files and writes the result to w.
-func Merge(w io.Writer, inputPaths ...string) error {
-	pdf, err := buildMergedPDF(inputPaths)
+// MergeWithOptions combines multiple PDF files and writes the result to w,
+// with the given options.
+func MergeWithOptions(w io.Writer, opts MergeOptions, inputPaths ...string) error {
+	pdf, err := buildMergedPDF(inputPaths, opts)
 	if err != nil {
 		return err
 	}
-	return writePDF(pdf, w)
+	if !opts.PreserveAnnotations {
+		return writePDF(pdf, w)
+	}
+	var buf bytes.Buffer
+	if err := writePDF(pdf, &buf); err != nil {
+		return err
+	}
+	return writeMergedAnnotations(w, buf.Bytes(), inputPaths)
 }
 
-func buildMergedPDF(inputPaths []string) (*gofpdf.Fpdf, error) {
+func buildMergedPDF(inputPaths []string, opts MergeOptions) (*gofpdf.Fpdf, error) {
 	if len(inputPaths) == 0 {
 		return nil, fmt.Errorf("pageops: no input files provided")
 	}
 
-	pdf, _ := newBasePDF()
+	first, err := reader.Open(inputPaths[0])
+	if err != nil {
+		return nil, fmt.Errorf("pageops: merging %s: %w", inputPaths[0], err)
+	}
+	meta := first.Metadata()
+	if opts.Title != "" {
+		meta["Title"] = opts.Title
+	}
+	if opts.Author != "" {
+		meta["Author"] = opts.Author
+	}
+
+	// A single importer keeps the template names of the sources apart
+	pdf, imp := newBasePDF()
+	setters := map[string]func(string, bool){
+		"Title":    pdf.SetTitle,
+		"Author":   pdf.SetAuthor,
+		"Subject":  pdf.SetSubject,
+		"Keywords": pdf.SetKeywords,
+		"Creator":  pdf.SetCreator,
+		"Producer": pdf.SetProducer,
+	}
+	for key, set := range setters {
+		if v, ok := meta[key]; ok {
+			set(v, true)
+		}
+	}
 
-	for _, inputPath := range inputPaths {
+	for n, inputPath := range inputPaths {
 		pageCount, err := getPageCount(inputPath)
 		if err != nil {
 			return nil, fmt.Errorf("pageops: merging %s: %w", inputPath, err)
 		}
 
-		imp := gofpdi.NewImporter()
 		for i := 1; i <= pageCount; i++ {
 			addImportedPage(pdf, imp, inputPath, i)
 		}
+		if opts.Progress != nil {
+			opts.Progress(n+1, len(inputPaths))
+		}
 	}
 
 	if pdf.Err() {
@@ -0,0 +1,109 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"time"
+	"unicode/utf16"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// metadataKeys are the document information entries SetMetadata writes.
+var metadataKeys = map[string]bool{
+	"Title":    true,
+	"Author":   true,
+	"Subject":  true,
+	"Keywords": true,
+	"Creator":  true,
+	"Producer": true,
+}
+
+// SetMetadata updates the document information dictionary of a PDF and
+// writes the result to w. fields maps the entries to change, "Title",
+// "Author", "Subject", "Keywords", "Creator" or "Producer", to their new
+// value; an empty value removes the entry. Other entries are kept and
+// /ModDate is set to the current time.
+//
+// The dictionary is rewritten in an incremental update, leaving the
+// original bytes untouched. Encrypted documents are not supported.
+func SetMetadata(w io.Writer, inputPath string, fields map[string]string) error {
+	for key := range fields {
+		if !metadataKeys[key] {
+			return fmt.Errorf("pageops: unsupported metadata field %q", key)
+		}
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	// A new /Info entry set in the trailer is written by AppendUpdate
+	trailer := doc.Trailer()
+	if _, ok := trailer["Encrypt"]; ok {
+		return fmt.Errorf("pageops: set metadata: encrypted documents are not supported")
+	}
+
+	// The dictionary is updated in place if it is an indirect object, and
+	// added as a new one otherwise
+	info := reader.Dict{}
+	ref, isRef := trailer["Info"].(reader.Reference)
+	switch v := trailer["Info"].(type) {
+	case reader.Reference:
+		obj, err := doc.ResolveReference(v)
+		if err != nil {
+			return fmt.Errorf("pageops: set metadata: %w", err)
+		}
+		if dict, ok := obj.(reader.Dict); ok {
+			info = maps.Clone(dict)
+		}
+	case reader.Dict:
+		info = maps.Clone(v)
+	}
+	if !isRef {
+		size, _ := trailer.GetInt("Size")
+		ref = reader.Reference{Number: int(size)}
+		trailer["Info"] = ref
+	}
+
+	for key, value := range fields {
+		if value == "" {
+			delete(info, reader.Name(key))
+			continue
+		}
+		info[reader.Name(key)] = textString(value)
+	}
+	info["ModDate"] = reader.String{Value: []byte(time.Now().UTC().Format("D:20060102150405Z"))}
+
+	if err := doc.AppendUpdate(w, map[reader.Reference]reader.Object{ref: info}); err != nil {
+		return fmt.Errorf("pageops: set metadata: %w", err)
+	}
+	return nil
+}
+
+// textString encodes s as a PDF text string: as is if it is ASCII, in
+// UTF-16BE with a byte order mark otherwise.
+func textString(s string) reader.String {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return reader.String{Value: []byte(s)}
+	}
+	value := []byte{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(s)) {
+		value = append(value, byte(u>>8), byte(u))
+	}
+	return reader.String{Value: value, IsHex: true}
+}
@@ -0,0 +1,76 @@
+package pageops
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberStyle selects how page numbers are written.
+type NumberStyle int
+
+const (
+	Arabic     NumberStyle = iota // 1, 2, 3
+	LowerRoman                    // i, ii, iii
+	UpperRoman                    // I, II, III
+	LowerAlpha                    // a, b, ..., z, aa, bb
+	UpperAlpha                    // A, B, ..., Z, AA, BB
+)
+
+// FormatNumber returns n written in style s. Numbers below 1 are written in
+// Arabic numerals, which are the only ones able to express them.
+func (s NumberStyle) FormatNumber(n int) string {
+	if n < 1 {
+		return strconv.Itoa(n)
+	}
+	switch s {
+	case LowerRoman:
+		return strings.ToLower(roman(n))
+	case UpperRoman:
+		return roman(n)
+	case LowerAlpha:
+		return strings.ToLower(alpha(n))
+	case UpperAlpha:
+		return alpha(n)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// roman returns n in upper-case Roman numerals. Thousands are written as
+// repeated Ms.
+func roman(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// alpha returns n in upper-case letters the way PDF page labels do: A to Z,
+// then AA to ZZ, AAA to ZZZ, and so on.
+func alpha(n int) string {
+	letter := byte('A' + (n-1)%26)
+	return strings.Repeat(string(letter), (n-1)/26+1)
+}
+
+// pageNumber is a page number that the %d, %s and %v verbs write in its
+// style, so that the same Format string works with every NumberStyle.
+type pageNumber struct {
+	n     int
+	style NumberStyle
+}
+
+// Format implements fmt.Formatter, honoring width and flags.
+func (p pageNumber) Format(f fmt.State, verb rune) {
+	if p.style == Arabic {
+		fmt.Fprintf(f, fmt.FormatString(f, 'd'), p.n)
+		return
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, 's'), p.style.FormatNumber(p.n))
+}
@@ -0,0 +1,83 @@
+package pageops
+
+import (
+	"fmt"
+	"io"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// NUp places the pages of a PDF cols×rows per sheet, in reading order, and
+// writes the result to w. Sheets have the named page size ("A4", "Letter",
+// "Legal", ...; default A4), in landscape orientation when cols > rows.
+// Each page is scaled to fit its cell, keeping its aspect ratio, and
+// centered in it. The last sheet holds the remaining pages, leaving its
+// other cells empty.
+func NUp(w io.Writer, inputPath string, cols, rows int, pageSize string) error {
+	pdf, err := buildNUpPDF(inputPath, cols, rows, pageSize)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// NUpToFile places the pages of a PDF cols×rows per sheet and saves the
+// result to a file. See NUp.
+func NUpToFile(inputPath, outputPath string, cols, rows int, pageSize string) error {
+	pdf, err := buildNUpPDF(inputPath, cols, rows, pageSize)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+func buildNUpPDF(inputPath string, cols, rows int, pageSize string) (*gofpdf.Fpdf, error) {
+	if cols < 1 || rows < 1 {
+		return nil, fmt.Errorf("pageops: n-up grid must have at least one column and row, got %dx%d", cols, rows)
+	}
+	if pageSize == "" {
+		pageSize = "A4"
+	}
+
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, imp := newBasePDF()
+	sheet := pdf.GetPageSizeStr(pageSize)
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: n-up: %w", pdf.Error())
+	}
+	if cols > rows {
+		sheet.Wd, sheet.Ht = sheet.Ht, sheet.Wd
+	}
+	cellW := sheet.Wd / float64(cols)
+	cellH := sheet.Ht / float64(rows)
+
+	perSheet := cols * rows
+	for i := 1; i <= pageCount; i++ {
+		tplID, pw, ph := importPage(pdf, imp, inputPath, i)
+		if pw == 0 || ph == 0 {
+			pw = defaultPageWidth
+			ph = defaultPageHeight
+		}
+
+		cell := (i - 1) % perSheet
+		if cell == 0 {
+			pdf.AddPageFormat("P", sheet)
+		}
+		col, row := cell%cols, cell/cols
+
+		scale := min(cellW/pw, cellH/ph)
+		w, h := pw*scale, ph*scale
+		x := float64(col)*cellW + (cellW-w)/2
+		y := float64(row)*cellH + (cellH-h)/2
+		useImportedPage(pdf, imp, tplID, i, x, y, w, h)
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: n-up: %w", pdf.Error())
+	}
+	return pdf, nil
+}
@@ -0,0 +1,252 @@
+package pageops
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// Optimize rewrites a PDF to make it smaller and writes the result to w.
+// Streams stored without a filter are compressed with Flate, objects that
+// cannot be reached from the document catalog or info dictionary are
+// dropped, and identical streams and resource dictionaries are merged into
+// a single object. This mostly pays off on the output of Merge and the
+// other page operations, which embed every source page as its own template
+// with its own copy of the fonts and images it uses.
+//
+// Merging compares objects by their content after the objects they refer to
+// have been merged, so that, for example, two copies of an image with
+// identical soft masks become one. Pages, annotations and any dictionary with
+// a /Parent entry keep their identity. XMP metadata streams stay
+// uncompressed, as PDF/A requires. Encrypted documents are not supported.
+func Optimize(w io.Writer, inputPath string) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	trailer := doc.Trailer()
+	if _, ok := trailer["Encrypt"]; ok {
+		return fmt.Errorf("pageops: optimize: encrypted documents are not supported")
+	}
+
+	objects, err := reachableObjects(doc)
+	if err != nil {
+		return fmt.Errorf("pageops: optimize: %w", err)
+	}
+	for num, obj := range objects {
+		if s, ok := obj.(reader.Stream); ok {
+			objects[num] = compressStream(s)
+		}
+	}
+	canon := mergeDuplicates(objects)
+
+	// Number the remaining objects densely, in their original order.
+	var nums []int
+	for num := range objects {
+		if canon[num] == num {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	renumber := make(map[int]int, len(objects))
+	for i, num := range nums {
+		renumber[num] = i + 1
+	}
+	for num := range objects {
+		renumber[num] = renumber[canon[num]]
+	}
+	remap := func(ref reader.Reference) reader.Reference {
+		return reader.Reference{Number: renumber[ref.Number]}
+	}
+
+	out := make([]reader.Object, len(nums))
+	for i, num := range nums {
+		out[i] = remapRefs(objects[num], remap)
+	}
+	return writeNewFile(w, doc.Version, out, remapRefs(trailer, remap).(reader.Dict))
+}
+
+// reachableObjects resolves every indirect object reachable from the
+// trailer's /Root and /Info entries, keyed by object number.
+func reachableObjects(doc *reader.Document) (map[int]reader.Object, error) {
+	objects := make(map[int]reader.Object)
+	var visit func(obj reader.Object) error
+	visit = func(obj reader.Object) error {
+		switch v := obj.(type) {
+		case reader.Reference:
+			if _, seen := objects[v.Number]; seen {
+				return nil
+			}
+			resolved, err := doc.ResolveReference(v)
+			if err != nil {
+				return err
+			}
+			if s, ok := resolved.(reader.Stream); ok {
+				if _, indirect := s.Dict["Length"].(reader.Reference); indirect {
+					return fmt.Errorf("object %d: indirect stream lengths are not supported", v.Number)
+				}
+			}
+			objects[v.Number] = resolved
+			return visit(resolved)
+		case reader.Array:
+			for _, item := range v {
+				if err := visit(item); err != nil {
+					return err
+				}
+			}
+		case reader.Dict:
+			for _, item := range v {
+				if err := visit(item); err != nil {
+					return err
+				}
+			}
+		case reader.Stream:
+			return visit(v.Dict)
+		}
+		return nil
+	}
+
+	trailer := doc.Trailer()
+	for _, key := range []reader.Name{"Root", "Info"} {
+		if err := visit(trailer[key]); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// compressStream returns s compressed with Flate if it has no filter and
+// compressing makes it smaller, and s unchanged otherwise.
+func compressStream(s reader.Stream) reader.Stream {
+	if _, filtered := s.Dict["Filter"]; filtered || s.Dict.GetName("Type") == "Metadata" {
+		return s
+	}
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zw.Write(s.Data)
+	zw.Close()
+	if buf.Len() >= len(s.Data) {
+		return s
+	}
+
+	dict := make(reader.Dict, len(s.Dict)+1)
+	for k, v := range s.Dict {
+		dict[k] = v
+	}
+	delete(dict, "DecodeParms")
+	dict["Filter"] = reader.Name("FlateDecode")
+	return reader.Stream{Dict: dict, Data: buf.Bytes()}
+}
+
+// mergeDuplicates finds objects with identical content and returns, for
+// each object number, the number of the object standing in for it. Merging
+// two objects can make the objects referring to them identical, so the
+// comparison is repeated until nothing more merges.
+func mergeDuplicates(objects map[int]reader.Object) map[int]int {
+	nums := make([]int, 0, len(objects))
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	canon := make(map[int]int, len(objects))
+	for _, num := range nums {
+		canon[num] = num
+	}
+	find := func(num int) int {
+		for canon[num] != num {
+			num = canon[num]
+		}
+		return num
+	}
+	remap := func(ref reader.Reference) reader.Reference {
+		if _, ok := canon[ref.Number]; !ok {
+			return ref
+		}
+		return reader.Reference{Number: find(ref.Number)}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		seen := make(map[[sha256.Size]byte]int)
+		for _, num := range nums {
+			if canon[num] != num || !mergeable(objects[num]) {
+				continue
+			}
+			var buf bytes.Buffer
+			reader.WriteObject(&buf, remapRefs(objects[num], remap))
+			sum := sha256.Sum256(buf.Bytes())
+			if first, ok := seen[sum]; ok {
+				canon[num] = first
+				changed = true
+				continue
+			}
+			seen[sum] = num
+		}
+	}
+
+	for _, num := range nums {
+		canon[num] = find(num)
+	}
+	return canon
+}
+
+// mergeable reports whether an object may be replaced by an identical one.
+// Objects that are nodes of a tree or are listed per page keep their
+// identity, since two equal entries would otherwise collapse into one.
+func mergeable(obj reader.Object) bool {
+	var dict reader.Dict
+	switch v := obj.(type) {
+	case reader.Stream:
+		dict = v.Dict
+	case reader.Dict:
+		dict = v
+	default:
+		return false
+	}
+	switch dict.GetName("Type") {
+	case "Catalog", "Pages", "Page", "Annot":
+		return false
+	}
+	// Annotations need not have a /Type, but always have a /Rect
+	for _, key := range []reader.Name{"Parent", "Rect"} {
+		if _, ok := dict[key]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// remapRefs returns a copy of obj with every reference replaced by
+// remap(ref).
+func remapRefs(obj reader.Object, remap func(reader.Reference) reader.Reference) reader.Object {
+	switch v := obj.(type) {
+	case reader.Reference:
+		return remap(v)
+	case reader.Array:
+		a := make(reader.Array, len(v))
+		for i, item := range v {
+			a[i] = remapRefs(item, remap)
+		}
+		return a
+	case reader.Dict:
+		d := make(reader.Dict, len(v))
+		for k, item := range v {
+			d[k] = remapRefs(item, remap)
+		}
+		return d
+	case reader.Stream:
+		return reader.Stream{Dict: remapRefs(v.Dict, remap).(reader.Dict), Data: v.Data}
+	}
+	return obj
+}
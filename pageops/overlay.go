@@ -0,0 +1,89 @@
+package pageops
+
+import (
+	"fmt"
+	"io"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// OverlayOptions controls how a stamp page is composited with a document.
+type OverlayOptions struct {
+	OnTop bool  // draw the stamp over the page content instead of under it
+	Pages []int // pages (1-based) to stamp; nil stamps every page
+}
+
+// Overlay draws the first page of the stamp PDF on pages of the base PDF and
+// writes the result to w: under the content, like a letterhead, or over it,
+// like a signature block. A stamp whose size differs from a base page is
+// scaled uniformly to fit the page and centered on it.
+func Overlay(w io.Writer, basePath, stampPath string, opts OverlayOptions) error {
+	pdf, err := buildOverlayPDF(basePath, stampPath, opts)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// OverlayToFile composites a stamp page with a document and saves the result
+// to a file. See Overlay.
+func OverlayToFile(basePath, stampPath, outputPath string, opts OverlayOptions) error {
+	pdf, err := buildOverlayPDF(basePath, stampPath, opts)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+func buildOverlayPDF(basePath, stampPath string, opts OverlayOptions) (*gofpdf.Fpdf, error) {
+	pageCount, err := getPageCount(basePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPages(opts.Pages, pageCount); err != nil {
+		return nil, err
+	}
+	if _, err := getPageCount(stampPath); err != nil {
+		return nil, err
+	}
+
+	stampPages := buildPageSet(opts.Pages, pageCount)
+	pdf, imp := newBasePDF()
+
+	// Template names are only unique within one importer, and only while
+	// each source is imported in one run, so the stamp goes first
+	stampID, sw, sh := importPage(pdf, imp, stampPath, 1)
+	if sw == 0 || sh == 0 {
+		sw = defaultPageWidth
+		sh = defaultPageHeight
+	}
+
+	for i := 1; i <= pageCount; i++ {
+		tplID, pw, ph := importPage(pdf, imp, basePath, i)
+		if pw == 0 || ph == 0 {
+			pw = defaultPageWidth
+			ph = defaultPageHeight
+		}
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pw, Ht: ph})
+
+		if !stampPages[i] {
+			imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+			continue
+		}
+		scale := min(pw/sw, ph/sh)
+		w, h := sw*scale, sh*scale
+		x, y := (pw-w)/2, (ph-h)/2
+		if opts.OnTop {
+			imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+			imp.UseImportedTemplate(pdf, stampID, x, y, w, h)
+		} else {
+			imp.UseImportedTemplate(pdf, stampID, x, y, w, h)
+			imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+		}
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: overlay: %w", pdf.Error())
+	}
+	return pdf, nil
+}
@@ -1,5 +1,6 @@
 // Package pageops provides operations for manipulating existing PDF documents,
-// including merging, splitting, watermarking, and rotating pages.
+// including merging, splitting, deleting, reordering and inserting pages,
+// watermarking, rotating pages, and recompressing images.
 //
 // It uses the reader package to parse input PDFs and the gofpdi contrib package
 // to import pages as templates into new PDF documents.
@@ -49,10 +50,26 @@ func addImportedPage(pdf *gofpdf.Fpdf, imp *gofpdi.Importer, sourceFile string,
 		ph = defaultPageHeight
 	}
 	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pw, Ht: ph})
-	imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+	useImportedPage(pdf, imp, tplID, pageNum, 0, 0, pw, ph)
 	return pw, ph
 }
 
+// useImportedPage draws page pageNum of the importer's current source,
+// imported as tplID, at x, y with size w, h. gofpdi gives every template of
+// a source the size of its first page, and scales templates by that size,
+// so the size passed to it is corrected for pages of another size. Such
+// pages are still clipped to the first page's box.
+func useImportedPage(pdf *gofpdf.Fpdf, imp *gofpdi.Importer, tplID, pageNum int, x, y, w, h float64) {
+	pw, ph := importedPageSize(imp, pageNum)
+	tw, th := importedPageSize(imp, 1)
+	if pw == 0 || ph == 0 || tw == 0 || th == 0 {
+		imp.UseImportedTemplate(pdf, tplID, x, y, w, h)
+		return
+	}
+	tplW, tplH := w*tw/pw, h*th/ph
+	imp.UseImportedTemplate(pdf, tplID, x, y+h-tplH, tplW, tplH)
+}
+
 // buildPageSet creates a map of selected page numbers.
 // If pages is nil, all pages 1..pageCount are selected.
 func buildPageSet(pages []int, pageCount int) map[int]bool {
@@ -69,10 +86,27 @@ func buildPageSet(pages []int, pageCount int) map[int]bool {
 	return m
 }
 
+// checkPages returns an error if a page number is outside 1..pageCount.
+func checkPages(pages []int, pageCount int) error {
+	for _, p := range pages {
+		if p < 1 || p > pageCount {
+			return fmt.Errorf("pageops: page %d out of range [1, %d]", p, pageCount)
+		}
+	}
+	return nil
+}
+
 // importPage imports a single page from a source file into the target PDF.
 // Returns the template ID and page dimensions.
 func importPage(pdf *gofpdf.Fpdf, imp *gofpdi.Importer, sourceFile string, pageNum int) (tplID int, w, h float64) {
 	tplID = imp.ImportPage(pdf, sourceFile, pageNum, "/MediaBox")
+	w, h = importedPageSize(imp, pageNum)
+	return
+}
+
+// importedPageSize returns the media box size of an imported page of the
+// importer's current source.
+func importedPageSize(imp *gofpdi.Importer, pageNum int) (w, h float64) {
 	sizes := imp.GetPageSizes()
 	if dims, ok := sizes[pageNum]; ok {
 		if mb, ok := dims["/MediaBox"]; ok {
@@ -2,12 +2,23 @@ package pageops_test
 
 import (
 	"bytes"
+	"compress/zlib"
 	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/form"
 	"github.com/lvillar/gofpdf/pageops"
 	"github.com/lvillar/gofpdf/reader"
 )
@@ -75,6 +86,198 @@ func TestMergeToWriter(t *testing.T) {
 	}
 }
 
+func TestMergeMetadata(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "doc1.pdf")
+	file2 := filepath.Join(dir, "doc2.pdf")
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Report – January", true)
+	pdf.SetAuthor("Finance", false)
+	pdf.AddPage()
+	if err := pdf.OutputFileAndClose(file1); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+	createTestPDF(t, file2, 1)
+
+	var buf bytes.Buffer
+	if err := pageops.Merge(&buf, file1, file2); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading merged PDF: %v", err)
+	}
+	meta := doc.Metadata()
+	if meta["Title"] != "Report – January" || meta["Author"] != "Finance" {
+		t.Errorf("expected the first file's metadata, got %v", meta)
+	}
+
+	// Each source keeps its own page template
+	doRe := regexp.MustCompile(`/(\w+) Do`)
+	var names []string
+	for _, page := range doc.Pages() {
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range doRe.FindAllSubmatch(content, -1) {
+			names = append(names, string(m[1]))
+		}
+	}
+	if len(names) != 2 || names[0] == names[1] {
+		t.Errorf("expected a distinct template per page, got %v", names)
+	}
+
+	buf.Reset()
+	opts := pageops.MergeOptions{Title: "Monthly reports"}
+	if err := pageops.MergeWithOptions(&buf, opts, file1, file2); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading merged PDF: %v", err)
+	}
+	meta = doc.Metadata()
+	if meta["Title"] != "Monthly reports" || meta["Author"] != "Finance" {
+		t.Errorf("expected the title to be overridden, got %v", meta)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "a.pdf")
+	file2 := filepath.Join(dir, "b.pdf")
+	file3 := filepath.Join(dir, "c.pdf")
+	createTestPDF(t, file1, 1)
+	createTestPDF(t, file2, 2)
+	createTestPDF(t, file3, 1)
+
+	var calls []string
+	record := func(done, total int) {
+		calls = append(calls, fmt.Sprintf("%d/%d", done, total))
+	}
+
+	opts := pageops.MergeOptions{Progress: record}
+	if err := pageops.MergeWithOptions(io.Discard, opts, file1, file2, file3); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if got, want := strings.Join(calls, " "), "1/3 2/3 3/3"; got != want {
+		t.Errorf("merge progress = %q, want %q", got, want)
+	}
+
+	calls = nil
+	wm := pageops.TextWatermark{Text: "DRAFT", Progress: record}
+	if err := pageops.AddTextWatermark(io.Discard, file2, wm); err != nil {
+		t.Fatalf("watermark: %v", err)
+	}
+	if got, want := strings.Join(calls, " "), "1/2 2/2"; got != want {
+		t.Errorf("watermark progress = %q, want %q", got, want)
+	}
+}
+
+func TestMergePreserveAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "form1.pdf")
+	file2 := filepath.Join(dir, "form2.pdf")
+
+	// The first form links from its cover to the page holding its field
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	link := pdf.AddLink()
+	pdf.AddPage()
+	pdf.Link(20, 20, 50, 10, link)
+	pdf.AddPage()
+	pdf.SetLink(link, 0, 2)
+	fb := form.NewFormBuilder(pdf)
+	fb.AddTextField("name", 2, 20, 40, 80, 10)
+	if err := fb.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pdf.OutputFileAndClose(file1); err != nil {
+		t.Fatalf("creating form PDF: %v", err)
+	}
+
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	fb = form.NewFormBuilder(pdf)
+	fb.AddTextField("name", 1, 20, 40, 80, 10)
+	if err := fb.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pdf.OutputFileAndClose(file2); err != nil {
+		t.Fatalf("creating form PDF: %v", err)
+	}
+
+	var buf bytes.Buffer
+	opts := pageops.MergeOptions{PreserveAnnotations: true}
+	if err := pageops.MergeWithOptions(&buf, opts, file1, file2); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading merged PDF: %v", err)
+	}
+	if doc.NumPages() != 3 {
+		t.Fatalf("expected 3 pages, got %d", doc.NumPages())
+	}
+
+	annots := func(n int) []reader.Dict {
+		page, _ := doc.Page(n)
+		obj, err := doc.ResolveReference(reader.Reference{Number: page.ObjNum})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dicts []reader.Dict
+		for _, item := range obj.(reader.Dict).GetArray("Annots") {
+			if ref, ok := item.(reader.Reference); ok {
+				item, _ = doc.ResolveReference(ref)
+			}
+			dicts = append(dicts, item.(reader.Dict))
+		}
+		return dicts
+	}
+	cover := annots(1)
+	if len(cover) != 1 || cover[0].GetName("Subtype") != "Link" {
+		t.Fatalf("expected a link on page 1, got %v", cover)
+	}
+	page2, _ := doc.Page(2)
+	if dest := cover[0].GetArray("Dest"); len(dest) == 0 || dest[0] != (reader.Reference{Number: page2.ObjNum}) {
+		t.Errorf("expected the link to point to merged page 2, got %v", dest)
+	}
+	for _, n := range []int{2, 3} {
+		if a := annots(n); len(a) != 1 || a[0].GetName("Subtype") != "Widget" {
+			t.Errorf("expected a widget on page %d, got %v", n, a)
+		}
+	}
+
+	fields, err := doc.FormFields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.FullName)
+	}
+	if strings.Join(names, ",") != "name,name_2" {
+		t.Fatalf("expected fields name and name_2, got %v", names)
+	}
+
+	// The merged packet can be filled like any form
+	var filled bytes.Buffer
+	err = form.Fill(bytes.NewReader(buf.Bytes()), &filled, map[string]string{"name": "Ada", "name_2": "Grace"})
+	if err != nil {
+		t.Fatalf("fill: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(filled.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := doc.FormField("name_2"); err != nil || f.Value != "Grace" {
+		t.Errorf("expected name_2 to be filled, got %v, %v", f, err)
+	}
+}
+
 func TestSplitToFiles(t *testing.T) {
 	dir := t.TempDir()
 	inputFile := filepath.Join(dir, "input.pdf")
@@ -102,6 +305,116 @@ func TestSplitToFiles(t *testing.T) {
 	t.Logf("Split into 3 individual page files")
 }
 
+func TestSplitByOutline(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "book.pdf")
+
+	// A cover page, then chapters of 2, 3 and 1 pages
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+	for _, chapter := range []struct {
+		title string
+		pages int
+	}{{"Chapter 1: Origins", 2}, {"Chapter 2/3", 3}, {"Épilogue", 1}} {
+		for i := 0; i < chapter.pages; i++ {
+			pdf.AddPage()
+			if i == 0 {
+				pdf.Bookmark(tr(chapter.title), 0, 0)
+			}
+		}
+	}
+	if err := pdf.OutputFileAndClose(inputFile); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	if err := pageops.SplitByOutline(inputFile, outputDir); err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	for name, pages := range map[string]int{
+		"01_Chapter 1_ Origins.pdf": 2,
+		"02_Chapter 2_3.pdf":        3,
+		"03_Épilogue.pdf":           1,
+	} {
+		doc, err := reader.Open(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if doc.NumPages() != pages {
+			t.Errorf("%s: expected %d pages, got %d", name, pages, doc.NumPages())
+		}
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 3 {
+		t.Errorf("expected 3 files, got %d", len(entries))
+	}
+
+	// Without an outline there is nothing to split on
+	plain := filepath.Join(dir, "plain.pdf")
+	createTestPDF(t, plain, 2)
+	if err := pageops.SplitByOutline(plain, outputDir); err == nil {
+		t.Error("expected an error for a document without an outline")
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createTestPDF(t, inputFile, 7)
+
+	// Room for about three pages per file
+	var buf bytes.Buffer
+	if err := pageops.ExtractPageRange(&buf, inputFile, 1, 3); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	maxBytes := int64(buf.Len())
+
+	outputDir := t.TempDir()
+	if err := pageops.SplitBySize(inputFile, outputDir, maxBytes); err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 3 {
+		t.Errorf("expected at least 3 files, got %d", len(entries))
+	}
+	total := 0
+	for i, entry := range entries {
+		if want := fmt.Sprintf("part_%03d.pdf", i+1); entry.Name() != want {
+			t.Errorf("file %d: expected %s, got %s", i+1, want, entry.Name())
+		}
+		info, _ := entry.Info()
+		if info.Size() > maxBytes {
+			t.Errorf("%s: %d bytes exceeds the %d byte limit", entry.Name(), info.Size(), maxBytes)
+		}
+		doc, err := reader.Open(filepath.Join(outputDir, entry.Name()))
+		if err != nil {
+			t.Fatalf("%s: %v", entry.Name(), err)
+		}
+		total += doc.NumPages()
+	}
+	if total != 7 {
+		t.Errorf("expected 7 pages in total, got %d", total)
+	}
+
+	// A limit smaller than any page still gives one page per file
+	outputDir = t.TempDir()
+	if err := pageops.SplitBySize(inputFile, outputDir, 1); err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 7 {
+		t.Errorf("expected 7 files, got %d", len(entries))
+	}
+
+	if err := pageops.SplitBySize(inputFile, outputDir, 0); err == nil {
+		t.Error("expected an error for a zero limit")
+	}
+}
+
 func TestExtractPages(t *testing.T) {
 	dir := t.TempDir()
 	inputFile := filepath.Join(dir, "input.pdf")
@@ -173,6 +486,117 @@ func TestAddTextWatermark(t *testing.T) {
 	t.Logf("Watermarked: orig=%d bytes, watermarked=%d bytes", origInfo.Size(), wmInfo.Size())
 }
 
+func TestAddImageWatermarkToPages(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createTestPDF(t, inputFile, 2)
+
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.RGBA{200, 0, 0, 255})
+		}
+	}
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, img); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+
+	var buf bytes.Buffer
+	wm := pageops.ImageWatermark{Data: logo.Bytes(), Opacity: 0.5, Angle: 30, Position: pageops.TopRight}
+	if err := pageops.AddImageWatermarkToPages(&buf, inputFile, wm, []int{2}); err != nil {
+		t.Fatalf("watermark: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading watermarked PDF: %v", err)
+	}
+	if doc.NumPages() != 2 {
+		t.Fatalf("expected 2 pages, got %d", doc.NumPages())
+	}
+	// Each page draws its imported template; the watermarked page also
+	// draws the image
+	for n, want := range map[int]int{1: 1, 2: 2} {
+		page, err := doc.Page(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("page %d content: %v", n, err)
+		}
+		if got := bytes.Count(content, []byte(" Do")); got != want {
+			t.Errorf("page %d draws %d XObjects, want %d", n, got, want)
+		}
+	}
+}
+
+func TestAddImageWatermarkRequiresImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.AddImageWatermark(&buf, "any.pdf", pageops.ImageWatermark{}); err == nil {
+		t.Error("expected error without an image")
+	}
+}
+
+func TestNUp(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createTestPDF(t, inputFile, 5)
+
+	var buf bytes.Buffer
+	if err := pageops.NUp(&buf, inputFile, 2, 2, "A4"); err != nil {
+		t.Fatalf("n-up: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading n-up PDF: %v", err)
+	}
+	if doc.NumPages() != 2 {
+		t.Fatalf("expected 2 sheets, got %d", doc.NumPages())
+	}
+	// The last sheet holds the fifth page only
+	for n, want := range map[int]int{1: 4, 2: 1} {
+		page, err := doc.Page(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatalf("sheet %d content: %v", n, err)
+		}
+		if got := bytes.Count(content, []byte(" Do")); got != want {
+			t.Errorf("sheet %d holds %d pages, want %d", n, got, want)
+		}
+	}
+
+	buf.Reset()
+	if err := pageops.NUp(&buf, inputFile, 2, 1, "Letter"); err != nil {
+		t.Fatalf("n-up: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading n-up PDF: %v", err)
+	}
+	if doc.NumPages() != 3 {
+		t.Fatalf("expected 3 sheets, got %d", doc.NumPages())
+	}
+	page, err := doc.Page(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box := page.MediaBox; box.Width() != 792 || box.Height() != 612 {
+		t.Errorf("expected a landscape Letter sheet, got %.2fx%.2f", box.Width(), box.Height())
+	}
+}
+
+func TestNUpInvalidGrid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.NUp(&buf, "any.pdf", 0, 2, "A4"); err == nil {
+		t.Error("expected error for an empty grid")
+	}
+}
+
 func TestAddPageNumbers(t *testing.T) {
 	dir := t.TempDir()
 	inputFile := filepath.Join(dir, "input.pdf")
@@ -198,30 +622,991 @@ func TestAddPageNumbers(t *testing.T) {
 	t.Logf("Page numbers added to %d pages", doc.NumPages())
 }
 
-func TestMergeNoInputs(t *testing.T) {
-	var buf bytes.Buffer
-	if err := pageops.Merge(&buf); err == nil {
-		t.Error("expected error for empty merge")
+func TestNumberStyle(t *testing.T) {
+	tests := []struct {
+		style pageops.NumberStyle
+		n     int
+		want  string
+	}{
+		{pageops.Arabic, 12, "12"},
+		{pageops.LowerRoman, 4, "iv"},
+		{pageops.UpperRoman, 1994, "MCMXCIV"},
+		{pageops.LowerAlpha, 3, "c"},
+		{pageops.UpperAlpha, 28, "BB"},
+		{pageops.UpperRoman, 0, "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.style.FormatNumber(tt.n); got != tt.want {
+			t.Errorf("style %d: FormatNumber(%d) = %q, want %q", tt.style, tt.n, got, tt.want)
+		}
 	}
 }
 
-func TestExtractPagesNoPages(t *testing.T) {
-	var buf bytes.Buffer
-	if err := pageops.ExtractPages(&buf, "nonexistent.pdf"); err == nil {
-		t.Error("expected error for no pages")
+func TestAddPageNumbersFrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	frontFile := filepath.Join(dir, "front.pdf")
+	outputFile := filepath.Join(dir, "numbered.pdf")
+	createTestPDF(t, inputFile, 5)
+
+	// Front matter i-iii, then body 1-2
+	front := pageops.PageNumberStyle{Format: "%s", Numbering: pageops.LowerRoman, SkipPages: []int{4, 5}}
+	if err := pageops.AddPageNumbersToFile(inputFile, frontFile, front); err != nil {
+		t.Fatalf("numbering front matter: %v", err)
+	}
+	body := pageops.PageNumberStyle{SkipPages: []int{1, 2, 3}}
+	if err := pageops.AddPageNumbersToFile(frontFile, outputFile, body); err != nil {
+		t.Fatalf("numbering body: %v", err)
+	}
+
+	// Each pass writes its numbers over the template of the previous one
+	for file, want := range map[string][]string{
+		frontFile:  {"i", "ii", "iii", "", ""},
+		outputFile: {"", "", "", "Page 1 of 2", "Page 2 of 2"},
+	} {
+		doc, err := reader.Open(file)
+		if err != nil {
+			t.Fatalf("reading numbered PDF: %v", err)
+		}
+		for i, w := range want {
+			page, err := doc.Page(i + 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := page.ExtractText()
+			if err != nil {
+				t.Fatalf("page %d text: %v", i+1, err)
+			}
+			if got := strings.TrimSpace(text); got != w {
+				t.Errorf("%s: page %d number = %q, want %q", filepath.Base(file), i+1, got, w)
+			}
+		}
 	}
 }
 
-func TestInvalidPageRange(t *testing.T) {
+func TestAddHeaderFooter(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createTestPDF(t, inputFile, 2)
+
 	var buf bytes.Buffer
-	if err := pageops.ExtractPageRange(&buf, "any.pdf", 5, 2); err == nil {
-		t.Error("expected error for invalid range")
+	err := pageops.AddHeaderFooter(&buf, inputFile, pageops.HeaderFooterOptions{
+		HeaderCenter: "CONFIDENTIAL",
+		FooterLeft:   "{date}",
+		FooterRight:  "{page}/{pages}",
+		Date:         time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("header and footer: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	for n := 1; n <= 2; n++ {
+		page, err := doc.Page(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := page.ExtractText()
+		if err != nil {
+			t.Fatalf("page %d text: %v", n, err)
+		}
+		for _, want := range []string{"CONFIDENTIAL", "2024-03-15", fmt.Sprintf("%d/2", n)} {
+			if !strings.Contains(text, want) {
+				t.Errorf("page %d: expected %q in %q", n, want, text)
+			}
+		}
 	}
 }
 
-func TestInvalidRotationAngle(t *testing.T) {
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.pdf")
+	stampFile := filepath.Join(dir, "stamp.pdf")
+	createTestPDF(t, baseFile, 2)
+
+	// A Letter stamp over A4 pages is scaled to fit
+	stamp := gofpdf.New("P", "pt", "Letter", "")
+	stamp.SetFont("Helvetica", "B", 20)
+	stamp.AddPage()
+	stamp.Text(40, 40, "LETTERHEAD")
+	if err := stamp.OutputFileAndClose(stampFile); err != nil {
+		t.Fatalf("creating stamp PDF: %v", err)
+	}
+
+	// The stamp is the template drawn scaled down to fit the A4 page
+	doRe := regexp.MustCompile(`([\d.]+) 0 0 [\d.]+ [\d.]+ [\d.]+ cm /\w+ Do`)
+	for _, onTop := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := pageops.Overlay(&buf, baseFile, stampFile, pageops.OverlayOptions{OnTop: onTop, Pages: []int{1}}); err != nil {
+			t.Fatalf("overlay: %v", err)
+		}
+		doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("reading result: %v", err)
+		}
+		if doc.NumPages() != 2 {
+			t.Fatalf("expected 2 pages, got %d", doc.NumPages())
+		}
+
+		var scales [][]string
+		for i := 1; i <= 2; i++ {
+			page, _ := doc.Page(i)
+			content, err := page.ContentStream()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s []string
+			for _, m := range doRe.FindAllSubmatch(content, -1) {
+				s = append(s, string(m[1]))
+			}
+			scales = append(scales, s)
+		}
+		if len(scales[1]) != 1 {
+			t.Errorf("onTop=%v: expected only the page template on page 2, got scales %v", onTop, scales[1])
+		}
+		want := []string{"0.9727", "1.0000"}
+		if onTop {
+			want[0], want[1] = want[1], want[0]
+		}
+		if strings.Join(scales[0], " ") != strings.Join(want, " ") {
+			t.Errorf("onTop=%v: page 1 template scales = %v, want %v", onTop, scales[0], want)
+		}
+	}
+
 	var buf bytes.Buffer
-	if err := pageops.RotatePages(&buf, "any.pdf", 45, nil); err == nil {
-		t.Error("expected error for invalid rotation angle")
+	if err := pageops.Overlay(&buf, baseFile, stampFile, pageops.OverlayOptions{Pages: []int{3}}); err == nil {
+		t.Error("expected an error for an out-of-range page")
+	}
+}
+
+func TestResizePages(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "mixed.pdf")
+
+	// An A4 page, a Letter page and a landscape A5 page
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddPage()
+	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: 612, Ht: 792})
+	pdf.AddPageFormat("L", pdf.GetPageSizeStr("A5"))
+	if err := pdf.OutputFileAndClose(inputFile); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+
+	doRe := regexp.MustCompile(`([\d.]+) 0 0 ([\d.]+) -?[\d.]+ -?[\d.]+ cm /\w+ Do`)
+	for fit, want := range map[string]string{
+		"fit":     "0.9727 0.9727",
+		"fill":    "1.0630 1.0630",
+		"stretch": "0.9727 1.0630",
+	} {
+		var buf bytes.Buffer
+		if err := pageops.ResizePages(&buf, inputFile, "A4", fit); err != nil {
+			t.Fatalf("%s: resize: %v", fit, err)
+		}
+		doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: reading result: %v", fit, err)
+		}
+		if doc.NumPages() != 3 {
+			t.Fatalf("%s: expected 3 pages, got %d", fit, doc.NumPages())
+		}
+		// Every page becomes A4, the landscape one in landscape
+		for i, w := range []int{595, 595, 841} {
+			page, _ := doc.Page(i + 1)
+			if got := int(page.MediaBox.Width()); got != w {
+				t.Errorf("%s: page %d width = %d, want %d", fit, i+1, got, w)
+			}
+		}
+
+		// The Letter page is scaled according to fit
+		page, _ := doc.Page(2)
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := doRe.FindSubmatch(content)
+		if m == nil {
+			t.Fatalf("%s: no template drawn on page 2: %q", fit, content)
+		}
+		if got := string(m[1]) + " " + string(m[2]); got != want {
+			t.Errorf("%s: page 2 scale = %s, want %s", fit, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.ResizePages(&buf, inputFile, "A4", "shrink"); err == nil {
+		t.Error("expected an error for an invalid fit")
+	}
+}
+
+func TestInsertPages(t *testing.T) {
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.pdf")
+	createTestPDF(t, baseFile, 3)
+
+	// The inserted pages are A5 so they can be told apart from the A4 base.
+	insert := gofpdf.New("P", "mm", "A5", "")
+	insert.SetFont("Helvetica", "", 14)
+	for i := 1; i <= 2; i++ {
+		insert.AddPage()
+		insert.Text(20, 30, fmt.Sprintf("Inserted %d", i))
+	}
+	var insertBuf bytes.Buffer
+	if err := insert.Output(&insertBuf); err != nil {
+		t.Fatalf("creating insert PDF: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.InsertPages(&buf, baseFile, &insertBuf, 1); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	if doc.NumPages() != 5 {
+		t.Fatalf("expected 5 pages, got %d", doc.NumPages())
+	}
+	// A4 is 595 points wide, A5 420.
+	want := []int{595, 420, 420, 595, 595}
+	doRe := regexp.MustCompile(`/(\w+) Do`)
+	seen := make(map[string]bool)
+	for i, w := range want {
+		page, _ := doc.Page(i + 1)
+		if got := int(page.MediaBox.Width()); got != w {
+			t.Errorf("page %d width = %d, want %d", i+1, got, w)
+		}
+		// Every page draws its own template
+		content, err := page.ContentStream()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := doRe.FindSubmatch(content)
+		if m == nil || seen[string(m[1])] {
+			t.Errorf("page %d does not draw a template of its own: %q", i+1, content)
+			continue
+		}
+		seen[string(m[1])] = true
+	}
+}
+
+func TestInsertPagesFromFile(t *testing.T) {
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.pdf")
+	insertFile := filepath.Join(dir, "insert.pdf")
+	createTestPDF(t, baseFile, 2)
+	createTestPDF(t, insertFile, 3)
+
+	var buf bytes.Buffer
+	if err := pageops.InsertPagesFromFile(&buf, baseFile, insertFile, 2); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	if doc.NumPages() != 5 {
+		t.Errorf("expected 5 pages, got %d", doc.NumPages())
+	}
+}
+
+// createSizedPDF generates a PDF whose page i is 100*i points wide, so that
+// pages can be told apart after they are moved.
+func createSizedPDF(t *testing.T, filename string, numPages int) {
+	t.Helper()
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	for i := 1; i <= numPages; i++ {
+		pdf.AddPageFormat("P", gofpdf.SizeType{Wd: float64(100 * i), Ht: 800})
+	}
+	if err := pdf.OutputFileAndClose(filename); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+}
+
+// pageWidths returns the width of each page of a PDF, in points.
+func pageWidths(t *testing.T, data []byte) []int {
+	t.Helper()
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	var widths []int
+	for _, page := range doc.Pages() {
+		widths = append(widths, int(page.MediaBox.Width()))
+	}
+	return widths
+}
+
+func TestDeletePages(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createSizedPDF(t, inputFile, 4)
+
+	var buf bytes.Buffer
+	if err := pageops.DeletePages(&buf, inputFile, []int{1, 3}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if got, want := fmt.Sprint(pageWidths(t, buf.Bytes())), "[200 400]"; got != want {
+		t.Errorf("page widths = %s, want %s", got, want)
+	}
+
+	for _, pages := range [][]int{nil, {5}, {0}, {1, 2, 3, 4}} {
+		if err := pageops.DeletePages(&buf, inputFile, pages); err == nil {
+			t.Errorf("expected error deleting pages %v", pages)
+		}
+	}
+}
+
+func TestReorderPages(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createSizedPDF(t, inputFile, 3)
+
+	var buf bytes.Buffer
+	if err := pageops.ReorderPages(&buf, inputFile, []int{3, 1, 2}); err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+	if got, want := fmt.Sprint(pageWidths(t, buf.Bytes())), "[300 100 200]"; got != want {
+		t.Errorf("page widths = %s, want %s", got, want)
+	}
+
+	for _, order := range [][]int{{1, 2}, {1, 1, 2}, {1, 2, 4}} {
+		if err := pageops.ReorderPages(&buf, inputFile, order); err == nil {
+			t.Errorf("expected error for order %v", order)
+		}
+	}
+}
+
+func TestInsertPagesInvalidPosition(t *testing.T) {
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.pdf")
+	createTestPDF(t, baseFile, 2)
+
+	var buf bytes.Buffer
+	if err := pageops.InsertPages(&buf, baseFile, bytes.NewReader(nil), 3); err == nil {
+		t.Error("expected error for insert position past the last page")
+	}
+}
+
+func TestMergeNoInputs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.Merge(&buf); err == nil {
+		t.Error("expected error for empty merge")
+	}
+}
+
+func TestExtractPagesNoPages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.ExtractPages(&buf, "nonexistent.pdf"); err == nil {
+		t.Error("expected error for no pages")
+	}
+}
+
+func TestInvalidPageRange(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.ExtractPageRange(&buf, "any.pdf", 5, 2); err == nil {
+		t.Error("expected error for invalid range")
+	}
+}
+
+func TestInvalidRotationAngle(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.RotatePages(&buf, "any.pdf", 45, nil); err == nil {
+		t.Error("expected error for invalid rotation angle")
+	}
+}
+
+var (
+	cmRe = regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+) (-?[\d.]+) (-?[\d.]+) (-?[\d.]+) (-?[\d.]+) cm`)
+	tdRe = regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+) Td \((\w+)\) Tj`)
+)
+
+// textPositions returns the position on a page of the text drawn with
+// Td/Tj by the imported page template, by applying the page's cm
+// transformations to the positions in the template.
+func textPositions(t *testing.T, doc *reader.Document, page *reader.Page) map[string][2]float64 {
+	t.Helper()
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatalf("page content: %v", err)
+	}
+	var matrices [][6]float64
+	for _, m := range cmRe.FindAllSubmatch(content, -1) {
+		var mat [6]float64
+		for i := range mat {
+			mat[i], _ = strconv.ParseFloat(string(m[i+1]), 64)
+		}
+		matrices = append(matrices, mat)
+	}
+
+	xobjects, _ := page.Resources["XObject"].(reader.Dict)
+	positions := make(map[string][2]float64)
+	for _, obj := range xobjects {
+		ref, _ := obj.(reader.Reference)
+		resolved, err := doc.ResolveReference(ref)
+		if err != nil {
+			t.Fatalf("resolving template: %v", err)
+		}
+		tpl := resolved.(reader.Stream)
+		data := tpl.Data
+		if tpl.Dict.GetName("Filter") == "FlateDecode" {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("template content: %v", err)
+			}
+			if data, err = io.ReadAll(zr); err != nil {
+				t.Fatalf("template content: %v", err)
+			}
+		}
+		for _, m := range tdRe.FindAllSubmatch(data, -1) {
+			x, _ := strconv.ParseFloat(string(m[1]), 64)
+			y, _ := strconv.ParseFloat(string(m[2]), 64)
+			// The innermost transformation applies first
+			for i := len(matrices) - 1; i >= 0; i-- {
+				a := matrices[i]
+				x, y = a[0]*x+a[2]*y+a[4], a[1]*x+a[3]*y+a[5]
+			}
+			positions[string(m[3])] = [2]float64{x, y}
+		}
+	}
+	return positions
+}
+
+func TestRotatePagesKeepsContentOnPage(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "landscape.pdf")
+	pdf := gofpdf.New("L", "pt", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.Text(20, 30, "TopLeft")
+	pdf.Text(780, 570, "BottomRight")
+	if err := pdf.OutputFileAndClose(input); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+
+	// Quadrant of the page where each text is expected after rotating
+	// clockwise: true for the right or top half
+	tests := []struct {
+		angle            int
+		wd, ht           float64
+		topLeft, btRight [2]bool
+	}{
+		{90, 595.28, 841.89, [2]bool{true, true}, [2]bool{false, false}},
+		{180, 841.89, 595.28, [2]bool{true, false}, [2]bool{false, true}},
+		{270, 595.28, 841.89, [2]bool{false, false}, [2]bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.angle), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := pageops.RotatePages(&buf, input, tt.angle, nil); err != nil {
+				t.Fatalf("rotate: %v", err)
+			}
+			doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("reading output: %v", err)
+			}
+			page, err := doc.Page(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			box := page.MediaBox
+			if box.Width() < tt.wd-0.01 || box.Width() > tt.wd+0.01 || box.Height() < tt.ht-0.01 || box.Height() > tt.ht+0.01 {
+				t.Fatalf("page box is %.2fx%.2f, want %.2fx%.2f", box.Width(), box.Height(), tt.wd, tt.ht)
+			}
+
+			positions := textPositions(t, doc, page)
+			for name, want := range map[string][2]bool{"TopLeft": tt.topLeft, "BottomRight": tt.btRight} {
+				pos, ok := positions[name]
+				if !ok {
+					t.Fatalf("text %q not found in %v", name, positions)
+				}
+				x, y := pos[0], pos[1]
+				if x < box.LLX || x > box.URX || y < box.LLY || y > box.URY {
+					t.Errorf("text %q at (%.2f, %.2f) is off the page %v", name, x, y, box)
+					continue
+				}
+				if got := [2]bool{x > box.Width()/2, y > box.Height()/2}; got != want {
+					t.Errorf("text %q at (%.2f, %.2f) is in quadrant %v, want %v", name, x, y, got, want)
+				}
+			}
+		})
+	}
+}
+
+// createImagePDF generates a PDF with a 2000x1500 pixel JPEG drawn 144x108
+// points wide on each of numPages pages.
+func createImagePDF(t *testing.T, filename string, numPages int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	for y := 0; y < 1500; y++ {
+		for x := 0; x < 2000; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * y), uint8(x + y), uint8(x ^ y), 255})
+		}
+	}
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	opt := gofpdf.ImageOptions{ImageType: "JPG"}
+	pdf.RegisterImageOptionsReader("photo", opt, &jpg)
+	for i := 0; i < numPages; i++ {
+		pdf.AddPage()
+		pdf.ImageOptions("photo", 72, 72, 144, 108, false, opt, 0, "")
+	}
+	if err := pdf.OutputFileAndClose(filename); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+}
+
+func TestRecompressImages(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "photo.pdf")
+	createImagePDF(t, input, 2)
+
+	var buf bytes.Buffer
+	if err := pageops.RecompressImages(&buf, input, 60, 150); err != nil {
+		t.Fatalf("recompress: %v", err)
+	}
+
+	original, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(original) {
+		t.Errorf("output is %d bytes, want less than the original %d", buf.Len(), len(original))
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if doc.NumPages() != 2 {
+		t.Errorf("expected 2 pages, got %d", doc.NumPages())
+	}
+	page, err := doc.Page(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := page.Images()
+	if err != nil {
+		t.Fatalf("images: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(images))
+	}
+	// 144x108 pt at 150 dpi is 300x225 pixels.
+	if images[0].Width != 300 || images[0].Height != 225 {
+		t.Errorf("image is %dx%d, want 300x225", images[0].Width, images[0].Height)
+	}
+}
+
+func TestRecompressImagesInvalidQuality(t *testing.T) {
+	var buf bytes.Buffer
+	if err := pageops.RecompressImages(&buf, "any.pdf", 0, 150); err == nil {
+		t.Error("expected error for invalid JPEG quality")
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.pdf")
+	merged := filepath.Join(dir, "merged.pdf")
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 14)
+	for i := 1; i <= 2; i++ {
+		pdf.AddPage()
+		pdf.Text(20, 280, fmt.Sprintf("Page %d", i))
+		pdf.MultiCell(0, 8, strings.Repeat("The same paragraph, over and over. ", 40), "", "L", false)
+	}
+	if err := pdf.OutputFileAndClose(src); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+	// Copies under other names are imported as separate sources
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copies []string
+	for i := 0; i < 4; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("copy%d.pdf", i))
+		if err := os.WriteFile(name, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		copies = append(copies, name)
+	}
+	if err := pageops.MergeFiles(merged, copies...); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.Optimize(&buf, merged); err != nil {
+		t.Fatalf("optimize: %v", err)
+	}
+
+	before, err := reader.Open(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	if after.NumPages() != 8 {
+		t.Fatalf("expected 8 pages, got %d", after.NumPages())
+	}
+	inUse := func(doc *reader.Document) int {
+		summary, err := doc.XRefSummary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, entries := range summary {
+			if entries[len(entries)-1].Type != "free" {
+				n++
+			}
+		}
+		return n
+	}
+	if n, m := inUse(before), inUse(after); m >= n {
+		t.Errorf("expected fewer objects, got %d from %d", m, n)
+	}
+	info, _ := os.Stat(merged)
+	if int64(buf.Len()) >= info.Size()/2 {
+		t.Errorf("expected the output to be less than half of %d bytes, got %d", info.Size(), buf.Len())
+	}
+
+	// The templates of the copies collapse into those of the first one
+	templates := make(map[reader.Reference]bool)
+	for _, page := range after.Pages() {
+		for _, v := range page.Resources.GetDict("XObject") {
+			templates[v.(reader.Reference)] = true
+		}
+		if page.Contents[0].Dict.GetName("Filter") != "FlateDecode" {
+			t.Errorf("page %d content is not compressed", page.Number)
+		}
+	}
+	if len(templates) != 2 {
+		t.Errorf("expected 2 distinct page templates, got %d", len(templates))
+	}
+}
+
+func TestCropPages(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	createTestPDF(t, inputFile, 3)
+
+	box := pageops.Rectangle{LLX: 36, LLY: 36, URX: 559, URY: 805}
+	var buf bytes.Buffer
+	if err := pageops.CropPages(&buf, inputFile, box, []int{1, 3}); err != nil {
+		t.Fatalf("crop: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	for n := 1; n <= 3; n++ {
+		page, _ := doc.Page(n)
+		if n == 2 {
+			if page.CropBox != nil {
+				t.Errorf("page 2: expected no crop box, got %v", *page.CropBox)
+			}
+			continue
+		}
+		if page.CropBox == nil || *page.CropBox != box {
+			t.Errorf("page %d: crop box = %v, want %v", n, page.CropBox, box)
+		}
+		if int(page.MediaBox.Width()) != 595 {
+			t.Errorf("page %d: media box changed to %v", n, page.MediaBox)
+		}
+	}
+
+	for _, bad := range []pageops.Rectangle{
+		{LLX: 0, LLY: 0, URX: 600, URY: 800}, // wider than A4
+		{LLX: 100, LLY: 100, URX: 100, URY: 200},
+	} {
+		if err := pageops.CropPages(&buf, inputFile, bad, nil); err == nil {
+			t.Errorf("expected an error for crop box %v", bad)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Quarterly Report", false)
+	pdf.SetAuthor("Finance", false)
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	pdf.Text(20, 30, "Confidential figures")
+	if err := pdf.OutputFileAndClose(inputFile); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.Encrypt(&buf, inputFile, "user", "owner", gofpdf.CnProtectPrint); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Quarterly Report")) {
+		t.Error("title left in plain text")
+	}
+	if _, err := reader.ReadFrom(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected an error opening without a password")
+	}
+	if _, err := reader.ReadFromWithPassword(bytes.NewReader(buf.Bytes()), "wrong"); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+
+	// Both passwords open the document, with every string and stream
+	// decrypted
+	for _, password := range []string{"user", "owner"} {
+		doc, err := reader.ReadFromWithPassword(bytes.NewReader(buf.Bytes()), password)
+		if err != nil {
+			t.Fatalf("%s password: %v", password, err)
+		}
+		meta := doc.Metadata()
+		if meta["Title"] != "Quarterly Report" || meta["Author"] != "Finance" {
+			t.Errorf("%s password: metadata = %v", password, meta)
+		}
+		page, _ := doc.Page(1)
+		text, err := page.ExtractText()
+		if err != nil {
+			t.Fatalf("%s password: page text: %v", password, err)
+		}
+		if !strings.Contains(text, "Confidential figures") {
+			t.Errorf("%s password: page text = %q", password, text)
+		}
+	}
+
+	// Without a user password anyone can open it
+	buf.Reset()
+	if err := pageops.Encrypt(&buf, inputFile, "", "", 0); err != nil {
+		t.Fatalf("encrypt without passwords: %v", err)
+	}
+	if _, err := reader.ReadFrom(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Errorf("opening without a user password: %v", err)
+	}
+	encrypted := filepath.Join(dir, "encrypted.pdf")
+	if err := os.WriteFile(encrypted, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pageops.Encrypt(&buf, encrypted, "user", "owner", 0); err == nil {
+		t.Error("expected an error encrypting an encrypted document")
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	plainFile := filepath.Join(dir, "plain.pdf")
+	createTestPDF(t, plainFile, 2)
+
+	// A document protected by gofpdf itself, and one encrypted by Encrypt
+	protected := filepath.Join(dir, "protected.pdf")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtection(gofpdf.CnProtectPrint, "user", "owner")
+	pdf.SetTitle("Protected", false)
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	pdf.Text(20, 30, "Page 1 of 1")
+	if err := pdf.OutputFileAndClose(protected); err != nil {
+		t.Fatalf("creating protected PDF: %v", err)
+	}
+	encrypted := filepath.Join(dir, "encrypted.pdf")
+	var buf bytes.Buffer
+	if err := pageops.Encrypt(&buf, plainFile, "user", "owner", 0); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err := os.WriteFile(encrypted, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		file, password string
+		pages          int
+	}{
+		{protected, "user", 1},
+		{protected, "owner", 1},
+		{encrypted, "owner", 2},
+	} {
+		outputFile := filepath.Join(dir, "decrypted.pdf")
+		if err := pageops.Decrypt(tc.file, tc.password, outputFile); err != nil {
+			t.Fatalf("%s with %s password: %v", filepath.Base(tc.file), tc.password, err)
+		}
+		doc, err := reader.Open(outputFile)
+		if err != nil {
+			t.Fatalf("%s with %s password: reading result: %v", filepath.Base(tc.file), tc.password, err)
+		}
+		if _, ok := doc.Trailer()["Encrypt"]; ok {
+			t.Errorf("%s: result is still encrypted", filepath.Base(tc.file))
+		}
+		if doc.NumPages() != tc.pages {
+			t.Errorf("%s: expected %d pages, got %d", filepath.Base(tc.file), tc.pages, doc.NumPages())
+		}
+		page, _ := doc.Page(1)
+		text, err := page.ExtractText()
+		if err != nil {
+			t.Fatalf("%s: page text: %v", filepath.Base(tc.file), err)
+		}
+		if want := fmt.Sprintf("Page 1 of %d", tc.pages); !strings.Contains(text, want) {
+			t.Errorf("%s: page text = %q, want %q", filepath.Base(tc.file), text, want)
+		}
+		if tc.file == protected && doc.Metadata()["Title"] != "Protected" {
+			t.Errorf("%s: metadata = %v", filepath.Base(tc.file), doc.Metadata())
+		}
+	}
+
+	err := pageops.Decrypt(protected, "wrong", filepath.Join(dir, "out.pdf"))
+	if err == nil || !strings.Contains(err.Error(), "invalid password") {
+		t.Errorf("expected an invalid password error, got %v", err)
+	}
+}
+
+func TestToGrayscale(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "color.pdf")
+
+	// A red PNG and a JPEG photo next to red and blue vector graphics
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = []byte{255, 0, 0, 255}[i%4]
+	}
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatal(err)
+	}
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pngOpt := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("red", pngOpt, &pngBuf)
+	pdf.AddPage()
+	pdf.SetFillColor(255, 0, 0)
+	pdf.Rect(72, 72, 100, 50, "F")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetTextColor(0, 0, 255)
+	pdf.Text(72, 200, "Blue text")
+	pdf.ImageOptions("red", 72, 300, 40, 40, false, pngOpt, 0, "")
+	if err := pdf.OutputFileAndClose(input); err != nil {
+		t.Fatalf("creating test PDF: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pageops.ToGrayscale(&buf, input); err != nil {
+		t.Fatalf("grayscale: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	page, _ := doc.Page(1)
+	content, err := page.ContentStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regexp.MustCompile(`\b(rg|RG|k|K)\b`).Match(content) {
+		t.Errorf("color operators left in %q", content)
+	}
+	for _, want := range []string{"0.299 g", "0.114 g"} {
+		if !bytes.Contains(content, []byte(want)) {
+			t.Errorf("expected %q in %q", want, content)
+		}
+	}
+	if text, _ := page.ExtractText(); !strings.Contains(text, "Blue text") {
+		t.Errorf("text lost: %q", text)
+	}
+
+	images, err := page.Images()
+	if err != nil || len(images) != 1 {
+		t.Fatalf("expected 1 image, got %d (%v)", len(images), err)
+	}
+	if images[0].ColorSpace != "DeviceGray" {
+		t.Fatalf("image color space = %s, want DeviceGray", images[0].ColorSpace)
+	}
+	pixels, err := images[0].Stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pixels) != 16 || pixels[0] != 76 {
+		t.Errorf("expected 16 gray pixels of 76, got %v", pixels)
+	}
+
+	// Imported pages are converted inside their templates
+	photo := filepath.Join(dir, "photo.pdf")
+	merged := filepath.Join(dir, "merged.pdf")
+	createImagePDF(t, photo, 1)
+	if err := pageops.MergeFiles(merged, input, photo); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	buf.Reset()
+	if err := pageops.ToGrayscale(&buf, merged); err != nil {
+		t.Fatalf("grayscale: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	for n, page := range doc.Pages() {
+		images, err := page.Images()
+		if err != nil || len(images) != 1 {
+			t.Fatalf("page %d: expected 1 image, got %d (%v)", n, len(images), err)
+		}
+		if images[0].ColorSpace != "DeviceGray" {
+			t.Errorf("page %d: image color space = %s, want DeviceGray", n, images[0].ColorSpace)
+		}
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "input.pdf")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Draft", false)
+	pdf.SetSubject("Old subject", false)
+	pdf.AddPage()
+	if err := pdf.OutputFileAndClose(inputFile); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	original, err := os.ReadFile(inputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = pageops.SetMetadata(&buf, inputFile, map[string]string{
+		"Title":   "Annual Report",
+		"Author":  "Acme Corp — Finanzas",
+		"Subject": "",
+	})
+	if err != nil {
+		t.Fatalf("set metadata: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), original) {
+		t.Error("expected the original bytes to be kept")
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading result: %v", err)
+	}
+	meta := doc.Metadata()
+	if meta["Title"] != "Annual Report" || meta["Author"] != "Acme Corp — Finanzas" {
+		t.Errorf("metadata = %v", meta)
+	}
+	if _, ok := meta["Subject"]; ok {
+		t.Errorf("expected the subject to be removed, got %q", meta["Subject"])
+	}
+	if meta["Producer"] == "" {
+		t.Error("expected the producer to be kept")
+	}
+
+	if err := pageops.SetMetadata(&buf, inputFile, map[string]string{"Color": "red"}); err == nil {
+		t.Error("expected an error for an unsupported field")
 	}
 }
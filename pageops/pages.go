@@ -0,0 +1,104 @@
+package pageops
+
+import (
+	"fmt"
+	"io"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// DeletePages removes the given pages (1-based) from a PDF and writes the
+// remaining pages to w.
+func DeletePages(w io.Writer, inputPath string, pages []int) error {
+	pdf, err := buildDeletedPDF(inputPath, pages)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// DeletePagesToFile removes the given pages and saves the result to a file.
+func DeletePagesToFile(inputPath, outputPath string, pages []int) error {
+	pdf, err := buildDeletedPDF(inputPath, pages)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+// ReorderPages writes the pages of a PDF to w in the given order. order
+// lists every page number (1-based) exactly once; for example, [5 1 2 3 4]
+// moves the fifth page of a five-page document to the front.
+func ReorderPages(w io.Writer, inputPath string, order []int) error {
+	pdf, err := buildReorderedPDF(inputPath, order)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// ReorderPagesToFile reorders the pages of a PDF and saves the result to a
+// file. See ReorderPages.
+func ReorderPagesToFile(inputPath, outputPath string, order []int) error {
+	pdf, err := buildReorderedPDF(inputPath, order)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+func buildDeletedPDF(inputPath string, pages []int) (*gofpdf.Fpdf, error) {
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("pageops: no pages specified")
+	}
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPages(pages, pageCount); err != nil {
+		return nil, err
+	}
+
+	deleted := buildPageSet(pages, pageCount)
+	if len(deleted) == pageCount {
+		return nil, fmt.Errorf("pageops: cannot delete all %d pages", pageCount)
+	}
+
+	pdf, imp := newBasePDF()
+	for i := 1; i <= pageCount; i++ {
+		if !deleted[i] {
+			addImportedPage(pdf, imp, inputPath, i)
+		}
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: delete: %w", pdf.Error())
+	}
+	return pdf, nil
+}
+
+func buildReorderedPDF(inputPath string, order []int) (*gofpdf.Fpdf, error) {
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(order) != pageCount {
+		return nil, fmt.Errorf("pageops: order lists %d pages, document has %d", len(order), pageCount)
+	}
+	if err := checkPages(order, pageCount); err != nil {
+		return nil, err
+	}
+	if seen := buildPageSet(order, pageCount); len(seen) != pageCount {
+		return nil, fmt.Errorf("pageops: order must list every page exactly once")
+	}
+
+	pdf, imp := newBasePDF()
+	for _, pageNum := range order {
+		addImportedPage(pdf, imp, inputPath, pageNum)
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: reorder: %w", pdf.Error())
+	}
+	return pdf, nil
+}
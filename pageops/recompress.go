@@ -0,0 +1,165 @@
+package pageops
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// RecompressImages reduces the size of the JPEG images in a PDF. Each
+// DCTDecode image in DeviceRGB or DeviceGray is re-encoded at jpegQuality
+// (1-100) and, if maxDPIForDrawnSize is positive, downsampled so that its
+// resolution at the largest size it is drawn on any page does not exceed
+// maxDPIForDrawnSize. An image is only replaced if the result is smaller.
+//
+// Only the affected image streams are rewritten: they are replaced in place
+// and a new cross-reference section is appended, leaving the bytes of every
+// other object as they were. If no image shrinks, the input is copied
+// unchanged. Encrypted documents are not supported.
+func RecompressImages(w io.Writer, inputPath string, jpegQuality int, maxDPIForDrawnSize float64) error {
+	if jpegQuality < 1 || jpegQuality > 100 {
+		return fmt.Errorf("pageops: JPEG quality must be between 1 and 100, got %d", jpegQuality)
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	if _, ok := doc.Trailer()["Encrypt"]; ok {
+		return fmt.Errorf("pageops: recompress: encrypted documents are not supported")
+	}
+
+	// Collect each image once, with the largest size it is drawn at.
+	var images []reader.ImageInfo
+	index := make(map[reader.Reference]int)
+	for _, page := range doc.Pages() {
+		pageImages, err := page.Images()
+		if err != nil {
+			return fmt.Errorf("pageops: recompress: %w", err)
+		}
+		for _, img := range pageImages {
+			if i, ok := index[img.Ref]; ok {
+				images[i].DrawnWidth = math.Max(images[i].DrawnWidth, img.DrawnWidth)
+				images[i].DrawnHeight = math.Max(images[i].DrawnHeight, img.DrawnHeight)
+				continue
+			}
+			index[img.Ref] = len(images)
+			images = append(images, img)
+		}
+	}
+
+	replaced := make(map[reader.Reference]reader.Stream)
+	for _, img := range images {
+		stream, ok := recompressImage(img, jpegQuality, maxDPIForDrawnSize)
+		if ok {
+			replaced[img.Ref] = stream
+		}
+	}
+
+	if len(replaced) == 0 {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("pageops: writing output: %w", err)
+		}
+		return nil
+	}
+	return writeReplacedObjects(w, doc, data, replaced)
+}
+
+// recompressImage re-encodes a single JPEG image. It reports false if the
+// image is not a plain DCTDecode RGB or gray image, cannot be decoded, or
+// would not get smaller.
+func recompressImage(img reader.ImageInfo, quality int, maxDPI float64) (reader.Stream, bool) {
+	if _, isName := img.Stream.Dict["Filter"].(reader.Name); !isName || img.Filter != "DCTDecode" {
+		return reader.Stream{}, false
+	}
+	if img.ColorSpace != "DeviceRGB" && img.ColorSpace != "DeviceGray" {
+		return reader.Stream{}, false
+	}
+
+	src, err := jpeg.Decode(bytes.NewReader(img.Stream.Data))
+	if err != nil {
+		return reader.Stream{}, false
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if maxDPI > 0 && img.DrawnWidth > 0 && img.DrawnHeight > 0 {
+		// Drawn sizes are in points, 72 per inch.
+		scale := math.Min(img.DrawnWidth/72*maxDPI/float64(w), img.DrawnHeight/72*maxDPI/float64(h))
+		if scale < 1 {
+			w = max(1, int(math.Round(float64(w)*scale)))
+			h = max(1, int(math.Round(float64(h)*scale)))
+		}
+	}
+	dst := downsample(src, w, h, img.ColorSpace == "DeviceGray")
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return reader.Stream{}, false
+	}
+	if buf.Len() >= len(img.Stream.Data) {
+		return reader.Stream{}, false
+	}
+
+	dict := make(reader.Dict, len(img.Stream.Dict))
+	for k, v := range img.Stream.Dict {
+		dict[k] = v
+	}
+	delete(dict, "DecodeParms")
+	dict["Width"] = reader.Integer(w)
+	dict["Height"] = reader.Integer(h)
+	dict["BitsPerComponent"] = reader.Integer(8)
+	return reader.Stream{Dict: dict, Data: buf.Bytes()}, true
+}
+
+// downsample scales src to w×h pixels by averaging the source pixels that
+// fall into each destination pixel. The result is gray if gray is true.
+// src is returned as is if it already has the requested size.
+func downsample(src image.Image, w, h int, gray bool) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if _, isGray := src.(*image.Gray); sw == w && sh == h && isGray == gray {
+		return src
+	}
+
+	var dst interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	if gray {
+		dst = image.NewGray(image.Rect(0, 0, w, h))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff,
+			})
+		}
+	}
+	return dst
+}
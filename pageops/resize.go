@@ -0,0 +1,91 @@
+package pageops
+
+import (
+	"fmt"
+	"io"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// ResizePages scales every page of a PDF to the named page size ("A4",
+// "Letter", "Legal", ...; default A4) and writes the result to w. The target
+// size is turned to landscape for landscape pages. fit selects how a page
+// whose aspect ratio differs from the target is scaled:
+//
+//   - "fit" (the default) scales it to fit inside the target, keeping its
+//     aspect ratio, and centers it, leaving blank margins.
+//   - "fill" scales it to cover the target, keeping its aspect ratio, and
+//     centers it, cropping what extends beyond the target.
+//   - "stretch" scales it to the target's width and height independently,
+//     distorting it.
+func ResizePages(w io.Writer, inputPath, targetSize string, fit string) error {
+	pdf, err := buildResizedPDF(inputPath, targetSize, fit)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+// ResizePagesToFile scales every page of a PDF to the named page size and
+// saves the result to a file. See ResizePages.
+func ResizePagesToFile(inputPath, outputPath, targetSize string, fit string) error {
+	pdf, err := buildResizedPDF(inputPath, targetSize, fit)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+func buildResizedPDF(inputPath, targetSize string, fit string) (*gofpdf.Fpdf, error) {
+	switch fit {
+	case "":
+		fit = "fit"
+	case "fit", "fill", "stretch":
+	default:
+		return nil, fmt.Errorf("pageops: invalid fit %q (must be fit, fill or stretch)", fit)
+	}
+	if targetSize == "" {
+		targetSize = "A4"
+	}
+
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, imp := newBasePDF()
+	target := pdf.GetPageSizeStr(targetSize)
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: resize: %w", pdf.Error())
+	}
+
+	for i := 1; i <= pageCount; i++ {
+		tplID, pw, ph := importPage(pdf, imp, inputPath, i)
+		if pw == 0 || ph == 0 {
+			pw = defaultPageWidth
+			ph = defaultPageHeight
+		}
+
+		size := target
+		if (pw > ph) != (size.Wd > size.Ht) {
+			size.Wd, size.Ht = size.Ht, size.Wd
+		}
+		pdf.AddPageFormat("P", size)
+
+		w, h := size.Wd, size.Ht
+		switch fit {
+		case "fit":
+			scale := min(size.Wd/pw, size.Ht/ph)
+			w, h = pw*scale, ph*scale
+		case "fill":
+			scale := max(size.Wd/pw, size.Ht/ph)
+			w, h = pw*scale, ph*scale
+		}
+		useImportedPage(pdf, imp, tplID, i, (size.Wd-w)/2, (size.Ht-h)/2, w, h)
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: resize: %w", pdf.Error())
+	}
+	return pdf, nil
+}
@@ -55,22 +55,23 @@ func buildRotatedPDF(inputPath string, angle int, pages []int) (*gofpdf.Fpdf, er
 				pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pw, Ht: ph})
 			}
 
+			// Rotating around the center of the square whose side is the source
+			// height (90) or width (270) maps the source page exactly onto the
+			// swapped page box
 			pdf.TransformBegin()
 			switch angle {
 			case 90:
-				pdf.TransformRotate(-90, 0, 0)
-				pdf.TransformTranslate(0, pw)
+				pdf.TransformRotate(-90, ph/2, ph/2)
 			case 180:
 				pdf.TransformRotate(-180, pw/2, ph/2)
 			case 270:
-				pdf.TransformRotate(-270, 0, 0)
-				pdf.TransformTranslate(ph, 0)
+				pdf.TransformRotate(-270, pw/2, pw/2)
 			}
-			imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+			useImportedPage(pdf, imp, tplID, i, 0, 0, pw, ph)
 			pdf.TransformEnd()
 		} else {
 			pdf.AddPageFormat("P", gofpdf.SizeType{Wd: pw, Ht: ph})
-			imp.UseImportedTemplate(pdf, tplID, 0, 0, pw, ph)
+			useImportedPage(pdf, imp, tplID, i, 0, 0, pw, ph)
 		}
 	}
 
@@ -5,6 +5,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lvillar/gofpdf/reader"
 )
 
 // SplitToFiles splits a PDF into individual pages, saving each to outputDir.
@@ -31,6 +36,175 @@ func SplitToFiles(inputPath, outputDir string) error {
 	return nil
 }
 
+// SplitByOutline splits a PDF into one file per top-level bookmark, such as
+// the chapters of a book, saving each to outputDir. A part spans from the
+// page of its bookmark to the page before the next one; pages before the
+// first bookmark are not written. Files are named after the bookmark
+// titles, numbered in page order: 01_Introduction.pdf, 02_Methods.pdf, etc.
+// Bookmarks without a destination page are skipped, as are bookmarks on the
+// same page as the previous one. It returns an error if the document has no
+// outline.
+func SplitByOutline(inputPath, outputDir string) error {
+	if info, err := os.Stat(outputDir); err != nil {
+		return fmt.Errorf("pageops: output directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("pageops: %s is not a directory", outputDir)
+	}
+
+	doc, err := reader.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("pageops: reading %s: %w", inputPath, err)
+	}
+	items, err := doc.Outlines()
+	if err != nil {
+		return fmt.Errorf("pageops: reading outline of %s: %w", inputPath, err)
+	}
+
+	var parts []*reader.OutlineItem
+	for _, item := range items {
+		if item.Page > 0 {
+			parts = append(parts, item)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("pageops: %s has no outline", inputPath)
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Page < parts[j].Page })
+
+	n := 0
+	for i, part := range parts {
+		if i > 0 && part.Page == parts[i-1].Page {
+			continue
+		}
+		end := doc.NumPages()
+		for _, next := range parts[i+1:] {
+			if next.Page > part.Page {
+				end = next.Page - 1
+				break
+			}
+		}
+
+		n++
+		name := fmt.Sprintf("%02d_%s.pdf", n, sanitizeFileName(part.Title))
+		pages := make([]int, 0, end-part.Page+1)
+		for p := part.Page; p <= end; p++ {
+			pages = append(pages, p)
+		}
+		if err := ExtractPagesToFile(inputPath, filepath.Join(outputDir, name), pages...); err != nil {
+			return fmt.Errorf("pageops: splitting %q: %w", part.Title, err)
+		}
+	}
+	return nil
+}
+
+// sanitizeFileName turns a title into a file name: characters that are not
+// letters, digits, spaces, dots, hyphens or underscores become underscores,
+// and the result is trimmed to at most 80 characters.
+func sanitizeFileName(title string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), strings.ContainsRune(" .-_", r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := []rune(b.String())
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	if s := strings.Trim(string(name), " ."); s != "" {
+		return s
+	}
+	return "untitled"
+}
+
+// SplitBySize splits a PDF into files of at most maxBytes each, such as for
+// an e-mail size limit, saving them to outputDir as part_001.pdf,
+// part_002.pdf, etc. Each file holds as many consecutive pages as fit,
+// measured by serializing them; a page that does not fit on its own is
+// written to a file by itself, which then exceeds maxBytes.
+func SplitBySize(inputPath, outputDir string, maxBytes int64) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("pageops: maximum size must be positive, got %d", maxBytes)
+	}
+	if info, err := os.Stat(outputDir); err != nil {
+		return fmt.Errorf("pageops: output directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("pageops: %s is not a directory", outputDir)
+	}
+
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return err
+	}
+	fits := func(start, end int) (bool, error) {
+		var w countingWriter
+		if err := ExtractPageRange(&w, inputPath, start, end); err != nil {
+			return false, err
+		}
+		return w.n <= maxBytes, nil
+	}
+
+	part := 0
+	for start := 1; start <= pageCount; {
+		// Grow the range exponentially while it fits, then narrow down the
+		// last page by bisection.
+		end, step := start, 1
+		for end+step <= pageCount {
+			ok, err := fits(start, end+step)
+			if err != nil {
+				return fmt.Errorf("pageops: splitting from page %d: %w", start, err)
+			}
+			if !ok {
+				break
+			}
+			end += step
+			step *= 2
+		}
+		lo, hi := end, min(end+step, pageCount+1) // lo fits, hi does not
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			ok, err := fits(start, mid)
+			if err != nil {
+				return fmt.Errorf("pageops: splitting from page %d: %w", start, err)
+			}
+			if ok {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+
+		part++
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("part_%03d.pdf", part))
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("pageops: creating %s: %w", outputPath, err)
+		}
+		err = ExtractPageRange(f, inputPath, start, lo)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("pageops: writing %s: %w", outputPath, err)
+		}
+		start = lo + 1
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to it and discards them.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
 // ExtractPages extracts specific pages from a PDF and writes them to w.
 // Page numbers are 1-based.
 func ExtractPages(w io.Writer, inputPath string, pages ...int) error {
@@ -1,8 +1,11 @@
 package pageops
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	gofpdf "github.com/lvillar/gofpdf"
 )
@@ -14,6 +17,10 @@ type TextWatermark struct {
 	Color    RGBColor // text color (default: light gray)
 	Opacity  float64  // 0.0 to 1.0 (default: 0.3)
 	Angle    float64  // rotation angle in degrees (default: 45)
+
+	// Progress, if set, is called after each page is copied, with the
+	// number of pages done so far and the page count.
+	Progress func(done, total int)
 }
 
 // RGBColor represents an RGB color value.
@@ -78,6 +85,9 @@ func buildWatermarkedPDF(inputPath string, wm TextWatermark, pages []int) (*gofp
 		if watermarkPages[i] {
 			drawTextWatermark(pdf, wm, pw, ph)
 		}
+		if wm.Progress != nil {
+			wm.Progress(i, pageCount)
+		}
 	}
 
 	if pdf.Err() {
@@ -104,6 +114,132 @@ func drawTextWatermark(pdf *gofpdf.Fpdf, wm TextWatermark, pageW, pageH float64)
 	pdf.SetAlpha(1.0, "Normal")
 }
 
+// ImageWatermark defines an image-based watermark, such as a logo or a
+// scanned stamp.
+type ImageWatermark struct {
+	Path     string   // JPEG, PNG or GIF file; ignored if Data is set
+	Data     []byte   // JPEG, PNG or GIF image data
+	Opacity  float64  // 0.0 to 1.0 (default: 0.3)
+	Scale    float64  // image width as a fraction of the page width (default: 0.5)
+	Position Position // where to place the image (default: Center)
+	Angle    float64  // rotation angle in degrees around the image center
+	Margin   float64  // margin from the page edges in points (default: 30)
+}
+
+// AddImageWatermark adds an image watermark to all pages of a PDF.
+func AddImageWatermark(w io.Writer, inputPath string, wm ImageWatermark) error {
+	return AddImageWatermarkToPages(w, inputPath, wm, nil)
+}
+
+// AddImageWatermarkToFile adds an image watermark and saves to a file.
+func AddImageWatermarkToFile(inputPath, outputPath string, wm ImageWatermark) error {
+	pdf, err := buildImageWatermarkedPDF(inputPath, wm, nil)
+	if err != nil {
+		return err
+	}
+	return writePDFToFile(pdf, outputPath)
+}
+
+// AddImageWatermarkToPages adds an image watermark to specific pages
+// (1-based). If pages is nil, the watermark is applied to all pages.
+func AddImageWatermarkToPages(w io.Writer, inputPath string, wm ImageWatermark, pages []int) error {
+	pdf, err := buildImageWatermarkedPDF(inputPath, wm, pages)
+	if err != nil {
+		return err
+	}
+	return writePDF(pdf, w)
+}
+
+func imageWatermarkDefaults(wm ImageWatermark) ImageWatermark {
+	if wm.Opacity == 0 {
+		wm.Opacity = 0.3
+	}
+	if wm.Scale == 0 {
+		wm.Scale = 0.5
+	}
+	if wm.Margin == 0 {
+		wm.Margin = 30
+	}
+	return wm
+}
+
+func buildImageWatermarkedPDF(inputPath string, wm ImageWatermark, pages []int) (*gofpdf.Fpdf, error) {
+	if wm.Path == "" && len(wm.Data) == 0 {
+		return nil, fmt.Errorf("pageops: image watermark requires Path or Data")
+	}
+	wm = imageWatermarkDefaults(wm)
+
+	pageCount, err := getPageCount(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	watermarkPages := buildPageSet(pages, pageCount)
+	pdf, imp := newBasePDF()
+
+	name := wm.Path
+	var info *gofpdf.ImageInfoType
+	if len(wm.Data) > 0 {
+		name = "watermark"
+		opt := gofpdf.ImageOptions{ImageType: pdf.ImageTypeFromMime(http.DetectContentType(wm.Data))}
+		info = pdf.RegisterImageOptionsReader(name, opt, bytes.NewReader(wm.Data))
+	} else {
+		info = pdf.RegisterImageOptions(name, gofpdf.ImageOptions{})
+	}
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: watermark image: %w", pdf.Error())
+	}
+
+	for i := 1; i <= pageCount; i++ {
+		pw, ph := addImportedPage(pdf, imp, inputPath, i)
+
+		if watermarkPages[i] {
+			drawImageWatermark(pdf, wm, name, info, pw, ph)
+		}
+	}
+
+	if pdf.Err() {
+		return nil, fmt.Errorf("pageops: watermark: %w", pdf.Error())
+	}
+	return pdf, nil
+}
+
+// drawImageWatermark draws the registered watermark image on the current
+// page, scaled to a fraction of the page width.
+func drawImageWatermark(pdf *gofpdf.Fpdf, wm ImageWatermark, name string, info *gofpdf.ImageInfoType, pageW, pageH float64) {
+	w := pageW * wm.Scale
+	h := w * info.Height() / info.Width()
+	x, y := imagePosition(wm.Position, pageW, pageH, w, h, wm.Margin)
+
+	pdf.SetAlpha(wm.Opacity, "Normal")
+	pdf.TransformBegin()
+	pdf.TransformRotate(wm.Angle, x+w/2, y+h/2)
+	pdf.ImageOptions(name, x, y, w, h, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.TransformEnd()
+	pdf.SetAlpha(1.0, "Normal")
+}
+
+// imagePosition returns the top-left corner of a w×h box placed on the
+// page.
+func imagePosition(pos Position, pageW, pageH, w, h, margin float64) (x, y float64) {
+	switch pos {
+	case TopLeft:
+		return margin, margin
+	case TopCenter:
+		return (pageW - w) / 2, margin
+	case TopRight:
+		return pageW - w - margin, margin
+	case BottomLeft:
+		return margin, pageH - h - margin
+	case BottomCenter:
+		return (pageW - w) / 2, pageH - h - margin
+	case BottomRight:
+		return pageW - w - margin, pageH - h - margin
+	default: // Center
+		return (pageW - w) / 2, (pageH - h) / 2
+	}
+}
+
 // AddPageNumbers adds page numbers to all pages of a PDF.
 func AddPageNumbers(w io.Writer, inputPath string, style PageNumberStyle) error {
 	pdf, err := buildPageNumberedPDF(inputPath, style)
@@ -123,12 +259,19 @@ func AddPageNumbersToFile(inputPath, outputPath string, style PageNumberStyle) e
 }
 
 // PageNumberStyle defines the appearance and position of page numbers.
+//
+// Numbering counts the pages that are not skipped, starting at StartAt, so
+// front matter can be numbered i, ii, iii by skipping the body, and the body
+// 1, 2, 3 by skipping the front matter in a second pass.
 type PageNumberStyle struct {
-	Format   string   // fmt format string, e.g. "Page %d of %d" (receives pageNum, totalPages)
-	Position Position // where to place the number (default: BottomCenter)
-	FontSize float64  // font size in points (default: 10)
-	Color    RGBColor // text color (default: black)
-	Margin   float64  // margin from page edge in points (default: 30)
+	Format    string      // fmt format string, e.g. "Page %d of %d" (receives pageNum, totalPages)
+	Position  Position    // where to place the number (default: BottomCenter)
+	FontSize  float64     // font size in points (default: 10)
+	Color     RGBColor    // text color (default: black)
+	Margin    float64     // margin from page edge in points (default: 30)
+	Numbering NumberStyle // how numbers are written (default: Arabic); %d and %s both work
+	StartAt   int         // number of the first numbered page (default: 1)
+	SkipPages []int       // pages (1-based) left unnumbered and not counted
 }
 
 func buildPageNumberedPDF(inputPath string, style PageNumberStyle) (*gofpdf.Fpdf, error) {
@@ -141,18 +284,39 @@ func buildPageNumberedPDF(inputPath string, style PageNumberStyle) (*gofpdf.Fpdf
 	if style.Margin == 0 {
 		style.Margin = 30
 	}
+	if style.StartAt == 0 {
+		style.StartAt = 1
+	}
 
 	pageCount, err := getPageCount(inputPath)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkPages(style.SkipPages, pageCount); err != nil {
+		return nil, err
+	}
+	skip := make(map[int]bool)
+	for _, p := range style.SkipPages {
+		skip[p] = true
+	}
+	// The total is the last number written
+	total := pageNumber{style.StartAt + pageCount - len(skip) - 1, style.Numbering}
 
 	pdf, imp := newBasePDF()
 
+	number := style.StartAt
 	for i := 1; i <= pageCount; i++ {
 		pw, ph := addImportedPage(pdf, imp, inputPath, i)
+		if skip[i] {
+			continue
+		}
 
-		text := fmt.Sprintf(style.Format, i, pageCount)
+		args := []any{pageNumber{number, style.Numbering}, total}
+		if strings.Count(strings.ReplaceAll(style.Format, "%%", ""), "%") < 2 {
+			args = args[:1] // a format showing only the page number
+		}
+		text := fmt.Sprintf(style.Format, args...)
+		number++
 		pdf.SetFont("Helvetica", "", style.FontSize)
 		pdf.SetTextColor(style.Color.R, style.Color.G, style.Color.B)
 
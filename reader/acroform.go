@@ -18,6 +18,22 @@ type FormField struct {
 	Kids     []*FormField  // child fields in hierarchy
 	ObjNum   int           // object number if from an indirect object
 	dict     Dict          // original field dictionary
+
+	// DefaultAppearance is the default appearance string (/DA), such as
+	// "/Helv 12 Tf 0 g", giving the font and color of variable text.
+	DefaultAppearance string
+	// Quadding is the justification of variable text (/Q): 0 left,
+	// 1 centered, 2 right.
+	Quadding int
+	// Tooltip is the alternate field name (/TU), shown as a tooltip and
+	// used by screen readers.
+	Tooltip string
+	// Values lists the selected options of a multi-select choice field
+	// whose /V is an array. Value then holds the first of them.
+	Values []string
+	// OnState is the name of the checked appearance state of a checkbox,
+	// such as "Yes" or "On", taken from its widget's /AP /N dictionary.
+	OnState string
 }
 
 // IsReadOnly returns true if the field has the ReadOnly flag set (bit 1).
@@ -26,6 +42,14 @@ func (f *FormField) IsReadOnly() bool { return f.Flags&1 != 0 }
 // IsRequired returns true if the field has the Required flag set (bit 2).
 func (f *FormField) IsRequired() bool { return f.Flags&2 != 0 }
 
+// IsMultiSelect returns true if the field is a choice field that allows
+// several options to be selected (bit 22).
+func (f *FormField) IsMultiSelect() bool { return f.Flags&(1<<21) != 0 }
+
+// IsRadio returns true if the field is a radio button group, a button field
+// with the Radio flag set (bit 16).
+func (f *FormField) IsRadio() bool { return f.Flags&(1<<15) != 0 }
+
 // Catalog returns the document's catalog dictionary (the /Root object).
 func (d *Document) Catalog() (Dict, error) {
 	rootObj, ok := d.trailer["Root"]
@@ -159,9 +183,18 @@ func (d *Document) parseFormField(obj Object, parentName string) (*FormField, er
 		field.Type = string(ft)
 	}
 
-	// Value (/V)
+	// Value (/V), an array for multi-select choice fields
 	if v, ok := dict["V"]; ok {
-		field.Value = objectToString(v)
+		if arr := d.resolveArray(v); arr != nil {
+			for _, item := range arr {
+				field.Values = append(field.Values, objectToString(item))
+			}
+			if len(field.Values) > 0 {
+				field.Value = field.Values[0]
+			}
+		} else {
+			field.Value = objectToString(v)
+		}
 	}
 
 	// Default value (/DV)
@@ -169,6 +202,18 @@ func (d *Document) parseFormField(obj Object, parentName string) (*FormField, er
 		field.Default = objectToString(dv)
 	}
 
+	// Default appearance (/DA)
+	if da, ok := dict["DA"].(String); ok {
+		field.DefaultAppearance = decodePDFString(da.Value)
+	}
+
+	if q, ok := dict.GetInt("Q"); ok {
+		field.Quadding = int(q)
+	}
+	if tu, ok := dict["TU"].(String); ok {
+		field.Tooltip = decodePDFString(tu.Value)
+	}
+
 	// Field flags (/Ff)
 	if ff, ok := dict.GetInt("Ff"); ok {
 		field.Flags = int(ff)
@@ -216,9 +261,38 @@ func (d *Document) parseFormField(obj Object, parentName string) (*FormField, er
 		}
 	}
 
+	// Radio button options are the on states of the kid widgets
+	if field.Type == "Btn" && field.IsRadio() && len(field.Options) == 0 {
+		for _, kid := range field.Kids {
+			if state := d.onState(kid.dict); state != "" {
+				field.Options = append(field.Options, state)
+			}
+		}
+	}
+
+	// A checkbox is either merged with its widget or has widget kids
+	if field.Type == "Btn" && !field.IsRadio() && field.Flags&(1<<16) == 0 {
+		field.OnState = d.onState(dict)
+		if field.OnState == "" && len(field.Kids) > 0 {
+			field.OnState = d.onState(field.Kids[0].dict)
+		}
+	}
+
 	return field, nil
 }
 
+// onState returns the name of the "on" appearance state of a button widget,
+// the key of its /AP /N dictionary other than /Off.
+func (d *Document) onState(widget Dict) string {
+	ap := d.resolveDict(widget["AP"])
+	for name := range d.resolveDict(ap["N"]) {
+		if name != "Off" {
+			return string(name)
+		}
+	}
+	return ""
+}
+
 // objectToString converts a PDF object to its string representation for field values.
 func objectToString(obj Object) string {
 	switch v := obj.(type) {
@@ -132,15 +132,8 @@ func (d *Document) decrypt(password string) error {
 // computeEncryptionKey implements Algorithm 2 from the PDF spec.
 // Computes the encryption key from the user password.
 func computeEncryptionKey(password []byte, info *encryptInfo) []byte {
-	// Pad or truncate password to 32 bytes
-	padded := make([]byte, 32)
-	copy(padded, password)
-	if len(password) < 32 {
-		copy(padded[len(password):], pdfPadding[:32-len(password)])
-	}
-
 	h := md5.New()
-	h.Write(padded)
+	h.Write(padPassword(password))
 	h.Write(info.ownerHash)
 
 	// Permission bytes (little-endian)
@@ -166,15 +159,26 @@ func computeEncryptionKey(password []byte, info *encryptInfo) []byte {
 // validateUserPassword checks if the computed key matches the /U value.
 // Algorithm 6 (R=2) or Algorithm 7 (R=3+).
 func validateUserPassword(key []byte, info *encryptInfo) bool {
+	computed := computeUserHash(key, info)
+	if info.revision == 2 {
+		return bytesEqual(computed, info.userHash)
+	}
+	// R >= 3: only the first 16 bytes are significant
+	if len(info.userHash) < 16 {
+		return false
+	}
+	return bytesEqual(computed[:16], info.userHash[:16])
+}
+
+// computeUserHash computes the /U value for an encryption key.
+// Algorithm 4 (R=2) or Algorithm 5 (R=3+).
+func computeUserHash(key []byte, info *encryptInfo) []byte {
 	if info.revision == 2 {
 		// Algorithm 4: encrypt padding with key
-		c, err := rc4.NewCipher(key)
-		if err != nil {
-			return false
-		}
 		computed := make([]byte, 32)
+		c, _ := rc4.NewCipher(key)
 		c.XORKeyStream(computed, pdfPadding)
-		return bytesEqual(computed, info.userHash)
+		return computed
 	}
 
 	// R >= 3: Algorithm 5
@@ -183,52 +187,31 @@ func validateUserPassword(key []byte, info *encryptInfo) bool {
 	h.Write(info.fileID)
 	digest := h.Sum(nil)
 
-	// RC4 encrypt with key
-	c, err := rc4.NewCipher(key)
-	if err != nil {
-		return false
-	}
-	c.XORKeyStream(digest, digest)
+	// RC4 encrypt with key, then 19 additional passes with modified keys
+	rc4Passes(key, digest, 0, 19)
 
-	// 19 additional RC4 passes with modified keys
-	newKey := make([]byte, len(key))
-	for i := 1; i <= 19; i++ {
-		for j := range key {
-			newKey[j] = key[j] ^ byte(i)
-		}
-		c, err = rc4.NewCipher(newKey)
-		if err != nil {
-			return false
-		}
-		c.XORKeyStream(digest, digest)
-	}
+	// Pad to 32 bytes with arbitrary data
+	return append(digest, pdfPadding[:16]...)
+}
 
-	// Compare first 16 bytes
-	if len(info.userHash) < 16 || len(digest) < 16 {
-		return false
+// computeOwnerHash computes the /O value from the owner and user
+// passwords. Algorithm 3 from the PDF spec.
+func computeOwnerHash(ownerPass, userPass []byte, info *encryptInfo) []byte {
+	hash := padPassword(userPass)
+	key := ownerKey(ownerPass, info)
+	if info.revision == 2 {
+		c, _ := rc4.NewCipher(key)
+		c.XORKeyStream(hash, hash)
+	} else {
+		rc4Passes(key, hash, 0, 19)
 	}
-	return bytesEqual(digest[:16], info.userHash[:16])
+	return hash
 }
 
 // recoverUserPassFromOwner recovers the user password from the owner password.
 // Algorithm 7 from the PDF spec.
 func recoverUserPassFromOwner(ownerPass []byte, info *encryptInfo) []byte {
-	padded := make([]byte, 32)
-	copy(padded, ownerPass)
-	if len(ownerPass) < 32 {
-		copy(padded[len(ownerPass):], pdfPadding[:32-len(ownerPass)])
-	}
-
-	digest := md5.Sum(padded)
-
-	if info.revision >= 3 {
-		for i := 0; i < 50; i++ {
-			tmp := md5.Sum(digest[:])
-			digest = tmp
-		}
-	}
-
-	key := digest[:info.keyLength]
+	key := ownerKey(ownerPass, info)
 
 	userPass := make([]byte, len(info.ownerHash))
 	copy(userPass, info.ownerHash)
@@ -238,30 +221,62 @@ func recoverUserPassFromOwner(ownerPass []byte, info *encryptInfo) []byte {
 		c.XORKeyStream(userPass, userPass)
 	} else {
 		// R >= 3: 20 RC4 passes in reverse
-		newKey := make([]byte, len(key))
-		for i := 19; i >= 0; i-- {
-			for j := range key {
-				newKey[j] = key[j] ^ byte(i)
-			}
-			c, _ := rc4.NewCipher(newKey)
-			c.XORKeyStream(userPass, userPass)
-		}
+		rc4Passes(key, userPass, 19, 0)
 	}
 
 	return userPass
 }
 
-// makeObjectCipher creates an RC4 cipher for decrypting strings/streams
-// in the given object. The cipher state must be maintained across all
-// strings in the same object because gofpdf reuses it during encryption.
-func (d *Document) makeObjectCipher(objNum, genNum int) *rc4.Cipher {
-	if d.encrypt == nil || d.encrypt.key == nil {
-		return nil
+// ownerKey computes the RC4 key that encrypts the /O value from the owner
+// password (steps a-d of Algorithm 3).
+func ownerKey(ownerPass []byte, info *encryptInfo) []byte {
+	digest := md5.Sum(padPassword(ownerPass))
+
+	if info.revision >= 3 {
+		for i := 0; i < 50; i++ {
+			digest = md5.Sum(digest[:])
+		}
 	}
 
+	return digest[:info.keyLength]
+}
+
+// rc4Passes encrypts data in place with key XORed with each value from
+// first to last, counting up or down. RC4 being symmetric, the reverse
+// sequence of passes decrypts.
+func rc4Passes(key, data []byte, first, last int) {
+	step := 1
+	if last < first {
+		step = -1
+	}
+	newKey := make([]byte, len(key))
+	for i := first; ; i += step {
+		for j := range key {
+			newKey[j] = key[j] ^ byte(i)
+		}
+		c, _ := rc4.NewCipher(newKey)
+		c.XORKeyStream(data, data)
+		if i == last {
+			return
+		}
+	}
+}
+
+// padPassword pads or truncates a password to 32 bytes (step a of
+// Algorithm 2).
+func padPassword(password []byte) []byte {
+	padded := make([]byte, 32)
+	n := copy(padded, password)
+	copy(padded[n:], pdfPadding)
+	return padded
+}
+
+// objectKey computes the RC4 key for the strings and streams of the given
+// object from the file key (Algorithm 1).
+func objectKey(fileKey []byte, objNum, genNum int) []byte {
 	// Per-object key: MD5(fileKey + objNum(3 bytes LE) + genNum(2 bytes LE))
 	var buf []byte
-	buf = append(buf, d.encrypt.key...)
+	buf = append(buf, fileKey...)
 
 	var objBuf [4]byte
 	binary.LittleEndian.PutUint32(objBuf[:], uint32(objNum))
@@ -272,13 +287,81 @@ func (d *Document) makeObjectCipher(objNum, genNum int) *rc4.Cipher {
 	buf = append(buf, genBuf[0], genBuf[1])
 
 	hash := md5.Sum(buf)
-	keyLen := len(d.encrypt.key) + 5
-	if keyLen > 16 {
-		keyLen = 16
+	return hash[:min(len(fileKey)+5, 16)]
+}
+
+// Encryptor encrypts the objects of a document with the standard security
+// handler, using RC4 with a 128-bit key (revision 3). Documents it encrypts
+// can be opened with OpenWithPassword.
+type Encryptor struct {
+	info *encryptInfo
+}
+
+// NewEncryptor derives the encryption key of a document from the user and
+// owner passwords, the /P permission flags and the file ID, the first
+// element of the trailer's /ID array.
+func NewEncryptor(userPass, ownerPass string, permissions int32, fileID []byte) *Encryptor {
+	info := &encryptInfo{
+		version:     2,
+		revision:    3,
+		keyLength:   16,
+		permissions: permissions,
+		fileID:      fileID,
+	}
+	info.ownerHash = computeOwnerHash([]byte(ownerPass), []byte(userPass), info)
+	info.key = computeEncryptionKey([]byte(userPass), info)
+	info.userHash = computeUserHash(info.key, info)
+	return &Encryptor{info: info}
+}
+
+// Dict returns the /Encrypt dictionary to reference from the trailer. It
+// must be written unencrypted.
+func (e *Encryptor) Dict() Dict {
+	return Dict{
+		"Filter": Name("Standard"),
+		"V":      Integer(e.info.version),
+		"R":      Integer(e.info.revision),
+		"Length": Integer(e.info.keyLength * 8),
+		"O":      String{Value: e.info.ownerHash, IsHex: true},
+		"U":      String{Value: e.info.userHash, IsHex: true},
+		"P":      Integer(e.info.permissions),
+	}
+}
+
+// EncryptObject returns a copy of obj, the value of the given indirect
+// object, with its strings and stream data encrypted.
+func (e *Encryptor) EncryptObject(obj Object, objNum, genNum int) Object {
+	key := objectKey(e.info.key, objNum, genNum)
+	crypt := func(data []byte) []byte {
+		out := make([]byte, len(data))
+		c, _ := rc4.NewCipher(key)
+		c.XORKeyStream(out, data)
+		return out
 	}
 
-	c, _ := rc4.NewCipher(hash[:keyLen])
-	return c
+	var encrypt func(obj Object) Object
+	encrypt = func(obj Object) Object {
+		switch v := obj.(type) {
+		case String:
+			return String{Value: crypt(v.Value), IsHex: true}
+		case Array:
+			a := make(Array, len(v))
+			for i, item := range v {
+				a[i] = encrypt(item)
+			}
+			return a
+		case Dict:
+			d := make(Dict, len(v))
+			for k, item := range v {
+				d[k] = encrypt(item)
+			}
+			return d
+		case Stream:
+			return Stream{Dict: encrypt(v.Dict).(Dict), Data: crypt(v.Data)}
+		}
+		return obj
+	}
+	return encrypt(obj)
 }
 
 // bytesEqual compares two byte slices for equality.
@@ -10,6 +10,7 @@ import (
 )
 
 // decodeStream applies the filter chain specified in the stream dictionary to decompress data.
+// DCTDecode and JPXDecode data is returned as the encoded JPEG or JPEG 2000 file.
 func decodeStream(s Stream) ([]byte, error) {
 	data := s.Data
 	filter := s.Dict["Filter"]
@@ -54,6 +55,9 @@ func applyFilter(name Name, data []byte) ([]byte, error) {
 		return asciiHexDecode(data)
 	case "ASCII85Decode":
 		return ascii85Decode(data)
+	case "DCTDecode", "JPXDecode":
+		// Image codecs: the data is left as a JPEG or JPEG 2000 file
+		return data, nil
 	default:
 		return nil, fmt.Errorf("unsupported filter: %s", name)
 	}
@@ -0,0 +1,352 @@
+package reader
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ImageInfo describes an image XObject used on a page.
+type ImageInfo struct {
+	Name             string    // resource name, e.g. "I1"
+	Ref              Reference // indirect reference of the image stream
+	Width            int       // width in pixels (/Width)
+	Height           int       // height in pixels (/Height)
+	BitsPerComponent int
+	// ColorSpace is the color space name, such as "DeviceRGB". For array
+	// color spaces it is the family name, such as "ICCBased" or "Indexed".
+	ColorSpace string
+	// Filter is the name of the last filter applied to the data, such as
+	// "DCTDecode" for JPEG images. It is empty for unfiltered images.
+	Filter string
+	// DrawnWidth and DrawnHeight give the largest size, in points, at which
+	// the image is painted on the page. They are zero if the image is
+	// listed in the resources but never drawn.
+	DrawnWidth, DrawnHeight float64
+	Stream                  Stream // the raw, still encoded image stream
+
+	doc *Document
+}
+
+// Images returns the image XObjects painted on the page, including those
+// drawn inside form XObjects, in order of first use. An image drawn several
+// times is listed once with its largest placement size. Images that are
+// present in the resources but never drawn are listed last.
+func (p *Page) Images() ([]ImageInfo, error) {
+	if p.doc == nil {
+		return nil, nil
+	}
+	content, err := p.ContentStream()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imageCollector{doc: p.doc, byRef: make(map[Reference]int), visited: make(map[int]bool)}
+	c.scan(content, p.Resources, identityMatrix)
+
+	// Add images that are in the resources but never drawn.
+	xobjects := p.doc.resolveDict(p.Resources["XObject"])
+	names := make([]string, 0, len(xobjects))
+	for name := range xobjects {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.add(name, xobjects[Name(name)], 0, 0)
+	}
+	return c.images, nil
+}
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identityMatrix = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// imageCollector walks content streams and records the images they paint.
+type imageCollector struct {
+	doc     *Document
+	images  []ImageInfo
+	byRef   map[Reference]int // index into images
+	visited map[int]bool      // form XObjects on the current path
+}
+
+// scan interprets the q, Q, cm and Do operators of a content stream.
+func (c *imageCollector) scan(data []byte, res Dict, ctm matrix) {
+	var stack []matrix
+	var nums []float64
+	var name string
+
+	i := 0
+	for i < len(data) {
+		b := data[i]
+		switch {
+		case isWhitespace(b):
+			i++
+			continue
+		case b == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+			continue
+		case b == '(':
+			i = skipLiteralString(data, i)
+			nums = nums[:0]
+			continue
+		case b == '<':
+			if i+1 < len(data) && data[i+1] == '<' {
+				i = skipDict(data, i)
+			} else {
+				i = skipAngleBrackets(data, i)
+			}
+			nums = nums[:0]
+			continue
+		case b == '[':
+			i = skipArray(data, i)
+			nums = nums[:0]
+			continue
+		case b == '/':
+			start := i + 1
+			i++
+			for i < len(data) && isRegular(data[i]) {
+				i++
+			}
+			name = string(data[start:i])
+			continue
+		case isDelimiter(b):
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(data) && isRegular(data[i]) {
+			i++
+		}
+		tok := string(data[start:i])
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			nums = append(nums, f)
+			continue
+		}
+
+		switch tok {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(nums) >= 6 {
+				n := nums[len(nums)-6:]
+				ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(ctm)
+			}
+		case "Do":
+			c.paint(name, res, ctm)
+		case "BI":
+			i = skipInlineImage(data, i)
+		}
+		nums = nums[:0]
+		name = ""
+	}
+}
+
+// paint handles a Do operator for the named XObject.
+func (c *imageCollector) paint(name string, res Dict, ctm matrix) {
+	v, ok := c.doc.resolveDict(res["XObject"])[Name(name)]
+	if !ok {
+		return
+	}
+	obj, err := c.doc.resolveIfRef(v)
+	if err != nil {
+		return
+	}
+	xobj, ok := obj.(Stream)
+	if !ok {
+		return
+	}
+
+	switch xobj.Dict.GetName("Subtype") {
+	case "Image":
+		// The image occupies the unit square in its user space.
+		w := math.Hypot(ctm[0], ctm[1])
+		h := math.Hypot(ctm[2], ctm[3])
+		c.add(name, v, w, h)
+	case "Form":
+		ref, isRef := v.(Reference)
+		if isRef {
+			if c.visited[ref.Number] {
+				return
+			}
+			c.visited[ref.Number] = true
+			defer delete(c.visited, ref.Number)
+		}
+		content, err := decodeStream(xobj)
+		if err != nil {
+			return
+		}
+		formMatrix := identityMatrix
+		if m := c.doc.resolveArray(xobj.Dict["Matrix"]); len(m) == 6 {
+			for i, n := range m {
+				formMatrix[i], _ = numberValue(n)
+			}
+		}
+		formRes := c.doc.resolveDict(xobj.Dict["Resources"])
+		if formRes == nil {
+			formRes = res
+		}
+		c.scan(content, formRes, formMatrix.multiply(ctm))
+	}
+}
+
+// add records an image XObject, keeping the largest drawn size for images
+// that are painted more than once.
+func (c *imageCollector) add(name string, v Object, drawnW, drawnH float64) {
+	ref, ok := v.(Reference)
+	if !ok {
+		return
+	}
+	if idx, seen := c.byRef[ref]; seen {
+		img := &c.images[idx]
+		img.DrawnWidth = math.Max(img.DrawnWidth, drawnW)
+		img.DrawnHeight = math.Max(img.DrawnHeight, drawnH)
+		return
+	}
+	obj, err := c.doc.resolve(ref)
+	if err != nil {
+		return
+	}
+	s, ok := obj.(Stream)
+	if !ok || s.Dict.GetName("Subtype") != "Image" {
+		return
+	}
+
+	img := c.doc.newImageInfo(name, ref, s)
+	img.DrawnWidth, img.DrawnHeight = drawnW, drawnH
+
+	c.byRef[ref] = len(c.images)
+	c.images = append(c.images, img)
+}
+
+// newImageInfo describes the image XObject s, referenced as ref.
+func (d *Document) newImageInfo(name string, ref Reference, s Stream) ImageInfo {
+	img := ImageInfo{
+		Name:   name,
+		Ref:    ref,
+		Stream: s,
+		doc:    d,
+	}
+	if n, ok := d.resolveInt(s.Dict["Width"]); ok {
+		img.Width = int(n)
+	}
+	if n, ok := d.resolveInt(s.Dict["Height"]); ok {
+		img.Height = int(n)
+	}
+	if n, ok := d.resolveInt(s.Dict["BitsPerComponent"]); ok {
+		img.BitsPerComponent = int(n)
+	}
+	cs, _ := d.resolveIfRef(s.Dict["ColorSpace"])
+	switch cs := cs.(type) {
+	case Name:
+		img.ColorSpace = string(cs)
+	case Array:
+		if len(cs) > 0 {
+			if n, ok := cs[0].(Name); ok {
+				img.ColorSpace = string(n)
+			}
+		}
+	}
+	switch f := s.Dict["Filter"].(type) {
+	case Name:
+		img.Filter = string(f)
+	case Array:
+		if len(f) > 0 {
+			if n, ok := f[len(f)-1].(Name); ok {
+				img.Filter = string(n)
+			}
+		}
+	}
+	return img
+}
+
+// resolveArray resolves obj and returns it as an array, or nil.
+func (d *Document) resolveArray(obj Object) Array {
+	if obj == nil {
+		return nil
+	}
+	resolved, err := d.resolveIfRef(obj)
+	if err != nil {
+		return nil
+	}
+	arr, _ := resolved.(Array)
+	return arr
+}
+
+// resolveInt resolves obj and returns its integer value.
+func (d *Document) resolveInt(obj Object) (int64, bool) {
+	if obj == nil {
+		return 0, false
+	}
+	resolved, err := d.resolveIfRef(obj)
+	if err != nil {
+		return 0, false
+	}
+	n, ok := resolved.(Integer)
+	return int64(n), ok
+}
+
+// skipDict advances past a dictionary at pos, including nested dictionaries.
+func skipDict(data []byte, pos int) int {
+	depth := 0
+	for pos < len(data) {
+		switch {
+		case data[pos] == '(':
+			pos = skipLiteralString(data, pos)
+			continue
+		case data[pos] == '<' && pos+1 < len(data) && data[pos+1] == '<':
+			depth++
+			pos += 2
+			continue
+		case data[pos] == '>' && pos+1 < len(data) && data[pos+1] == '>':
+			depth--
+			pos += 2
+			if depth == 0 {
+				return pos
+			}
+			continue
+		}
+		pos++
+	}
+	return pos
+}
+
+// skipInlineImage advances from just after a BI operator past the matching
+// EI operator.
+func skipInlineImage(data []byte, pos int) int {
+	for pos+1 < len(data) {
+		if data[pos] == 'I' && data[pos-1] == 'D' && isWhitespace(data[pos+1]) &&
+			(pos < 2 || isWhitespace(data[pos-2])) {
+			break
+		}
+		pos++
+	}
+	for pos+2 < len(data) {
+		if isWhitespace(data[pos]) && data[pos+1] == 'E' && data[pos+2] == 'I' &&
+			(pos+3 >= len(data) || isWhitespace(data[pos+3]) || isDelimiter(data[pos+3])) {
+			return pos + 3
+		}
+		pos++
+	}
+	return len(data)
+}
@@ -0,0 +1,268 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// Decode decodes the image. JPEG images (DCTDecode) are decoded as they
+// are; JPEG 2000 images (JPXDecode) are not supported. Other images must be
+// stored unfiltered or with the filters the reader supports, optionally
+// with PNG predictors, and use the DeviceGray, DeviceRGB, DeviceCMYK or
+// Indexed color space, or a calibrated or ICC-based equivalent, with 1, 2,
+// 4, 8 or 16 bits per component. Image masks decode as gray images. Soft
+// masks, /Decode arrays and rendering intents are ignored.
+func (img ImageInfo) Decode() (image.Image, error) {
+	s := img.Stream
+	if img.Filter == "JPXDecode" {
+		return nil, fmt.Errorf("reader: image %s: JPXDecode images are not supported", img.Name)
+	}
+	data, err := decodeStream(s)
+	if err != nil {
+		return nil, fmt.Errorf("reader: image %s: %w", img.Name, err)
+	}
+	if img.Filter == "DCTDecode" {
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("reader: image %s: %w", img.Name, err)
+		}
+		return decoded, nil
+	}
+	w, h, bpc := img.Width, img.Height, img.BitsPerComponent
+	mask := img.resolve(s.Dict["ImageMask"]) == Boolean(true)
+	if mask {
+		bpc = 1
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("reader: image %s: unsupported %d bits per component", img.Name, bpc)
+	}
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("reader: image %s: invalid size %dx%d", img.Name, w, h)
+	}
+
+	colors, palette := 1, color.Palette(nil)
+	if !mask {
+		if colors, palette, err = img.colorSpace(img.resolve(s.Dict["ColorSpace"]), bpc); err != nil {
+			return nil, err
+		}
+	}
+
+	stride := (w*colors*bpc + 7) / 8
+	parms, _ := img.resolve(s.Dict["DecodeParms"]).(Dict)
+	if arr, ok := img.resolve(s.Dict["DecodeParms"]).(Array); ok && len(arr) > 0 {
+		parms, _ = img.resolve(arr[len(arr)-1]).(Dict)
+	}
+	if predictor, _ := parms.GetInt("Predictor"); predictor >= 10 {
+		if data, err = unpredictPNG(data, max(1, colors*bpc/8), stride); err != nil {
+			return nil, fmt.Errorf("reader: image %s: %w", img.Name, err)
+		}
+	} else if predictor > 1 {
+		return nil, fmt.Errorf("reader: image %s: unsupported predictor %d", img.Name, predictor)
+	}
+	if len(data) < stride*h {
+		return nil, fmt.Errorf("reader: image %s: %d bytes of data for %dx%d pixels", img.Name, len(data), w, h)
+	}
+
+	// sample returns component c of pixel x of row, scaled to 0-255
+	// unless the image is indexed
+	maxValue := 1<<bpc - 1
+	sample := func(row []byte, x, c int) uint8 {
+		i := x*colors + c
+		var v int
+		switch bpc {
+		case 8:
+			return row[i]
+		case 16:
+			return row[2*i]
+		default:
+			bit := i * bpc
+			v = int(row[bit/8]>>(8-bpc-bit%8)) & maxValue
+		}
+		if palette != nil {
+			return uint8(v)
+		}
+		return uint8(v * 255 / maxValue)
+	}
+
+	rect := image.Rect(0, 0, w, h)
+	switch {
+	case palette != nil:
+		out := image.NewPaletted(rect, palette)
+		for y := 0; y < h; y++ {
+			row := data[y*stride:]
+			for x := 0; x < w; x++ {
+				out.Pix[y*out.Stride+x] = sample(row, x, 0)
+			}
+		}
+		return out, nil
+	case colors == 1:
+		out := image.NewGray(rect)
+		for y := 0; y < h; y++ {
+			row := data[y*stride:]
+			for x := 0; x < w; x++ {
+				out.Pix[y*out.Stride+x] = sample(row, x, 0)
+			}
+		}
+		return out, nil
+	case colors == 3:
+		out := image.NewRGBA(rect)
+		for y := 0; y < h; y++ {
+			row := data[y*stride:]
+			for x := 0; x < w; x++ {
+				out.Set(x, y, color.RGBA{sample(row, x, 0), sample(row, x, 1), sample(row, x, 2), 255})
+			}
+		}
+		return out, nil
+	default:
+		out := image.NewCMYK(rect)
+		for y := 0; y < h; y++ {
+			row := data[y*stride:]
+			for x := 0; x < w; x++ {
+				out.Set(x, y, color.CMYK{sample(row, x, 0), sample(row, x, 1), sample(row, x, 2), sample(row, x, 3)})
+			}
+		}
+		return out, nil
+	}
+}
+
+// colorSpace returns the number of color components of an image color
+// space, and the palette of an indexed one.
+func (img ImageInfo) colorSpace(cs Object, bpc int) (int, color.Palette, error) {
+	var family Name
+	var arr Array
+	switch v := cs.(type) {
+	case Name:
+		family = v
+	case Array:
+		if len(v) > 0 {
+			family, _ = v[0].(Name)
+			arr = v
+		}
+	}
+
+	switch family {
+	case "DeviceGray", "CalGray", "G":
+		return 1, nil, nil
+	case "DeviceRGB", "CalRGB", "RGB":
+		return 3, nil, nil
+	case "DeviceCMYK", "CMYK":
+		return 4, nil, nil
+	case "ICCBased":
+		if len(arr) > 1 {
+			if profile, ok := img.resolve(arr[1]).(Stream); ok {
+				if n, _ := profile.Dict.GetInt("N"); n == 1 || n == 3 || n == 4 {
+					return int(n), nil, nil
+				}
+			}
+		}
+	case "Indexed", "I":
+		if len(arr) == 4 && bpc <= 8 {
+			base, _, err := img.colorSpace(img.resolve(arr[1]), 8)
+			if err != nil {
+				return 0, nil, err
+			}
+			var lookup []byte
+			switch v := img.resolve(arr[3]).(type) {
+			case String:
+				lookup = v.Value
+			case Stream:
+				if lookup, err = decodeStream(v); err != nil {
+					return 0, nil, fmt.Errorf("reader: image %s: palette: %w", img.Name, err)
+				}
+			}
+			palette := make(color.Palette, 0, len(lookup)/base)
+			for i := 0; i+base <= len(lookup) && len(palette) < 1<<bpc; i += base {
+				c := lookup[i : i+base]
+				switch base {
+				case 1:
+					palette = append(palette, color.Gray{c[0]})
+				case 3:
+					palette = append(palette, color.RGBA{c[0], c[1], c[2], 255})
+				case 4:
+					palette = append(palette, color.CMYK{c[0], c[1], c[2], c[3]})
+				}
+			}
+			// Out of range indices show as black
+			for len(palette) < 1<<bpc {
+				palette = append(palette, color.Black)
+			}
+			return 1, palette, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("reader: image %s: unsupported color space %v", img.Name, cs)
+}
+
+// resolve resolves obj in the image's document if it is a reference.
+func (img ImageInfo) resolve(obj Object) Object {
+	if img.doc == nil {
+		return obj
+	}
+	resolved, err := img.doc.resolveIfRef(obj)
+	if err != nil {
+		return nil
+	}
+	return resolved
+}
+
+// unpredictPNG reverses the PNG predictors of image rows of stride bytes,
+// with bpp bytes per pixel.
+func unpredictPNG(data []byte, bpp, stride int) ([]byte, error) {
+	if len(data)%(stride+1) != 0 {
+		return nil, fmt.Errorf("predicted data is not a whole number of rows")
+	}
+	out := make([]byte, 0, len(data)/(stride+1)*stride)
+	prev := make([]byte, stride)
+	for pos := 0; pos < len(data); pos += stride + 1 {
+		filter, row := data[pos], data[pos+1:pos+1+stride]
+		cur := make([]byte, stride)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = cur[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 0:
+				cur[i] = row[i]
+			case 1:
+				cur[i] = row[i] + left
+			case 2:
+				cur[i] = row[i] + up
+			case 3:
+				cur[i] = row[i] + byte((int(left)+int(up))/2)
+			case 4:
+				cur[i] = row[i] + paeth(left, up, upLeft)
+			default:
+				return nil, fmt.Errorf("unknown PNG filter %d", filter)
+			}
+		}
+		out = append(out, cur...)
+		prev = cur
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of PNG.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package reader
+
+// Link is a link annotation on a page.
+type Link struct {
+	Rect       Rectangle // clickable area in default user space
+	URL        string    // target of a URI action, "" for other links
+	TargetPage int       // 1-based destination page, 0 if it has none in this document
+}
+
+// Links returns the link annotations of the page in the order of its
+// /Annots array, with the URL of external links and the destination page
+// of internal ones. It returns nil if the page has no links.
+func (p *Page) Links() ([]Link, error) {
+	annots := p.doc.resolveArray(p.dict["Annots"])
+	if len(annots) == 0 {
+		return nil, nil
+	}
+	catalog, err := p.doc.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	pages := p.doc.pageNumbers()
+
+	var links []Link
+	for _, obj := range annots {
+		annot := p.doc.resolveDict(obj)
+		if annot.GetName("Subtype") != "Link" {
+			continue
+		}
+		var link Link
+		if rect, err := p.doc.resolveIfRef(annot["Rect"]); err == nil {
+			link.Rect, _ = parseRectangle(rect)
+		}
+		if action := p.doc.resolveDict(annot["A"]); action.GetName("S") == "URI" {
+			link.URL = p.doc.textString(action["URI"])
+		} else {
+			link.TargetPage = p.doc.destinationPage(annot, catalog, pages)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
@@ -99,6 +99,25 @@ func (d Dict) GetInt(key Name) (int64, bool) {
 	return 0, false
 }
 
+// GetFloat returns the value of a numeric entry, or 0 if not found.
+func (d Dict) GetFloat(key Name) (float64, bool) {
+	if v, ok := d[key]; ok {
+		return numberValue(v)
+	}
+	return 0, false
+}
+
+// numberValue returns the value of an Integer or Real object.
+func numberValue(obj Object) (float64, bool) {
+	switch n := obj.(type) {
+	case Integer:
+		return float64(n), true
+	case Real:
+		return float64(n), true
+	}
+	return 0, false
+}
+
 // GetDict returns a sub-dictionary, or nil if not found.
 func (d Dict) GetDict(key Name) Dict {
 	if v, ok := d[key]; ok {
@@ -128,6 +147,15 @@ type Stream struct {
 func (Stream) pdfObject()       {}
 func (s Stream) String() string { return fmt.Sprintf("<<stream len=%d>>", len(s.Data)) }
 
+// Decode returns the stream data with the filters of its /Filter entry
+// applied. Only FlateDecode, ASCIIHexDecode and ASCII85Decode are
+// supported, and predictors given in /DecodeParms are not reversed. The
+// image filters DCTDecode and JPXDecode are left in place, so the data of
+// such a stream is a JPEG or JPEG 2000 file.
+func (s Stream) Decode() ([]byte, error) {
+	return decodeStream(s)
+}
+
 // Reference represents an indirect object reference (e.g., "10 0 R").
 type Reference struct {
 	Number     int
@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// objectStream is a decoded object stream (/Type /ObjStm) of a PDF 1.5+
+// file, which stores several non-stream objects in one compressed stream.
+type objectStream struct {
+	data    []byte // decoded stream data, starting with the header
+	numbers []int  // object number of each stored object
+	offsets []int  // offset of each stored object in data
+}
+
+// object returns the serialized bytes of the object at the given index.
+func (s *objectStream) object(index int) ([]byte, error) {
+	if index < 0 || index >= len(s.offsets) {
+		return nil, fmt.Errorf("index %d out of range [0, %d)", index, len(s.offsets))
+	}
+	end := len(s.data)
+	if index+1 < len(s.offsets) {
+		end = s.offsets[index+1]
+	}
+	start := s.offsets[index]
+	if start > end || end > len(s.data) {
+		return nil, fmt.Errorf("object %d out of bounds", index)
+	}
+	return s.data[start:end], nil
+}
+
+// objectStream returns the decoded object stream with the given object
+// number, reading it on first use. It is safe for concurrent use.
+func (d *Document) objectStream(num int) (*objectStream, error) {
+	d.objStreamsMu.Lock()
+	s, ok := d.objStreams[num]
+	d.objStreamsMu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	entry, ok := d.xref[num]
+	if !ok || !entry.InUse || entry.Compressed {
+		return nil, fmt.Errorf("reader: object stream %d not found", num)
+	}
+	obj, err := d.resolve(Reference{Number: num, Generation: entry.Generation})
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(Stream)
+	if !ok || stream.Dict.GetName("Type") != "ObjStm" {
+		return nil, fmt.Errorf("reader: object %d is not an object stream", num)
+	}
+	data, err := decodeStream(stream)
+	if err != nil {
+		return nil, fmt.Errorf("reader: object stream %d: %w", num, err)
+	}
+
+	n, _ := stream.Dict.GetInt("N")
+	first, _ := stream.Dict.GetInt("First")
+	if n < 0 || first < 0 || int(first) > len(data) {
+		return nil, fmt.Errorf("reader: object stream %d has an invalid header", num)
+	}
+
+	// The header holds N pairs of object number and offset relative to /First.
+	header := bytes.Fields(data[:first])
+	if len(header) < int(2*n) {
+		return nil, fmt.Errorf("reader: object stream %d header has %d entries, want %d", num, len(header)/2, n)
+	}
+	s = &objectStream{data: data}
+	for i := 0; i < int(n); i++ {
+		objNum, err1 := strconv.Atoi(string(header[2*i]))
+		offset, err2 := strconv.Atoi(string(header[2*i+1]))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("reader: object stream %d header entry %d is invalid", num, i)
+		}
+		s.numbers = append(s.numbers, objNum)
+		s.offsets = append(s.offsets, int(first)+offset)
+	}
+
+	d.objStreamsMu.Lock()
+	defer d.objStreamsMu.Unlock()
+	if d.objStreams == nil {
+		d.objStreams = make(map[int]*objectStream)
+	}
+	d.objStreams[num] = s
+	return s, nil
+}
+
+// resolveCompressed parses the object stored at the given index of an
+// object stream. Objects in object streams are not encrypted individually,
+// since the whole stream is.
+func (d *Document) resolveCompressed(num, streamNum, index int) (Object, error) {
+	s, err := d.objectStream(streamNum)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.object(index)
+	if err != nil {
+		return nil, fmt.Errorf("reader: object %d in object stream %d: %w", num, streamNum, err)
+	}
+	if s.numbers[index] != num {
+		return nil, fmt.Errorf("reader: object stream %d holds object %d at index %d, want %d", streamNum, s.numbers[index], index, num)
+	}
+	obj, err := newParser(raw).ParseObject()
+	if err != nil {
+		return nil, fmt.Errorf("reader: parsing object %d: %w", num, err)
+	}
+	return obj, nil
+}
+
+// RawObject returns the serialized bytes of the current revision of an
+// object, without the surrounding "N G obj" and "endobj" keywords. For an
+// object stored in an object stream, they are taken from the decompressed
+// stream. Strings of encrypted documents are returned as stored.
+func (d *Document) RawObject(num int) ([]byte, error) {
+	entry, ok := d.xref[num]
+	if !ok || !entry.InUse {
+		return nil, fmt.Errorf("reader: object %d not found", num)
+	}
+	if entry.Compressed {
+		s, err := d.objectStream(int(entry.Offset))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := s.object(entry.Generation)
+		if err != nil {
+			return nil, fmt.Errorf("reader: object %d in object stream %d: %w", num, entry.Offset, err)
+		}
+		return bytes.TrimSpace(raw), nil
+	}
+
+	if entry.Offset < 0 || int(entry.Offset) >= len(d.data) {
+		return nil, fmt.Errorf("reader: object %d offset %d out of bounds", num, entry.Offset)
+	}
+	p := newParser(d.data[entry.Offset:])
+	if _, err := p.ParseIndirectObject(); err != nil {
+		return nil, fmt.Errorf("reader: parsing object %d: %w", num, err)
+	}
+	raw := d.data[entry.Offset : int(entry.Offset)+p.pos]
+	start := bytes.Index(raw, []byte("obj")) + len("obj")
+	raw = bytes.TrimSuffix(bytes.TrimSpace(raw[start:]), []byte("endobj"))
+	return bytes.TrimSpace(raw), nil
+}
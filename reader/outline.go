@@ -0,0 +1,134 @@
+package reader
+
+import "fmt"
+
+// OutlineItem is an entry of the document outline, the bookmarks shown in
+// a viewer's navigation pane.
+type OutlineItem struct {
+	Title    string
+	Page     int // 1-based destination page, 0 if it has none in this document
+	Children []*OutlineItem
+}
+
+// Outlines returns the top-level entries of the document outline, with
+// their sub-entries as children. It returns nil if the document has no
+// outline.
+func (d *Document) Outlines() ([]*OutlineItem, error) {
+	catalog, err := d.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	root := d.resolveDict(catalog["Outlines"])
+	if root == nil {
+		return nil, nil
+	}
+
+	return d.outlineItems(root["First"], catalog, d.pageNumbers(), make(map[int]bool))
+}
+
+// pageNumbers maps the object number of each page dictionary to its page
+// number, for resolving destinations.
+func (d *Document) pageNumbers() map[int]int {
+	pages := make(map[int]int, len(d.pages))
+	for _, p := range d.pages {
+		pages[p.ObjNum] = p.Number
+	}
+	return pages
+}
+
+// outlineItems reads the chain of outline items starting at first and
+// linked through /Next. seen guards against cycles.
+func (d *Document) outlineItems(first Object, catalog Dict, pages map[int]int, seen map[int]bool) ([]*OutlineItem, error) {
+	var items []*OutlineItem
+	for obj := first; obj != nil; {
+		ref, ok := obj.(Reference)
+		if !ok {
+			break
+		}
+		if seen[ref.Number] {
+			return nil, fmt.Errorf("reader: outline item %d is linked more than once", ref.Number)
+		}
+		seen[ref.Number] = true
+
+		dict := d.resolveDict(ref)
+		if dict == nil {
+			break
+		}
+		item := &OutlineItem{
+			Title: d.textString(dict["Title"]),
+			Page:  d.destinationPage(dict, catalog, pages),
+		}
+		children, err := d.outlineItems(dict["First"], catalog, pages, seen)
+		if err != nil {
+			return nil, err
+		}
+		item.Children = children
+		items = append(items, item)
+		obj = dict["Next"]
+	}
+	return items, nil
+}
+
+// destinationPage returns the page number targeted by the /Dest or GoTo
+// action of an outline item or link annotation, or 0.
+func (d *Document) destinationPage(dict, catalog Dict, pages map[int]int) int {
+	dest := dict["Dest"]
+	if dest == nil {
+		action := d.resolveDict(dict["A"])
+		if action.GetName("S") != "GoTo" {
+			return 0
+		}
+		dest = action["D"]
+	}
+	dest, _ = d.resolveIfRef(dest)
+
+	// Named destinations are looked up in the catalog's /Dests dictionary
+	// (names) or the /Dests name tree (strings)
+	switch v := dest.(type) {
+	case Name:
+		dest = d.resolveDict(catalog["Dests"])[v]
+	case String:
+		dest = d.lookupName(d.resolveDict(d.resolveDict(catalog["Names"])["Dests"]), string(v.Value), 0)
+	}
+	dest, _ = d.resolveIfRef(dest)
+	if dict, ok := dest.(Dict); ok {
+		dest, _ = d.resolveIfRef(dict["D"])
+	}
+
+	arr, ok := dest.(Array)
+	if !ok || len(arr) == 0 {
+		return 0
+	}
+	if ref, ok := arr[0].(Reference); ok {
+		return pages[ref.Number]
+	}
+	return 0
+}
+
+// lookupName finds key in a name tree. depth bounds the recursion into
+// malformed trees.
+func (d *Document) lookupName(node Dict, key string, depth int) Object {
+	if node == nil || depth > 32 {
+		return nil
+	}
+	names := d.resolveArray(node["Names"])
+	for i := 0; i+1 < len(names); i += 2 {
+		if s, ok := names[i].(String); ok && string(s.Value) == key {
+			return names[i+1]
+		}
+	}
+	for _, kid := range d.resolveArray(node["Kids"]) {
+		kidDict := d.resolveDict(kid)
+		if limits := d.resolveArray(kidDict["Limits"]); len(limits) == 2 {
+			lo, _ := limits[0].(String)
+			hi, _ := limits[1].(String)
+			if key < string(lo.Value) || key > string(hi.Value) {
+				continue
+			}
+		}
+		if v := d.lookupName(kidDict, key, depth+1); v != nil {
+			return v
+		}
+	}
+	return nil
+}
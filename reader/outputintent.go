@@ -0,0 +1,92 @@
+package reader
+
+import "fmt"
+
+// OutputIntent describes an entry of the catalog's /OutputIntents array,
+// which names the intended output device for color management, as used by
+// PDF/X and PDF/A.
+type OutputIntent struct {
+	Subtype                   string // /S, e.g. "GTS_PDFX" or "GTS_PDFA1"
+	OutputCondition           string // human-readable condition (/OutputCondition)
+	OutputConditionIdentifier string // e.g. "FOGRA39" or "CGATS TR 001"
+	RegistryName              string // registry of the identifier, usually a URL
+	Info                      string // additional information (/Info)
+	// Profile holds the decoded ICC profile of /DestOutputProfile. It is nil
+	// if the output intent does not embed a profile.
+	Profile []byte
+	// Components is the number of color components of the profile (/N).
+	Components int
+}
+
+// OutputIntents returns the document's output intents from the catalog's
+// /OutputIntents array. It returns nil if the document has none.
+func (d *Document) OutputIntents() ([]OutputIntent, error) {
+	catalog, err := d.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := catalog["OutputIntents"]
+	if !ok {
+		return nil, nil
+	}
+	resolved, err := d.resolveIfRef(obj)
+	if err != nil {
+		return nil, fmt.Errorf("reader: resolving /OutputIntents: %w", err)
+	}
+	arr, ok := resolved.(Array)
+	if !ok {
+		return nil, fmt.Errorf("reader: /OutputIntents is not an array")
+	}
+
+	var intents []OutputIntent
+	for i, item := range arr {
+		dict := d.resolveDict(item)
+		if dict == nil {
+			return nil, fmt.Errorf("reader: output intent %d is not a dictionary", i)
+		}
+		intent := OutputIntent{
+			Subtype:                   string(dict.GetName("S")),
+			OutputCondition:           d.textString(dict["OutputCondition"]),
+			OutputConditionIdentifier: d.textString(dict["OutputConditionIdentifier"]),
+			RegistryName:              d.textString(dict["RegistryName"]),
+			Info:                      d.textString(dict["Info"]),
+		}
+
+		if profileObj, ok := dict["DestOutputProfile"]; ok {
+			resolved, err := d.resolveIfRef(profileObj)
+			if err != nil {
+				return nil, fmt.Errorf("reader: output intent %d profile: %w", i, err)
+			}
+			profile, ok := resolved.(Stream)
+			if !ok {
+				return nil, fmt.Errorf("reader: output intent %d /DestOutputProfile is not a stream", i)
+			}
+			intent.Profile, err = decodeStream(profile)
+			if err != nil {
+				return nil, fmt.Errorf("reader: output intent %d profile: %w", i, err)
+			}
+			if n, ok := d.resolveInt(profile.Dict["N"]); ok {
+				intent.Components = int(n)
+			}
+		}
+		intents = append(intents, intent)
+	}
+	return intents, nil
+}
+
+// textString resolves obj and returns it as decoded text, or "" if it is
+// not a string.
+func (d *Document) textString(obj Object) string {
+	if obj == nil {
+		return ""
+	}
+	resolved, err := d.resolveIfRef(obj)
+	if err != nil {
+		return ""
+	}
+	s, ok := resolved.(String)
+	if !ok {
+		return ""
+	}
+	return decodePDFString(s.Value)
+}
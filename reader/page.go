@@ -23,7 +23,8 @@ type Page struct {
 	Resources Dict
 	Contents  []Stream
 	Rotate    int
-	dict      Dict     // original page dictionary
+	ObjNum    int       // object number of the page dictionary
+	dict      Dict      // original page dictionary
 	doc       *Document // back-reference for resolving objects
 }
 
@@ -98,11 +99,12 @@ func (d *Document) buildPageList() error {
 	}
 
 	d.pages = nil
-	return d.traversePageTree(pagesDict, nil, 0)
+	return d.traversePageTree(pagesDict, pagesRef.Number, nil, 0)
 }
 
 // traversePageTree recursively traverses the page tree collecting leaf pages.
-func (d *Document) traversePageTree(node Dict, inherited Dict, rotate int) error {
+// objNum is the object number of node, or 0 if it is a direct object.
+func (d *Document) traversePageTree(node Dict, objNum int, inherited Dict, rotate int) error {
 	nodeType := node.GetName("Type")
 
 	// Inherit properties from parent
@@ -110,9 +112,11 @@ func (d *Document) traversePageTree(node Dict, inherited Dict, rotate int) error
 	for k, v := range inherited {
 		merged[k] = v
 	}
-	// Override with node's own properties
+	// Override with node's own properties. The merged map is passed down
+	// to the kids, so a page inherits from its nearest ancestor that sets
+	// an attribute. A null value is the same as an absent one.
 	for _, key := range []Name{"MediaBox", "CropBox", "Resources", "Rotate"} {
-		if v, ok := node[key]; ok {
+		if v, ok := node[key]; ok && v != (Null{}) {
 			merged[key] = v
 		}
 	}
@@ -120,6 +124,7 @@ func (d *Document) traversePageTree(node Dict, inherited Dict, rotate int) error
 	if nodeType == "Page" {
 		page := &Page{
 			Number: len(d.pages) + 1,
+			ObjNum: objNum,
 			dict:   node,
 			doc:    d,
 		}
@@ -212,10 +217,72 @@ func (d *Document) traversePageTree(node Dict, inherited Dict, rotate int) error
 		if !ok {
 			continue
 		}
-		if err := d.traversePageTree(kidDict, merged, rotate); err != nil {
+		kidNum := 0
+		if ref, ok := kid.(Reference); ok {
+			kidNum = ref.Number
+		}
+		if err := d.traversePageTree(kidDict, kidNum, merged, rotate); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// BoxStyle describes how a viewer should draw the guidelines for a page
+// boundary, as given by a box style dictionary in /BoxColorInfo.
+type BoxStyle struct {
+	Color [3]float64 // RGB components in the range 0-1 (default: black)
+	Width float64    // guideline width in points (default: 1)
+	Style string     // "S" (solid) or "D" (dashed) (default: "S")
+	Dash  []float64  // dash array for dashed lines (default: [3])
+}
+
+// BoxColorInfo returns the guideline styles from the page's /BoxColorInfo
+// dictionary, keyed by boundary name ("CropBox", "BleedBox", "TrimBox",
+// "ArtBox"). It returns nil if the page has no /BoxColorInfo entry.
+func (p *Page) BoxColorInfo() (map[string]BoxStyle, error) {
+	obj, ok := p.dict["BoxColorInfo"]
+	if !ok || p.doc == nil {
+		return nil, nil
+	}
+	resolved, err := p.doc.resolveIfRef(obj)
+	if err != nil {
+		return nil, fmt.Errorf("reader: page %d box color info: %w", p.Number, err)
+	}
+	info, ok := resolved.(Dict)
+	if !ok {
+		return nil, fmt.Errorf("reader: page %d /BoxColorInfo is not a dictionary", p.Number)
+	}
+
+	result := make(map[string]BoxStyle)
+	for _, box := range []Name{"CropBox", "BleedBox", "TrimBox", "ArtBox"} {
+		styleDict := p.doc.resolveDict(info[box])
+		if styleDict == nil {
+			continue
+		}
+
+		style := BoxStyle{Width: 1, Style: "S", Dash: []float64{3}}
+		if c := styleDict.GetArray("C"); len(c) == 3 {
+			for i, v := range c {
+				style.Color[i], _ = numberValue(v)
+			}
+		}
+		if w, ok := styleDict.GetFloat("W"); ok {
+			style.Width = w
+		}
+		if s := styleDict.GetName("S"); s != "" {
+			style.Style = string(s)
+		}
+		if d := styleDict.GetArray("D"); d != nil {
+			style.Dash = style.Dash[:0]
+			for _, v := range d {
+				if n, ok := numberValue(v); ok {
+					style.Dash = append(style.Dash, n)
+				}
+			}
+		}
+		result[string(box)] = style
+	}
+	return result, nil
+}
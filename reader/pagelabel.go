@@ -0,0 +1,116 @@
+package reader
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pageLabelRange is an entry of the /PageLabels number tree: the pages
+// from index start on are labeled by dict.
+type pageLabelRange struct {
+	start int
+	dict  Dict
+}
+
+// PageLabels returns the label a viewer shows for each page, in page
+// order, as defined by the catalog's /PageLabels number tree. A label is
+// the range's prefix (/P) followed by the page number within the range,
+// counted from /St, in the range's style (/S): decimal, upper or lower
+// Roman numerals, or upper or lower letters. A range without a style
+// labels its pages with the prefix alone. Pages not covered by any range,
+// and all pages of a document without page labels, are numbered in
+// decimal from 1.
+func (d *Document) PageLabels() ([]string, error) {
+	catalog, err := d.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	var ranges []pageLabelRange
+	d.collectNums(d.resolveDict(catalog["PageLabels"]), &ranges, 0)
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	labels := make([]string, len(d.pages))
+	r := -1 // index of the range covering page i
+	for i := range labels {
+		for r+1 < len(ranges) && ranges[r+1].start <= i {
+			r++
+		}
+		if r < 0 {
+			labels[i] = strconv.Itoa(i + 1)
+			continue
+		}
+		rng := ranges[r]
+		label := d.textString(rng.dict["P"])
+		first, ok := d.resolveInt(rng.dict["St"])
+		if !ok || first < 1 {
+			first = 1
+		}
+		if style := rng.dict.GetName("S"); style != "" {
+			label += formatPageNumber(style, int(first)+i-rng.start)
+		}
+		labels[i] = label
+	}
+	return labels, nil
+}
+
+// collectNums appends the entries of the number tree node and its kids to
+// ranges. depth bounds the recursion into malformed trees.
+func (d *Document) collectNums(node Dict, ranges *[]pageLabelRange, depth int) {
+	if node == nil || depth > 32 {
+		return
+	}
+	nums := d.resolveArray(node["Nums"])
+	for i := 0; i+1 < len(nums); i += 2 {
+		start, ok := nums[i].(Integer)
+		if !ok {
+			continue
+		}
+		if dict := d.resolveDict(nums[i+1]); dict != nil {
+			*ranges = append(*ranges, pageLabelRange{start: int(start), dict: dict})
+		}
+	}
+	for _, kid := range d.resolveArray(node["Kids"]) {
+		d.collectNums(d.resolveDict(kid), ranges, depth+1)
+	}
+}
+
+// formatPageNumber writes n in a page label numbering style: D (decimal),
+// R or r (Roman numerals) or A or a (letters). Unknown styles are written
+// in decimal.
+func formatPageNumber(style Name, n int) string {
+	switch style {
+	case "R":
+		return romanNumeral(n)
+	case "r":
+		return strings.ToLower(romanNumeral(n))
+	case "A":
+		return alphaNumeral(n)
+	case "a":
+		return strings.ToLower(alphaNumeral(n))
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// romanNumeral returns n in upper-case Roman numerals. Thousands are
+// written as repeated Ms.
+func romanNumeral(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
+
+// alphaNumeral returns n in upper-case letters: A to Z, then AA to ZZ, AAA
+// to ZZZ, and so on.
+func alphaNumeral(n int) string {
+	letter := byte('A' + (n-1)%26)
+	return strings.Repeat(string(letter), (n-1)/26+1)
+}
@@ -10,9 +10,15 @@ import (
 
 // parser is a recursive descent parser for PDF syntax.
 type parser struct {
-	data   []byte
-	pos    int
-	cipher *rc4.Cipher // optional: decrypts strings/streams in byte order
+	data []byte
+	pos  int
+
+	// Optional decryption of strings and streams with the object's RC4
+	// key. With shareCipher one cipher runs over all of them in byte order,
+	// as gofpdf encrypts, instead of a fresh one for each.
+	key         []byte
+	shareCipher bool
+	cipher      *rc4.Cipher
 }
 
 // newParser creates a parser from a byte slice.
@@ -20,6 +26,17 @@ func newParser(data []byte) *parser {
 	return &parser{data: data}
 }
 
+// decrypt decrypts a string or stream in place if the parser has a key.
+func (p *parser) decrypt(data []byte) {
+	if p.key == nil {
+		return
+	}
+	if p.cipher == nil || !p.shareCipher {
+		p.cipher, _ = rc4.NewCipher(p.key)
+	}
+	p.cipher.XORKeyStream(data, data)
+}
+
 // newParserFromReader creates a parser by reading all data from a reader.
 func newParserFromReader(r io.Reader) (*parser, error) {
 	data, err := io.ReadAll(r)
@@ -204,13 +221,16 @@ func (p *parser) parseNumberOrRef() (Object, error) {
 			genVal, err2 := strconv.ParseInt(tok2, 10, 64)
 			if err2 == nil {
 				p.skipWhitespace()
-				if p.pos < len(p.data) && p.data[p.pos] == 'R' {
+				if p.pos < len(p.data) && p.data[p.pos] == 'R' &&
+					(p.pos+1 == len(p.data) || !isRegular(p.data[p.pos+1])) {
 					p.pos++ // consume 'R'
 					return Reference{Number: int(intVal), Generation: int(genVal)}, nil
 				}
 			}
 		}
-		// Not a reference, restore position after first token
+		// Not a reference: restore the position after the first token, so
+		// that a following number, such as the 3.5 of "12 3.5", or a
+		// keyword starting with R is parsed on its own.
 		p.pos = pos2
 		return Integer(intVal), nil
 	}
@@ -289,9 +309,7 @@ func (p *parser) parseLiteralString() (String, error) {
 		return String{}, fmt.Errorf("reader: unterminated literal string")
 	}
 	data := buf.Bytes()
-	if p.cipher != nil {
-		p.cipher.XORKeyStream(data, data)
-	}
+	p.decrypt(data)
 	return String{Value: data}, nil
 }
 
@@ -314,9 +332,7 @@ func (p *parser) parseHexString() (String, error) {
 				buf.WriteByte(byte(hi << 4)) // trailing nibble
 			}
 			data := buf.Bytes()
-			if p.cipher != nil {
-				p.cipher.XORKeyStream(data, data)
-			}
+			p.decrypt(data)
 			return String{Value: data, IsHex: true}, nil
 		}
 
@@ -459,9 +475,7 @@ func (p *parser) ParseIndirectObject() (*IndirectObject, error) {
 		copy(streamData, p.data[p.pos:p.pos+length])
 		p.pos += length
 
-		if p.cipher != nil {
-			p.cipher.XORKeyStream(streamData, streamData)
-		}
+		p.decrypt(streamData)
 
 		// Skip "endstream"
 		p.skipWhitespace()
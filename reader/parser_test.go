@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -201,6 +202,41 @@ func TestParseReference(t *testing.T) {
 	}
 }
 
+func TestParseNumberNotReference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Array
+	}{
+		{"[12 3.5 /X]", Array{Integer(12), Real(3.5), Name("X")}},
+		{"[12 3 .5]", Array{Integer(12), Integer(3), Real(0.5)}},
+		{"[12 0 R 3.5]", Array{Reference{Number: 12}, Real(3.5)}},
+		{"[1 2 3 0 R]", Array{Integer(1), Integer(2), Reference{Number: 3}}},
+	}
+	for _, tt := range tests {
+		obj, err := newParser([]byte(tt.in)).ParseObject()
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(obj, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.in, obj, tt.want)
+		}
+	}
+
+	// R only ends a reference as a token of its own.
+	p := newParser([]byte("1 0 RG"))
+	obj, err := p.ParseObject()
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	if obj != Integer(1) {
+		t.Errorf("got %#v, want Integer(1)", obj)
+	}
+	if tok := p.readToken(); tok != "0" {
+		t.Errorf("next token %q, want %q", tok, "0")
+	}
+}
+
 func TestParseIndirectObject(t *testing.T) {
 	p := newParser([]byte("5 0 obj\n<< /Type /Page >>\nendobj"))
 	obj, err := p.ParseIndirectObject()
@@ -6,6 +6,7 @@ import (
 	"iter"
 	"os"
 	"strings"
+	"sync"
 )
 
 // Document represents a parsed PDF document.
@@ -16,6 +17,11 @@ type Document struct {
 	data    []byte
 	pages   []*Page
 	encrypt *encryptInfo // non-nil if document is encrypted and decrypted
+
+	objStreamsMu sync.Mutex
+	objStreams   map[int]*objectStream // decoded object streams by object number
+
+	edits map[int]IndirectObject // objects replaced by SetObject; nil Value if deleted
 }
 
 // Open opens and parses a PDF file from disk.
@@ -68,7 +74,7 @@ func parseWithPassword(data []byte, password string) (*Document, error) {
 	doc.Version = parseVersion(data)
 
 	// Find and parse cross-reference table
-	startXRef, err := findStartXRef(data)
+	startXRef, err := StartXRef(data)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +89,7 @@ func parseWithPassword(data []byte, password string) (*Document, error) {
 	// Handle encryption
 	if doc.isEncrypted() {
 		if err := doc.decrypt(password); err != nil {
-			return nil, fmt.Errorf("reader: %w", err)
+			return nil, err
 		}
 	}
 
@@ -170,12 +176,26 @@ func (d *Document) Metadata() map[string]string {
 	return meta
 }
 
+// Trailer returns the trailer dictionary of the most recent revision.
+func (d *Document) Trailer() Dict {
+	return d.trailer
+}
+
 // resolve resolves an indirect reference to the actual object.
 func (d *Document) resolve(ref Reference) (Object, error) {
+	if edit, ok := d.edits[ref.Number]; ok {
+		if edit.Value == nil {
+			return Null{}, nil
+		}
+		return edit.Value, nil
+	}
 	entry, ok := d.xref[ref.Number]
 	if !ok || !entry.InUse {
 		return Null{}, nil
 	}
+	if entry.Compressed {
+		return d.resolveCompressed(ref.Number, int(entry.Offset), entry.Generation)
+	}
 
 	if entry.Offset < 0 || int(entry.Offset) >= len(d.data) {
 		return nil, fmt.Errorf("reader: object %d offset %d out of bounds", ref.Number, entry.Offset)
@@ -183,11 +203,13 @@ func (d *Document) resolve(ref Reference) (Object, error) {
 
 	p := newParser(d.data[entry.Offset:])
 
-	// Set up per-object RC4 cipher for decryption.
-	// gofpdf reuses cipher state across strings in the same object,
-	// so we must decrypt strings in byte order during parsing.
+	// Set up per-object RC4 key for decryption.
+	// gofpdf, which writes revision 2, reuses cipher state across strings
+	// in the same object, so those are decrypted in byte order with one
+	// cipher. Later revisions use a fresh cipher for each, as specified.
 	if d.encrypt != nil && d.encrypt.key != nil {
-		p.cipher = d.makeObjectCipher(ref.Number, ref.Generation)
+		p.key = objectKey(d.encrypt.key, ref.Number, ref.Generation)
+		p.shareCipher = d.encrypt.revision == 2
 	}
 
 	obj, err := p.ParseIndirectObject()
@@ -2,6 +2,17 @@ package reader_test
 
 import (
 	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
 	gofpdf "github.com/lvillar/gofpdf"
@@ -122,6 +133,54 @@ func TestTextExtraction(t *testing.T) {
 	t.Logf("Extracted text: %q", text)
 }
 
+func TestExtractAllText(t *testing.T) {
+	var texts []string
+	for i := 1; i <= 40; i++ {
+		texts = append(texts, fmt.Sprintf("Text of page %d", i))
+	}
+	data := generateTestPDF(t, texts...)
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	all, err := doc.ExtractAllText()
+	if err != nil {
+		t.Fatalf("extracting text: %v", err)
+	}
+	if len(all) != len(texts) {
+		t.Fatalf("got text for %d pages, want %d", len(all), len(texts))
+	}
+	for n, page := range doc.Pages() {
+		want, err := page.ExtractText()
+		if err != nil {
+			t.Fatalf("extracting page %d: %v", n, err)
+		}
+		if all[n] != want || !strings.Contains(want, texts[n-1]) {
+			t.Errorf("page %d text = %q, want %q", n, all[n], want)
+		}
+	}
+
+	// A page with a corrupt content stream is reported but does not stop
+	// the others.
+	// gofpdf writes the content stream of each page right after the page.
+	broken := bytes.Clone(data)
+	first := bytes.Index(broken, []byte("stream\n"))
+	second := first + 1 + bytes.Index(broken[first+1:], []byte("\nstream\n")) + len("\nstream\n")
+	copy(broken[second:], "XX")
+	doc, err = reader.ReadFrom(bytes.NewReader(broken))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	all, err = doc.ExtractAllText()
+	if err == nil || !strings.Contains(err.Error(), "page 2") {
+		t.Errorf("error = %v, want one for page 2", err)
+	}
+	if _, ok := all[2]; ok || len(all) != len(texts)-1 {
+		t.Errorf("got text for %d pages including page 2: %v; want all but page 2", len(all), ok)
+	}
+}
+
 func TestMetadata(t *testing.T) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetTitle("Test Document", false)
@@ -172,3 +231,1026 @@ func TestMultiPageContentStream(t *testing.T) {
 	}
 	t.Logf("Content stream length: %d bytes", len(content))
 }
+
+func TestUsesTransparency(t *testing.T) {
+	// A semi-transparent diagonal watermark, as drawn by pageops.
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "B", 48)
+	pdf.AddPage()
+	pdf.Text(10, 20, "Body text")
+	pdf.SetAlpha(0.3, "Normal")
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, 105, 148)
+	pdf.Text(50, 148, "CONFIDENTIAL")
+	pdf.TransformEnd()
+	pdf.SetAlpha(1, "Normal")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, err := doc.Page(1)
+	if err != nil {
+		t.Fatalf("getting page: %v", err)
+	}
+	if !page.UsesTransparency() {
+		t.Error("expected watermarked page to use transparency")
+	}
+
+	plain, err := reader.ReadFrom(bytes.NewReader(generateTestPDF(t, "Opaque")))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, err = plain.Page(1)
+	if err != nil {
+		t.Fatalf("getting page: %v", err)
+	}
+	if page.UsesTransparency() {
+		t.Error("expected plain page not to use transparency")
+	}
+}
+
+func TestTransparencyGroup(t *testing.T) {
+	// gofpdf marks pages as RGB transparency groups once alpha is used.
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetAlpha(0.5, "Multiply")
+	pdf.Rect(10, 10, 50, 50, "F")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, err := doc.Page(1)
+	if err != nil {
+		t.Fatalf("getting page: %v", err)
+	}
+
+	group, err := page.TransparencyGroup()
+	if err != nil {
+		t.Fatalf("reading group: %v", err)
+	}
+	if group == nil {
+		t.Fatal("expected a transparency group")
+	}
+	if group.ColorSpace != "DeviceRGB" {
+		t.Errorf("ColorSpace = %q, want %q", group.ColorSpace, "DeviceRGB")
+	}
+
+	boxes, err := page.BoxColorInfo()
+	if err != nil {
+		t.Fatalf("reading box color info: %v", err)
+	}
+	if boxes != nil {
+		t.Errorf("expected no box color info, got %v", boxes)
+	}
+}
+
+// appendIncrementalUpdate appends a revision to data that redefines object
+// objNum with the given body, linking the new xref section to the old one.
+func appendIncrementalUpdate(t *testing.T, data []byte, objNum int, body string) []byte {
+	t.Helper()
+	trailer := regexp.MustCompile(`(?s)trailer\s*<<(.*?)>>\s*startxref\s*(\d+)`).FindAllSubmatch(data, -1)
+	if trailer == nil {
+		t.Fatal("trailer not found")
+	}
+	last := trailer[len(trailer)-1]
+	dict := regexp.MustCompile(`/Prev \d+`).ReplaceAll(last[1], nil)
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	objOffset := buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", objNum, body)
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 1\n0000000000 65535 f \n%d 1\n%010d 00000 n \n", objNum, objOffset)
+	fmt.Fprintf(&buf, "trailer\n<<%s /Prev %s>>\nstartxref\n%d\n%%%%EOF\n", dict, last[2], xrefOffset)
+	return buf.Bytes()
+}
+
+func TestXRefSummary(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Original", false)
+	pdf.AddPage()
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	m := regexp.MustCompile(`/Info (\d+) 0 R`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("/Info not found in trailer")
+	}
+	infoNum, _ := strconv.Atoi(string(m[1]))
+	data := appendIncrementalUpdate(t, buf.Bytes(), infoNum, "<</Title (Updated)>>")
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if got := doc.Metadata()["Title"]; got != "Updated" {
+		t.Errorf("Title = %q, want %q", got, "Updated")
+	}
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		t.Fatalf("XRefSummary: %v", err)
+	}
+	entries := summary[infoNum]
+	if len(entries) != 2 {
+		t.Fatalf("object %d: expected 2 revisions, got %+v", infoNum, entries)
+	}
+	if entries[0].Revision != 0 || entries[1].Revision != 1 {
+		t.Errorf("unexpected revisions: %+v", entries)
+	}
+	if entries[0].Offset == entries[1].Offset {
+		t.Errorf("expected different offsets per revision: %+v", entries)
+	}
+	for _, e := range entries {
+		if e.Type != "in-use" {
+			t.Errorf("expected in-use entries, got %+v", e)
+		}
+	}
+	if len(summary[0]) != 2 || summary[0][0].Type != "free" {
+		t.Errorf("object 0: expected a free entry per revision, got %+v", summary[0])
+	}
+}
+
+// xrefBuilder writes a PDF revision by revision, recording the offset of
+// every object so that cross-reference sections can be written by hand.
+type xrefBuilder struct {
+	buf        bytes.Buffer
+	offsets    map[int]int
+	compressed map[int][2]int // object stream number and index
+}
+
+func newXRefBuilder() *xrefBuilder {
+	b := &xrefBuilder{offsets: make(map[int]int), compressed: make(map[int][2]int)}
+	b.buf.WriteString("%PDF-1.5\n")
+	b.obj(1, "<</Type /Catalog /Pages 2 0 R>>")
+	b.obj(2, "<</Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 200]>>")
+	b.obj(3, "<</Type /Page /Parent 2 0 R>>")
+	b.obj(4, "<</Title (Original)>>")
+	return b
+}
+
+func (b *xrefBuilder) obj(num int, body string) {
+	b.offsets[num] = b.buf.Len()
+	fmt.Fprintf(&b.buf, "%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// objStm writes an uncompressed object stream, numbered num, holding bodies
+// as the objects nums.
+func (b *xrefBuilder) objStm(num int, nums []int, bodies []string) {
+	var header, content strings.Builder
+	for i, n := range nums {
+		fmt.Fprintf(&header, "%d %d ", n, content.Len())
+		content.WriteString(bodies[i] + "\n")
+		b.compressed[n] = [2]int{num, i}
+	}
+	data := header.String() + content.String()
+	b.obj(num, fmt.Sprintf("<</Type /ObjStm /N %d /First %d /Length %d>>\nstream\n%s\nendstream",
+		len(nums), header.Len(), len(data), data))
+}
+
+// table writes a classic cross-reference table for nums followed by a
+// trailer with the extra entries, and returns its offset.
+func (b *xrefBuilder) table(nums []int, extra string) int {
+	offset := b.buf.Len()
+	b.buf.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	size := 1
+	for _, n := range nums {
+		fmt.Fprintf(&b.buf, "%d 1\n%010d 00000 n \n", n, b.offsets[n])
+		size = max(size, n+1)
+	}
+	fmt.Fprintf(&b.buf, "trailer\n<</Size %d /Root 1 0 R /Info 4 0 R%s>>\n", size, extra)
+	return offset
+}
+
+// stream writes an uncompressed cross-reference stream, numbered num, for
+// nums and itself with the extra dictionary entries, and returns its offset.
+// Objects written by objStm get compressed entries.
+func (b *xrefBuilder) stream(num int, nums []int, extra string) int {
+	offset := b.buf.Len()
+	b.offsets[num] = offset
+	var data []byte
+	var index []string
+	size := num + 1
+	for _, n := range append(nums, num) {
+		if c, ok := b.compressed[n]; ok {
+			data = append(data, 2, byte(c[0]>>24), byte(c[0]>>16), byte(c[0]>>8), byte(c[0]), byte(c[1]>>8), byte(c[1]))
+		} else {
+			off := b.offsets[n]
+			data = append(data, 1, byte(off>>24), byte(off>>16), byte(off>>8), byte(off), 0, 0)
+		}
+		index = append(index, fmt.Sprintf("%d 1", n))
+		size = max(size, n+1)
+	}
+	fmt.Fprintf(&b.buf, "%d 0 obj\n<</Type /XRef /Size %d /W [1 4 2] /Index [%s] /Root 1 0 R /Info 4 0 R%s /Length %d>>\nstream\n",
+		num, size, strings.Join(index, " "), extra, len(data))
+	b.buf.Write(data)
+	b.buf.WriteString("\nendstream\nendobj\n")
+	return offset
+}
+
+func (b *xrefBuilder) startxref(offset int) {
+	fmt.Fprintf(&b.buf, "startxref\n%d\n%%%%EOF\n", offset)
+}
+
+func TestXRefStreamPrevChain(t *testing.T) {
+	b := newXRefBuilder()
+	table := b.table([]int{1, 2, 3, 4}, "")
+	b.startxref(table)
+
+	// A cross-reference stream updating a classic table...
+	b.obj(4, "<</Title (Second)>>")
+	first := b.stream(5, []int{4}, fmt.Sprintf(" /Prev %d", table))
+	b.startxref(first)
+
+	// ...and one updating that stream.
+	b.obj(4, "<</Title (Third)>>")
+	b.obj(6, "<</Kind (added)>>")
+	second := b.stream(7, []int{4, 6}, fmt.Sprintf(" /Prev %d", first))
+	b.startxref(second)
+
+	doc, err := reader.ReadFrom(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if got := doc.Metadata()["Title"]; got != "Third" {
+		t.Errorf("Title = %q, want %q", got, "Third")
+	}
+	if n := doc.NumPages(); n != 1 {
+		t.Errorf("NumPages = %d, want 1", n)
+	}
+	obj, err := doc.ResolveReference(reader.Reference{Number: 6})
+	if err != nil {
+		t.Fatalf("resolving object 6: %v", err)
+	}
+	if d, ok := obj.(reader.Dict); !ok || d["Kind"] == nil {
+		t.Errorf("object 6 = %#v, want the added dictionary", obj)
+	}
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		t.Fatalf("XRefSummary: %v", err)
+	}
+	if entries := summary[4]; len(entries) != 3 {
+		t.Errorf("object 4: expected 3 revisions, got %+v", entries)
+	}
+}
+
+func TestXRefHybrid(t *testing.T) {
+	b := newXRefBuilder()
+	table := b.table([]int{1, 2, 3, 4}, "")
+	b.startxref(table)
+
+	// Object 6 is listed only in the stream named by /XRefStm, which
+	// readers of classic tables ignore.
+	b.obj(4, "<</Title (Hybrid)>>")
+	b.obj(6, "<</Kind (hidden)>>")
+	stm := b.stream(5, []int{6}, "")
+	update := b.table([]int{4}, fmt.Sprintf(" /Size 7 /Prev %d /XRefStm %d", table, stm))
+	b.startxref(update)
+
+	doc, err := reader.ReadFrom(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if got := doc.Metadata()["Title"]; got != "Hybrid" {
+		t.Errorf("Title = %q, want %q", got, "Hybrid")
+	}
+	obj, err := doc.ResolveReference(reader.Reference{Number: 6})
+	if err != nil {
+		t.Fatalf("resolving object 6: %v", err)
+	}
+	if d, ok := obj.(reader.Dict); !ok || d["Kind"] == nil {
+		t.Errorf("object 6 = %#v, want the dictionary from /XRefStm", obj)
+	}
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		t.Fatalf("XRefSummary: %v", err)
+	}
+	if entries := summary[6]; len(entries) != 1 {
+		t.Errorf("object 6: expected 1 entry, got %+v", entries)
+	}
+}
+
+func TestXRefPrevLoop(t *testing.T) {
+	b := newXRefBuilder()
+	// The original section's /Prev points back at itself.
+	first := b.buf.Len()
+	b.stream(5, []int{1, 2, 3, 4}, fmt.Sprintf(" /Prev %d", first))
+	b.startxref(first)
+	b.obj(4, "<</Title (Looped)>>")
+	second := b.stream(6, []int{4}, fmt.Sprintf(" /Prev %d", first))
+	b.startxref(second)
+
+	doc, err := reader.ReadFrom(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if got := doc.Metadata()["Title"]; got != "Looped" {
+		t.Errorf("Title = %q, want %q", got, "Looped")
+	}
+}
+
+func TestInheritedResources(t *testing.T) {
+	// The root sets the MediaBox and the middle node of a three-level tree
+	// sets /Resources; neither page has its own.
+	b := newXRefBuilder()
+	b.obj(2, "<</Type /Pages /Kids [5 0 R] /Count 2 /MediaBox [0 0 300 400]>>")
+	b.obj(5, "<</Type /Pages /Parent 2 0 R /Kids [7 0 R 3 0 R] /Count 2 /Resources 6 0 R>>")
+	b.obj(6, "<</Font <</F1 <</Type /Font /Subtype /Type1 /BaseFont /Helvetica>>>>>>")
+	b.obj(7, "<</Type /Pages /Parent 5 0 R /Kids [8 0 R] /Count 1>>")
+	b.obj(8, "<</Type /Page /Parent 7 0 R>>")
+	b.obj(3, "<</Type /Page /Parent 5 0 R /Resources null>>")
+	b.startxref(b.table([]int{1, 2, 3, 4, 5, 6, 7, 8}, ""))
+
+	doc, err := reader.ReadFrom(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	if n := doc.NumPages(); n != 2 {
+		t.Fatalf("NumPages = %d, want 2", n)
+	}
+	for i, page := range doc.Pages() {
+		if page.Resources.GetDict("Font") == nil {
+			t.Errorf("page %d: Resources = %v, want the middle node's", i, page.Resources)
+		}
+		if page.MediaBox.URX != 300 || page.MediaBox.URY != 400 {
+			t.Errorf("page %d: MediaBox = %+v, want the root's", i, page.MediaBox)
+		}
+	}
+}
+
+func TestAppendUpdate(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Original", false)
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	orig := buf.Bytes()
+
+	doc, err := reader.ReadFrom(bytes.NewReader(orig))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	infoRef, ok := doc.Trailer()["Info"].(reader.Reference)
+	if !ok {
+		t.Fatal("/Info is not a reference")
+	}
+	info, err := doc.ResolveReference(infoRef)
+	if err != nil {
+		t.Fatalf("resolving /Info: %v", err)
+	}
+	updated := reader.Dict{}
+	for k, v := range info.(reader.Dict) {
+		updated[k] = v
+	}
+	updated["Title"] = reader.String{Value: []byte("Edited (draft)")}
+	size, _ := doc.Trailer().GetInt("Size")
+	added := reader.Reference{Number: int(size)}
+
+	var out bytes.Buffer
+	err = doc.AppendUpdate(&out, map[reader.Reference]reader.Object{
+		infoRef: updated,
+		added:   reader.Stream{Dict: reader.Dict{}, Data: []byte("extra")},
+	})
+	if err != nil {
+		t.Fatalf("AppendUpdate: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), orig) {
+		t.Fatal("update does not start with the original bytes")
+	}
+	if off, err := reader.StartXRef(out.Bytes()); err != nil || !bytes.HasPrefix(out.Bytes()[off:], []byte("xref")) {
+		t.Errorf("StartXRef = %d, %v, want the offset of the update's xref section", off, err)
+	}
+
+	doc, err = reader.ReadFrom(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("reading updated PDF: %v", err)
+	}
+	if got := doc.Metadata()["Title"]; got != "Edited (draft)" {
+		t.Errorf("Title = %q, want %q", got, "Edited (draft)")
+	}
+	if n := doc.NumPages(); n != 1 {
+		t.Errorf("NumPages = %d, want 1", n)
+	}
+	obj, err := doc.ResolveReference(added)
+	if err != nil {
+		t.Fatalf("resolving added object: %v", err)
+	}
+	if s, ok := obj.(reader.Stream); !ok || string(s.Data) != "extra" {
+		t.Errorf("added object = %#v, want a stream with data %q", obj, "extra")
+	}
+	if got, _ := doc.Trailer().GetInt("Size"); got != size+1 {
+		t.Errorf("/Size = %d, want %d", got, size+1)
+	}
+
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		t.Fatalf("XRefSummary: %v", err)
+	}
+	if entries := summary[infoRef.Number]; len(entries) != 2 {
+		t.Errorf("object %d: expected 2 revisions, got %+v", infoRef.Number, entries)
+	}
+
+	// Encrypted documents cannot be updated.
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.SetProtection(0, "", "owner")
+	pdf.AddPage()
+	buf.Reset()
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading protected PDF: %v", err)
+	}
+	if err := doc.AppendUpdate(io.Discard, nil); err == nil {
+		t.Error("expected an error updating an encrypted document")
+	}
+}
+
+func TestWriteObject(t *testing.T) {
+	tests := []struct {
+		obj  reader.Object
+		want string
+	}{
+		{reader.Null{}, "null"},
+		{reader.Real(1.5), "1.5"},
+		{reader.Name("A B#"), "/A#20B#23"},
+		{reader.String{Value: []byte("a(b)\\")}, `(a\(b\)\\)`},
+		{reader.String{Value: []byte{0xAB, 0x01}, IsHex: true}, "<AB01>"},
+		{reader.Array{reader.Integer(1), reader.Reference{Number: 4}}, "[1 4 0 R]"},
+		{reader.Dict{"Type": reader.Name("Page"), "Count": reader.Boolean(true)}, "<</Count true/Type /Page>>"},
+		{reader.Stream{Dict: reader.Dict{}, Data: []byte("q Q")}, "<</Length 3>>\nstream\nq Q\nendstream"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := reader.WriteObject(&buf, tt.obj); err != nil {
+			t.Fatalf("WriteObject(%v): %v", tt.obj, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("WriteObject(%v) = %q, want %q", tt.obj, got, tt.want)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	data := generateTestPDF(t, "Hello World", "Page Two")
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+
+	size, _ := doc.Trailer().GetInt("Size")
+	info := reader.Reference{Number: int(size)}
+	doc.SetObject(info, reader.Dict{"Title": reader.String{Value: []byte("Saved")}})
+	doc.Trailer()["Info"] = info
+
+	var out bytes.Buffer
+	if err := doc.Save(&out); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved, err := reader.ReadFrom(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("reading saved PDF: %v", err)
+	}
+	if got := saved.Metadata()["Title"]; got != "Saved" {
+		t.Errorf("Title = %q, want %q", got, "Saved")
+	}
+	if saved.NumPages() != doc.NumPages() {
+		t.Fatalf("NumPages = %d, want %d", saved.NumPages(), doc.NumPages())
+	}
+	for i, page := range doc.Pages() {
+		want, err := page.ExtractText()
+		if err != nil {
+			t.Fatalf("page %d: %v", i, err)
+		}
+		savedPage, _ := saved.Page(i)
+		got, err := savedPage.ExtractText()
+		if err != nil {
+			t.Fatalf("saved page %d: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("page %d text = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSaveObjectStreams(t *testing.T) {
+	b := newXRefBuilder()
+	b.objStm(5, []int{3, 6}, []string{"<</Type /Page /Parent 2 0 R>>", "<</Kind (extra)>>"})
+	b.startxref(b.stream(7, []int{1, 2, 3, 4, 5, 6}, ""))
+
+	doc, err := reader.ReadFrom(bytes.NewReader(b.buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	doc.SetObject(reader.Reference{Number: 4}, reader.Dict{"Title": reader.String{Value: []byte("Edited")}})
+	doc.SetObject(reader.Reference{Number: 6}, nil)
+
+	var out bytes.Buffer
+	if err := doc.Save(&out); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	for _, s := range []string{"/ObjStm", "/XRef", "/Prev"} {
+		if bytes.Contains(out.Bytes(), []byte(s)) {
+			t.Errorf("saved PDF contains %s", s)
+		}
+	}
+
+	saved, err := reader.ReadFrom(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("reading saved PDF: %v", err)
+	}
+	if got := saved.Metadata()["Title"]; got != "Edited" {
+		t.Errorf("Title = %q, want %q", got, "Edited")
+	}
+	if n := saved.NumPages(); n != 1 {
+		t.Errorf("NumPages = %d, want 1", n)
+	}
+	if obj, err := saved.ResolveReference(reader.Reference{Number: 6}); err != nil || obj != (reader.Null{}) {
+		t.Errorf("deleted object 6 = %#v, %v; want null", obj, err)
+	}
+}
+
+func TestPageImages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 40, 30))
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, img, nil); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+	jpgData := bytes.Clone(jpg.Bytes())
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	opt := gofpdf.ImageOptions{ImageType: "JPG"}
+	pdf.RegisterImageOptionsReader("logo", opt, &jpg)
+	pdf.AddPage()
+	pdf.ImageOptions("logo", 10, 10, 80, 60, false, opt, 0, "")
+	pdf.TransformBegin()
+	pdf.TransformScale(200, 200, 0, 0)
+	pdf.ImageOptions("logo", 100, 100, 80, 60, false, opt, 0, "")
+	pdf.TransformEnd()
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, _ := doc.Page(1)
+	images, err := page.Images()
+	if err != nil {
+		t.Fatalf("images: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("expected 1 image, got %d", len(images))
+	}
+	got := images[0]
+	if got.Width != 40 || got.Height != 30 || got.ColorSpace != "DeviceGray" || got.Filter != "DCTDecode" {
+		t.Errorf("image = %dx%d %s %s, want 40x30 DeviceGray DCTDecode",
+			got.Width, got.Height, got.ColorSpace, got.Filter)
+	}
+	// The second placement is scaled by 200%.
+	if got.DrawnWidth != 160 || got.DrawnHeight != 120 {
+		t.Errorf("drawn size = %gx%g, want 160x120", got.DrawnWidth, got.DrawnHeight)
+	}
+	// Decoding leaves the JPEG file as it is.
+	if data, err := got.Stream.Decode(); err != nil || !bytes.Equal(data, jpgData) {
+		t.Errorf("decoded stream = %d bytes, %v; want the %d byte JPEG", len(data), err, len(jpgData))
+	}
+}
+
+func TestStreamDecodeImageFilters(t *testing.T) {
+	jpegData := "\xff\xd8\xff\xd9"
+	for _, tc := range []struct {
+		filter reader.Object
+		data   string
+	}{
+		{reader.Name("DCTDecode"), jpegData},
+		{reader.Name("JPXDecode"), jpegData},
+		{reader.Array{reader.Name("ASCIIHexDecode"), reader.Name("DCTDecode")}, "FFD8FFD9>"},
+	} {
+		s := reader.Stream{Dict: reader.Dict{"Filter": tc.filter}, Data: []byte(tc.data)}
+		got, err := s.Decode()
+		if err != nil {
+			t.Errorf("%v: %v", tc.filter, err)
+			continue
+		}
+		if string(got) != jpegData {
+			t.Errorf("%v: decoded %x, want %x", tc.filter, got, jpegData)
+		}
+	}
+}
+
+func TestImageDecode(t *testing.T) {
+	rgb := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	for i := range rgb.Pix {
+		rgb.Pix[i] = uint8(i * 7)
+	}
+	for i := 3; i < len(rgb.Pix); i += 4 {
+		rgb.Pix[i] = 255
+	}
+	palette := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	indexed := image.NewPaletted(image.Rect(0, 0, 5, 3), palette)
+	indexed.SetColorIndex(2, 1, 1)
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	opt := gofpdf.ImageOptions{ImageType: "PNG"}
+	for name, img := range map[string]image.Image{"rgb": rgb, "indexed": indexed} {
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			t.Fatalf("encoding PNG: %v", err)
+		}
+		pdf.RegisterImageOptionsReader(name, opt, &b)
+	}
+	pdf.AddPage()
+	pdf.ImageOptions("rgb", 10, 10, 60, 40, false, opt, 0, "")
+	pdf.ImageOptions("indexed", 10, 60, 50, 30, false, opt, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, _ := doc.Page(1)
+	images, err := page.Images()
+	if err != nil {
+		t.Fatalf("images: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(images))
+	}
+
+	got, err := images[0].Decode()
+	if err != nil {
+		t.Fatalf("decoding RGB image: %v", err)
+	}
+	if got.Bounds() != rgb.Bounds() {
+		t.Fatalf("RGB image bounds = %v, want %v", got.Bounds(), rgb.Bounds())
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			if c, want := color.RGBAModel.Convert(got.At(x, y)), rgb.At(x, y); c != want {
+				t.Fatalf("RGB pixel (%d,%d) = %v, want %v", x, y, c, want)
+			}
+		}
+	}
+
+	got, err = images[1].Decode()
+	if err != nil {
+		t.Fatalf("decoding indexed image: %v", err)
+	}
+	if c := color.RGBAModel.Convert(got.At(2, 1)); c != palette[1] {
+		t.Errorf("indexed pixel (2,1) = %v, want %v", c, palette[1])
+	}
+	if c := color.RGBAModel.Convert(got.At(0, 0)); c != palette[0] {
+		t.Errorf("indexed pixel (0,0) = %v, want %v", c, palette[0])
+	}
+}
+
+func TestPageRender(t *testing.T) {
+	blue := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(blue.Pix); i += 4 {
+		copy(blue.Pix[i:], []byte{0, 0, 255, 255})
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, blue); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+
+	pdf := gofpdf.New("P", "pt", "", "")
+	opt := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("blue", opt, &pngData)
+	pdf.AddPageFormat("P", gofpdf.SizeType{Wd: 200, Ht: 100})
+	pdf.SetFillColor(255, 0, 0)
+	pdf.Rect(10, 10, 50, 30, "F")
+	pdf.ImageOptions("blue", 100, 10, 40, 40, false, opt, 0, "")
+	pdf.SetFont("Helvetica", "", 20)
+	pdf.Text(10, 90, "Hello")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, _ := doc.Page(1)
+	img, err := page.Render(2)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if img.Bounds().Dx() != 400 || img.Bounds().Dy() != 200 {
+		t.Fatalf("rendered size = %v, want 400x200", img.Bounds())
+	}
+
+	// Points are given in top-down page coordinates, like gofpdf's
+	at := func(x, y float64) color.RGBA { return img.RGBAAt(int(x*2), int(y*2)) }
+	for _, tc := range []struct {
+		name string
+		x, y float64
+		want color.RGBA
+	}{
+		{"rectangle", 35, 25, color.RGBA{255, 0, 0, 255}},
+		{"image", 120, 30, color.RGBA{0, 0, 255, 255}},
+		{"background", 80, 60, color.RGBA{255, 255, 255, 255}},
+	} {
+		if got := at(tc.x, tc.y); got != tc.want {
+			t.Errorf("%s pixel = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	// Text shows as a gray bar above the baseline
+	if got := at(20, 87); got.R == 255 || got.R == 0 || got.R != got.B {
+		t.Errorf("text pixel = %v, want gray", got)
+	}
+
+	if _, err := page.Render(0); err == nil {
+		t.Error("expected an error for a zero scale")
+	}
+
+	// A page rotated clockwise shows its bottom-left corner at the top left
+	rotated := &reader.Page{
+		MediaBox: reader.Rectangle{URX: 200, URY: 100},
+		Rotate:   90,
+		Contents: []reader.Stream{{Dict: reader.Dict{}, Data: []byte("1 0 0 rg 0 0 10 10 re f")}},
+	}
+	img, err = rotated.Render(1)
+	if err != nil {
+		t.Fatalf("render rotated page: %v", err)
+	}
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 200 {
+		t.Errorf("rotated page rendered as %v, want 100x200", img.Bounds())
+	}
+	if got := img.RGBAAt(5, 5); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("rotated page top-left pixel = %v, want red", got)
+	}
+}
+
+func TestExtractTextByLayer(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	draft := pdf.AddLayer("Draft", true)
+	notes := pdf.AddLayer("Notes", false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.Text(20, 20, "Body text")
+	pdf.BeginLayer(draft)
+	pdf.Text(20, 30, "Draft stamp")
+	pdf.EndLayer()
+	pdf.BeginLayer(notes)
+	pdf.Text(20, 40, "Reviewer note")
+	pdf.EndLayer()
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	page, _ := doc.Page(1)
+	layers, err := page.ExtractTextByLayer()
+	if err != nil {
+		t.Fatalf("extracting text: %v", err)
+	}
+
+	want := map[string]string{"": "Body text", "Draft": "Draft stamp", "Notes": "Reviewer note"}
+	if len(layers) != len(want) {
+		t.Errorf("got %d layers %q, want %d", len(layers), layers, len(want))
+	}
+	for name, text := range want {
+		if layers[name] != text {
+			t.Errorf("layer %q text = %q, want %q", name, layers[name], text)
+		}
+	}
+
+	// Plain extraction still returns all text.
+	all, err := page.ExtractText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range want {
+		if !strings.Contains(all, text) {
+			t.Errorf("ExtractText() = %q, missing %q", all, text)
+		}
+	}
+}
+
+func TestOutputIntents(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("generating PDF: %v", err)
+	}
+
+	m := regexp.MustCompile(`(?s)(\d+) 0 obj\s*(<<\s*/Type /Catalog.*?)>>\s*endobj`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("catalog not found")
+	}
+	catalogNum, _ := strconv.Atoi(string(m[1]))
+	size := regexp.MustCompile(`/Size (\d+)`).FindSubmatch(buf.Bytes())
+	profileNum, _ := strconv.Atoi(string(size[1]))
+
+	icc := "fake ICC profile data"
+	data := appendIncrementalUpdate(t, buf.Bytes(), profileNum,
+		fmt.Sprintf("<</N 4 /Length %d>>\nstream\n%s\nendstream", len(icc), icc))
+	data = appendIncrementalUpdate(t, data, catalogNum, fmt.Sprintf(
+		"%s/OutputIntents [<</Type /OutputIntent /S /GTS_PDFX /OutputConditionIdentifier (FOGRA39) "+
+			"/RegistryName (http://www.color.org) /DestOutputProfile %d 0 R>>]>>", m[2], profileNum))
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	intents, err := doc.OutputIntents()
+	if err != nil {
+		t.Fatalf("output intents: %v", err)
+	}
+	if len(intents) != 1 {
+		t.Fatalf("expected 1 output intent, got %d", len(intents))
+	}
+	got := intents[0]
+	if got.Subtype != "GTS_PDFX" || got.OutputConditionIdentifier != "FOGRA39" || got.RegistryName != "http://www.color.org" {
+		t.Errorf("unexpected output intent %+v", got)
+	}
+	if string(got.Profile) != icc || got.Components != 4 {
+		t.Errorf("profile = %q with %d components, want %q with 4", got.Profile, got.Components, icc)
+	}
+
+	// A document without output intents returns none.
+	plain, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intents, err := plain.OutputIntents(); err != nil || intents != nil {
+		t.Errorf("OutputIntents() = %v, %v; want nil, nil", intents, err)
+	}
+}
+
+func TestOutlines(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	for _, chapter := range []string{"Introduction", "Methods"} {
+		pdf.AddPage()
+		pdf.Bookmark(chapter, 0, 0)
+		pdf.AddPage()
+		pdf.Bookmark(chapter+" details", 1, 20)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	items, err := doc.Outlines()
+	if err != nil {
+		t.Fatalf("outlines: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 top-level items, got %d", len(items))
+	}
+	for i, want := range []struct {
+		title string
+		page  int
+	}{{"Introduction", 1}, {"Methods", 3}} {
+		item := items[i]
+		if item.Title != want.title || item.Page != want.page {
+			t.Errorf("item %d = %q on page %d, want %q on page %d", i, item.Title, item.Page, want.title, want.page)
+		}
+		if len(item.Children) != 1 || item.Children[0].Page != want.page+1 {
+			t.Errorf("item %d: expected one child on page %d, got %+v", i, want.page+1, item.Children)
+		}
+	}
+
+	// A document without an outline has no items
+	doc, err = reader.ReadFrom(bytes.NewReader(generateTestPDF(t, "plain")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, err := doc.Outlines(); err != nil || items != nil {
+		t.Errorf("expected no outline, got %v, %v", items, err)
+	}
+}
+
+func TestPageLinks(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	target := pdf.AddLink()
+	pdf.LinkString(10, 10, 50, 10, "https://example.com/a?b=c")
+	pdf.Link(10, 30, 40, 10, target)
+	pdf.AddPage()
+	pdf.SetLink(target, 0, -1)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	page, _ := doc.Page(1)
+	links, err := page.Links()
+	if err != nil {
+		t.Fatalf("links: %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %+v", links)
+	}
+	if links[0].URL != "https://example.com/a?b=c" || links[0].TargetPage != 0 {
+		t.Errorf("external link = %+v", links[0])
+	}
+	if w := links[0].Rect.Width(); math.Abs(w-50*72/25.4) > 0.01 {
+		t.Errorf("external link width = %.2f, want %.2f", w, 50*72/25.4)
+	}
+	if links[1].URL != "" || links[1].TargetPage != 2 {
+		t.Errorf("internal link = %+v, want target page 2", links[1])
+	}
+
+	// A page without annotations has no links
+	page, _ = doc.Page(2)
+	if links, err := page.Links(); err != nil || links != nil {
+		t.Errorf("expected no links, got %v, %v", links, err)
+	}
+}
+
+func TestPageLabels(t *testing.T) {
+	data := generateTestPDF(t, "1", "2", "3", "4", "5", "6", "7")
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	labels, err := doc.PageLabels()
+	if err != nil {
+		t.Fatalf("labels: %v", err)
+	}
+	if want := []string{"1", "2", "3", "4", "5", "6", "7"}; !slices.Equal(labels, want) {
+		t.Errorf("labels without /PageLabels = %q, want %q", labels, want)
+	}
+
+	// Add a number tree split over two kids to the catalog
+	catalog, err := doc.Catalog()
+	if err != nil {
+		t.Fatalf("catalog: %v", err)
+	}
+	str := func(s string) reader.String { return reader.String{Value: []byte(s)} }
+	updated := reader.Dict{}
+	for k, v := range catalog {
+		updated[k] = v
+	}
+	updated["PageLabels"] = reader.Dict{"Kids": reader.Array{
+		reader.Dict{"Limits": reader.Array{reader.Integer(0), reader.Integer(2)}, "Nums": reader.Array{
+			reader.Integer(0), reader.Dict{"S": reader.Name("r")},
+			reader.Integer(2), reader.Dict{"S": reader.Name("D")},
+		}},
+		reader.Dict{"Limits": reader.Array{reader.Integer(4), reader.Integer(6)}, "Nums": reader.Array{
+			reader.Integer(4), reader.Dict{"S": reader.Name("D"), "P": str("A-"), "St": reader.Integer(3)},
+			reader.Integer(5), reader.Dict{"S": reader.Name("A"), "St": reader.Integer(27)},
+			reader.Integer(6), reader.Dict{"P": str("Back")},
+		}},
+	}}
+	var out bytes.Buffer
+	rootRef := doc.Trailer()["Root"].(reader.Reference)
+	if err := doc.AppendUpdate(&out, map[reader.Reference]reader.Object{rootRef: updated}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	doc, err = reader.ReadFrom(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("reading updated: %v", err)
+	}
+	labels, err = doc.PageLabels()
+	if err != nil {
+		t.Fatalf("labels: %v", err)
+	}
+	if want := []string{"i", "ii", "1", "2", "A-3", "AA", "Back"}; !slices.Equal(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+}
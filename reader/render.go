@@ -0,0 +1,660 @@
+package reader
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/vector"
+)
+
+// maxRenderSize bounds the width and height in pixels of a rendered page.
+const maxRenderSize = 10000
+
+// Render draws the page at scale pixels per point (1 gives 72 dpi), for
+// previews and thumbnails. The visible area is the crop box, or the media
+// box if the page has none, turned by the page rotation.
+//
+// The renderer is deliberately simple. It fills and strokes paths in
+// DeviceGray, DeviceRGB and DeviceCMYK colors, honors constant opacity and
+// draws the images that Decode supports. Text is not rasterized: each word
+// is drawn as a translucent bar in its fill color, at its position, size
+// and direction, with widths estimated from the font's /Widths. Clipping,
+// shadings, patterns, line dashes and joins, soft masks and inline images
+// are ignored.
+func (p *Page) Render(scale float64) (*image.RGBA, error) {
+	if scale <= 0 {
+		return nil, fmt.Errorf("reader: invalid render scale %g", scale)
+	}
+	box := p.MediaBox
+	if p.CropBox != nil {
+		box = *p.CropBox
+	}
+	w := int(math.Ceil(box.Width() * scale))
+	h := int(math.Ceil(box.Height() * scale))
+	rotate := (p.Rotate%360 + 360) % 360
+	if rotate == 90 || rotate == 270 {
+		w, h = h, w
+	}
+	if w <= 0 || h <= 0 || w > maxRenderSize || h > maxRenderSize {
+		return nil, fmt.Errorf("reader: cannot render page %d at %dx%d pixels", p.Number, w, h)
+	}
+
+	content, err := p.ContentStream()
+	if err != nil {
+		return nil, err
+	}
+
+	// device maps default user space to pixels, y pointing down
+	s := scale
+	var device matrix
+	switch rotate {
+	case 90:
+		device = matrix{0, s, s, 0, -box.LLY * s, -box.LLX * s}
+	case 180:
+		device = matrix{-s, 0, 0, s, box.URX * s, -box.LLY * s}
+	case 270:
+		device = matrix{0, -s, -s, 0, box.URY * s, box.URX * s}
+	default:
+		device = matrix{s, 0, 0, -s, -box.LLX * s, box.URY * s}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range dst.Pix {
+		dst.Pix[i] = 0xff
+	}
+	r := &renderer{
+		doc:     p.doc,
+		dst:     dst,
+		ras:     vector.NewRasterizer(w, h),
+		visited: make(map[int]bool),
+	}
+	r.run(content, p.Resources, newGraphicsState(device))
+	return dst, nil
+}
+
+// graphicsState is the part of the PDF graphics and text state that the
+// renderer uses.
+type graphicsState struct {
+	ctm                matrix
+	fill, stroke       color.RGBA // opaque colors
+	fillAlpha          float64
+	strokeAlpha        float64
+	lineWidth          float64
+	font               Dict
+	fontSize           float64
+	charSpace          float64
+	wordSpace          float64
+	hScale             float64
+	leading            float64
+	rise               float64
+	textRender         int
+	textMatrix         matrix
+	textLineMatrix     matrix
+	currentX, currentY float64 // current point in device space
+}
+
+func newGraphicsState(ctm matrix) graphicsState {
+	return graphicsState{
+		ctm:         ctm,
+		fill:        color.RGBA{0, 0, 0, 0xff},
+		stroke:      color.RGBA{0, 0, 0, 0xff},
+		fillAlpha:   1,
+		strokeAlpha: 1,
+		lineWidth:   1,
+		hScale:      1,
+	}
+}
+
+// renderer rasterizes content streams onto an image.
+type renderer struct {
+	doc     *Document
+	dst     *image.RGBA
+	ras     *vector.Rasterizer
+	path    [][]point // subpaths of the current path, in device space
+	visited map[int]bool
+}
+
+type point struct{ x, y float64 }
+
+// apply returns the point (x, y) transformed by m.
+func (m matrix) apply(x, y float64) point {
+	return point{x*m[0] + y*m[2] + m[4], x*m[1] + y*m[3] + m[5]}
+}
+
+// run interprets a content stream with the resources res.
+func (r *renderer) run(data []byte, res Dict, gs graphicsState) {
+	var stack []graphicsState
+	var operands []Object
+	p := newParser(data)
+	for {
+		p.skipWhitespace()
+		b, ok := p.peek()
+		if !ok {
+			return
+		}
+
+		numeric := b >= '0' && b <= '9' || b == '+' || b == '-' || b == '.'
+		if isRegular(b) && !numeric {
+			op := p.readToken()
+			if op == "BI" {
+				p.pos = skipInlineImage(data, p.pos)
+			}
+			switch op {
+			case "q":
+				stack = append(stack, gs)
+			case "Q":
+				if len(stack) > 0 {
+					gs = stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+				}
+			default:
+				r.operator(op, operands, res, &gs)
+			}
+			operands = operands[:0]
+			continue
+		}
+
+		var obj Object
+		var err error
+		if numeric {
+			var f float64
+			f, err = strconv.ParseFloat(p.readToken(), 64)
+			obj = Real(f)
+		} else {
+			obj, err = p.ParseObject()
+		}
+		if err != nil {
+			if p.pos < len(data) && !isRegular(data[p.pos]) {
+				p.pos++
+			}
+			operands = operands[:0]
+			continue
+		}
+		operands = append(operands, obj)
+	}
+}
+
+// operator executes a content stream operator other than q and Q.
+func (r *renderer) operator(op string, operands []Object, res Dict, gs *graphicsState) {
+	nums := make([]float64, 0, len(operands))
+	for _, o := range operands {
+		if f, ok := numberValue(o); ok {
+			nums = append(nums, f)
+		}
+	}
+	// Operands are taken from the end, skipping any junk before them
+	has := func(n int) bool { return len(nums) >= n }
+	last := func() float64 {
+		if len(nums) == 0 {
+			return 0
+		}
+		return nums[len(nums)-1]
+	}
+
+	switch op {
+	// Graphics state
+	case "cm":
+		if has(6) {
+			n := nums[len(nums)-6:]
+			gs.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.multiply(gs.ctm)
+		}
+	case "w":
+		if has(1) {
+			gs.lineWidth = last()
+		}
+	case "gs":
+		if len(operands) > 0 {
+			name, _ := operands[len(operands)-1].(Name)
+			extGState := r.doc.resolveDict(r.doc.resolveDict(res["ExtGState"])[name])
+			if a, ok := extGState.GetFloat("ca"); ok {
+				gs.fillAlpha = a
+			}
+			if a, ok := extGState.GetFloat("CA"); ok {
+				gs.strokeAlpha = a
+			}
+			if lw, ok := extGState.GetFloat("LW"); ok {
+				gs.lineWidth = lw
+			}
+		}
+
+	// Colors
+	case "g", "rg", "k", "sc", "scn", "cs":
+		gs.fill = deviceColor(nums)
+	case "G", "RG", "K", "SC", "SCN", "CS":
+		gs.stroke = deviceColor(nums)
+
+	// Paths
+	case "m":
+		if has(2) {
+			pt := gs.ctm.apply(nums[len(nums)-2], nums[len(nums)-1])
+			r.path = append(r.path, []point{pt})
+			gs.currentX, gs.currentY = pt.x, pt.y
+		}
+	case "l":
+		if has(2) {
+			r.lineTo(gs, gs.ctm.apply(nums[len(nums)-2], nums[len(nums)-1]))
+		}
+	case "c", "v", "y":
+		want := 6
+		if op != "c" {
+			want = 4
+		}
+		if !has(want) {
+			break
+		}
+		n := nums[len(nums)-want:]
+		p0 := point{gs.currentX, gs.currentY}
+		var p1, p2, p3 point
+		switch op {
+		case "c":
+			p1, p2, p3 = gs.ctm.apply(n[0], n[1]), gs.ctm.apply(n[2], n[3]), gs.ctm.apply(n[4], n[5])
+		case "v":
+			p1, p2, p3 = p0, gs.ctm.apply(n[0], n[1]), gs.ctm.apply(n[2], n[3])
+		case "y":
+			p1 = gs.ctm.apply(n[0], n[1])
+			p2, p3 = gs.ctm.apply(n[2], n[3]), gs.ctm.apply(n[2], n[3])
+		}
+		r.curveTo(gs, p0, p1, p2, p3)
+	case "h":
+		r.closePath(gs)
+	case "re":
+		if has(4) {
+			n := nums[len(nums)-4:]
+			x, y, w, h := n[0], n[1], n[2], n[3]
+			start := gs.ctm.apply(x, y)
+			r.path = append(r.path, []point{start, gs.ctm.apply(x+w, y), gs.ctm.apply(x+w, y+h), gs.ctm.apply(x, y+h), start})
+			gs.currentX, gs.currentY = start.x, start.y
+		}
+	case "S", "s":
+		if op == "s" {
+			r.closePath(gs)
+		}
+		r.strokePath(gs)
+		r.path = nil
+	case "f", "F", "f*":
+		r.fillPath(gs)
+		r.path = nil
+	case "B", "B*", "b", "b*":
+		if op == "b" || op == "b*" {
+			r.closePath(gs)
+		}
+		r.fillPath(gs)
+		r.strokePath(gs)
+		r.path = nil
+	case "n":
+		r.path = nil
+
+	// Text
+	case "BT":
+		gs.textMatrix, gs.textLineMatrix = identityMatrix, identityMatrix
+	case "Tf":
+		if len(operands) >= 2 {
+			name, _ := operands[len(operands)-2].(Name)
+			gs.font = r.doc.resolveDict(r.doc.resolveDict(res["Font"])[name])
+			gs.fontSize, _ = numberValue(operands[len(operands)-1])
+		}
+	case "Tc":
+		gs.charSpace = last()
+	case "Tw":
+		gs.wordSpace = last()
+	case "Tz":
+		gs.hScale = last() / 100
+	case "TL":
+		gs.leading = last()
+	case "Ts":
+		gs.rise = last()
+	case "Tr":
+		gs.textRender = int(last())
+	case "Td", "TD":
+		if has(2) {
+			tx, ty := nums[len(nums)-2], nums[len(nums)-1]
+			if op == "TD" {
+				gs.leading = -ty
+			}
+			gs.textLineMatrix = matrix{1, 0, 0, 1, tx, ty}.multiply(gs.textLineMatrix)
+			gs.textMatrix = gs.textLineMatrix
+		}
+	case "Tm":
+		if has(6) {
+			n := nums[len(nums)-6:]
+			gs.textLineMatrix = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+			gs.textMatrix = gs.textLineMatrix
+		}
+	case "T*":
+		r.nextLine(gs)
+	case "Tj":
+		if len(operands) > 0 {
+			r.showText(gs, operands[len(operands)-1])
+		}
+	case "'", "\"":
+		if op == "\"" && has(2) {
+			gs.wordSpace, gs.charSpace = nums[len(nums)-2], nums[len(nums)-1]
+		}
+		r.nextLine(gs)
+		if len(operands) > 0 {
+			r.showText(gs, operands[len(operands)-1])
+		}
+	case "TJ":
+		if len(operands) == 0 {
+			break
+		}
+		arr, _ := operands[len(operands)-1].(Array)
+		for _, item := range arr {
+			if adj, ok := numberValue(item); ok {
+				gs.textMatrix = matrix{1, 0, 0, 1, -adj / 1000 * gs.fontSize * gs.hScale, 0}.multiply(gs.textMatrix)
+				continue
+			}
+			r.showText(gs, item)
+		}
+
+	// XObjects
+	case "Do":
+		if len(operands) > 0 {
+			name, _ := operands[len(operands)-1].(Name)
+			r.xobject(name, res, *gs)
+		}
+	}
+}
+
+// deviceColor returns the color given by gray, RGB or CMYK components.
+// Other color spaces render black.
+func deviceColor(c []float64) color.RGBA {
+	b := func(f float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+	}
+	switch len(c) {
+	case 1:
+		return color.RGBA{b(c[0]), b(c[0]), b(c[0]), 0xff}
+	case 3:
+		return color.RGBA{b(c[0]), b(c[1]), b(c[2]), 0xff}
+	case 4:
+		return color.RGBAModel.Convert(color.CMYK{b(c[0]), b(c[1]), b(c[2]), b(c[3])}).(color.RGBA)
+	}
+	return color.RGBA{0, 0, 0, 0xff}
+}
+
+// withAlpha returns c, which must be opaque, with opacity alpha, as a
+// premultiplied color.
+func withAlpha(c color.RGBA, alpha float64) color.RGBA {
+	a := math.Max(0, math.Min(1, alpha))
+	return color.RGBA{
+		uint8(float64(c.R) * a),
+		uint8(float64(c.G) * a),
+		uint8(float64(c.B) * a),
+		uint8(255 * a),
+	}
+}
+
+func (r *renderer) lineTo(gs *graphicsState, pt point) {
+	if len(r.path) == 0 {
+		r.path = append(r.path, []point{{gs.currentX, gs.currentY}})
+	}
+	last := len(r.path) - 1
+	r.path[last] = append(r.path[last], pt)
+	gs.currentX, gs.currentY = pt.x, pt.y
+}
+
+// curveTo flattens a cubic Bézier curve into line segments of a few pixels.
+func (r *renderer) curveTo(gs *graphicsState, p0, p1, p2, p3 point) {
+	length := math.Hypot(p1.x-p0.x, p1.y-p0.y) + math.Hypot(p2.x-p1.x, p2.y-p1.y) + math.Hypot(p3.x-p2.x, p3.y-p2.y)
+	steps := int(math.Min(64, math.Max(1, math.Ceil(length/3))))
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		r.lineTo(gs, point{
+			a*p0.x + b*p1.x + c*p2.x + d*p3.x,
+			a*p0.y + b*p1.y + c*p2.y + d*p3.y,
+		})
+	}
+}
+
+func (r *renderer) closePath(gs *graphicsState) {
+	if len(r.path) == 0 {
+		return
+	}
+	sub := r.path[len(r.path)-1]
+	start := sub[0]
+	r.lineTo(gs, start)
+	// A new subpath starts at the same point
+	r.path = append(r.path, []point{start})
+}
+
+// fillPath fills the current path with the nonzero winding rule.
+func (r *renderer) fillPath(gs *graphicsState) {
+	r.ras.Reset(r.dst.Bounds().Dx(), r.dst.Bounds().Dy())
+	for _, sub := range r.path {
+		if len(sub) < 3 {
+			continue
+		}
+		r.ras.MoveTo(float32(sub[0].x), float32(sub[0].y))
+		for _, pt := range sub[1:] {
+			r.ras.LineTo(float32(pt.x), float32(pt.y))
+		}
+		r.ras.ClosePath()
+	}
+	r.draw(withAlpha(gs.fill, gs.fillAlpha))
+}
+
+// strokePath strokes the current path with square caps, at least a pixel
+// wide.
+func (r *renderer) strokePath(gs *graphicsState) {
+	scale := math.Sqrt(math.Abs(gs.ctm[0]*gs.ctm[3] - gs.ctm[1]*gs.ctm[2]))
+	half := math.Max(1, gs.lineWidth*scale) / 2
+
+	r.ras.Reset(r.dst.Bounds().Dx(), r.dst.Bounds().Dy())
+	for _, sub := range r.path {
+		for i := 1; i < len(sub); i++ {
+			p, q := sub[i-1], sub[i]
+			length := math.Hypot(q.x-p.x, q.y-p.y)
+			if length == 0 {
+				continue
+			}
+			// Unit direction, extended by the cap, and unit normal. Every
+			// segment winds the same way so overlaps do not cancel out.
+			dx, dy := (q.x-p.x)/length*half, (q.y-p.y)/length*half
+			nx, ny := -dy, dx
+			r.ras.MoveTo(float32(p.x-dx+nx), float32(p.y-dy+ny))
+			r.ras.LineTo(float32(q.x+dx+nx), float32(q.y+dy+ny))
+			r.ras.LineTo(float32(q.x+dx-nx), float32(q.y+dy-ny))
+			r.ras.LineTo(float32(p.x-dx-nx), float32(p.y-dy-ny))
+			r.ras.ClosePath()
+		}
+	}
+	r.draw(withAlpha(gs.stroke, gs.strokeAlpha))
+}
+
+// draw paints c through the rasterizer's coverage.
+func (r *renderer) draw(c color.RGBA) {
+	if c.A == 0 {
+		return
+	}
+	r.ras.Draw(r.dst, r.dst.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+func (r *renderer) nextLine(gs *graphicsState) {
+	gs.textLineMatrix = matrix{1, 0, 0, 1, 0, -gs.leading}.multiply(gs.textLineMatrix)
+	gs.textMatrix = gs.textLineMatrix
+}
+
+// showText advances the text matrix over a string and draws each of its
+// words as a bar of half the font size's height.
+func (r *renderer) showText(gs *graphicsState, obj Object) {
+	s, ok := obj.(String)
+	if !ok {
+		return
+	}
+	codeBytes := 1
+	if gs.font.GetName("Subtype") == "Type0" {
+		codeBytes = 2
+	}
+	widths := r.doc.resolveArray(gs.font["Widths"])
+	firstChar, _ := r.doc.resolveInt(gs.font["FirstChar"])
+	visible := gs.textRender != 3 && gs.textRender != 7
+
+	r.ras.Reset(r.dst.Bounds().Dx(), r.dst.Bounds().Dy())
+	x, wordStart := 0.0, -1.0
+	endWord := func() {
+		if wordStart < 0 || !visible {
+			wordStart = -1
+			return
+		}
+		// The text space box of the word, transformed to device space
+		m := gs.textMatrix.multiply(gs.ctm)
+		y0, y1 := gs.rise, gs.rise+gs.fontSize/2
+		corners := []point{m.apply(wordStart, y0), m.apply(x, y0), m.apply(x, y1), m.apply(wordStart, y1)}
+		r.ras.MoveTo(float32(corners[0].x), float32(corners[0].y))
+		for _, c := range corners[1:] {
+			r.ras.LineTo(float32(c.x), float32(c.y))
+		}
+		r.ras.ClosePath()
+		wordStart = -1
+	}
+
+	for i := 0; i+codeBytes <= len(s.Value); i += codeBytes {
+		code := int(s.Value[i])
+		if codeBytes == 2 {
+			code = code<<8 | int(s.Value[i+1])
+		}
+		width := 0.5
+		if idx := code - int(firstChar); codeBytes == 1 && idx >= 0 && idx < len(widths) {
+			if wd, ok := numberValue(widths[idx]); ok {
+				width = wd / 1000
+			}
+		}
+		space := codeBytes == 1 && code == ' '
+		if space {
+			endWord()
+		} else if wordStart < 0 {
+			wordStart = x
+		}
+		advance := width*gs.fontSize + gs.charSpace
+		if space {
+			advance += gs.wordSpace
+		}
+		x += advance * gs.hScale
+	}
+	endWord()
+	r.draw(withAlpha(gs.fill, gs.fillAlpha/2))
+
+	gs.textMatrix = matrix{1, 0, 0, 1, x, 0}.multiply(gs.textMatrix)
+}
+
+// xobject draws the named image or form XObject.
+func (r *renderer) xobject(name Name, res Dict, gs graphicsState) {
+	v := r.doc.resolveDict(res["XObject"])[name]
+	obj, err := r.doc.resolveIfRef(v)
+	if err != nil {
+		return
+	}
+	xobj, ok := obj.(Stream)
+	if !ok {
+		return
+	}
+
+	switch xobj.Dict.GetName("Subtype") {
+	case "Image":
+		ref, _ := v.(Reference)
+		r.image(r.doc.newImageInfo(string(name), ref, xobj), gs)
+	case "Form":
+		if ref, isRef := v.(Reference); isRef {
+			if r.visited[ref.Number] {
+				return
+			}
+			r.visited[ref.Number] = true
+			defer delete(r.visited, ref.Number)
+		}
+		content, err := decodeStream(xobj)
+		if err != nil {
+			return
+		}
+		formMatrix := identityMatrix
+		if m := r.doc.resolveArray(xobj.Dict["Matrix"]); len(m) == 6 {
+			for i, n := range m {
+				formMatrix[i], _ = numberValue(n)
+			}
+		}
+		formRes := r.doc.resolveDict(xobj.Dict["Resources"])
+		if formRes == nil {
+			formRes = res
+		}
+		gs.ctm = formMatrix.multiply(gs.ctm)
+		saved := r.path
+		r.path = nil
+		r.run(content, formRes, gs)
+		r.path = saved
+	}
+}
+
+// image draws an image, which occupies the unit square of user space, with
+// nearest-neighbor sampling. Images that cannot be decoded are drawn as a
+// gray box.
+func (r *renderer) image(img ImageInfo, gs graphicsState) {
+	m := gs.ctm
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return
+	}
+	corners := []point{m.apply(0, 0), m.apply(1, 0), m.apply(1, 1), m.apply(0, 1)}
+
+	decoded, err := img.Decode()
+	if err != nil {
+		r.ras.Reset(r.dst.Bounds().Dx(), r.dst.Bounds().Dy())
+		r.ras.MoveTo(float32(corners[0].x), float32(corners[0].y))
+		for _, c := range corners[1:] {
+			r.ras.LineTo(float32(c.x), float32(c.y))
+		}
+		r.ras.ClosePath()
+		r.draw(withAlpha(color.RGBA{0xc0, 0xc0, 0xc0, 0xff}, gs.fillAlpha))
+		return
+	}
+	stencil := img.resolve(img.Stream.Dict["ImageMask"]) == Boolean(true)
+
+	minX, minY, maxX, maxY := corners[0].x, corners[0].y, corners[0].x, corners[0].y
+	for _, c := range corners[1:] {
+		minX, maxX = math.Min(minX, c.x), math.Max(maxX, c.x)
+		minY, maxY = math.Min(minY, c.y), math.Max(maxY, c.y)
+	}
+	area := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(r.dst.Bounds())
+
+	bounds := decoded.Bounds()
+	iw, ih := bounds.Dx(), bounds.Dy()
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			// Invert m to find the unit square point under the pixel center
+			dx, dy := float64(px)+0.5-m[4], float64(py)+0.5-m[5]
+			u := (dx*m[3] - dy*m[2]) / det
+			v := (dy*m[0] - dx*m[1]) / det
+			if u < 0 || u >= 1 || v < 0 || v >= 1 {
+				continue
+			}
+			// Image rows run from the top, at v = 1
+			sx := bounds.Min.X + int(u*float64(iw))
+			sy := bounds.Min.Y + int((1-v)*float64(ih))
+			var c color.RGBA
+			if stencil {
+				if g := color.GrayModel.Convert(decoded.At(sx, sy)).(color.Gray); g.Y >= 0x80 {
+					continue
+				}
+				c = gs.fill
+			} else {
+				c = color.RGBAModel.Convert(decoded.At(sx, sy)).(color.RGBA)
+			}
+			if gs.fillAlpha < 1 {
+				c = blend(r.dst.RGBAAt(px, py), c, gs.fillAlpha)
+			}
+			r.dst.SetRGBA(px, py, c)
+		}
+	}
+}
+
+// blend mixes c into the opaque color under it with opacity alpha.
+func blend(under, c color.RGBA, alpha float64) color.RGBA {
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha)
+	}
+	return color.RGBA{mix(under.R, c.R), mix(under.G, c.G), mix(under.B, c.B), 0xff}
+}
@@ -0,0 +1,110 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SetObject replaces the object with the given number in memory, or adds
+// it if the number is not in use. A nil obj deletes the object. The change
+// is seen by every later lookup and written by Save; the parsed file is not
+// modified.
+func (d *Document) SetObject(ref Reference, obj Object) {
+	if d.edits == nil {
+		d.edits = make(map[int]IndirectObject)
+	}
+	d.edits[ref.Number] = IndirectObject{Reference: ref, Value: obj}
+}
+
+// Save writes the document to w as a new PDF with a single revision. Every
+// object in use is written as a plain indirect object: objects stored in
+// object streams are unpacked, the object and cross-reference streams that
+// held them are dropped, and earlier revisions are not kept. Changes made
+// with SetObject or to the dictionary returned by Trailer are included.
+//
+// An encrypted document is saved decrypted, without its /Encrypt entry.
+func (d *Document) Save(w io.Writer) error {
+	refs := make(map[int]Reference)
+	for num, entry := range d.xref {
+		if num <= 0 || !entry.InUse {
+			continue
+		}
+		gen := entry.Generation
+		if entry.Compressed {
+			gen = 0 // Generation holds the index in the object stream
+		}
+		refs[num] = Reference{Number: num, Generation: gen}
+	}
+	for num, edit := range d.edits {
+		if edit.Value == nil {
+			delete(refs, num)
+		} else {
+			refs[num] = edit.Reference
+		}
+	}
+	if ref, ok := d.trailer["Encrypt"].(Reference); ok {
+		delete(refs, ref.Number)
+	}
+
+	nums := make([]int, 0, len(refs))
+	for num := range refs {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	version := d.Version
+	if version == "" {
+		version = "1.7"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%%PDF-%s\n%%\xe2\xe3\xcf\xd3\n", version)
+
+	offsets := make(map[int]int, len(nums))
+	for _, num := range nums {
+		ref := refs[num]
+		obj, err := d.resolve(ref)
+		if err != nil {
+			return err
+		}
+		if s, ok := obj.(Stream); ok {
+			if t := s.Dict.GetName("Type"); t == "ObjStm" || t == "XRef" {
+				continue
+			}
+		}
+		offsets[num] = buf.Len()
+		fmt.Fprintf(&buf, "%d %d obj\n", ref.Number, ref.Generation)
+		writeObject(&buf, obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	size := 1
+	if len(nums) > 0 {
+		size = nums[len(nums)-1] + 1
+	}
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n", size)
+	for num := range size {
+		if off, ok := offsets[num]; ok {
+			fmt.Fprintf(&buf, "%010d %05d n \n", off, refs[num].Generation)
+		} else {
+			buf.WriteString("0000000000 65535 f \n")
+		}
+	}
+
+	trailer := Dict{"Size": Integer(size)}
+	for _, key := range []Name{"Root", "Info", "ID"} {
+		if v, ok := d.trailer[key]; ok {
+			trailer[key] = v
+		}
+	}
+	buf.WriteString("trailer\n")
+	writeObject(&buf, trailer)
+	fmt.Fprintf(&buf, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("reader: writing document: %w", err)
+	}
+	return nil
+}
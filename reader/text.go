@@ -2,7 +2,11 @@ package reader
 
 import (
 	"bytes"
+	"errors"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"unicode/utf16"
 )
 
@@ -20,10 +24,147 @@ func (p *Page) ExtractText() (string, error) {
 	return extractTextFromContentStream(data), nil
 }
 
+// ExtractAllText extracts the text of every page, keyed by 1-based page
+// number. Pages are processed concurrently by up to GOMAXPROCS goroutines;
+// the document is only read while doing so. Pages whose text cannot be
+// extracted are left out of the map and their errors are joined into the
+// returned error.
+func (d *Document) ExtractAllText() (map[int]string, error) {
+	texts := make([]string, len(d.pages))
+	errs := make([]error, len(d.pages))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(d.pages)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				texts[i], errs[i] = d.pages[i].ExtractText()
+			}
+		}()
+	}
+	for i := range d.pages {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	result := make(map[int]string, len(d.pages))
+	for i, page := range d.pages {
+		if errs[i] == nil {
+			result[page.Number] = texts[i]
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// ExtractTextByLayer extracts the text content of this page grouped by
+// optional content group (layer) name. Text is assigned to the innermost
+// marked-content sequence tagged /OC that encloses it; text outside any
+// layer is returned under the key "". For an optional content membership
+// dictionary the name of its first group is used.
+func (p *Page) ExtractTextByLayer() (map[string]string, error) {
+	data, err := p.ContentStream()
+	if err != nil {
+		return nil, err
+	}
+
+	builders := make(map[string]*strings.Builder)
+	scanContentText(data, func(oc string) *strings.Builder {
+		b, ok := builders[oc]
+		if !ok {
+			b = &strings.Builder{}
+			builders[oc] = b
+		}
+		return b
+	})
+
+	var props Dict
+	if p.doc != nil {
+		props = p.doc.resolveDict(p.Resources["Properties"])
+	}
+	tags := make([]string, 0, len(builders))
+	for oc := range builders {
+		tags = append(tags, oc)
+	}
+	sort.Strings(tags)
+
+	layers := make(map[string]string)
+	for _, oc := range tags {
+		text := strings.TrimSpace(builders[oc].String())
+		if text == "" {
+			continue
+		}
+		name := ""
+		if oc != "" && p.doc != nil {
+			name = p.doc.optionalContentName(props[Name(oc)])
+			if name == "" {
+				name = oc
+			}
+		}
+		if layers[name] != "" {
+			text = layers[name] + " " + text
+		}
+		layers[name] = text
+	}
+	return layers, nil
+}
+
+// optionalContentName returns the /Name of an optional content group, or of
+// the first group of an optional content membership dictionary.
+func (d *Document) optionalContentName(obj Object) string {
+	dict := d.resolveDict(obj)
+	if dict == nil {
+		return ""
+	}
+	if dict.GetName("Type") == "OCMD" {
+		ocgs, err := d.resolveIfRef(dict["OCGs"])
+		if err != nil {
+			return ""
+		}
+		if arr, ok := ocgs.(Array); ok {
+			if len(arr) == 0 {
+				return ""
+			}
+			ocgs = arr[0]
+		}
+		return d.optionalContentName(ocgs)
+	}
+	name, err := d.resolveIfRef(dict["Name"])
+	if err != nil {
+		return ""
+	}
+	if s, ok := name.(String); ok {
+		return decodePDFString(s.Value)
+	}
+	return ""
+}
+
 // extractTextFromContentStream parses text operators from a PDF content stream.
 func extractTextFromContentStream(data []byte) string {
 	var result strings.Builder
+	scanContentText(data, func(string) *strings.Builder { return &result })
+	return strings.TrimSpace(result.String())
+}
+
+// scanContentText parses text operators from a PDF content stream and writes
+// the text to the builder returned by out. It tracks marked-content
+// sequences (BDC/BMC ... EMC) and passes out the property name of the
+// innermost enclosing /OC sequence, or "" for text outside optional content.
+func scanContentText(data []byte, out func(oc string) *strings.Builder) {
 	var inText bool
+	var names [2]string // operands of the next BDC: tag and property list
+	var marked []string // property names of open /OC sequences, "" for others
+
+	layer := func() string {
+		for k := len(marked) - 1; k >= 0; k-- {
+			if marked[k] != "" {
+				return marked[k]
+			}
+		}
+		return ""
+	}
 
 	i := 0
 	for i < len(data) {
@@ -35,6 +176,42 @@ func extractTextFromContentStream(data []byte) string {
 			break
 		}
 
+		// Track marked content operators and their operands
+		if data[i] == '/' {
+			start := i + 1
+			i++
+			for i < len(data) && isRegular(data[i]) {
+				i++
+			}
+			names = [2]string{names[1], string(data[start:i])}
+			continue
+		}
+		if data[i] == '<' && i+1 < len(data) && data[i+1] == '<' {
+			// Inline property list
+			i = skipDict(data, i)
+			names = [2]string{names[1], ""}
+			continue
+		}
+		if op, ok := markedContentOp(data, i); ok {
+			switch op {
+			case "BDC":
+				if names[0] == "OC" {
+					marked = append(marked, names[1])
+				} else {
+					marked = append(marked, "")
+				}
+			case "BMC":
+				marked = append(marked, "")
+			case "EMC":
+				if len(marked) > 0 {
+					marked = marked[:len(marked)-1]
+				}
+			}
+			i += 3
+			continue
+		}
+		result := out(layer())
+
 		// Check for BT (begin text) / ET (end text)
 		if i+2 <= len(data) && data[i] == 'B' && data[i+1] == 'T' &&
 			(i+2 >= len(data) || isWhitespace(data[i+2]) || isDelimiter(data[i+2])) {
@@ -116,8 +293,21 @@ func extractTextFromContentStream(data []byte) string {
 
 		i++
 	}
+}
 
-	return strings.TrimSpace(result.String())
+// markedContentOp reports whether a BDC, BMC or EMC operator starts at pos.
+func markedContentOp(data []byte, pos int) (string, bool) {
+	if pos+3 > len(data) || (pos > 0 && isRegular(data[pos-1])) {
+		return "", false
+	}
+	if pos+3 < len(data) && isRegular(data[pos+3]) {
+		return "", false
+	}
+	switch op := string(data[pos : pos+3]); op {
+	case "BDC", "BMC", "EMC":
+		return op, true
+	}
+	return "", false
 }
 
 // parseLiteralStringRaw extracts raw bytes from a literal string starting at pos.
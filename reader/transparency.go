@@ -0,0 +1,188 @@
+package reader
+
+import "fmt"
+
+// UsesTransparency reports whether the page uses transparency. A page is
+// considered transparent if its resources (including those of nested form
+// XObjects) contain a graphics state with a constant alpha (/CA or /ca)
+// below 1, a soft mask, or a blend mode other than Normal, an image with a
+// soft mask, or if the page or one of its form XObjects is a transparency
+// group.
+func (p *Page) UsesTransparency() bool {
+	if p.doc == nil {
+		return false
+	}
+	if p.doc.isTransparencyGroup(p.dict["Group"]) {
+		return true
+	}
+	return p.doc.resourcesUseTransparency(p.Resources, make(map[int]bool))
+}
+
+// resolveDict resolves obj and returns it as a dictionary, or nil.
+func (d *Document) resolveDict(obj Object) Dict {
+	if obj == nil {
+		return nil
+	}
+	resolved, err := d.resolveIfRef(obj)
+	if err != nil {
+		return nil
+	}
+	dict, _ := resolved.(Dict)
+	return dict
+}
+
+// isTransparencyGroup reports whether obj is a group attributes dictionary
+// with subtype /Transparency.
+func (d *Document) isTransparencyGroup(obj Object) bool {
+	group := d.resolveDict(obj)
+	return group != nil && group.GetName("S") == "Transparency"
+}
+
+// resourcesUseTransparency scans a resource dictionary for transparency.
+// visited guards against cycles between form XObjects.
+func (d *Document) resourcesUseTransparency(res Dict, visited map[int]bool) bool {
+	if res == nil {
+		return false
+	}
+
+	for _, v := range d.resolveDict(res["ExtGState"]) {
+		if gs := d.resolveDict(v); gs != nil && d.extGStateUsesTransparency(gs) {
+			return true
+		}
+	}
+
+	for _, v := range d.resolveDict(res["XObject"]) {
+		if ref, ok := v.(Reference); ok {
+			if visited[ref.Number] {
+				continue
+			}
+			visited[ref.Number] = true
+		}
+		obj, err := d.resolveIfRef(v)
+		if err != nil {
+			continue
+		}
+		xobj, ok := obj.(Stream)
+		if !ok {
+			continue
+		}
+		if sm, ok := xobj.Dict["SMask"]; ok {
+			if _, isNull := sm.(Null); !isNull {
+				return true
+			}
+		}
+		if xobj.Dict.GetName("Subtype") == "Form" {
+			if d.isTransparencyGroup(xobj.Dict["Group"]) {
+				return true
+			}
+			if d.resourcesUseTransparency(d.resolveDict(xobj.Dict["Resources"]), visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// extGStateUsesTransparency reports whether a graphics state parameter
+// dictionary introduces transparency.
+func (d *Document) extGStateUsesTransparency(gs Dict) bool {
+	for _, key := range []Name{"CA", "ca"} {
+		v, ok := gs[key]
+		if !ok {
+			continue
+		}
+		resolved, err := d.resolveIfRef(v)
+		if err != nil {
+			continue
+		}
+		if n, ok := numberValue(resolved); ok && n < 1 {
+			return true
+		}
+	}
+
+	if bm, ok := gs["BM"]; ok {
+		resolved, err := d.resolveIfRef(bm)
+		if err == nil {
+			switch mode := resolved.(type) {
+			case Name:
+				if mode != "Normal" && mode != "Compatible" {
+					return true
+				}
+			case Array:
+				// An array of blend modes; the first recognised one applies.
+				if len(mode) > 0 {
+					if n, ok := mode[0].(Name); ok && n != "Normal" && n != "Compatible" {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	if sm, ok := gs["SMask"]; ok {
+		resolved, err := d.resolveIfRef(sm)
+		if err == nil {
+			if n, ok := resolved.(Name); !ok || n != "None" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// GroupInfo describes the transparency group attributes of a page.
+type GroupInfo struct {
+	// ColorSpace is the group color space name, such as "DeviceRGB" or
+	// "DeviceCMYK". For array color spaces it is the family name, such as
+	// "ICCBased". It is empty if the group does not specify one.
+	ColorSpace string
+	Isolated   bool // /I
+	Knockout   bool // /K
+}
+
+// TransparencyGroup returns the page's transparency group attributes from
+// its /Group entry, or nil if the page is not a transparency group.
+func (p *Page) TransparencyGroup() (*GroupInfo, error) {
+	obj, ok := p.dict["Group"]
+	if !ok || p.doc == nil {
+		return nil, nil
+	}
+	resolved, err := p.doc.resolveIfRef(obj)
+	if err != nil {
+		return nil, fmt.Errorf("reader: page %d group: %w", p.Number, err)
+	}
+	group, ok := resolved.(Dict)
+	if !ok {
+		return nil, fmt.Errorf("reader: page %d /Group is not a dictionary", p.Number)
+	}
+	if group.GetName("S") != "Transparency" {
+		return nil, nil
+	}
+
+	info := &GroupInfo{}
+	if cs, ok := group["CS"]; ok {
+		resolved, err := p.doc.resolveIfRef(cs)
+		if err != nil {
+			return nil, fmt.Errorf("reader: page %d group color space: %w", p.Number, err)
+		}
+		switch v := resolved.(type) {
+		case Name:
+			info.ColorSpace = string(v)
+		case Array:
+			if len(v) > 0 {
+				if n, ok := v[0].(Name); ok {
+					info.ColorSpace = string(n)
+				}
+			}
+		}
+	}
+	if b, ok := group["I"].(Boolean); ok {
+		info.Isolated = bool(b)
+	}
+	if b, ok := group["K"].(Boolean); ok {
+		info.Knockout = bool(b)
+	}
+	return info, nil
+}
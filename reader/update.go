@@ -0,0 +1,180 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AppendUpdate writes the original file followed by an incremental update
+// that replaces or adds the given objects. The update holds only the new
+// object bodies, a cross-reference section listing them and a trailer whose
+// /Prev points at the previous section, so the bytes of earlier revisions,
+// including any signed ranges, are left untouched.
+//
+// A reference to an object that does not exist yet adds it; the trailer's
+// /Size grows to cover it. Stream data is written as is and must already be
+// encoded with the stream's /Filter; /Length is set from it. Updates of
+// encrypted documents are not supported, since the new objects would have to
+// be encrypted.
+func (d *Document) AppendUpdate(w io.Writer, changes map[Reference]Object) error {
+	if d.isEncrypted() {
+		return fmt.Errorf("reader: incremental update of an encrypted document is not supported")
+	}
+	prev, err := StartXRef(d.data)
+	if err != nil {
+		return err
+	}
+
+	refs := make([]Reference, 0, len(changes))
+	for ref := range changes {
+		if ref.Number <= 0 {
+			return fmt.Errorf("reader: invalid object number %d", ref.Number)
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Number < refs[j].Number })
+
+	var buf bytes.Buffer
+	buf.Write(d.data)
+	if len(d.data) > 0 && d.data[len(d.data)-1] != '\n' && d.data[len(d.data)-1] != '\r' {
+		buf.WriteByte('\n')
+	}
+
+	offsets := make([]int, len(refs))
+	for i, ref := range refs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d %d obj\n", ref.Number, ref.Generation)
+		writeObject(&buf, changes[ref])
+		buf.WriteString("\nendobj\n")
+	}
+
+	xrefOffset := buf.Len()
+	buf.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	for i, ref := range refs {
+		fmt.Fprintf(&buf, "%d 1\n%010d %05d n \n", ref.Number, offsets[i], ref.Generation)
+	}
+
+	size, _ := d.trailer.GetInt("Size")
+	for _, ref := range refs {
+		size = max(size, int64(ref.Number)+1)
+	}
+	trailer := Dict{"Size": Integer(size), "Prev": Integer(prev)}
+	for _, key := range []Name{"Root", "Info", "ID"} {
+		if v, ok := d.trailer[key]; ok {
+			trailer[key] = v
+		}
+	}
+	buf.WriteString("trailer\n")
+	writeObject(&buf, trailer)
+	fmt.Fprintf(&buf, "\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("reader: writing update: %w", err)
+	}
+	return nil
+}
+
+// WriteObject writes obj to w in PDF file syntax, as AppendUpdate and Save
+// write object bodies. Dictionary keys are written in sorted order, and the
+// /Length of a stream is set from its data, which is written as is.
+func WriteObject(w io.Writer, obj Object) error {
+	var buf bytes.Buffer
+	writeObject(&buf, obj)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeObject serializes a PDF object in its file syntax.
+func writeObject(buf *bytes.Buffer, obj Object) {
+	switch v := obj.(type) {
+	case nil, Null:
+		buf.WriteString("null")
+	case Boolean:
+		buf.WriteString(strconv.FormatBool(bool(v)))
+	case Integer:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case Real:
+		buf.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 64))
+	case Name:
+		buf.WriteString(v.Encode())
+	case String:
+		writeString(buf, v)
+	case Reference:
+		fmt.Fprintf(buf, "%d %d R", v.Number, v.Generation)
+	case Array:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			writeObject(buf, item)
+		}
+		buf.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		buf.WriteString("<<")
+		for _, k := range keys {
+			buf.WriteString(Name(k).Encode())
+			buf.WriteByte(' ')
+			writeObject(buf, v[Name(k)])
+		}
+		buf.WriteString(">>")
+	case Stream:
+		dict := make(Dict, len(v.Dict)+1)
+		for k, item := range v.Dict {
+			dict[k] = item
+		}
+		dict["Length"] = Integer(len(v.Data))
+		writeObject(buf, dict)
+		buf.WriteString("\nstream\n")
+		buf.Write(v.Data)
+		buf.WriteString("\nendstream")
+	}
+}
+
+// Encode returns n in PDF file syntax: a slash followed by the name, with
+// delimiters, whitespace and bytes outside the printable ASCII range
+// escaped as #xx. String, in contrast, returns the name unescaped.
+func (n Name) Encode() string {
+	var b strings.Builder
+	b.WriteByte('/')
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c < '!' || c > '~' || strings.IndexByte("#()<>[]{}/%", c) >= 0 {
+			fmt.Fprintf(&b, "#%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// writeString writes a string object as a hexadecimal or escaped literal
+// string, matching its original form.
+func writeString(buf *bytes.Buffer, s String) {
+	if s.IsHex {
+		fmt.Fprintf(buf, "<%X>", s.Value)
+		return
+	}
+	buf.WriteByte('(')
+	for _, c := range s.Value {
+		switch c {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\r':
+			buf.WriteString("\\r")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte(')')
+}
@@ -11,13 +11,14 @@ type xrefEntry struct {
 	Offset     int64
 	Generation int
 	InUse      bool
+	Compressed bool // stored in an object stream
 }
 
 // xrefTable maps object numbers to their file offsets.
 type xrefTable map[int]xrefEntry
 
 // findStartXRef locates the "startxref" position from the end of the file.
-func findStartXRef(data []byte) (int64, error) {
+func StartXRef(data []byte) (int64, error) {
 	// Search backward from end of file for "startxref"
 	searchLen := 1024
 	if len(data) < searchLen {
@@ -41,23 +42,100 @@ func findStartXRef(data []byte) (int64, error) {
 	return offset, nil
 }
 
-// parseXRefTable parses a traditional cross-reference table starting at the given offset.
-// Returns the xref entries and the trailer dictionary.
+// xrefRecord is a single raw entry of one cross-reference section.
+type xrefRecord struct {
+	Number int
+	Type   int   // 0 = free, 1 = in use, 2 = compressed in an object stream
+	Field2 int64 // byte offset, next free object number, or object stream number
+	Field3 int64 // generation number, or index within the object stream
+}
+
+// parseXRefTable parses the cross-reference table or stream starting at the
+// given offset and the sections linked from it through /Prev, which may mix
+// tables and streams. An object defined in several sections takes its entry
+// from the newest one. Returns the xref entries and the trailer dictionary
+// of the newest section.
 func parseXRefTable(data []byte, offset int64) (xrefTable, Dict, error) {
+	table := make(xrefTable)
+	var trailer Dict
+	visited := make(map[int64]bool)
+	for !visited[offset] {
+		visited[offset] = true
+		records, dict, err := parseXRefRevision(data, offset)
+		if err != nil {
+			if trailer != nil {
+				return nil, nil, fmt.Errorf("reader: previous xref: %w", err)
+			}
+			return nil, nil, err
+		}
+		if trailer == nil {
+			trailer = dict
+		}
+		for _, rec := range records {
+			// Only add if not already present (first definition wins for incremental updates)
+			if _, exists := table[rec.Number]; !exists {
+				table[rec.Number] = rec.entry()
+			}
+		}
+
+		// Follow /Prev link for incremental updates
+		prev, ok := dict.GetInt("Prev")
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+	return table, trailer, nil
+}
+
+// parseXRefRevision parses the cross-reference section at the given offset
+// together with the cross-reference stream named by the /XRefStm entry of a
+// hybrid-reference file's trailer, whose records follow those of the table.
+func parseXRefRevision(data []byte, offset int64) ([]xrefRecord, Dict, error) {
+	records, trailer, err := parseXRefSection(data, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stm, ok := trailer.GetInt("XRefStm"); ok {
+		if stm < 0 || int(stm) >= len(data) {
+			return nil, nil, fmt.Errorf("reader: /XRefStm offset %d out of bounds", stm)
+		}
+		hidden, _, err := parseXRefStreamSection(data, stm)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, hidden...)
+	}
+	return records, trailer, nil
+}
+
+// entry converts a raw record to the entry stored in an xrefTable.
+// Compressed objects keep the object stream number in Offset and the index
+// within the stream in Generation.
+func (rec xrefRecord) entry() xrefEntry {
+	return xrefEntry{
+		Offset:     rec.Field2,
+		Generation: int(rec.Field3),
+		InUse:      rec.Type != 0,
+		Compressed: rec.Type == 2,
+	}
+}
+
+// parseXRefSection parses the single cross-reference table or stream at the
+// given offset without following /Prev links. It returns the raw records in
+// file order and the trailer (or cross-reference stream) dictionary.
+func parseXRefSection(data []byte, offset int64) ([]xrefRecord, Dict, error) {
 	if offset < 0 || int(offset) >= len(data) {
 		return nil, nil, fmt.Errorf("reader: xref offset %d out of bounds", offset)
 	}
 
 	p := newParser(data[offset:])
-	table := make(xrefTable)
-
-	// Expect "xref" keyword
-	tok := p.readToken()
-	if tok != "xref" {
-		// Could be a cross-reference stream (PDF 1.5+)
-		return parseXRefStream(data, offset)
+	if tok := p.readToken(); tok != "xref" {
+		return parseXRefStreamSection(data, offset)
 	}
 
+	var records []xrefRecord
+
 	// Parse subsections: startObj count
 	for {
 		p.skipWhitespace()
@@ -67,7 +145,7 @@ func parseXRefTable(data []byte, offset int64) (xrefTable, Dict, error) {
 
 		// Check if we've reached the trailer
 		savedPos := p.pos
-		tok = p.readToken()
+		tok := p.readToken()
 		if tok == "trailer" {
 			break
 		}
@@ -107,15 +185,11 @@ func parseXRefTable(data []byte, offset int64) (xrefTable, Dict, error) {
 			p.skipWhitespace()
 			typeTok := p.readToken()
 
-			objNum := int(startObj + i)
-			// Only add if not already present (first definition wins for incremental updates)
-			if _, exists := table[objNum]; !exists {
-				table[objNum] = xrefEntry{
-					Offset:     entryOffset,
-					Generation: int(gen),
-					InUse:      typeTok == "n",
-				}
+			rec := xrefRecord{Number: int(startObj + i), Field2: entryOffset, Field3: gen}
+			if typeTok == "n" {
+				rec.Type = 1
 			}
+			records = append(records, rec)
 		}
 	}
 
@@ -130,25 +204,12 @@ func parseXRefTable(data []byte, offset int64) (xrefTable, Dict, error) {
 		return nil, nil, fmt.Errorf("reader: trailer is not a dictionary")
 	}
 
-	// Follow /Prev link for incremental updates
-	if prevVal, ok := trailer.GetInt("Prev"); ok {
-		prevTable, _, err := parseXRefTable(data, prevVal)
-		if err != nil {
-			return nil, nil, fmt.Errorf("reader: previous xref: %w", err)
-		}
-		// Merge: current entries take precedence
-		for num, entry := range prevTable {
-			if _, exists := table[num]; !exists {
-				table[num] = entry
-			}
-		}
-	}
-
-	return table, trailer, nil
+	return records, trailer, nil
 }
 
-// parseXRefStream parses a cross-reference stream (PDF 1.5+).
-func parseXRefStream(data []byte, offset int64) (xrefTable, Dict, error) {
+// parseXRefStreamSection decodes the entries of the cross-reference stream
+// at the given offset.
+func parseXRefStreamSection(data []byte, offset int64) ([]xrefRecord, Dict, error) {
 	p := newParser(data[offset:])
 	obj, err := p.ParseIndirectObject()
 	if err != nil {
@@ -193,7 +254,7 @@ func parseXRefStream(data []byte, offset int64) (xrefTable, Dict, error) {
 		indices = []int{0, int(size)}
 	}
 
-	table := make(xrefTable)
+	var records []xrefRecord
 	dataPos := 0
 
 	for i := 0; i+1 < len(indices); i += 2 {
@@ -217,8 +278,6 @@ func parseXRefStream(data []byte, offset int64) (xrefTable, Dict, error) {
 			}
 			dataPos += entrySize
 
-			objNum := startObj + j
-
 			// Default type is 1 if width[0] is 0
 			fieldType := fields[0]
 			if widths[0] == 0 {
@@ -226,24 +285,81 @@ func parseXRefStream(data []byte, offset int64) (xrefTable, Dict, error) {
 			}
 
 			switch fieldType {
-			case 0: // free object
-				table[objNum] = xrefEntry{InUse: false, Generation: int(fields[2])}
-			case 1: // in-use object
-				table[objNum] = xrefEntry{
-					Offset:     fields[1],
-					Generation: int(fields[2]),
-					InUse:      true,
-				}
-			case 2: // compressed object (in object stream)
-				// fields[1] = object stream number, fields[2] = index within stream
-				table[objNum] = xrefEntry{
-					Offset:     fields[1], // store stream object number in Offset
-					Generation: int(fields[2]),
-					InUse:      true,
-				}
+			case 0, 1, 2:
+				// 0 = free, 1 = in use, 2 = compressed (fields[1] is the
+				// object stream number, fields[2] the index within it)
+				records = append(records, xrefRecord{
+					Number: startObj + j,
+					Type:   int(fieldType),
+					Field2: fields[1],
+					Field3: fields[2],
+				})
 			}
 		}
 	}
 
-	return table, stream.Dict, nil
+	return records, stream.Dict, nil
+}
+
+// XRefEntry describes the cross-reference entry for an object in one
+// revision of the file.
+type XRefEntry struct {
+	Revision   int    // 0 for the original file, increasing with each incremental update
+	Type       string // "free", "in-use" or "compressed"
+	Offset     int64  // byte offset (in-use) or next free object number (free)
+	StreamObj  int    // object stream containing the object (compressed)
+	Index      int    // index within the object stream (compressed)
+	Generation int    // generation number (in-use and free)
+}
+
+// XRefSummary walks every cross-reference section linked through /Prev and
+// returns, for each object number, its entries ordered from the oldest
+// revision to the newest. An object number with several entries has been
+// redefined, freed or reused by incremental updates.
+func (d *Document) XRefSummary() (map[int][]XRefEntry, error) {
+	offset, err := StartXRef(d.data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect sections from the newest to the oldest.
+	var sections [][]xrefRecord
+	visited := make(map[int64]bool)
+	for !visited[offset] {
+		visited[offset] = true
+		records, trailer, err := parseXRefRevision(d.data, offset)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, records)
+
+		prev, ok := trailer.GetInt("Prev")
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+
+	summary := make(map[int][]XRefEntry)
+	for rev := 0; rev < len(sections); rev++ {
+		for _, rec := range sections[len(sections)-1-rev] {
+			entry := XRefEntry{Revision: rev}
+			switch rec.Type {
+			case 0:
+				entry.Type = "free"
+				entry.Offset = rec.Field2
+				entry.Generation = int(rec.Field3)
+			case 1:
+				entry.Type = "in-use"
+				entry.Offset = rec.Field2
+				entry.Generation = int(rec.Field3)
+			case 2:
+				entry.Type = "compressed"
+				entry.StreamObj = int(rec.Field2)
+				entry.Index = int(rec.Field3)
+			}
+			summary[rec.Number] = append(summary[rec.Number], entry)
+		}
+	}
+	return summary, nil
 }
@@ -0,0 +1,213 @@
+package sign
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"sync"
+
+	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// appearanceLines returns the text lines of a visible signature: the
+// caller's text if given, otherwise the signer name, reason, location and
+// signing date.
+func appearanceLines(opts Options, vis *VisibleRect) []string {
+	if vis.Text != "" {
+		return strings.Split(vis.Text, "\n")
+	}
+	name := vis.Name
+	if name == "" {
+		name = opts.Certificate.Subject.CommonName
+	}
+	lines := []string{"Digitally signed by " + name}
+	if opts.Reason != "" {
+		lines = append(lines, "Reason: "+opts.Reason)
+	}
+	if opts.Location != "" {
+		lines = append(lines, "Location: "+opts.Location)
+	}
+	return append(lines, "Date: "+opts.SignTime.Format("2006-01-02 15:04:05 -07:00"))
+}
+
+// addAppearance adds the normal appearance of a w×h point visible
+// signature to u, showing lines in Helvetica next to the optional image,
+// and returns its object number.
+func addAppearance(u *pdfUpdate, w, h float64, lines []string, img []byte) (int, error) {
+	var content bytes.Buffer
+	resources := reader.Dict{
+		"Font": reader.Dict{"Helv": reader.Dict{
+			"Type":     reader.Name("Font"),
+			"Subtype":  reader.Name("Type1"),
+			"BaseFont": reader.Name("Helvetica"),
+			"Encoding": reader.Name("WinAnsiEncoding"),
+		}},
+	}
+
+	textX := 2.0
+	if len(img) > 0 {
+		imgNum, imgW, imgH, err := addImage(u, img)
+		if err != nil {
+			return 0, err
+		}
+		resources["XObject"] = reader.Dict{"Img0": reader.Reference{Number: imgNum}}
+
+		// The image takes up to 40% of the width, keeping its aspect ratio
+		boxW := w - 4
+		if len(lines) > 0 {
+			boxW = w * 0.4
+		}
+		scale := min(boxW/imgW, (h-4)/imgH)
+		dw, dh := imgW*scale, imgH*scale
+		fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Img0 Do Q\n", dw, dh, 2+(boxW-dw)/2, (h-dh)/2)
+		textX = boxW + 4
+	}
+
+	if len(lines) > 0 {
+		texts := make([]string, len(lines))
+		widest := 0.0
+		for i, line := range lines {
+			texts[i] = latin1Text(line)
+			widest = max(widest, helveticaWidth(texts[i]))
+		}
+		fontSize := min(10, (h-4)/(float64(len(lines))*1.15))
+		if avail := w - textX - 2; widest > 0 && widest*fontSize > avail {
+			fontSize = avail / widest
+		}
+		leading := fontSize * 1.15
+		// Center the block of lines vertically
+		y := (h+float64(len(lines))*leading)/2 - fontSize
+		fmt.Fprintf(&content, "BT\n/Helv %.2f Tf 0 g\n", fontSize)
+		for i, text := range texts {
+			fmt.Fprintf(&content, "1 0 0 1 %.2f %.2f Tm\n(%s) Tj\n", textX, y-float64(i)*leading, escapePDF(text))
+		}
+		content.WriteString("ET\n")
+	}
+
+	return u.add(reader.Stream{
+		Dict: reader.Dict{
+			"Type":      reader.Name("XObject"),
+			"Subtype":   reader.Name("Form"),
+			"BBox":      reader.Array{reader.Integer(0), reader.Integer(0), reader.Real(w), reader.Real(h)},
+			"Resources": resources,
+		},
+		Data: content.Bytes(),
+	}), nil
+}
+
+// addImage adds a JPEG or PNG image as an image XObject to u and returns
+// its object number and pixel size. JPEG data is embedded as is; PNG
+// images are decoded and stored as RGB, with an alpha soft mask if they
+// are not opaque.
+func addImage(u *pdfUpdate, data []byte) (int, float64, float64, error) {
+	if bytes.HasPrefix(data, []byte("\x89PNG")) {
+		return addPNG(u, data)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("sign: signature image is neither PNG nor JPEG: %w", err)
+	}
+	var cs reader.Name
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		cs = "DeviceGray"
+	case color.YCbCrModel:
+		cs = "DeviceRGB"
+	case color.CMYKModel:
+		cs = "DeviceCMYK"
+	default:
+		return 0, 0, 0, fmt.Errorf("sign: signature image has unsupported color space (%v)", cfg.ColorModel)
+	}
+	num := u.add(imageStream(cfg.Width, cfg.Height, cs, "DCTDecode", data))
+	return num, float64(cfg.Width), float64(cfg.Height), nil
+}
+
+// addPNG adds a PNG image as an image XObject to u. See addImage.
+func addPNG(u *pdfUpdate, data []byte) (int, float64, float64, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("sign: decoding signature image: %w", err)
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	rgb := make([]byte, 0, w*h*3)
+	alpha := make([]byte, 0, w*h)
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xFF {
+				opaque = false
+			}
+		}
+	}
+
+	image := imageStream(w, h, "DeviceRGB", "FlateDecode", compress(rgb))
+	if !opaque {
+		mask := imageStream(w, h, "DeviceGray", "FlateDecode", compress(alpha))
+		image.Dict["SMask"] = reader.Reference{Number: u.add(mask)}
+	}
+	return u.add(image), float64(w), float64(h), nil
+}
+
+// imageStream returns an image XObject of w×h pixels with 8 bits per
+// component in color space cs, whose data is encoded with filter.
+func imageStream(w, h int, cs, filter reader.Name, data []byte) reader.Stream {
+	return reader.Stream{
+		Dict: reader.Dict{
+			"Type":             reader.Name("XObject"),
+			"Subtype":          reader.Name("Image"),
+			"Width":            reader.Integer(w),
+			"Height":           reader.Integer(h),
+			"ColorSpace":       cs,
+			"BitsPerComponent": reader.Integer(8),
+			"Filter":           filter,
+		},
+		Data: data,
+	}
+}
+
+var (
+	helvOnce    sync.Once
+	helvMetrics *gofpdf.Fpdf
+)
+
+// helveticaWidth returns the width of Latin-1 text in Helvetica, in units
+// of the font size.
+func helveticaWidth(text string) float64 {
+	helvOnce.Do(func() {
+		helvMetrics = gofpdf.New("P", "pt", "A4", "")
+		helvMetrics.SetFont("Helvetica", "", 12)
+	})
+	return float64(helvMetrics.GetStringSymbolWidth(text)) / 1000
+}
+
+// latin1Text converts UTF-8 text to Latin-1 for display with a standard
+// font. Characters outside Latin-1 become '?'.
+func latin1Text(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b.WriteByte(byte(r))
+	}
+	return b.String()
+}
+
+// compress returns data compressed for the FlateDecode filter.
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,371 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Object identifiers used in CMS signatures (RFC 5652).
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// contentInfo is the outer CMS structure wrapping the signed data.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData is the CMS SignedData structure. The encapsulated content is
+// absent, since PDF signatures are detached.
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,tag:0"` // [0] EXPLICIT OCTET STRING
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    algorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm algorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// buildPKCS7 returns a DER-encoded CMS SignedData structure, as used by the
+// adbe.pkcs7.detached subfilter, signing the SHA-256 digest of the signed
+// byte ranges. It embeds the signer certificate and chain, and signs the
+// content type, message digest and signing time attributes. If
+// opts.TimestampURL is set, an RFC 3161 timestamp token of the signature is
+// added as an unsigned attribute.
+func buildPKCS7(digest []byte, opts Options) ([]byte, error) {
+	si, err := newSignerInfo(opts.Certificate, opts.PrivateKey, oidData, digest, opts.SignTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TimestampURL != "" {
+		token, err := requestTimestamp(opts.TimestampClient, opts.TimestampURL, si.Signature)
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(attribute{
+			Type:   oidTimeStampToken,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: token},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign: encoding timestamp token: %w", err)
+		}
+		si.UnsignedAttrs = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: attr}
+	}
+
+	certs := append([]*x509.Certificate{opts.Certificate}, opts.CertChain...)
+	return encodeSignedData(encapContentInfo{EContentType: oidData}, si, certs)
+}
+
+// newSignerInfo signs the SHA-256 digest of some content of the given type
+// with key, through the signed attributes, and returns the signer info
+// identifying cert.
+func newSignerInfo(cert *x509.Certificate, key crypto.Signer, contentType asn1.ObjectIdentifier, digest []byte, signTime time.Time) (signerInfo, error) {
+	sigAlg, err := signatureAlgorithm(key.Public())
+	if err != nil {
+		return signerInfo{}, err
+	}
+
+	attrs, err := signedAttributes(contentType, digest, signTime)
+	if err != nil {
+		return signerInfo{}, err
+	}
+	// The signature covers the attributes encoded as a SET OF
+	attrsHash := crypto.SHA256.New()
+	attrsHash.Write(attrs)
+	signature, err := key.Sign(rand.Reader, attrsHash.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return signerInfo{}, fmt.Errorf("sign: signing: %w", err)
+	}
+
+	return signerInfo{
+		Version: 1,
+		SID: issuerAndSerial{
+			Issuer: asn1.RawValue{FullBytes: cert.RawIssuer},
+			Serial: cert.SerialNumber,
+		},
+		DigestAlgorithm: algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+		// Stored as [0] IMPLICIT instead of the SET tag
+		SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: setContents(attrs)},
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	}, nil
+}
+
+// encodeSignedData returns the DER encoding of a ContentInfo holding the
+// SignedData of one signer, embedding certs.
+func encodeSignedData(eci encapContentInfo, si signerInfo, certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, c := range certs {
+		raw = append(raw, c.Raw...)
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithmIdentifier{si.DigestAlgorithm},
+		EncapContentInfo: eci,
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      []signerInfo{si},
+	}
+	if !eci.EContentType.Equal(oidData) {
+		sd.Version = 3
+	}
+	content, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("sign: encoding signed data: %w", err)
+	}
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign: encoding content info: %w", err)
+	}
+	return der, nil
+}
+
+// signatureAlgorithm returns the signature algorithm identifier for a
+// public key.
+func signatureAlgorithm(pub crypto.PublicKey) (algorithmIdentifier, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return algorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}, nil
+	case *ecdsa.PublicKey:
+		return algorithmIdentifier{Algorithm: oidECDSAWithSHA256}, nil
+	default:
+		return algorithmIdentifier{}, fmt.Errorf("sign: unsupported key type %T", pub)
+	}
+}
+
+// signedAttributes returns the DER encoding, as a SET OF, of the signed
+// attributes of a signature over digest, the digest of content of the given
+// type, made at signTime.
+func signedAttributes(contentType asn1.ObjectIdentifier, digest []byte, signTime time.Time) ([]byte, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value any
+	}{
+		{oidContentType, contentType},
+		{oidSigningTime, signTime.UTC()},
+		{oidMessageDigest, digest},
+	}
+
+	// DER requires the elements of a SET OF sorted by their encoding
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		der, err := asn1.Marshal(v.value)
+		if err != nil {
+			return nil, fmt.Errorf("sign: encoding attribute %v: %w", v.oid, err)
+		}
+		attr, err := asn1.Marshal(attribute{
+			Type:   v.oid,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sign: encoding attribute %v: %w", v.oid, err)
+		}
+		encoded[i] = attr
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+
+	return asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true,
+		Bytes: bytes.Join(encoded, nil),
+	})
+}
+
+// setContents returns the contents of a DER-encoded SET, without its tag
+// and length.
+func setContents(der []byte) []byte {
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &raw); err != nil {
+		return nil
+	}
+	return raw.Bytes
+}
+
+// pkcs7Signature is a parsed CMS SignedData signature.
+type pkcs7Signature struct {
+	certificates   []*x509.Certificate
+	signer         *x509.Certificate // certificate matching the signer info, if embedded
+	contentType    asn1.ObjectIdentifier
+	content        []byte      // encapsulated content, nil if detached
+	hash           crypto.Hash // digest algorithm of the signer info
+	signedAttrs    []byte      // signed attributes re-encoded as a SET OF
+	messageDigest  []byte
+	signingTime    time.Time
+	signature      []byte
+	sigAlgorithm   asn1.ObjectIdentifier
+	timestampToken []byte // RFC 3161 timestamp token of the signature, if any
+}
+
+// parsePKCS7 parses the DER-encoded CMS SignedData of a /Contents entry.
+// Trailing bytes, such as the zero padding of the reserved space, are
+// ignored.
+func parsePKCS7(der []byte) (*pkcs7Signature, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("parsing content info: %w", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("content type %v is not signed data", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("parsing signed data: %w", err)
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, fmt.Errorf("signed data has no signer")
+	}
+
+	p := &pkcs7Signature{contentType: sd.EncapContentInfo.EContentType}
+	if len(sd.EncapContentInfo.EContent.Bytes) > 0 {
+		if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &p.content); err != nil {
+			return nil, fmt.Errorf("parsing encapsulated content: %w", err)
+		}
+	}
+	if len(sd.Certificates.Bytes) > 0 {
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificates: %w", err)
+		}
+		p.certificates = certs
+	}
+
+	si := sd.SignerInfos[0]
+	for _, c := range p.certificates {
+		if bytes.Equal(c.RawIssuer, si.SID.Issuer.FullBytes) && c.SerialNumber.Cmp(si.SID.Serial) == 0 {
+			p.signer = c
+			break
+		}
+	}
+	p.signature = si.Signature
+	p.sigAlgorithm = si.SignatureAlgorithm.Algorithm
+	hash, ok := digestHash(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+	}
+	p.hash = hash
+
+	if len(si.SignedAttrs.Bytes) == 0 {
+		return nil, fmt.Errorf("signer info has no signed attributes")
+	}
+	attrs, err := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true,
+		Bytes: si.SignedAttrs.Bytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding signed attributes: %w", err)
+	}
+	p.signedAttrs = attrs
+
+	rest := si.SignedAttrs.Bytes
+	for len(rest) > 0 {
+		var attr attribute
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing signed attribute: %w", err)
+		}
+		switch {
+		case attr.Type.Equal(oidMessageDigest):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &p.messageDigest); err != nil {
+				return nil, fmt.Errorf("parsing message digest: %w", err)
+			}
+		case attr.Type.Equal(oidSigningTime):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &p.signingTime); err != nil {
+				return nil, fmt.Errorf("parsing signing time: %w", err)
+			}
+		}
+	}
+	if p.messageDigest == nil {
+		return nil, fmt.Errorf("signer info has no message digest")
+	}
+
+	rest = si.UnsignedAttrs.Bytes
+	for len(rest) > 0 {
+		var attr attribute
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing unsigned attribute: %w", err)
+		}
+		if attr.Type.Equal(oidTimeStampToken) {
+			p.timestampToken = attr.Values.Bytes
+		}
+	}
+	return p, nil
+}
+
+// digestHash returns the hash function of a digest algorithm identifier.
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// verify checks that the signature covers digest, the digest of the signed
+// content, and was made with the private key of pub.
+func (p *pkcs7Signature) verify(pub crypto.PublicKey, digest []byte) error {
+	if !bytes.Equal(p.messageDigest, digest) {
+		return fmt.Errorf("message digest does not match the signed content")
+	}
+	h := p.hash.New()
+	h.Write(p.signedAttrs)
+	if !verifyRawSignature(pub, p.hash, h.Sum(nil), p.signature) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
@@ -10,10 +10,10 @@ package sign
 import (
 	"bytes"
 	"crypto"
-	"crypto/rand"
 	"crypto/x509"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -22,45 +22,111 @@ import (
 
 // Options configures the digital signature parameters.
 type Options struct {
-	Certificate *x509.Certificate // signer certificate
-	PrivateKey  crypto.Signer     // private key for signing
+	Certificate *x509.Certificate   // signer certificate
+	PrivateKey  crypto.Signer       // private key for signing
 	CertChain   []*x509.Certificate // certificate chain (optional)
-	Reason      string             // reason for signing
-	Location    string             // signing location
-	ContactInfo string             // signer contact info
-	SignTime    time.Time          // signature timestamp (default: now)
-	VisualSig   *VisualSignature   // optional visual signature
+	Reason      string              // reason for signing
+	Location    string              // signing location
+	ContactInfo string              // signer contact info
+	SignTime    time.Time           // signature timestamp (default: now)
+
+	// VisibleRect, if set, draws the signature on a page. Signatures are
+	// invisible by default.
+	VisibleRect *VisibleRect
+	// FieldName is the name of an unsigned signature field to sign, such
+	// as one added with form.FormBuilder.AddSignatureField. If empty, the
+	// first unsigned signature field is used, or a new field is added if
+	// there is none.
+	FieldName string
+	// TimestampURL is the URL of an RFC 3161 time-stamping authority. If
+	// set, the signature carries a timestamp token from that authority,
+	// as required by PAdES-T.
+	TimestampURL string
+	// TimestampClient sends the timestamp request. If nil, a client with
+	// a 30 second timeout is used; set one to change the timeout or the
+	// transport.
+	TimestampClient *http.Client
+
+	// Deprecated: VisualSig is converted to a VisibleRect; use VisibleRect.
+	VisualSig *VisualSignature
+}
+
+// VisibleRect places a visible signature on a page. Its appearance shows
+// the signer name, reason, location and signing date, or Text if set, next
+// to an optional image.
+type VisibleRect struct {
+	Page       int     // page number (1-based)
+	X, Y, W, H float64 // position and size in points, from the bottom-left corner
+	Name       string  // signer name (default: the certificate's common name)
+	Text       string  // replaces the generated lines; newlines separate lines
+	Image      []byte  // optional JPEG or PNG image, e.g. a handwritten signature
 }
 
 // VisualSignature defines the visual representation of a signature on a page.
+//
+// Deprecated: use VisibleRect.
 type VisualSignature struct {
-	Page   int     // page number (1-based)
-	X, Y   float64 // position in points
-	W, H   float64 // dimensions in points
-	Text   string  // text to display (e.g., "Signed by: John Doe")
+	Page int     // page number (1-based)
+	X, Y float64 // position in points
+	W, H float64 // dimensions in points
+	Text string  // text to display (e.g., "Signed by: John Doe")
+}
+
+// visibleRect returns the visible signature of opts, or nil if the
+// signature is invisible.
+func (opts Options) visibleRect() *VisibleRect {
+	if opts.VisibleRect != nil {
+		return opts.VisibleRect
+	}
+	if v := opts.VisualSig; v != nil {
+		return &VisibleRect{Page: v.Page, X: v.X, Y: v.Y, W: v.W, H: v.H, Text: v.Text}
+	}
+	return nil
 }
 
 // SignatureInfo contains information about an existing signature.
 type SignatureInfo struct {
 	Signer       *x509.Certificate
-	SignedAt     time.Time
+	SignerName   string    // common name of the signer certificate, or its subject
+	CertNotAfter time.Time // expiry of the signer certificate
+	SignedAt     time.Time // signing time claimed by the signer (/M)
+	Timestamp    time.Time // time asserted by an RFC 3161 timestamp token, zero if none
 	Reason       string
 	Location     string
 	Valid        bool
-	Errors       []error
-	digest       []byte // computed byte-range digest (internal)
-	rawSignature []byte // raw signature bytes (internal)
+	// ChainTrusted reports whether the signer certificate was validated
+	// against trusted roots; only VerifyWithRoots sets it.
+	ChainTrusted bool
+	// TimestampTrusted reports whether the certificate of the authority
+	// that issued Timestamp was validated against trusted roots, so that
+	// the signer certificate was checked at that time rather than now;
+	// only VerifyWithRoots sets it.
+	TimestampTrusted bool
+	// CoversWholeFile reports whether the signed byte ranges extend to the
+	// end of the file. It is false for a signature followed by later
+	// revisions, such as further signatures, which it does not cover.
+	CoversWholeFile bool
+	Errors          []error
+	digest          []byte // computed byte-range digest (internal)
 }
 
 // Sign applies a digital signature to a PDF document.
 //
 // The signing process:
 // 1. Reads the input PDF
-// 2. Adds a signature dictionary with a /ByteRange placeholder
+// 2. Appends an incremental update holding the signature field, its widget
+// and a signature dictionary with a /ByteRange placeholder
 // 3. Computes the digest over the byte ranges
-// 4. Generates a PKCS#7 detached signature
+// 4. Generates a PKCS#7 (CMS) detached signature embedding the certificates
 // 5. Inserts the signature into the reserved space
 //
+// The original bytes are kept unchanged. The signature is invisible unless
+// opts.VisibleRect is set, in which case its appearance shows the signer
+// name, reason, location and date. An unsigned signature field of the
+// document is signed in place, with an appearance drawn at its rectangle.
+// Signing an already signed document adds a signature in a new revision,
+// which leaves the earlier signatures valid.
+//
 // Note: This is a foundation implementation. Full PAdES-B and LTV support
 // will be added in future versions.
 func Sign(input io.ReadSeeker, output io.Writer, opts Options) error {
@@ -80,33 +146,56 @@ func Sign(input io.ReadSeeker, output io.Writer, opts Options) error {
 		return fmt.Errorf("sign: reading input: %w", err)
 	}
 
-	// Verify it's a valid PDF
-	_, err = reader.ReadFrom(bytes.NewReader(data))
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("sign: parsing input PDF: %w", err)
 	}
+	if _, ok := doc.Trailer()["Encrypt"]; ok {
+		return fmt.Errorf("sign: signing encrypted documents is not supported")
+	}
 
-	// Build the signature dictionary properties
-	sigProps := buildSignatureDict(opts)
+	u, err := newPDFUpdate(doc)
+	if err != nil {
+		return err
+	}
+	sigNum := u.add(nil)
+	if err := addSignatureField(doc, u, sigNum, opts); err != nil {
+		return err
+	}
 
-	// Reserve space for signature: 8192 bytes = 16384 hex chars
+	// Reserve space for signature: 8192 bytes = 16384 hex chars. The byte
+	// range placeholders have the width of the final values.
 	sigHexLen := 16384
+	placeholder := strings.Repeat("0", sigHexLen)
+	sigDict := buildSignatureDict(opts)
+	sigDict["ByteRange"] = reader.Array{reader.Integer(0), rangePlaceholder, rangePlaceholder, rangePlaceholder}
+	sigDict["Contents"] = reader.String{Value: make([]byte, sigHexLen/2), IsHex: true}
+	u.set(reader.Reference{Number: sigNum}, sigDict)
+
+	signed, err := u.bytes()
+	if err != nil {
+		return err
+	}
 
-	// Build the signature object appended to the PDF
-	// Two-pass approach: first compute layout, then fill actual values
-	update, byteRange, sigOffset := buildSignedPDF(data, sigProps, sigHexLen)
+	// The byte range covers everything but the hex string, delimiters
+	// included. Its fixed-width numbers are patched in place.
+	contentsAt := len(data) + bytes.Index(signed[len(data):], []byte("/Contents <"+placeholder))
+	sigOffset := contentsAt + len("/Contents <")
+	byteRange := [4]int{0, sigOffset - 1, sigOffset + sigHexLen + 1, 0}
+	byteRange[3] = len(signed) - byteRange[2]
+	rangeAt := len(data) + bytes.Index(signed[len(data):], []byte("/ByteRange ["))
+	copy(signed[rangeAt:], fmt.Sprintf("/ByteRange [0 %010d %010d %010d]", byteRange[1], byteRange[2], byteRange[3]))
 
 	// Compute digest over the byte ranges
-	hash := crypto.SHA256
-	h := hash.New()
-	h.Write(update[:byteRange[0]+byteRange[1]])
-	h.Write(update[byteRange[2] : byteRange[2]+byteRange[3]])
+	h := crypto.SHA256.New()
+	h.Write(signed[:byteRange[0]+byteRange[1]])
+	h.Write(signed[byteRange[2] : byteRange[2]+byteRange[3]])
 	digest := h.Sum(nil)
 
-	// Sign the digest
-	signature, err := opts.PrivateKey.Sign(rand.Reader, digest, hash)
+	// Wrap the digest in a detached CMS signature
+	signature, err := buildPKCS7(digest, opts)
 	if err != nil {
-		return fmt.Errorf("sign: signing: %w", err)
+		return err
 	}
 
 	// Encode signature as hex
@@ -114,100 +203,274 @@ func Sign(input io.ReadSeeker, output io.Writer, opts Options) error {
 	if len(sigHex) > sigHexLen {
 		return fmt.Errorf("sign: signature too large (%d > %d)", len(sigHex), sigHexLen)
 	}
-	// Pad with zeros to fill the reserved space
-	padded := make([]byte, sigHexLen)
-	copy(padded, sigHex)
-	for i := len(sigHex); i < sigHexLen; i++ {
-		padded[i] = '0'
+	// The rest of the reserved space keeps its zero padding
+	copy(signed[sigOffset:], sigHex)
+
+	_, err = output.Write(signed)
+	return err
+}
+
+// addSignatureField adds to u the signature field whose value is the
+// signature dictionary sigNum. An unsigned signature field of the document
+// is reused if there is one; otherwise a new field is added, with a widget
+// on the page of the visible signature or, for invisible signatures, a
+// zero-size widget on the first page.
+func addSignatureField(doc *reader.Document, u *pdfUpdate, sigNum int, opts Options) error {
+	vis := opts.visibleRect()
+	field, err := unsignedField(doc, opts.FieldName)
+	if err != nil {
+		return err
+	}
+
+	var fieldRef reader.Reference
+	var dict reader.Dict
+	var w, h float64
+	if field != nil {
+		fieldRef = u.ref(field.ObjNum)
+		obj, err := doc.ResolveReference(fieldRef)
+		if err != nil {
+			return fmt.Errorf("sign: reading field %q: %w", field.FullName, err)
+		}
+		orig, ok := obj.(reader.Dict)
+		if !ok {
+			return fmt.Errorf("sign: field %q is not a dictionary", field.FullName)
+		}
+		dict = make(reader.Dict, len(orig)+2)
+		for k, v := range orig {
+			dict[k] = v
+		}
+		w, h = field.Rect.Width(), field.Rect.Height()
+	} else {
+		pageNum := 1
+		if vis != nil && vis.Page > 0 {
+			pageNum = vis.Page
+		}
+		page, err := doc.Page(pageNum)
+		if err != nil {
+			return fmt.Errorf("sign: signature page: %w", err)
+		}
+		pageRef := u.ref(page.ObjNum)
+
+		rect := reader.Array{reader.Integer(0), reader.Integer(0), reader.Integer(0), reader.Integer(0)}
+		if vis != nil {
+			rect = reader.Array{reader.Real(vis.X), reader.Real(vis.Y), reader.Real(vis.X + vis.W), reader.Real(vis.Y + vis.H)}
+			w, h = vis.W, vis.H
+		}
+		fieldRef = reader.Reference{Number: u.add(nil)}
+		dict = reader.Dict{
+			"Type":    reader.Name("Annot"),
+			"Subtype": reader.Name("Widget"),
+			"FT":      reader.Name("Sig"),
+			"T":       reader.String{Value: []byte(newFieldName(doc))},
+			"F":       reader.Integer(132), // print, locked
+			"Rect":    rect,
+			"P":       pageRef,
+		}
+		if err := appendToArray(doc, u, pageRef, "Annots", fieldRef); err != nil {
+			return fmt.Errorf("sign: adding signature widget to page %d: %w", pageNum, err)
+		}
 	}
 
-	// Replace placeholder with actual signature
-	copy(update[sigOffset:sigOffset+sigHexLen], padded)
+	dict["V"] = reader.Reference{Number: sigNum}
+	if w > 0 && h > 0 {
+		if vis == nil {
+			vis = &VisibleRect{}
+		}
+		apNum, err := addAppearance(u, w, h, appearanceLines(opts, vis), vis.Image)
+		if err != nil {
+			return err
+		}
+		dict["AP"] = reader.Dict{"N": reader.Reference{Number: apNum}}
+	}
+	u.set(fieldRef, dict)
 
-	_, err = output.Write(update)
-	return err
+	return updateAcroForm(doc, u, fieldRef, field == nil)
+}
+
+// unsignedField returns the signature field to sign: the one named name,
+// or if name is empty the first signature field without a value. It returns
+// nil if name is empty and there is no unsigned signature field.
+func unsignedField(doc *reader.Document, name string) (*reader.FormField, error) {
+	fields, err := doc.FormFields()
+	if err != nil {
+		return nil, fmt.Errorf("sign: reading form fields: %w", err)
+	}
+	var found *reader.FormField
+	var walk func([]*reader.FormField)
+	walk = func(fields []*reader.FormField) {
+		for _, f := range fields {
+			if found != nil {
+				return
+			}
+			if f.Type == "Sig" && f.ObjNum != 0 && len(f.Kids) == 0 && (name == "" || f.FullName == name) && !isSigned(doc, f) {
+				found = f
+				return
+			}
+			walk(f.Kids)
+		}
+	}
+	walk(fields)
+	if found == nil && name != "" {
+		return nil, fmt.Errorf("sign: no unsigned signature field named %q", name)
+	}
+	return found, nil
 }
 
-// buildSignatureDict constructs the PDF signature dictionary string.
-func buildSignatureDict(opts Options) string {
-	dict := "/Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached"
+// isSigned reports whether a signature field has a value, the reference to
+// its signature dictionary.
+func isSigned(doc *reader.Document, f *reader.FormField) bool {
+	obj, err := doc.ResolveReference(reader.Reference{Number: f.ObjNum})
+	if err != nil {
+		return true
+	}
+	dict, ok := obj.(reader.Dict)
+	if !ok {
+		return true
+	}
+	_, ok = dict["V"]
+	return ok
+}
 
-	if opts.Reason != "" {
-		dict += fmt.Sprintf(" /Reason (%s)", escapePDF(opts.Reason))
+// newFieldName returns a field name of the form SignatureN not used by any
+// field of doc.
+func newFieldName(doc *reader.Document) string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("Signature%d", i)
+		if f, _ := doc.FormField(name); f == nil {
+			return name
+		}
 	}
-	if opts.Location != "" {
-		dict += fmt.Sprintf(" /Location (%s)", escapePDF(opts.Location))
+}
+
+// updateAcroForm sets the signature flags of the document's interactive
+// form, creating it if needed, and adds fieldRef to its fields if
+// addField is set.
+func updateAcroForm(doc *reader.Document, u *pdfUpdate, fieldRef reader.Reference, addField bool) error {
+	catalog, err := doc.Catalog()
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
 	}
-	if opts.ContactInfo != "" {
-		dict += fmt.Sprintf(" /ContactInfo (%s)", escapePDF(opts.ContactInfo))
+	rootRef, ok := doc.Trailer()["Root"].(reader.Reference)
+	if !ok {
+		return fmt.Errorf("sign: /Root is not an indirect object")
+	}
+
+	// The form is either an object of its own or inline in the catalog
+	formRef, inline := catalog["AcroForm"].(reader.Reference)
+	inline = !inline
+	var acroForm reader.Dict
+	if inline {
+		acroForm, _ = catalog["AcroForm"].(reader.Dict)
+	} else {
+		obj, err := doc.ResolveReference(formRef)
+		if err != nil {
+			return fmt.Errorf("sign: reading AcroForm: %w", err)
+		}
+		acroForm, _ = obj.(reader.Dict)
 	}
+	form := reader.Dict{"Fields": reader.Array{}}
+	for k, v := range acroForm {
+		form[k] = v
+	}
+	form["SigFlags"] = reader.Integer(3) // signatures exist, append only
 
-	dict += fmt.Sprintf(" /M (D:%s)", opts.SignTime.Format("20060102150405-07'00'"))
+	if addField {
+		fields, isRef := form["Fields"].(reader.Reference)
+		if isRef {
+			if err := appendToArray(doc, u, fields, "", fieldRef); err != nil {
+				return fmt.Errorf("sign: adding signature field: %w", err)
+			}
+		} else {
+			arr, _ := form["Fields"].(reader.Array)
+			form["Fields"] = append(append(reader.Array{}, arr...), fieldRef)
+		}
+	}
 
-	return dict
+	if !inline {
+		u.set(formRef, form)
+		return nil
+	}
+	root := make(reader.Dict, len(catalog))
+	for k, v := range catalog {
+		root[k] = v
+	}
+	root["AcroForm"] = form
+	u.set(rootRef, root)
+	return nil
 }
 
-// buildSignedPDF appends a proper signature dictionary to the PDF data.
-// Returns the complete PDF bytes, the byte range, and the offset of the hex signature.
-func buildSignedPDF(data []byte, sigProps string, sigHexLen int) ([]byte, [4]int, int) {
-	// Zero-filled placeholder
-	placeholder := make([]byte, sigHexLen)
-	for i := range placeholder {
-		placeholder[i] = '0'
-	}
-
-	// We need to know the final layout to compute byte ranges.
-	// The signature dict format is:
-	// \n<< {sigProps} /ByteRange [0 {br1} {br2start} {br2len}] /Contents <{hex}> >>
-	//
-	// Two-pass: first estimate, then finalize with correct byte range values.
-
-	// Build template with placeholder byte range (use fixed-width numbers for stability)
-	byteRangeStr := fmt.Sprintf("/ByteRange [0 %010d %010d %010d]", 0, 0, 0)
-	sigDictStr := fmt.Sprintf("\n<< %s %s /Contents <%s> >>", sigProps, byteRangeStr, string(placeholder))
-
-	// Calculate positions
-	contentsStart := bytes.Index([]byte(sigDictStr), []byte("<"+string(placeholder[:10])))
-	if contentsStart < 0 {
-		// Fallback: search for the hex start
-		contentsStart = len(sigDictStr) - sigHexLen - 4 // approximate
-	}
-	// contentsStart is relative to sigDictStr, add data length for absolute position
-	// The '<' is at data len + contentsStart, hex starts at +1
-	hexAbsOffset := len(data) + contentsStart + 1
-
-	// Byte range: [0, before_hex_start, after_hex_end, remaining]
-	br1End := hexAbsOffset - 1            // just before '<'
-	br2Start := hexAbsOffset + sigHexLen + 1 // just after '>'
-	totalLen := len(data) + len(sigDictStr)
-	br2Len := totalLen - br2Start
-
-	// Now rebuild with actual byte range values
-	byteRangeStr = fmt.Sprintf("/ByteRange [0 %010d %010d %010d]", br1End, br2Start, br2Len)
-	sigDictStr = fmt.Sprintf("\n<< %s %s /Contents <%s> >>", sigProps, byteRangeStr, string(placeholder))
-
-	// Verify total length matches (it should since we use fixed-width numbers)
-	result := make([]byte, len(data)+len(sigDictStr))
-	copy(result, data)
-	copy(result[len(data):], []byte(sigDictStr))
-
-	byteRange := [4]int{0, br1End, br2Start, br2Len}
-
-	return result, byteRange, hexAbsOffset
+// appendToArray appends item to an array and redefines the object holding
+// it in u. With an empty key, ref is the array object itself; otherwise the
+// array is the entry key of dictionary ref, either inline or an indirect
+// object, and is created if missing.
+func appendToArray(doc *reader.Document, u *pdfUpdate, ref reader.Reference, key reader.Name, item reader.Object) error {
+	obj, err := doc.ResolveReference(ref)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		arr, ok := obj.(reader.Array)
+		if !ok {
+			return fmt.Errorf("object %d is not an array", ref.Number)
+		}
+		u.set(ref, append(append(reader.Array{}, arr...), item))
+		return nil
+	}
+
+	dict, ok := obj.(reader.Dict)
+	if !ok {
+		return fmt.Errorf("object %d is not a dictionary", ref.Number)
+	}
+	if arrRef, ok := dict[key].(reader.Reference); ok {
+		return appendToArray(doc, u, arrRef, "", item)
+	}
+	arr, _ := dict[key].(reader.Array)
+	updated := make(reader.Dict, len(dict)+1)
+	for k, v := range dict {
+		updated[k] = v
+	}
+	updated[key] = append(append(reader.Array{}, arr...), item)
+	u.set(ref, updated)
+	return nil
 }
 
+// rangePlaceholder stands for a /ByteRange value until the offsets are
+// known; it has the ten digits they are patched in with.
+const rangePlaceholder = reader.Integer(9999999999)
+
+// buildSignatureDict constructs the PDF signature dictionary, without its
+// /ByteRange and /Contents entries.
+func buildSignatureDict(opts Options) reader.Dict {
+	dict := reader.Dict{
+		"Type":      reader.Name("Sig"),
+		"Filter":    reader.Name("Adobe.PPKLite"),
+		"SubFilter": reader.Name("adbe.pkcs7.detached"),
+		"M":         reader.String{Value: []byte("D:" + opts.SignTime.Format("20060102150405-07'00'"))},
+	}
+	if opts.Reason != "" {
+		dict["Reason"] = reader.String{Value: []byte(opts.Reason)}
+	}
+	if opts.Location != "" {
+		dict["Location"] = reader.String{Value: []byte(opts.Location)}
+	}
+	if opts.ContactInfo != "" {
+		dict["ContactInfo"] = reader.String{Value: []byte(opts.ContactInfo)}
+	}
+	return dict
+}
+
+// escapePDF escapes the delimiters of a PDF literal string, byte by byte.
 func escapePDF(s string) string {
 	var b strings.Builder
 	b.Grow(len(s))
-	for _, c := range s {
-		switch c {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
 		case '(', ')', '\\':
 			b.WriteByte('\\')
-			b.WriteRune(c)
+			b.WriteByte(c)
 		default:
-			b.WriteRune(c)
+			b.WriteByte(c)
 		}
 	}
 	return b.String()
 }
-
@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	gofpdf "github.com/lvillar/gofpdf"
+	"github.com/lvillar/gofpdf/form"
+	"github.com/lvillar/gofpdf/reader"
 	"github.com/lvillar/gofpdf/sign"
 )
 
@@ -241,3 +244,396 @@ func TestVerifyTamperedPDF(t *testing.T) {
 
 	t.Logf("Tampered verification: valid=%v errors=%v", sigs[0].Valid, sigs[0].Errors)
 }
+
+func TestVerifyAppendedData(t *testing.T) {
+	cert, key := generateTestCert(t)
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(generateTestPDF(t)), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	sigs, err := sign.Verify(bytes.NewReader(signed.Bytes()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid || !sigs[0].CoversWholeFile {
+		t.Fatalf("expected a valid signature covering the file, got %+v", sigs)
+	}
+
+	// Appended bytes leave the signature valid but no longer cover the file
+	appended := append(bytes.Clone(signed.Bytes()), "% appended\n"...)
+	sigs, err = sign.Verify(bytes.NewReader(appended))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid signature, got %+v", sigs)
+	}
+	if sigs[0].CoversWholeFile {
+		t.Error("expected the appended bytes not to be covered")
+	}
+}
+
+func TestSignEmbedsCMS(t *testing.T) {
+	cert, key := generateTestCert(t)
+	pdfData := generateTestPDF(t)
+
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(pdfData), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+		SignTime:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	// Verify checks the signature against the embedded certificate
+	sigs, err := sign.Verify(bytes.NewReader(signed.Bytes()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(sigs))
+	}
+	if !sigs[0].Valid {
+		t.Errorf("expected valid signature, got errors: %v", sigs[0].Errors)
+	}
+	if sigs[0].Signer == nil || sigs[0].Signer.Subject.CommonName != "Test Signer" {
+		t.Errorf("expected embedded signer certificate, got %v", sigs[0].Signer)
+	}
+
+	// A different key does not verify the signature
+	other, _ := generateTestCert(t)
+	sigs, err = sign.VerifyWithCertificate(bytes.NewReader(signed.Bytes()), other.PublicKey)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if sigs[0].Valid {
+		t.Error("expected signature to fail with another key")
+	}
+}
+
+func TestSignRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "RSA Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+
+	var signed bytes.Buffer
+	err = sign.Sign(bytes.NewReader(generateTestPDF(t)), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	sigs, err := sign.VerifyWithCertificate(bytes.NewReader(signed.Bytes()), &key.PublicKey)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid RSA signature, got %+v", sigs)
+	}
+}
+
+func TestSignVisible(t *testing.T) {
+	cert, key := generateTestCert(t)
+	pdfData := generateTestPDF(t)
+
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(pdfData), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+		Reason:      "Approval",
+		SignTime:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		VisibleRect: &sign.VisibleRect{Page: 1, X: 50, Y: 50, W: 200, H: 60},
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	out := signed.Bytes()
+
+	// The signature is an incremental update
+	if !bytes.HasPrefix(out, pdfData) {
+		t.Error("expected the original bytes to be kept as a prefix")
+	}
+	for _, want := range []string{"(Digitally signed by Test Signer) Tj", "(Reason: Approval) Tj", "/SigFlags 3"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("expected %q in signed PDF", want)
+		}
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("reading signed PDF: %v", err)
+	}
+	field, err := doc.FormField("Signature1")
+	if err != nil || field == nil {
+		t.Fatalf("signature field: %v", err)
+	}
+	if field.Type != "Sig" {
+		t.Errorf("expected a signature field, got %q", field.Type)
+	}
+	if want := (reader.Rectangle{LLX: 50, LLY: 50, URX: 250, URY: 110}); field.Rect != want {
+		t.Errorf("expected rectangle %v, got %v", want, field.Rect)
+	}
+
+	sigs, err := sign.Verify(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid signature, got %+v", sigs)
+	}
+}
+
+func TestSignSignatureField(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	fb := form.NewFormBuilder(pdf)
+	fb.AddSignatureField("approval", 1, 20, 20, 60, 20)
+	if err := fb.Build(); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	cert, key := generateTestCert(t)
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(buf.Bytes()), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+		FieldName:   "approval",
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(signed.Bytes()))
+	if err != nil {
+		t.Fatalf("reading signed PDF: %v", err)
+	}
+	fields, err := doc.FormFields()
+	if err != nil {
+		t.Fatalf("form fields: %v", err)
+	}
+	if len(fields) != 1 {
+		t.Fatalf("expected the existing field to be signed, got %d fields", len(fields))
+	}
+	if !bytes.Contains(signed.Bytes(), []byte("(Digitally signed by Test Signer) Tj")) {
+		t.Error("expected an appearance at the field's rectangle")
+	}
+
+	sigs, err := sign.Verify(bytes.NewReader(signed.Bytes()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid signature, got %+v", sigs)
+	}
+
+	err = sign.Sign(bytes.NewReader(buf.Bytes()), &signed, sign.Options{
+		Certificate: cert,
+		PrivateKey:  key,
+		FieldName:   "missing",
+	})
+	if err == nil {
+		t.Error("expected an error for a missing field")
+	}
+}
+
+func TestSignSequentially(t *testing.T) {
+	data := generateTestPDF(t)
+	signers := []string{"First", "Second", "Third"}
+	var certs []*x509.Certificate
+	for i, name := range signers {
+		cert, key := generateTestCert(t)
+		cert.Subject.CommonName = name
+		certs = append(certs, cert)
+
+		var signed bytes.Buffer
+		err := sign.Sign(bytes.NewReader(data), &signed, sign.Options{
+			Certificate: cert,
+			PrivateKey:  key,
+			Reason:      "Approval " + name,
+			VisibleRect: &sign.VisibleRect{Page: 1, X: 50, Y: 50 + 70*float64(i), W: 200, H: 60},
+		})
+		if err != nil {
+			t.Fatalf("signature %d: %v", i+1, err)
+		}
+		if !bytes.HasPrefix(signed.Bytes(), data) {
+			t.Fatalf("signature %d rewrote the previous revision", i+1)
+		}
+		data = signed.Bytes()
+	}
+
+	sigs, err := sign.Verify(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != len(signers) {
+		t.Fatalf("expected %d signatures, got %d", len(signers), len(sigs))
+	}
+	for i, sig := range sigs {
+		if !sig.Valid {
+			t.Errorf("signature %d: expected valid, got errors %v", i+1, sig.Errors)
+		}
+		if want := "Approval " + signers[i]; sig.Reason != want {
+			t.Errorf("signature %d: expected reason %q, got %q", i+1, want, sig.Reason)
+		}
+		if !sig.Signer.Equal(certs[i]) {
+			t.Errorf("signature %d: unexpected signer %v", i+1, sig.Signer.Subject)
+		}
+		if last := i == len(sigs)-1; sig.CoversWholeFile != last {
+			t.Errorf("signature %d: CoversWholeFile = %v, want %v", i+1, sig.CoversWholeFile, last)
+		}
+	}
+
+	doc, err := reader.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading signed PDF: %v", err)
+	}
+	fields, err := doc.FormFields()
+	if err != nil {
+		t.Fatalf("form fields: %v", err)
+	}
+	if len(fields) != len(signers) {
+		t.Errorf("expected %d signature fields, got %d", len(signers), len(fields))
+	}
+}
+
+// generateCACert creates a CA certificate and a signer certificate issued
+// by it with the given key usage.
+func generateCACert(t *testing.T, usage x509.KeyUsage) (ca, leaf *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(10),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(48 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating CA certificate: %v", err)
+	}
+	if ca, err = x509.ParseCertificate(der); err != nil {
+		t.Fatalf("parsing CA certificate: %v", err)
+	}
+
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	der, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(11),
+		Subject:      pkix.Name{CommonName: "Jane Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     usage,
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating signer certificate: %v", err)
+	}
+	if leaf, err = x509.ParseCertificate(der); err != nil {
+		t.Fatalf("parsing signer certificate: %v", err)
+	}
+	return ca, leaf, key
+}
+
+func TestVerifyWithRoots(t *testing.T) {
+	ca, leaf, key := generateCACert(t, x509.KeyUsageDigitalSignature)
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(generateTestPDF(t)), &signed, sign.Options{
+		Certificate: leaf,
+		PrivateKey:  key,
+		CertChain:   []*x509.Certificate{ca},
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	sigs, err := sign.VerifyWithRoots(bytes.NewReader(signed.Bytes()), roots)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(sigs))
+	}
+	sig := sigs[0]
+	if !sig.Valid || !sig.ChainTrusted {
+		t.Errorf("expected a valid, trusted signature, got valid=%v trusted=%v errors=%v", sig.Valid, sig.ChainTrusted, sig.Errors)
+	}
+	if sig.SignerName != "Jane Signer" {
+		t.Errorf("expected signer name %q, got %q", "Jane Signer", sig.SignerName)
+	}
+	if !sig.CertNotAfter.Equal(leaf.NotAfter) {
+		t.Errorf("expected certificate expiry %v, got %v", leaf.NotAfter, sig.CertNotAfter)
+	}
+
+	// Another CA does not vouch for the signer
+	other, _, _ := generateCACert(t, x509.KeyUsageDigitalSignature)
+	untrusted := x509.NewCertPool()
+	untrusted.AddCert(other)
+	sigs, err = sign.VerifyWithRoots(bytes.NewReader(signed.Bytes()), untrusted)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !sigs[0].Valid || sigs[0].ChainTrusted || len(sigs[0].Errors) == 0 {
+		t.Errorf("expected a valid but untrusted signature, got valid=%v trusted=%v errors=%v", sigs[0].Valid, sigs[0].ChainTrusted, sigs[0].Errors)
+	}
+}
+
+func TestVerifyWithRootsKeyUsage(t *testing.T) {
+	ca, leaf, key := generateCACert(t, x509.KeyUsageKeyEncipherment)
+	var signed bytes.Buffer
+	err := sign.Sign(bytes.NewReader(generateTestPDF(t)), &signed, sign.Options{
+		Certificate: leaf,
+		PrivateKey:  key,
+		CertChain:   []*x509.Certificate{ca},
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	sigs, err := sign.VerifyWithRoots(bytes.NewReader(signed.Bytes()), roots)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if sigs[0].ChainTrusted {
+		t.Error("expected a certificate without the digital signature usage to be rejected")
+	}
+}
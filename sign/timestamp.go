@@ -0,0 +1,170 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Object identifiers of RFC 3161 timestamps.
+var (
+	oidTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidTSTInfo        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+// maxTimestampResponse bounds the size of a TSA response.
+const maxTimestampResponse = 1 << 20
+
+// timestampClient requests timestamps when Options.TimestampClient is nil,
+// so that an authority that stops responding cannot block Sign forever.
+var timestampClient = &http.Client{Timeout: 30 * time.Second}
+
+type messageImprint struct {
+	HashAlgorithm algorithmIdentifier
+	HashedMessage []byte
+}
+
+// timeStampReq is an RFC 3161 TimeStampReq.
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+// timeStampResp is an RFC 3161 TimeStampResp. Only the status code of the
+// PKIStatusInfo is decoded.
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status int
+}
+
+// tstInfo is the content of a timestamp token. The fields following the
+// generation time are not decoded.
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+// requestTimestamp asks the time-stamping authority at url for an RFC 3161
+// timestamp token of signature, using client, and returns its DER
+// encoding.
+func requestTimestamp(client *http.Client, url string, signature []byte) ([]byte, error) {
+	imprint := crypto.SHA256.New()
+	imprint.Write(signature)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("sign: generating timestamp nonce: %w", err)
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: algorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: imprint.Sum(nil),
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign: encoding timestamp request: %w", err)
+	}
+
+	if client == nil {
+		client = timestampClient
+	}
+	resp, err := client.Post(url, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, fmt.Errorf("sign: requesting timestamp: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sign: requesting timestamp: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampResponse+1))
+	if err != nil {
+		return nil, fmt.Errorf("sign: reading timestamp response: %w", err)
+	}
+	if len(body) > maxTimestampResponse {
+		return nil, fmt.Errorf("sign: timestamp response exceeds %d bytes", maxTimestampResponse)
+	}
+
+	var tsr timeStampResp
+	if _, err := asn1.Unmarshal(body, &tsr); err != nil {
+		return nil, fmt.Errorf("sign: parsing timestamp response: %w", err)
+	}
+	// 0 is granted, 1 granted with modifications
+	if tsr.Status.Status > 1 || len(tsr.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("sign: timestamp request rejected (status %d)", tsr.Status.Status)
+	}
+	token := tsr.TimeStampToken.FullBytes
+
+	info, _, err := parseTimestamp(token)
+	if err != nil {
+		return nil, fmt.Errorf("sign: timestamp token: %w", err)
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, imprint.Sum(nil)) {
+		return nil, fmt.Errorf("sign: timestamp token does not match the signature")
+	}
+	return token, nil
+}
+
+// parseTimestamp parses an RFC 3161 timestamp token.
+func parseTimestamp(token []byte) (*tstInfo, *pkcs7Signature, error) {
+	p7, err := parsePKCS7(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !p7.contentType.Equal(oidTSTInfo) {
+		return nil, nil, fmt.Errorf("content type %v is not a timestamp", p7.contentType)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(p7.content, &info); err != nil {
+		return nil, nil, fmt.Errorf("parsing timestamp info: %w", err)
+	}
+	return &info, p7, nil
+}
+
+// verifyTimestamp checks that an RFC 3161 timestamp token covers signature
+// and was signed by the time-stamping authority certificate it embeds, and
+// returns the time it asserts. The authority certificate is not validated
+// here; VerifyWithRoots does so with checkTimestampAuthority before relying
+// on the time.
+func verifyTimestamp(token, signature []byte) (time.Time, error) {
+	info, p7, err := parseTimestamp(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hash, ok := digestHash(info.MessageImprint.HashAlgorithm.Algorithm)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unsupported imprint algorithm %v", info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	imprint := hash.New()
+	imprint.Write(signature)
+	if !bytes.Equal(info.MessageImprint.HashedMessage, imprint.Sum(nil)) {
+		return time.Time{}, fmt.Errorf("token does not match the signature")
+	}
+
+	if p7.signer == nil {
+		return time.Time{}, fmt.Errorf("authority certificate not embedded")
+	}
+	content := p7.hash.New()
+	content.Write(p7.content)
+	if err := p7.verify(p7.signer.PublicKey, content.Sum(nil)); err != nil {
+		return time.Time{}, err
+	}
+	return info.GenTime, nil
+}
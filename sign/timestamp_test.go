@@ -0,0 +1,286 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// newTestTSA starts a time-stamping authority answering every request with
+// a token asserting genTime, and returns it with its self-signed
+// certificate.
+func newTestTSA(t *testing.T, genTime time.Time) (*httptest.Server, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req timeStampReq
+		if _, err := asn1.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The nonce follows the decoded fields of tstInfo
+		info, err := asn1.Marshal(struct {
+			Version        int
+			Policy         asn1.ObjectIdentifier
+			MessageImprint messageImprint
+			SerialNumber   *big.Int
+			GenTime        time.Time `asn1:"generalized"`
+			Nonce          *big.Int
+		}{1, asn1.ObjectIdentifier{1, 2, 3}, req.MessageImprint, big.NewInt(1), genTime, req.Nonce})
+		if err != nil {
+			t.Errorf("encoding timestamp info: %v", err)
+			return
+		}
+		digest := crypto.SHA256.New()
+		digest.Write(info)
+		si, err := newSignerInfo(cert, key, oidTSTInfo, digest.Sum(nil), genTime)
+		if err != nil {
+			t.Errorf("signing timestamp: %v", err)
+			return
+		}
+		octets, _ := asn1.Marshal(info)
+		token, err := encodeSignedData(encapContentInfo{
+			EContentType: oidTSTInfo,
+			EContent:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: octets},
+		}, si, []*x509.Certificate{cert})
+		if err != nil {
+			t.Errorf("encoding token: %v", err)
+			return
+		}
+		resp, _ := asn1.Marshal(timeStampResp{TimeStampToken: asn1.RawValue{FullBytes: token}})
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, cert
+}
+
+// newTestSigner returns a self-signed signer certificate and its key.
+func newTestSigner(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+// newTestDocument returns a one-page PDF to sign.
+func newTestDocument(t *testing.T) []byte {
+	t.Helper()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	var doc bytes.Buffer
+	if err := pdf.Output(&doc); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	return doc.Bytes()
+}
+
+func TestSignTimestamp(t *testing.T) {
+	cert, key := newTestSigner(t)
+	doc := newTestDocument(t)
+
+	genTime := time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC)
+	tsa, _ := newTestTSA(t, genTime)
+	var signed bytes.Buffer
+	err := Sign(bytes.NewReader(doc), &signed, Options{
+		Certificate:  cert,
+		PrivateKey:   key,
+		SignTime:     time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC),
+		TimestampURL: tsa.URL,
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	sigs, err := Verify(bytes.NewReader(signed.Bytes()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid signature, got %+v", sigs)
+	}
+	if !sigs[0].Timestamp.Equal(genTime) {
+		t.Errorf("expected timestamp %v, got %v", genTime, sigs[0].Timestamp)
+	}
+	if sigs[0].SignedAt.Equal(sigs[0].Timestamp) {
+		t.Error("expected the claimed signing time to be reported separately")
+	}
+
+	// An unreachable authority fails the signature
+	tsa.Close()
+	err = Sign(bytes.NewReader(doc), io.Discard, Options{
+		Certificate:  cert,
+		PrivateKey:   key,
+		TimestampURL: tsa.URL,
+	})
+	if err == nil {
+		t.Error("expected an error when the authority is unreachable")
+	}
+}
+
+func TestSignTimestampUnresponsive(t *testing.T) {
+	cert, key := newTestSigner(t)
+	doc := newTestDocument(t)
+
+	// An authority that never answers fails once the client times out
+	done := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(done) })
+	start := time.Now()
+	err := Sign(bytes.NewReader(doc), io.Discard, Options{
+		Certificate:     cert,
+		PrivateKey:      key,
+		TimestampURL:    hung.URL,
+		TimestampClient: &http.Client{Timeout: 100 * time.Millisecond},
+	})
+	if err == nil {
+		t.Error("expected an error when the authority does not answer")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("signing took %v despite the client timeout", elapsed)
+	}
+
+	// An oversized response is rejected rather than truncated
+	big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxTimestampResponse+1))
+	}))
+	t.Cleanup(big.Close)
+	err = Sign(bytes.NewReader(doc), io.Discard, Options{
+		Certificate:  cert,
+		PrivateKey:   key,
+		TimestampURL: big.URL,
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("error = %v, want one for the response size", err)
+	}
+}
+
+func TestVerifyWithRootsTimestampAuthority(t *testing.T) {
+	// A signer certificate from a trusted CA that expired after the
+	// document was time-stamped
+	now := time.Now().Truncate(time.Second)
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(20),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-24 * time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating CA certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	der, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(21),
+		Subject:      pkix.Name{CommonName: "Expired Signer"},
+		NotBefore:    now.Add(-2 * time.Hour),
+		NotAfter:     now.Add(-10 * time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("creating signer certificate: %v", err)
+	}
+	signer, _ := x509.ParseCertificate(der)
+
+	tsa, tsaCert := newTestTSA(t, now.Add(-30*time.Minute))
+	var signed bytes.Buffer
+	err = Sign(bytes.NewReader(newTestDocument(t)), &signed, Options{
+		Certificate:  signer,
+		PrivateKey:   key,
+		CertChain:    []*x509.Certificate{ca},
+		TimestampURL: tsa.URL,
+	})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	// The self-issued authority certificate is not trusted, so the token
+	// cannot move the check back to before the signer certificate expired
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	sigs, err := VerifyWithRoots(bytes.NewReader(signed.Bytes()), roots)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(sigs) != 1 || !sigs[0].Valid {
+		t.Fatalf("expected a valid signature, got %+v", sigs)
+	}
+	if sigs[0].TimestampTrusted || sigs[0].ChainTrusted {
+		t.Errorf("untrusted authority: got timestamp trusted=%v, chain trusted=%v; want neither", sigs[0].TimestampTrusted, sigs[0].ChainTrusted)
+	}
+
+	// Once the authority is trusted, the signer is checked at the
+	// timestamp
+	roots.AddCert(tsaCert)
+	sigs, err = VerifyWithRoots(bytes.NewReader(signed.Bytes()), roots)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !sigs[0].TimestampTrusted || !sigs[0].ChainTrusted {
+		t.Errorf("trusted authority: got timestamp trusted=%v, chain trusted=%v, errors %v; want both", sigs[0].TimestampTrusted, sigs[0].ChainTrusted, sigs[0].Errors)
+	}
+}
@@ -0,0 +1,68 @@
+package sign
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lvillar/gofpdf/reader"
+)
+
+// pdfUpdate collects the objects of an incremental update, which is
+// appended to the original file so that the signed bytes of earlier
+// revisions stay untouched.
+type pdfUpdate struct {
+	doc     *reader.Document
+	nextObj int
+	objects map[reader.Reference]reader.Object
+	current map[int]int // generation numbers of the objects of doc
+}
+
+// newPDFUpdate starts an incremental update of doc. New objects are
+// numbered from the trailer's /Size.
+func newPDFUpdate(doc *reader.Document) (*pdfUpdate, error) {
+	summary, err := doc.XRefSummary()
+	if err != nil {
+		return nil, fmt.Errorf("sign: reading cross-reference sections: %w", err)
+	}
+	size, _ := doc.Trailer().GetInt("Size")
+	u := &pdfUpdate{
+		doc:     doc,
+		nextObj: max(int(size), 1),
+		objects: make(map[reader.Reference]reader.Object),
+		current: make(map[int]int, len(summary)),
+	}
+	for num, entries := range summary {
+		u.current[num] = entries[len(entries)-1].Generation
+		u.nextObj = max(u.nextObj, num+1)
+	}
+	return u, nil
+}
+
+// ref returns a reference to the current revision of object num.
+func (u *pdfUpdate) ref(num int) reader.Reference {
+	return reader.Reference{Number: num, Generation: u.current[num]}
+}
+
+// add adds obj as a new object and returns its number. A nil obj reserves
+// the number for an object defined later with set.
+func (u *pdfUpdate) add(obj reader.Object) int {
+	num := u.nextObj
+	u.nextObj++
+	u.objects[reader.Reference{Number: num}] = obj
+	return num
+}
+
+// set defines object ref as obj, either a new object reserved with add or
+// an object of the original file being redefined.
+func (u *pdfUpdate) set(ref reader.Reference, obj reader.Object) {
+	u.objects[ref] = obj
+}
+
+// bytes returns the original file followed by the update.
+func (u *pdfUpdate) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := u.doc.AppendUpdate(&buf, u.objects); err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -1,9 +1,11 @@
 package sign
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -14,18 +16,56 @@ import (
 )
 
 var (
-	verifySigTypeRe    = regexp.MustCompile(`/Type\s+/Sig\b`)
-	verifyByteRangeRe  = regexp.MustCompile(`/ByteRange\s*\[([^\]]+)\]`)
-	verifyContentsRe   = regexp.MustCompile(`/Contents\s*<([0-9a-fA-F]+)>`)
+	verifySigTypeRe   = regexp.MustCompile(`/Type\s+/Sig\b`)
+	verifyByteRangeRe = regexp.MustCompile(`/ByteRange\s*\[([^\]]+)\]`)
+	verifyContentsRe  = regexp.MustCompile(`/Contents\s*<([0-9a-fA-F]+)>`)
 )
 
 // Verify checks the digital signatures in a PDF document.
 // It extracts signature dictionaries, recomputes digests from byte ranges,
-// and returns information about each signature found.
+// and returns information about each signature found, in the order they
+// were applied. Each signature covers the revision it was applied to, so it
+// stays valid when later signatures are appended as incremental updates.
 //
-// Note: Without certificates embedded in the PDF, cryptographic verification
-// requires the signer's certificate. Use VerifyWithCertificate for full validation.
+// Each signature is checked against the signer certificate embedded in its
+// CMS structure, which proves that the bytes covered by its /ByteRange were
+// not modified since it was signed, but not who signed it: the certificate
+// is not validated. Use VerifyWithRoots to validate it, or
+// VerifyWithCertificate to check signatures against a known key. The byte
+// range must start at the beginning of the file and leave out exactly the
+// signature's /Contents string; bytes appended after it, such as later
+// incremental updates, are not covered, which CoversWholeFile reports.
 func Verify(input io.ReadSeeker) ([]SignatureInfo, error) {
+	return verify(input, nil, nil)
+}
+
+// VerifyWithCertificate verifies signatures using the provided certificate.
+// This performs full cryptographic verification of each signature found.
+func VerifyWithCertificate(input io.ReadSeeker, cert crypto.PublicKey) ([]SignatureInfo, error) {
+	return verify(input, cert, nil)
+}
+
+// VerifyWithRoots verifies signatures like Verify and validates the signer
+// certificate embedded in each of them against roots: the chain built with
+// the embedded intermediate certificates must lead to one of the roots, and
+// the signer certificate must allow digital signatures. Certificates are
+// checked at the time of the signature's RFC 3161 timestamp if the
+// certificate of the time-stamping authority is itself trusted by roots for
+// time stamping, and at the current time otherwise, so that a token from an
+// unknown authority cannot back-date an expired certificate. ChainTrusted
+// and TimestampTrusted report the outcome, and the reason of a failure is
+// added to Errors; Valid only reflects the integrity of the signature.
+func VerifyWithRoots(input io.ReadSeeker, roots *x509.CertPool) ([]SignatureInfo, error) {
+	if roots == nil {
+		return nil, fmt.Errorf("sign: root certificate pool is required")
+	}
+	return verify(input, nil, roots)
+}
+
+// verify checks every signature of a PDF against pub or, if pub is nil,
+// against the signer certificate embedded in the signature, which is
+// validated against roots if roots is not nil.
+func verify(input io.ReadSeeker, pub crypto.PublicKey, roots *x509.CertPool) ([]SignatureInfo, error) {
 	data, err := io.ReadAll(input)
 	if err != nil {
 		return nil, fmt.Errorf("sign: reading input: %w", err)
@@ -38,83 +78,166 @@ func Verify(input io.ReadSeeker) ([]SignatureInfo, error) {
 
 	var results []SignatureInfo
 	for _, sig := range sigs {
-		info := SignatureInfo{
-			Reason:   sig.reason,
-			Location: sig.location,
-			SignedAt:  sig.signedAt,
-		}
-
-		// Verify byte range integrity
-		if sig.byteRange[1] > 0 && sig.byteRange[3] > 0 {
-			digest, err := computeByteRangeDigest(data, sig.byteRange)
-			if err != nil {
-				info.Errors = append(info.Errors, fmt.Errorf("computing digest: %w", err))
+		info, p7 := checkSignature(data, sig, pub)
+		if roots != nil && info.Valid {
+			var at time.Time
+			if p7.timestampToken != nil {
+				if err := checkTimestampAuthority(p7.timestampToken, roots); err != nil {
+					info.Errors = append(info.Errors, fmt.Errorf("timestamp authority: %w", err))
+				} else {
+					info.TimestampTrusted = true
+					at = info.Timestamp
+				}
+			}
+			if err := checkCertificate(p7, at, roots); err != nil {
+				info.Errors = append(info.Errors, fmt.Errorf("certificate: %w", err))
 			} else {
-				info.digest = digest
-				info.rawSignature = sig.contents
+				info.ChainTrusted = true
 			}
 		}
-
 		results = append(results, info)
 	}
-
 	return results, nil
 }
 
-// VerifyWithCertificate verifies signatures using the provided certificate.
-// This performs full cryptographic verification of each signature found.
-func VerifyWithCertificate(input io.ReadSeeker, cert crypto.PublicKey) ([]SignatureInfo, error) {
-	data, err := io.ReadAll(input)
-	if err != nil {
-		return nil, fmt.Errorf("sign: reading input: %w", err)
+// checkSignature verifies the byte-range digest and the CMS signature of
+// one signature dictionary. If pub is nil, the public key of the embedded
+// signer certificate is used. The parsed CMS structure is returned along
+// with the result if it could be parsed.
+func checkSignature(data []byte, sig rawSigInfo, pub crypto.PublicKey) (SignatureInfo, *pkcs7Signature) {
+	info := SignatureInfo{
+		Reason:   sig.reason,
+		Location: sig.location,
+		SignedAt: sig.signedAt,
 	}
 
-	sigs := findSignatureDicts(data)
-	if len(sigs) == 0 {
-		return nil, nil
+	br := sig.byteRange
+	if br[1] == 0 || br[3] == 0 {
+		info.Errors = append(info.Errors, fmt.Errorf("invalid byte range"))
+		return info, nil
+	}
+	if br[0] != 0 {
+		info.Errors = append(info.Errors, fmt.Errorf("byte range does not start at the beginning of the file"))
+		return info, nil
+	}
+	if sig.contentsStart < 0 {
+		info.Errors = append(info.Errors, fmt.Errorf("signature dictionary has no /Contents string"))
+		return info, nil
+	}
+	// The only bytes left out must be the signature itself
+	if br[0]+br[1] != sig.contentsStart || br[2] != sig.contentsEnd {
+		info.Errors = append(info.Errors, fmt.Errorf("byte range gap is not the /Contents string of the signature"))
+		return info, nil
 	}
+	info.CoversWholeFile = br[2]+br[3] == len(data)
 
-	var results []SignatureInfo
-	for _, sig := range sigs {
-		info := SignatureInfo{
-			Reason:   sig.reason,
-			Location: sig.location,
-			SignedAt:  sig.signedAt,
+	p7, err := parsePKCS7(sig.contents)
+	if err != nil {
+		info.Errors = append(info.Errors, fmt.Errorf("parsing signature: %w", err))
+		return info, nil
+	}
+	info.Signer = p7.signer
+	if p7.signer != nil {
+		info.SignerName = p7.signer.Subject.CommonName
+		if info.SignerName == "" {
+			info.SignerName = p7.signer.Subject.String()
 		}
+		info.CertNotAfter = p7.signer.NotAfter
+	}
+	if info.SignedAt.IsZero() {
+		info.SignedAt = p7.signingTime
+	}
 
-		if sig.byteRange[1] == 0 || sig.byteRange[3] == 0 {
-			info.Errors = append(info.Errors, fmt.Errorf("invalid byte range"))
-			results = append(results, info)
-			continue
-		}
+	digest, err := computeByteRangeDigest(data, br, p7.hash)
+	if err != nil {
+		info.Errors = append(info.Errors, fmt.Errorf("computing digest: %w", err))
+		return info, p7
+	}
+	info.digest = digest
 
-		digest, err := computeByteRangeDigest(data, sig.byteRange)
+	if pub == nil {
+		if p7.signer == nil {
+			info.Errors = append(info.Errors, fmt.Errorf("signer certificate not embedded"))
+			return info, p7
+		}
+		pub = p7.signer.PublicKey
+	}
+	if err := p7.verify(pub, digest); err != nil {
+		info.Errors = append(info.Errors, err)
+		return info, p7
+	}
+	if p7.timestampToken != nil {
+		genTime, err := verifyTimestamp(p7.timestampToken, p7.signature)
 		if err != nil {
-			info.Errors = append(info.Errors, fmt.Errorf("computing digest: %w", err))
-			results = append(results, info)
-			continue
+			info.Errors = append(info.Errors, fmt.Errorf("timestamp: %w", err))
+			return info, p7
 		}
+		info.Timestamp = genTime
+	}
+	info.Valid = true
+	return info, p7
+}
 
-		// Verify the signature
-		valid := verifyRawSignature(cert, digest, sig.contents)
-		info.Valid = valid
-		if !valid {
-			info.Errors = append(info.Errors, fmt.Errorf("signature verification failed"))
-		}
+// checkCertificate validates the signer certificate of p7 against roots at
+// time at, or at the current time if at is zero.
+func checkCertificate(p7 *pkcs7Signature, at time.Time, roots *x509.CertPool) error {
+	_, err := p7.signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates(p7),
+		CurrentTime:   at,
+		// Document signing certificates carry various extended key usages
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return err
+	}
+	if ku := p7.signer.KeyUsage; ku != 0 && ku&(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) == 0 {
+		return fmt.Errorf("key usage does not allow digital signatures")
+	}
+	return nil
+}
 
-		results = append(results, info)
+// checkTimestampAuthority validates the certificate that signed an RFC 3161
+// timestamp token against roots at the time the token asserts. The
+// certificate must be allowed to sign timestamps.
+func checkTimestampAuthority(token []byte, roots *x509.CertPool) error {
+	info, p7, err := parseTimestamp(token)
+	if err != nil {
+		return err
+	}
+	if p7.signer == nil {
+		return fmt.Errorf("certificate not embedded")
 	}
+	_, err = p7.signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates(p7),
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	return err
+}
 
-	return results, nil
+// intermediates returns the certificates embedded in p7 other than the
+// signer's, to build its chain from.
+func intermediates(p7 *pkcs7Signature) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range p7.certificates {
+		if c != p7.signer {
+			pool.AddCert(c)
+		}
+	}
+	return pool
 }
 
 // rawSigInfo holds parsed signature dictionary data.
 type rawSigInfo struct {
-	byteRange [4]int
-	contents  []byte // decoded hex contents
-	reason    string
-	location  string
-	signedAt  time.Time
+	byteRange     [4]int
+	contents      []byte // decoded hex contents
+	contentsStart int    // offset of the /Contents string's "<", -1 if absent
+	contentsEnd   int    // offset following its ">"
+	reason        string
+	location      string
+	signedAt      time.Time
 }
 
 // findSignatureDicts searches the raw PDF bytes for /Type /Sig dictionaries.
@@ -139,7 +262,7 @@ func findSignatureDicts(data []byte) []rawSigInfo {
 		sig.byteRange = extractByteRange(dict)
 
 		// Extract /Contents <hex>
-		sig.contents = extractContents(data, dictStart, dictEnd)
+		sig.contents, sig.contentsStart, sig.contentsEnd = extractContents(data, dictStart, dictEnd)
 
 		// Extract /Reason (text)
 		sig.reason = extractPDFString(dict, "/Reason")
@@ -156,8 +279,9 @@ func findSignatureDicts(data []byte) []rawSigInfo {
 	return results
 }
 
-// computeByteRangeDigest computes SHA-256 digest over the specified byte ranges.
-func computeByteRangeDigest(data []byte, br [4]int) ([]byte, error) {
+// computeByteRangeDigest computes the digest of the specified byte ranges
+// with hash, the digest algorithm of the signature.
+func computeByteRangeDigest(data []byte, br [4]int, hash crypto.Hash) ([]byte, error) {
 	if br[0]+br[1] > len(data) || br[2]+br[3] > len(data) {
 		return nil, fmt.Errorf("byte range exceeds data length")
 	}
@@ -165,19 +289,20 @@ func computeByteRangeDigest(data []byte, br [4]int) ([]byte, error) {
 		return nil, fmt.Errorf("negative byte range value")
 	}
 
-	h := crypto.SHA256.New()
+	h := hash.New()
 	h.Write(data[br[0] : br[0]+br[1]])
 	h.Write(data[br[2] : br[2]+br[3]])
 	return h.Sum(nil), nil
 }
 
-// verifyRawSignature verifies a raw signature against a digest using the given public key.
-func verifyRawSignature(pub crypto.PublicKey, digest, signature []byte) bool {
+// verifyRawSignature verifies a raw signature against a digest computed
+// with hash using the given public key.
+func verifyRawSignature(pub crypto.PublicKey, hash crypto.Hash, digest, signature []byte) bool {
 	switch key := pub.(type) {
 	case *ecdsa.PublicKey:
 		return ecdsa.VerifyASN1(key, digest, signature)
 	case *rsa.PublicKey:
-		err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature)
+		err := rsa.VerifyPKCS1v15(key, hash, digest, signature)
 		return err == nil
 	default:
 		return false
@@ -201,7 +326,9 @@ func findSigDictStart(data []byte, pos int) int {
 	return -1
 }
 
-// findSigDictEnd finds the end of the outermost dictionary.
+// findSigDictEnd finds the end of the outermost dictionary. Hex and
+// literal strings are skipped, so that a hex string closing just before
+// the dictionary, as in "<ab12>>>", is not taken for its end.
 func findSigDictEnd(data []byte, pos int) int {
 	start := findSigDictStart(data, pos)
 	if start < 0 {
@@ -214,6 +341,18 @@ func findSigDictEnd(data []byte, pos int) int {
 			i++
 			continue
 		}
+		if data[i] == '<' {
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return -1
+			}
+			i += end
+			continue
+		}
+		if data[i] == '(' {
+			i = skipLiteralString(data, i)
+			continue
+		}
 		if data[i] == '>' && data[i+1] == '>' {
 			depth--
 			if depth == 0 {
@@ -252,27 +391,49 @@ func extractByteRange(dict []byte) [4]int {
 	return br
 }
 
-// extractContents extracts and hex-decodes the /Contents value from the signature.
-func extractContents(data []byte, dictStart, dictEnd int) []byte {
-	// Look for /Contents <hex...> in the broader context
-	// The hex string may be very large, so we search from dictStart
-	searchArea := data[dictStart:]
-	m := verifyContentsRe.FindSubmatch(searchArea)
+// extractContents extracts and hex-decodes the /Contents value of the
+// signature dictionary data[dictStart:dictEnd+1]. It also returns the
+// offsets in data of the hex string, from its "<" to just after its ">",
+// or -1 and -1 if the dictionary has none.
+func extractContents(data []byte, dictStart, dictEnd int) (contents []byte, start, end int) {
+	m := verifyContentsRe.FindSubmatchIndex(data[dictStart : dictEnd+1])
 	if m == nil {
-		return nil
+		return nil, -1, -1
 	}
+	// The submatch holds the digits, delimited by the angle brackets
+	start, end = dictStart+m[2]-1, dictStart+m[3]+1
 
-	// Remove trailing zero padding
-	hexStr := strings.TrimRight(string(m[1]), "0")
+	// The zero padding after the DER structure is ignored when parsing it
+	hexStr := string(data[dictStart+m[2] : dictStart+m[3]])
 	if len(hexStr)%2 != 0 {
 		hexStr += "0"
 	}
 
 	decoded, err := hex.DecodeString(hexStr)
 	if err != nil {
-		return nil
+		return nil, start, end
+	}
+	return decoded, start, end
+}
+
+// skipLiteralString returns the offset of the ")" closing the literal
+// string that starts at data[i], or the last offset if it is not closed.
+func skipLiteralString(data []byte, i int) int {
+	depth := 0
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
 	}
-	return decoded
+	return len(data) - 1
 }
 
 // extractPDFString extracts a PDF string value for a given key.
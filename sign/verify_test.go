@@ -0,0 +1,125 @@
+package sign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// signedTestPDF returns a PDF signed by a new self-signed certificate,
+// along with that certificate, its key and the byte range of the signature.
+func signedTestPDF(t *testing.T) ([]byte, *x509.Certificate, *ecdsa.PrivateKey, [4]int) {
+	t.Helper()
+	cert, key := newTestSigner(t)
+	var signed bytes.Buffer
+	if err := Sign(bytes.NewReader(newTestDocument(t)), &signed, Options{Certificate: cert, PrivateKey: key}); err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	sigs := findSignatureDicts(signed.Bytes())
+	if len(sigs) != 1 {
+		t.Fatalf("expected 1 signature dictionary, got %d", len(sigs))
+	}
+	return signed.Bytes(), cert, key, sigs[0].byteRange
+}
+
+// resign replaces the byte range of the signature in data with br and the
+// signature with one computed over br with hash, as another signing tool
+// could produce it.
+func resign(t *testing.T, data []byte, br [4]int, hash crypto.Hash, cert *x509.Certificate, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	oids := map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: oidSHA256,
+		crypto.SHA384: oidSHA384,
+		crypto.SHA512: oidSHA512,
+	}
+	signed := bytes.Clone(data)
+	at := bytes.LastIndex(signed, []byte("/ByteRange ["))
+	copy(signed[at:], fmt.Sprintf("/ByteRange [%d %010d %010d %010d]", br[0], br[1], br[2], br[3]))
+
+	h := hash.New()
+	h.Write(signed[br[0] : br[0]+br[1]])
+	h.Write(signed[br[2] : br[2]+br[3]])
+	attrs, err := signedAttributes(oidData, h.Sum(nil), time.Now())
+	if err != nil {
+		t.Fatalf("signed attributes: %v", err)
+	}
+	attrsHash := hash.New()
+	attrsHash.Write(attrs)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, attrsHash.Sum(nil))
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	sigAlg, _ := signatureAlgorithm(key.Public())
+	der, err := encodeSignedData(encapContentInfo{EContentType: oidData}, signerInfo{
+		Version:            1,
+		SID:                issuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, Serial: cert.SerialNumber},
+		DigestAlgorithm:    algorithmIdentifier{Algorithm: oids[hash], Parameters: asn1.NullRawValue},
+		SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: setContents(attrs)},
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	}, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("encoding signature: %v", err)
+	}
+
+	start := bytes.LastIndex(signed, []byte("/Contents <")) + len("/Contents <")
+	end := start + bytes.IndexByte(signed[start:], '>')
+	copy(signed[start:end], bytes.Repeat([]byte("0"), end-start))
+	copy(signed[start:], fmt.Sprintf("%x", der))
+	return signed
+}
+
+func TestVerifyDigestAlgorithms(t *testing.T) {
+	data, cert, key, br := signedTestPDF(t)
+	for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		sigs, err := Verify(bytes.NewReader(resign(t, data, br, hash, cert, key)))
+		if err != nil {
+			t.Fatalf("%v: verify: %v", hash, err)
+		}
+		if len(sigs) != 1 || !sigs[0].Valid {
+			t.Errorf("%v: expected a valid signature, got %+v", hash, sigs)
+		}
+	}
+}
+
+func TestVerifyByteRangeGap(t *testing.T) {
+	data, cert, key, br := signedTestPDF(t)
+	tests := []struct {
+		name string
+		br   [4]int
+	}{
+		// The gap also leaves out the bytes before the /Contents string
+		{"wider gap", [4]int{0, br[1] - 20, br[2], br[3]}},
+		{"gap after contents", [4]int{0, br[1], br[2] + 5, br[3] - 5}},
+		{"not from the start", [4]int{1, br[1] - 1, br[2], br[3]}},
+	}
+	for _, tt := range tests {
+		sigs, err := Verify(bytes.NewReader(resign(t, data, tt.br, crypto.SHA256, cert, key)))
+		if err != nil {
+			t.Fatalf("%s: verify: %v", tt.name, err)
+		}
+		if len(sigs) != 1 {
+			t.Fatalf("%s: expected 1 signature, got %d", tt.name, len(sigs))
+		}
+		if sigs[0].Valid {
+			t.Errorf("%s: expected the byte range to be rejected", tt.name)
+		}
+	}
+}
+
+func TestExtractContentsStaysInDictionary(t *testing.T) {
+	data := []byte("1 0 obj\n<</Type /Sig /ByteRange [0 1 2 3]>>\nendobj\n2 0 obj\n<</Contents <abcd>>>\nendobj\n")
+	sigs := findSignatureDicts(data)
+	if len(sigs) != 1 {
+		t.Fatalf("expected 1 signature dictionary, got %d", len(sigs))
+	}
+	if sigs[0].contents != nil || sigs[0].contentsStart != -1 {
+		t.Errorf("found /Contents %x at %d outside the signature dictionary", sigs[0].contents, sigs[0].contentsStart)
+	}
+}
@@ -2,6 +2,7 @@ package table
 
 import (
 	"fmt"
+	"strings"
 )
 
 // CellContent represents the content of a table cell.
@@ -16,19 +17,68 @@ type TextContent struct {
 
 func (TextContent) cellContent() {}
 
-// ImageContent is an image cell content.
+// ImageContent is an image cell content. The image is scaled to fit the
+// cell's content area with its aspect ratio preserved.
 type ImageContent struct {
 	Path string
 	Type string // "jpg", "png", etc. Empty for auto-detect.
+	// Data holds the encoded image bytes for images that are not read from
+	// a file. If set, Path is only used as the name the image is registered
+	// under and may be empty.
+	Data []byte
 }
 
 func (ImageContent) cellContent() {}
 
+// TextRun is a piece of rich text drawn in one style. A newline in Text
+// starts a new line, and an empty line between two newlines starts a new
+// paragraph.
+type TextRun struct {
+	Text string
+	// Font overrides the cell font for this run. Empty fields keep the
+	// cell's family, style or size, so FontSpec{Style: "B"} makes the run
+	// bold.
+	Font  *FontSpec
+	Color *RGBColor // overrides the cell's text color
+}
+
+// RichTextContent is cell content made of differently styled runs of text,
+// such as a bold title line followed by a smaller gray description. The
+// runs flow one after another and wrap at word boundaries.
+type RichTextContent struct {
+	Runs []TextRun
+}
+
+func (RichTextContent) cellContent() {}
+
+// text returns the text of all runs without styling.
+func (c RichTextContent) text() string {
+	var b strings.Builder
+	for _, run := range c.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
 // Cell represents a single cell in a table row.
 type Cell struct {
 	content CellContent
 	colspan int
+	rowspan int
 	style   *CellStyle
+	link    int    // internal link identifier from Fpdf.AddLink
+	linkStr string // external link target
+}
+
+// text returns the unstyled text of the cell, or "" for an image cell.
+func (c *Cell) text() string {
+	switch content := c.content.(type) {
+	case TextContent:
+		return content.Text
+	case RichTextContent:
+		return content.text()
+	}
+	return ""
 }
 
 // SetColspan sets the number of columns this cell spans.
@@ -39,6 +89,29 @@ func (c *Cell) SetColspan(n int) *Cell {
 	return c
 }
 
+// SetRowspan sets the number of rows this cell spans. Cells in the
+// following rows skip the column positions it covers.
+func (c *Cell) SetRowspan(n int) *Cell {
+	if n > 0 {
+		c.rowspan = n
+	}
+	return c
+}
+
+// SetLink makes the cell's text a link to the given URL, such as
+// "https://example.com" or "mailto:alice@example.com".
+func (c *Cell) SetLink(url string) *Cell {
+	c.linkStr = url
+	return c
+}
+
+// SetInternalLink makes the cell's text a link to a location in the
+// document. link is an identifier returned by Fpdf.AddLink.
+func (c *Cell) SetInternalLink(link int) *Cell {
+	c.link = link
+	return c
+}
+
 // SetStyle sets the style for this cell, overriding table/row defaults.
 func (c *Cell) SetStyle(s CellStyle) *Cell {
 	c.style = &s
@@ -68,6 +141,7 @@ type Row struct {
 	cells    []*Cell
 	style    *CellStyle
 	isHeader bool
+	isFooter bool
 	minH     float64 // minimum row height
 }
 
@@ -76,6 +150,7 @@ func (r *Row) AddCell(text string) *Cell {
 	c := &Cell{
 		content: TextContent{Text: text},
 		colspan: 1,
+		rowspan: 1,
 	}
 	r.cells = append(r.cells, c)
 	return c
@@ -86,11 +161,35 @@ func (r *Row) AddCellf(format string, args ...any) *Cell {
 	return r.AddCell(fmt.Sprintf(format, args...))
 }
 
+// AddRichCell adds a cell containing the styled runs of text to the row.
+func (r *Row) AddRichCell(runs ...TextRun) *Cell {
+	c := &Cell{
+		content: RichTextContent{Runs: runs},
+		colspan: 1,
+		rowspan: 1,
+	}
+	r.cells = append(r.cells, c)
+	return c
+}
+
 // AddImageCell adds an image cell to the row.
 func (r *Row) AddImageCell(imagePath string) *Cell {
 	c := &Cell{
 		content: ImageContent{Path: imagePath},
 		colspan: 1,
+		rowspan: 1,
+	}
+	r.cells = append(r.cells, c)
+	return c
+}
+
+// AddImageDataCell adds an image cell to the row from in-memory image data.
+// imageType is "jpg", "png" or "gif"; if empty, it is detected from data.
+func (r *Row) AddImageDataCell(data []byte, imageType string) *Cell {
+	c := &Cell{
+		content: ImageContent{Type: imageType, Data: data},
+		colspan: 1,
+		rowspan: 1,
 	}
 	r.cells = append(r.cells, c)
 	return c
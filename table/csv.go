@@ -0,0 +1,36 @@
+package table
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// ToCSV writes the text of the table to w as CSV: header rows first, then
+// body rows, then footer rows, one record per row and one field per
+// column. Rich text cells are written without styling; image cells, and
+// the columns covered by a cell's colspan or rowspan, are written as empty
+// fields.
+func (t *Table) ToCSV(w io.Writer) error {
+	numCols := len(t.columns)
+	if numCols == 0 && len(t.rows) > 0 {
+		numCols = len(t.rows[0].cells)
+	}
+
+	headerRows, bodyRows, footerRows := t.splitRows()
+	rows := append(append(headerRows, bodyRows...), footerRows...)
+
+	cw := csv.NewWriter(w)
+	for i, cols := range placeCells(rows, numCols) {
+		record := make([]string, numCols)
+		for j, cell := range rows[i].cells {
+			if cols[j] >= 0 {
+				record[cols[j]] = cell.text()
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
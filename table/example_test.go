@@ -7,7 +7,7 @@ import (
 )
 
 // ExampleTable demonstrates creating a styled data table with headers,
-// alternating row colors, and custom column widths.
+// alternating row colors, custom column widths, and clickable email links.
 func ExampleTable() {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetFont("Helvetica", "", 10)
@@ -57,7 +57,7 @@ func ExampleTable() {
 		row := tbl.AddRow()
 		row.AddCell(d[0]).SetAlign("C")
 		row.AddCell(d[1])
-		row.AddCell(d[2])
+		row.AddCell(d[2]).SetLink("mailto:" + d[2])
 		row.AddCell(d[3]).SetAlign("C")
 		cell := row.AddCell(d[4]).SetAlign("C")
 		if d[4] == "Active" {
@@ -0,0 +1,82 @@
+package table
+
+import (
+	"testing"
+
+	gofpdf "github.com/lvillar/gofpdf"
+)
+
+// largeTable returns a table listing the given number of orders. Its
+// descriptions and statuses repeat, as they do in real data, so that
+// measurements can be reused.
+func largeTable(orders int) *Table {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddPage()
+
+	statuses := []string{"Shipped", "Pending", "Back-ordered until further notice from the supplier"}
+	t := New(pdf)
+	t.SetColumnWidths(20, 90, 40, 40)
+	t.AddHeaderRow().AddCell("Order")
+	for i := range orders {
+		r := t.AddRow()
+		r.AddCellf("%d", 1000+i)
+		r.AddCellf("Replacement part %d for the assembly line conveyor belt", i%50)
+		r.AddCell(statuses[i%len(statuses)])
+		r.AddCellf("%d.%02d", i%200, i%100)
+	}
+	return t
+}
+
+// renderCached renders tb and returns the measure cache of the call. Its
+// hits and misses count the measurements made to lay out the cells; those
+// gofpdf makes itself while drawing text are not counted.
+func renderCached(t testing.TB, tb *Table) *measureCache {
+	t.Helper()
+	var cache *measureCache
+	orig := newMeasureCache
+	defer func() { newMeasureCache = orig }()
+	newMeasureCache = func() *measureCache {
+		cache = orig()
+		return cache
+	}
+	if err := tb.Render(); err != nil {
+		t.Fatal(err)
+	}
+	if cache == nil {
+		t.Fatal("Render did not cache measurements")
+	}
+	return cache
+}
+
+func TestRenderMeasurements(t *testing.T) {
+	tb := largeTable(1000)
+	texts := make(map[string]bool)
+	for _, r := range tb.rows {
+		for _, c := range r.cells {
+			texts[c.text()] = true
+		}
+	}
+	cache := renderCached(t, tb)
+
+	// All cells share one font and one column width each, so every
+	// distinct text needs at most a width and a line count. Rows measured
+	// again while drawing, or on each page, must hit the cache.
+	if max := 2 * len(texts); cache.misses > max {
+		t.Errorf("rendering %d distinct texts measured %d times, want at most %d", len(texts), cache.misses, max)
+	}
+	if cache.hits == 0 {
+		t.Error("no measurement was answered by the cache")
+	}
+}
+
+func BenchmarkRenderLargeTable(b *testing.B) {
+	var hits, misses int
+	for range b.N {
+		cache := renderCached(b, largeTable(5000))
+		hits += cache.hits
+		misses += cache.misses
+	}
+	b.ReportMetric(float64(misses)/float64(b.N), "measurements/op")
+	b.ReportMetric(float64(hits)/float64(b.N), "cache-hits/op")
+}
@@ -0,0 +1,108 @@
+package table
+
+import (
+	"strings"
+)
+
+// richFragment is the part of a run of rich text placed on one line.
+type richFragment struct {
+	run  int // index of the run in RichTextContent.Runs
+	text string
+}
+
+// richLine is a line of rich text laid out by layoutRich.
+type richLine struct {
+	frags  []richFragment
+	width  float64 // width of the text, not counting trailing spaces
+	height float64 // line height of the largest font on the line
+}
+
+// layoutRich breaks the runs of c into lines no wider than w, measuring each
+// run in the current font changed by the run's font. A word wider than w is
+// kept on a line of its own.
+func (t *Table) layoutRich(c RichTextContent, w float64) []richLine {
+	var lines []richLine
+	var line richLine
+	trail := 0.0 // width of the spaces at the end of line
+	newLine := func() {
+		line.width -= trail
+		lines = append(lines, line)
+		line, trail = richLine{}, 0
+	}
+
+	for i, run := range c.Runs {
+		restore := t.useFont(CellStyle{Font: run.Font})
+		lineH := t.lineHeight()
+		for k, para := range strings.Split(run.Text, "\n") {
+			if k > 0 {
+				line.height = max(line.height, lineH)
+				newLine()
+			}
+			for _, chunk := range strings.SplitAfter(para, " ") {
+				if chunk == "" {
+					continue
+				}
+				word := strings.TrimRight(chunk, " ")
+				wordW := t.stringWidth(word)
+				if len(line.frags) > 0 && line.width+wordW > w {
+					newLine()
+				}
+				if n := len(line.frags); n > 0 && line.frags[n-1].run == i {
+					line.frags[n-1].text += chunk
+				} else {
+					line.frags = append(line.frags, richFragment{run: i, text: chunk})
+				}
+				chunkW := t.stringWidth(chunk)
+				line.width += chunkW
+				trail = chunkW - wordW
+				line.height = max(line.height, lineH)
+			}
+		}
+		restore()
+	}
+	if len(line.frags) > 0 {
+		newLine()
+	}
+	return lines
+}
+
+// richHeight returns the total height of lines.
+func richHeight(lines []richLine) float64 {
+	h := 0.0
+	for _, line := range lines {
+		h += line.height
+	}
+	return h
+}
+
+// renderRich draws the lines of c laid out by layoutRich in a box of width
+// w at (x, y), aligning each line within the box. Runs without a color of
+// their own are drawn in color, or black if it is nil.
+func (t *Table) renderRich(c RichTextContent, lines []richLine, x, y, w float64, align string, color *RGBColor) {
+	textW := w - 2*t.pdf.GetCellMargin()
+	for _, line := range lines {
+		offset := 0.0
+		switch align {
+		case "C":
+			offset = max((textW-line.width)/2, 0)
+		case "R":
+			offset = max(textW-line.width, 0)
+		}
+		t.pdf.SetXY(x+offset, y)
+		for _, frag := range line.frags {
+			run := c.Runs[frag.run]
+			restore := t.useFont(CellStyle{Font: run.Font})
+			switch {
+			case run.Color != nil:
+				t.pdf.SetTextColor(run.Color.R, run.Color.G, run.Color.B)
+			case color != nil:
+				t.pdf.SetTextColor(color.R, color.G, color.B)
+			default:
+				t.pdf.SetTextColor(0, 0, 0)
+			}
+			t.pdf.Write(line.height, frag.text)
+			restore()
+		}
+		y += line.height
+	}
+}
@@ -1,8 +1,8 @@
 // Package table provides a high-level API for creating tables in PDF documents.
 //
 // It integrates with the Fpdf type from the parent package and provides
-// features like auto-width columns, repeating headers, alternating row colors,
-// colspan/rowspan, and automatic page breaks.
+// features like auto-width columns, repeating headers and footers, alternating
+// row colors, colspan/rowspan, and automatic page breaks.
 package table
 
 // RGBColor represents an RGB color value.
@@ -31,22 +31,51 @@ func UniformPadding(v float64) Padding {
 type BorderStyle struct {
 	Width float64
 	Color RGBColor
+	Sides *Borders // sides drawn for every cell; nil draws the full box
+}
+
+// BorderSide defines the appearance of one side of a cell border.
+type BorderSide struct {
+	Width float64   // 0 uses the table border width
+	Color *RGBColor // nil uses the table border color
+}
+
+// Borders selects the sides of a cell border to draw. Sides left nil are
+// not drawn, so Borders{Top: &BorderSide{}, Bottom: &BorderSide{}} draws
+// only horizontal rules.
+type Borders struct {
+	Top, Right, Bottom, Left *BorderSide
 }
 
 // CellStyle defines the visual appearance of a cell.
 type CellStyle struct {
-	FillColor   *RGBColor
-	TextColor   *RGBColor
-	BorderColor *RGBColor
-	Font        *FontSpec
-	Align       string // "L", "C", "R" (horizontal), "T", "M", "B" (vertical)
-	Padding     *Padding
+	FillColor     *RGBColor
+	TextColor     *RGBColor
+	BorderColor   *RGBColor
+	Font          *FontSpec
+	Align         string   // "L", "C", "R"
+	VerticalAlign string   // "T" (top, default), "M" (middle), "B" (bottom)
+	Padding       *Padding // overrides the table's CellPadding
+	Borders       *Borders // overrides the table's border sides
+	// Overflow controls single-line text wider than the cell: "wrap"
+	// (default) wraps it onto several lines, "ellipsis" cuts it and appends
+	// the table's ellipsis, and "clip" cuts it. Text containing newlines
+	// always wraps.
+	Overflow string
+	// Rotate draws the text as a single line rotated counter-clockwise by
+	// this many degrees around the cell center, growing the row to fit it.
+	// It is meant for the HeaderStyle of tables with narrow columns; 90
+	// gives vertical headers.
+	Rotate float64
 }
 
 // AlternateStyle defines alternating row colors.
 type AlternateStyle struct {
 	Even CellStyle
 	Odd  CellStyle
+	// GroupSize is the number of consecutive body rows sharing a style, so
+	// that related rows are shaded as one band. Values below 1 mean 1.
+	GroupSize int
 }
 
 // TableStyle defines the overall appearance of a table.
@@ -1,6 +1,10 @@
 package table
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"math"
 	"strings"
 
 	gofpdf "github.com/lvillar/gofpdf"
@@ -12,6 +16,10 @@ type ColumnDef struct {
 	MinWidth float64 // Minimum width for auto columns.
 	MaxWidth float64 // Maximum width for auto columns. 0 means unlimited.
 	Align    string  // Default alignment for this column ("L", "C", "R").
+	// AutoSize makes an auto column share the space left for auto columns
+	// in proportion to the width of its widest text, instead of evenly.
+	// Table.SetAutoFit applies it to every auto column.
+	AutoSize bool
 }
 
 // Table is a high-level table builder for generating PDF tables.
@@ -20,9 +28,18 @@ type Table struct {
 	columns    []ColumnDef
 	rows       []*Row
 	headerRows int
+	footerRows int    // number of trailing data rows rendered as footer rows
+	footerLast bool   // render footer rows only after the last body row
+	autoFit    bool   // size all auto columns by their content
+	ellipsis   string // appended to text cut by the "ellipsis" overflow mode ("" picks one by font)
 	style      TableStyle
+	cellFunc   func(rowIdx, colIdx int, text string) *CellStyle
+	minRowH    float64 // minimum height of every row (0 means 5 user units)
+	maxRows    int     // maximum body rows per page (0 means no limit)
 	x, y       float64 // starting position (0,0 means current)
 	tableWidth float64 // total table width (0 means page width minus margins)
+
+	cache *measureCache // text measurements of the current Render or Measure call
 }
 
 // New creates a new Table associated with the given PDF document.
@@ -35,6 +52,42 @@ func New(pdf *gofpdf.Fpdf) *Table {
 	}
 }
 
+// Quick renders a table with a single header row followed by rows at the
+// current position, using auto-width columns. It is a shortcut for building
+// the table with New, AddHeaderRow and AddRow. If style has no cell padding,
+// the default padding of New is kept.
+func Quick(pdf *gofpdf.Fpdf, headers []string, rows [][]string, style TableStyle) error {
+	t := New(pdf)
+
+	numCols := len(headers)
+	for _, row := range rows {
+		if len(row) > numCols {
+			numCols = len(row)
+		}
+	}
+	t.SetColumns(make([]ColumnDef, numCols)...)
+
+	if style.CellPadding == (Padding{}) {
+		style.CellPadding = t.style.CellPadding
+	}
+	t.SetStyle(style)
+
+	if len(headers) > 0 {
+		hr := t.AddHeaderRow()
+		for _, h := range headers {
+			hr.AddCell(h)
+		}
+	}
+	for _, row := range rows {
+		r := t.AddRow()
+		for _, cell := range row {
+			r.AddCell(cell)
+		}
+	}
+
+	return t.Render()
+}
+
 // SetColumns sets column definitions for the table.
 func (t *Table) SetColumns(cols ...ColumnDef) *Table {
 	t.columns = cols
@@ -51,6 +104,26 @@ func (t *Table) SetColumnWidths(widths ...float64) *Table {
 	return t
 }
 
+// SetAutoFit sizes every auto column (one without a fixed width) in
+// proportion to its widest text, as ColumnDef.AutoSize does for a single
+// column, so that a long description column gets more room than short
+// numeric ones. By default auto columns split the space left evenly.
+func (t *Table) SetAutoFit(on bool) *Table {
+	t.autoFit = on
+	return t
+}
+
+// SetCellFunc sets a function that styles body cells by position and text,
+// for example to show negative amounts in red. It is called with the index
+// of the body row, the first column of the cell and its text (empty for
+// image cells); a nil result leaves the cell unchanged. The style it
+// returns overrides the table style and alternating rows, and is itself
+// overridden by row and cell styles.
+func (t *Table) SetCellFunc(fn func(rowIdx, colIdx int, text string) *CellStyle) *Table {
+	t.cellFunc = fn
+	return t
+}
+
 // SetHeaderRows marks the first n rows as header rows.
 // Header rows are repeated at the top of each new page.
 func (t *Table) SetHeaderRows(n int) *Table {
@@ -58,6 +131,16 @@ func (t *Table) SetHeaderRows(n int) *Table {
 	return t
 }
 
+// SetEllipsis sets the text appended to cells cut by the "ellipsis"
+// overflow mode. It must be encoded for the cell fonts: with the core fonts
+// text is cp1252, where "\x85" is the ellipsis character. The default,
+// restored by an empty s, is "…" in UTF-8 fonts and "..." in the core
+// fonts.
+func (t *Table) SetEllipsis(s string) *Table {
+	t.ellipsis = s
+	return t
+}
+
 // SetStyle sets the table-wide style.
 func (t *Table) SetStyle(s TableStyle) *Table {
 	t.style = s
@@ -85,6 +168,46 @@ func (t *Table) AddRow() *Row {
 	return r
 }
 
+// AddFooterRow adds a new footer row and returns it for chaining. Footer
+// rows are rendered after the body rows, and by default also at the bottom
+// of each page before a page break, such as a total or subtotal row.
+func (t *Table) AddFooterRow() *Row {
+	r := &Row{isFooter: true}
+	t.rows = append(t.rows, r)
+	return r
+}
+
+// SetFooterRows marks the last n data rows as footer rows, in addition to
+// rows added with AddFooterRow.
+func (t *Table) SetFooterRows(n int) *Table {
+	t.footerRows = n
+	return t
+}
+
+// SetMinRowHeight sets the minimum height of every row, in user units.
+// Row.SetMinHeight raises it for a single row. Zero restores the default
+// of 5.
+func (t *Table) SetMinRowHeight(h float64) *Table {
+	t.minRowH = h
+	return t
+}
+
+// SetMaxRowsPerPage limits the number of body rows rendered on each page;
+// further rows continue on a new page after the repeated headers. Rows
+// joined by a rowspan are kept together even if they exceed the limit.
+// Zero removes the limit.
+func (t *Table) SetMaxRowsPerPage(n int) *Table {
+	t.maxRows = n
+	return t
+}
+
+// SetFooterLastPageOnly controls whether footer rows are rendered only once
+// after the last body row instead of on every page.
+func (t *Table) SetFooterLastPageOnly(lastOnly bool) *Table {
+	t.footerLast = lastOnly
+	return t
+}
+
 // AddHeaderRow adds a new header row and returns it for chaining.
 func (t *Table) AddHeaderRow() *Row {
 	r := &Row{isHeader: true}
@@ -110,6 +233,8 @@ func (t *Table) Render() error {
 	if t.pdf.Err() {
 		return t.pdf.Error()
 	}
+	t.cache = newMeasureCache()
+	defer func() { t.cache = nil }()
 
 	widths := t.calculateWidths()
 
@@ -122,40 +247,274 @@ func (t *Table) Render() error {
 		t.pdf.SetY(t.y)
 	}
 
-	// Separate header and body rows
-	var headerRows, bodyRows []*Row
+	headerRows, bodyRows, footerRows := t.splitRows()
+	header := t.layoutRows(headerRows, widths, false)
+	body := t.layoutRows(bodyRows, widths, true)
+	footer := t.layoutRows(footerRows, widths, false)
+	footerH := t.pageFooterHeight(footer)
+
+	_, pageH := t.pdf.GetPageSize()
+	_, tMargin, _, bMargin := t.pdf.GetMargins()
+
+	// A row taller than a page would not fit after any page break
+	room := pageH - tMargin - bMargin - sumHeights(header) - footerH
+	for i, h := range body.blockH {
+		if h > room {
+			t.pdf.SetErrorf("table: body row %d is %.2f high, more than the %.2f available on a page", i+1, h, room)
+			return t.pdf.Error()
+		}
+	}
+
+	// Render header rows first
+	t.renderRows(header, widths, startX, true)
+
+	// Render body rows
+	onPage := 0
+	for i := range body.rows {
+		// Check if we need a page break, leaving room for repeated footer
+		// rows. Rows joined by a rowspan are kept on the same page.
+		if t.breakBefore(body, i, onPage, t.pdf.GetY()+footerH, pageH-bMargin) {
+			if footerH > 0 {
+				t.renderRows(footer, widths, startX, false)
+			}
+			t.pdf.AddPage()
+			// Re-render headers on new page
+			t.renderRows(header, widths, startX, true)
+			onPage = 0
+		}
+
+		t.renderRow(body, i, widths, startX, i, false)
+		onPage++
+	}
+
+	// Render footer rows after the last body row
+	if len(footer.rows) > 0 {
+		if t.pdf.GetY()+sumHeights(footer) > pageH-bMargin {
+			t.pdf.AddPage()
+			t.renderRows(header, widths, startX, true)
+		}
+		t.renderRows(footer, widths, startX, false)
+	}
+
+	return t.pdf.Error()
+}
+
+// breakBefore reports whether a page break is needed before body row i,
+// with onPage body rows already on the page and the bottom of the room
+// left for the row, after the footer, at y.
+func (t *Table) breakBefore(body rowLayout, i, onPage int, y, limit float64) bool {
+	if body.blockH[i] == 0 {
+		return false // inside a rowspan block
+	}
+	if t.maxRows > 0 && onPage > 0 {
+		n := 1
+		for i+n < len(body.rows) && body.blockH[i+n] == 0 {
+			n++
+		}
+		if onPage+n > t.maxRows {
+			return true
+		}
+	}
+	return y+body.blockH[i] > limit
+}
+
+// pageFooterHeight returns the room to keep free at the bottom of each page
+// for footer rows: their height if they repeat on every page, 0 otherwise.
+func (t *Table) pageFooterHeight(footer rowLayout) float64 {
+	if t.footerLast {
+		return 0
+	}
+	return sumHeights(footer)
+}
+
+// sumHeights returns the total height of the rows of a layout.
+func sumHeights(l rowLayout) float64 {
+	h := 0.0
+	for _, rh := range l.heights {
+		h += rh
+	}
+	return h
+}
+
+// Measure returns the total height the table occupies when rendered,
+// including header rows repeated after page breaks, without drawing
+// anything or moving the cursor. Page breaks are estimated from the
+// starting position in the same way as Render.
+func (t *Table) Measure() float64 {
+	t.cache = newMeasureCache()
+	defer func() { t.cache = nil }()
+
+	widths := t.calculateWidths()
+	headerRows, bodyRows, footerRows := t.splitRows()
+	header := t.layoutRows(headerRows, widths, false)
+	body := t.layoutRows(bodyRows, widths, true)
+	footer := t.layoutRows(footerRows, widths, false)
+
+	headerH := sumHeights(header)
+	footerH := sumHeights(footer)
+	pageFooterH := t.pageFooterHeight(footer)
+
+	y := t.pdf.GetY()
+	if t.y != 0 {
+		y = t.y
+	}
+	_, pageH := t.pdf.GetPageSize()
+	_, tMargin, _, bMargin := t.pdf.GetMargins()
+
+	total := headerH
+	y += headerH
+	onPage := 0
+	for i, h := range body.heights {
+		if t.breakBefore(body, i, onPage, y+pageFooterH, pageH-bMargin) {
+			// Render closes the page with the footer rows, starts a new
+			// page and repeats the headers
+			y = tMargin + headerH
+			total += pageFooterH + headerH
+			onPage = 0
+		}
+		y += h
+		total += h
+		onPage++
+	}
+	if footerH > 0 {
+		if y+footerH > pageH-bMargin {
+			total += headerH
+		}
+		total += footerH
+	}
+	return total
+}
+
+// splitRows separates header, body and footer rows, keeping their order.
+// The last footerRows body rows become footer rows.
+func (t *Table) splitRows() (headerRows, bodyRows, footerRows []*Row) {
+	var marked []*Row
 	for _, r := range t.rows {
-		if r.isHeader {
+		switch {
+		case r.isHeader:
 			headerRows = append(headerRows, r)
-		} else {
+		case r.isFooter:
+			marked = append(marked, r)
+		default:
 			bodyRows = append(bodyRows, r)
 		}
 	}
+	n := min(max(t.footerRows, 0), len(bodyRows))
+	footerRows = append(bodyRows[len(bodyRows)-n:len(bodyRows):len(bodyRows)], marked...)
+	return headerRows, bodyRows[:len(bodyRows)-n], footerRows
+}
 
-	// Render header rows first
-	for _, r := range headerRows {
-		t.renderRow(r, widths, startX, -1, true)
+// rowLayout holds the placement of a group of rows (header or body).
+type rowLayout struct {
+	rows    []*Row
+	cols    [][]int   // starting column of each cell; -1 if it does not fit
+	heights []float64 // height of each row
+	blockH  []float64 // height of the rowspan block starting at each row; 0 inside a block
+}
+
+// layoutRows assigns columns to the cells of rows and computes row heights.
+// body tells whether rows are the body rows of the table.
+func (t *Table) layoutRows(rows []*Row, widths []float64, body bool) rowLayout {
+	l := rowLayout{
+		rows:    rows,
+		cols:    placeCells(rows, len(widths)),
+		heights: make([]float64, len(rows)),
+		blockH:  make([]float64, len(rows)),
+	}
+	bodyIdx := func(i int) int {
+		if body {
+			return i
+		}
+		return -1
+	}
+	for i, r := range rows {
+		l.heights[i] = t.calculateRowHeight(r, bodyIdx(i), l.cols[i], widths)
 	}
 
-	// Render body rows
-	for i, r := range bodyRows {
-		// Check if we need a page break
-		rowH := t.calculateRowHeight(r, widths)
-		_, pageH := t.pdf.GetPageSize()
-		_, _, _, bMargin := t.pdf.GetMargins()
+	// Grow the last row of each span so the spanning cell's content fits.
+	for i, r := range rows {
+		for j, cell := range r.cells {
+			if cell.rowspan <= 1 || l.cols[i][j] < 0 {
+				continue
+			}
+			last := min(i+cell.rowspan, len(rows)) - 1
+			need := t.cellHeight(cell, r, bodyIdx(i), l.cols[i][j], cellWidth(widths, l.cols[i][j], cell.colspan))
+			have := 0.0
+			for k := i; k <= last; k++ {
+				have += l.heights[k]
+			}
+			if need > have {
+				l.heights[last] += need - have
+			}
+		}
+	}
 
-		if t.pdf.GetY()+rowH > pageH-bMargin {
-			t.pdf.AddPage()
-			// Re-render headers on new page
-			for _, hr := range headerRows {
-				t.renderRow(hr, widths, startX, -1, true)
+	// Rows joined by rowspans form blocks that must not be split by a page break.
+	blockStart, blockEnd := -1, -1
+	for i, r := range rows {
+		if i > blockEnd {
+			blockStart = i
+		}
+		for j, cell := range r.cells {
+			if l.cols[i][j] >= 0 {
+				blockEnd = max(blockEnd, min(i+max(cell.rowspan, 1), len(rows))-1)
+			}
+		}
+		blockEnd = max(blockEnd, i)
+		l.blockH[blockStart] += l.heights[i]
+	}
+
+	return l
+}
+
+// placeCells returns the starting column of each cell of rows, or -1 for
+// cells that do not fit, skipping positions covered by rowspan cells of
+// earlier rows.
+func placeCells(rows []*Row, numCols int) [][]int {
+	placed := make([][]int, len(rows))
+	covered := make([]int, numCols) // rows still covered per column, including the current one
+	for i, r := range rows {
+		cols := make([]int, len(r.cells))
+		col := 0
+		for j, cell := range r.cells {
+			for col < numCols && covered[col] > 0 {
+				col++
+			}
+			if col >= numCols {
+				cols[j] = -1
+				continue
+			}
+			cols[j] = col
+			end := min(col+cell.colspan, numCols)
+			for ; col < end; col++ {
+				covered[col] = max(cell.rowspan, 1)
+			}
+		}
+		for c := range covered {
+			if covered[c] > 0 {
+				covered[c]--
 			}
 		}
+		placed[i] = cols
+	}
+	return placed
+}
 
-		t.renderRow(r, widths, startX, i, false)
+// renderRows renders all rows of a layout in order.
+func (t *Table) renderRows(l rowLayout, widths []float64, startX float64, isHeader bool) {
+	for i := range l.rows {
+		t.renderRow(l, i, widths, startX, -1, isHeader)
 	}
+}
 
-	return t.pdf.Error()
+// cellWidth returns the width of a cell starting at col and spanning
+// colspan columns.
+func cellWidth(widths []float64, col, colspan int) float64 {
+	w := 0.0
+	for j := col; j < col+max(colspan, 1) && j < len(widths); j++ {
+		w += widths[j]
+	}
+	return w
 }
 
 // calculateWidths computes final column widths based on definitions and available space.
@@ -199,99 +558,204 @@ func (t *Table) calculateWidths() []float64 {
 			remaining = 0
 		}
 		autoWidth := remaining / float64(autoCount)
+
+		// Auto-sized columns split their even share in proportion to the
+		// width their content needs.
+		var sized []int
 		for i, col := range t.columns {
-			if col.Width == 0 {
-				w := autoWidth
-				if col.MinWidth > 0 && w < col.MinWidth {
-					w = col.MinWidth
-				}
-				if col.MaxWidth > 0 && w > col.MaxWidth {
-					w = col.MaxWidth
-				}
-				widths[i] = w
+			if col.Width > 0 {
+				continue
+			}
+			if col.AutoSize || t.autoFit {
+				sized = append(sized, i)
+				continue
 			}
+			widths[i] = clampWidth(autoWidth, col)
+		}
+		if len(sized) > 0 {
+			t.shareByContent(widths, sized, autoWidth*float64(len(sized)))
 		}
 	}
 
 	return widths
 }
 
-// calculateRowHeight computes the height needed for a row based on cell content.
-func (t *Table) calculateRowHeight(r *Row, widths []float64) float64 {
-	maxH := 5.0 // minimum row height
-	if r.minH > maxH {
-		maxH = r.minH
+// clampWidth limits w to the minimum and maximum width of col.
+func clampWidth(w float64, col ColumnDef) float64 {
+	if col.MinWidth > 0 && w < col.MinWidth {
+		w = col.MinWidth
 	}
+	if col.MaxWidth > 0 && w > col.MaxWidth {
+		w = col.MaxWidth
+	}
+	return w
+}
 
-	padding := t.style.CellPadding
-
-	for i, cell := range r.cells {
-		if i >= len(widths) {
-			break
+// shareByContent divides share between the columns cols in proportion to
+// the width of their content. A column held at its minimum or maximum
+// width takes or gives up the difference, which the other columns then
+// share.
+func (t *Table) shareByContent(widths []float64, cols []int, share float64) {
+	demand := t.contentWidths()
+	for len(cols) > 0 {
+		total := 0.0
+		for _, i := range cols {
+			total += demand[i]
 		}
-
-		// Calculate cell width (including colspan)
-		cellW := widths[i]
-		for j := 1; j < cell.colspan && i+j < len(widths); j++ {
-			cellW += widths[i+j]
+		var free []int
+		for _, i := range cols {
+			w := share / float64(len(cols))
+			if total > 0 {
+				w = share * demand[i] / total
+			}
+			widths[i] = clampWidth(w, t.columns[i])
+			if widths[i] != w {
+				share -= widths[i]
+				continue
+			}
+			free = append(free, i)
 		}
-
-		contentW := cellW - padding.Left - padding.Right
-		if contentW < 1 {
-			contentW = 1
+		if len(free) == len(cols) {
+			return
 		}
+		share = max(share, 0)
+		cols = free
+	}
+}
 
-		switch c := cell.content.(type) {
-		case TextContent:
-			// Calculate number of lines needed
-			lines := t.pdf.SplitLines([]byte(c.Text), contentW)
-			_, fontSize := t.pdf.GetFontSize()
-			lineH := fontSize * 1.5
-			cellH := float64(len(lines))*lineH + padding.Top + padding.Bottom
-			if cellH > maxH {
-				maxH = cellH
+// contentWidths returns, for each column, the width needed to show its
+// widest line of text on one line, including cell padding. Cells spanning
+// several columns are ignored. Widths of text in a font other than the
+// current one are estimated by scaling to the font size.
+func (t *Table) contentWidths() []float64 {
+	widths := make([]float64, len(t.columns))
+	headerRows, bodyRows, footerRows := t.splitRows()
+	for k, r := range append(append(headerRows, bodyRows...), footerRows...) {
+		bodyIdx := k - len(headerRows)
+		if bodyIdx < 0 || bodyIdx >= len(bodyRows) {
+			bodyIdx = -1
+		}
+		col := 0
+		for _, cell := range r.cells {
+			j := col
+			col += max(cell.colspan, 1)
+			if _, ok := cell.content.(ImageContent); ok || j >= len(widths) || cell.colspan > 1 {
+				continue
 			}
-		case ImageContent:
-			// Use a default image height
-			cellH := 10.0 + padding.Top + padding.Bottom
-			if cellH > maxH {
-				maxH = cellH
+			style := t.resolveCellStyle(cell, r, bodyIdx, j, r.isHeader)
+			restore := t.useFont(style)
+			padding := t.cellPadding(style)
+			switch c := cell.content.(type) {
+			case TextContent:
+				if style.Rotate != 0 {
+					w, _ := rotatedSize(t.stringWidth(c.Text), t.lineHeight(), style.Rotate)
+					widths[j] = max(widths[j], w+padding.Left+padding.Right)
+					break
+				}
+				for _, line := range strings.Split(c.Text, "\n") {
+					w := t.stringWidth(line) + padding.Left + padding.Right
+					widths[j] = max(widths[j], w)
+				}
+			case RichTextContent:
+				for _, line := range t.layoutRich(c, math.Inf(1)) {
+					widths[j] = max(widths[j], line.width+padding.Left+padding.Right)
+				}
 			}
+			restore()
+		}
+	}
+	return widths
+}
+
+// calculateRowHeight computes the height needed for a row based on cell content.
+// Cells spanning several rows are accounted for in layoutRows instead.
+func (t *Table) calculateRowHeight(r *Row, bodyIdx int, cols []int, widths []float64) float64 {
+	maxH := t.minRowH
+	if maxH <= 0 {
+		maxH = 5.0 // default minimum row height
+	}
+	if r.minH > maxH {
+		maxH = r.minH
+	}
+
+	for j, cell := range r.cells {
+		if cols[j] < 0 || cell.rowspan > 1 {
+			continue
+		}
+		if cellH := t.cellHeight(cell, r, bodyIdx, cols[j], cellWidth(widths, cols[j], cell.colspan)); cellH > maxH {
+			maxH = cellH
 		}
 	}
 
 	return maxH
 }
 
-// renderRow renders a single row to the PDF.
-func (t *Table) renderRow(r *Row, widths []float64, startX float64, bodyIdx int, isHeader bool) {
-	rowH := t.calculateRowHeight(r, widths)
-	padding := t.style.CellPadding
+// cellHeight returns the height needed by a cell's content, including padding.
+func (t *Table) cellHeight(cell *Cell, r *Row, bodyIdx, col int, cellW float64) float64 {
+	style := t.resolveCellStyle(cell, r, bodyIdx, col, r.isHeader)
+	padding := t.cellPadding(style)
+	defer t.useFont(style)()
+
+	contentW := cellW - padding.Left - padding.Right
+	if contentW < 1 {
+		contentW = 1
+	}
+
+	switch c := cell.content.(type) {
+	case TextContent:
+		if style.Rotate != 0 {
+			_, h := rotatedSize(t.stringWidth(c.Text), t.lineHeight(), style.Rotate)
+			return h + padding.Top + padding.Bottom
+		}
+		if t.fitsOneLine(c.Text, style) {
+			return t.lineHeight() + padding.Top + padding.Bottom
+		}
+		// Calculate number of lines needed
+		return float64(t.lineCount(c.Text, contentW))*t.lineHeight() + padding.Top + padding.Bottom
+	case RichTextContent:
+		lines := t.layoutRich(c, contentW-2*t.pdf.GetCellMargin())
+		return richHeight(lines) + padding.Top + padding.Bottom
+	case ImageContent:
+		// Use a default image height
+		return 10.0 + padding.Top + padding.Bottom
+	}
+	return 0
+}
+
+// renderRow renders row i of the layout to the PDF. Cells spanning several
+// rows are drawn with the combined height of the rows they cover.
+func (t *Table) renderRow(l rowLayout, i int, widths []float64, startX float64, bodyIdx int, isHeader bool) {
+	r := l.rows[i]
+	rowH := l.heights[i]
 
 	t.pdf.SetX(startX)
 	y := t.pdf.GetY()
 
-	for i, cell := range r.cells {
-		if i >= len(widths) {
-			break
+	for j, cell := range r.cells {
+		col := l.cols[i][j]
+		if col < 0 {
+			continue
 		}
 
-		// Calculate cell width (including colspan)
-		cellW := widths[i]
-		for j := 1; j < cell.colspan && i+j < len(widths); j++ {
-			cellW += widths[i+j]
+		// Calculate cell width (including colspan) and height (including rowspan)
+		cellW := cellWidth(widths, col, cell.colspan)
+		cellH := 0.0
+		for k := i; k < i+max(cell.rowspan, 1) && k < len(l.heights); k++ {
+			cellH += l.heights[k]
 		}
 
 		// Determine cell style
-		style := t.resolveCellStyle(cell, r, bodyIdx, isHeader)
+		style := t.resolveCellStyle(cell, r, bodyIdx, col, isHeader)
 
-		// Save state
-		x := t.pdf.GetX()
+		x := startX
+		for k := 0; k < col; k++ {
+			x += widths[k]
+		}
 
 		// Draw background
 		if style.FillColor != nil {
 			t.pdf.SetFillColor(style.FillColor.R, style.FillColor.G, style.FillColor.B)
-			t.pdf.Rect(x, y, cellW, rowH, "F")
+			t.pdf.Rect(x, y, cellW, cellH, "F")
 		}
 
 		// Draw border
@@ -304,45 +768,70 @@ func (t *Table) renderRow(r *Row, widths []float64, startX float64, bodyIdx int,
 				t.pdf.SetLineWidth(t.style.Border.Width)
 			}
 		}
-		t.pdf.Rect(x, y, cellW, rowH, "D")
+		sides := style.Borders
+		if sides == nil && t.style.Border != nil {
+			sides = t.style.Border.Sides
+		}
+		if sides == nil {
+			t.pdf.Rect(x, y, cellW, cellH, "D")
+		} else {
+			t.drawBorderSides(sides, x, y, cellW, cellH)
+		}
 
 		// Set text properties
 		if style.TextColor != nil {
 			t.pdf.SetTextColor(style.TextColor.R, style.TextColor.G, style.TextColor.B)
 		}
-		if style.Font != nil {
-			t.pdf.SetFont(style.Font.Family, style.Font.Style, style.Font.Size)
-		}
+		restore := t.useFont(style)
 
 		// Render content
 		align := "L"
 		if style.Align != "" {
 			align = style.Align
-		} else if i < len(t.columns) && t.columns[i].Align != "" {
-			align = t.columns[i].Align
+		} else if col < len(t.columns) && t.columns[col].Align != "" {
+			align = t.columns[col].Align
 		}
 
+		padding := t.cellPadding(style)
 		contentX := x + padding.Left
 		contentY := y + padding.Top
 		contentW := cellW - padding.Left - padding.Right
+		availH := cellH - padding.Top - padding.Bottom
 
 		switch c := cell.content.(type) {
 		case TextContent:
+			lineH := t.lineHeight()
+			oneLine := t.fitsOneLine(c.Text, style)
+			contentH := lineH
+			if style.Rotate != 0 {
+				contentH = availH
+			} else if !oneLine {
+				contentH = float64(t.lineCount(c.Text, contentW)) * lineH
+			}
+			contentY += verticalOffset(style.VerticalAlign, availH, contentH)
 			t.pdf.SetXY(contentX, contentY)
 			// Use MultiCell for wrapped text, but we need to handle alignment
-			if strings.Contains(c.Text, "\n") || t.pdf.GetStringWidth(c.Text) > contentW {
-				t.pdf.MultiCell(contentW, rowH-padding.Top-padding.Bottom, c.Text, "", align, false)
+			if style.Rotate != 0 {
+				t.renderRotatedText(c.Text, style.Rotate, contentX, contentY, contentW, availH)
+			} else if oneLine {
+				t.pdf.CellFormat(contentW, lineH, t.cutText(c.Text, contentW, style.Overflow), "", 0, align, false, 0, "")
+			} else if strings.Contains(c.Text, "\n") || t.stringWidth(c.Text) > contentW {
+				t.pdf.MultiCell(contentW, lineH, c.Text, "", align, false)
 			} else {
-				t.pdf.CellFormat(contentW, rowH-padding.Top-padding.Bottom,
-					c.Text, "", 0, align, false, 0, "")
+				t.pdf.CellFormat(contentW, lineH, c.Text, "", 0, align, false, 0, "")
 			}
+
+			t.linkCell(cell, contentX, contentY, contentW, contentH)
+		case RichTextContent:
+			lines := t.layoutRich(c, contentW-2*t.pdf.GetCellMargin())
+			contentH := richHeight(lines)
+			contentY += verticalOffset(style.VerticalAlign, availH, contentH)
+			t.renderRich(c, lines, contentX, contentY, contentW, align, style.TextColor)
+			t.linkCell(cell, contentX, contentY, contentW, contentH)
 		case ImageContent:
-			imgH := rowH - padding.Top - padding.Bottom
-			t.pdf.Image(c.Path, contentX, contentY, 0, imgH, false, c.Type, 0, "")
+			t.renderImage(c, contentX, contentY, contentW, availH, align, style.VerticalAlign)
 		}
-
-		// Move to next cell position
-		t.pdf.SetXY(x+cellW, y)
+		restore()
 	}
 
 	// Restore colors to defaults
@@ -354,9 +843,284 @@ func (t *Table) renderRow(r *Row, widths []float64, startX float64, bodyIdx int,
 	t.pdf.SetXY(startX, y+rowH)
 }
 
+// linkCell makes the content area of a cell at (x, y) clickable if the cell
+// has a link.
+func (t *Table) linkCell(cell *Cell, x, y, w, h float64) {
+	if cell.linkStr != "" {
+		t.pdf.LinkString(x, y, w, h, cell.linkStr)
+	} else if cell.link != 0 {
+		t.pdf.Link(x, y, w, h, cell.link)
+	}
+}
+
+// renderRotatedText draws text as a single line rotated by angle degrees
+// around the center of the w×h content area at (x, y).
+func (t *Table) renderRotatedText(text string, angle, x, y, w, h float64) {
+	cx, cy := x+w/2, y+h/2
+	textW := t.stringWidth(text)
+	lineH := t.lineHeight()
+
+	t.pdf.TransformBegin()
+	t.pdf.TransformRotate(angle, cx, cy)
+	t.pdf.SetXY(cx-textW/2, cy-lineH/2)
+	t.pdf.CellFormat(textW, lineH, text, "", 0, "C", false, 0, "")
+	t.pdf.TransformEnd()
+}
+
+// rotatedSize returns the width and height of the bounding box of a w×h
+// box rotated by angle degrees.
+func rotatedSize(w, h, angle float64) (float64, float64) {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	return w*cos + h*sin, w*sin + h*cos
+}
+
+// renderImage draws an image cell scaled to fit within the w×h content
+// area, keeping its aspect ratio, and aligned within the area that is left.
+func (t *Table) renderImage(c ImageContent, x, y, w, h float64, align, valign string) {
+	name := c.Path
+	var info *gofpdf.ImageInfoType
+	if c.Data != nil {
+		if name == "" {
+			name = fmt.Sprintf("table-image-%x", sha1.Sum(c.Data))
+		}
+		tp := c.Type
+		if tp == "" {
+			tp = imageType(c.Data)
+		}
+		info = t.pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: tp}, bytes.NewReader(c.Data))
+	} else {
+		info = t.pdf.RegisterImageOptions(name, gofpdf.ImageOptions{ImageType: c.Type})
+	}
+	if info == nil {
+		return
+	}
+
+	imgW, imgH := info.Extent()
+	if imgW <= 0 || imgH <= 0 || w <= 0 || h <= 0 {
+		return
+	}
+	scale := math.Min(w/imgW, h/imgH)
+	imgW, imgH = imgW*scale, imgH*scale
+
+	switch align {
+	case "C":
+		x += (w - imgW) / 2
+	case "R":
+		x += w - imgW
+	}
+	y += verticalOffset(valign, h, imgH)
+	t.pdf.ImageOptions(name, x, y, imgW, imgH, false, gofpdf.ImageOptions{ImageType: c.Type}, 0, "")
+}
+
+// imageType detects the type of encoded image data from its signature.
+// It returns "" if the format is not recognized.
+func imageType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "jpg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "gif"
+	}
+	return ""
+}
+
+// drawBorderSides draws the selected sides of a cell border with pdf.Line.
+// Each side falls back to the current line width and draw color, which hold
+// the table border style, and these are restored afterwards.
+func (t *Table) drawBorderSides(sides *Borders, x, y, w, h float64) {
+	baseWidth := t.pdf.GetLineWidth()
+	br, bg, bb := t.pdf.GetDrawColor()
+
+	line := func(side *BorderSide, x1, y1, x2, y2 float64) {
+		if side == nil {
+			return
+		}
+		if side.Width > 0 {
+			t.pdf.SetLineWidth(side.Width)
+		} else {
+			t.pdf.SetLineWidth(baseWidth)
+		}
+		if side.Color != nil {
+			t.pdf.SetDrawColor(side.Color.R, side.Color.G, side.Color.B)
+		} else {
+			t.pdf.SetDrawColor(br, bg, bb)
+		}
+		t.pdf.Line(x1, y1, x2, y2)
+	}
+	line(sides.Top, x, y, x+w, y)
+	line(sides.Right, x+w, y, x+w, y+h)
+	line(sides.Bottom, x, y+h, x+w, y+h)
+	line(sides.Left, x, y, x, y+h)
+
+	t.pdf.SetLineWidth(baseWidth)
+	t.pdf.SetDrawColor(br, bg, bb)
+}
+
+// fitsOneLine reports whether text is kept on a single line because the
+// cell's overflow mode cuts it rather than wrapping it.
+func (t *Table) fitsOneLine(text string, style CellStyle) bool {
+	return (style.Overflow == "ellipsis" || style.Overflow == "clip") && !strings.Contains(text, "\n")
+}
+
+// cutText shortens text to fit width w in the current font. In "ellipsis"
+// mode the table's ellipsis is appended to the shortened text.
+func (t *Table) cutText(text string, w float64, overflow string) string {
+	if t.stringWidth(text) <= w {
+		return text
+	}
+	marker := ""
+	if overflow == "ellipsis" {
+		marker = t.ellipsisMarker()
+	}
+
+	// Find the longest prefix that fits together with the marker.
+	runes := []rune(text)
+	lo, hi := 0, len(runes)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if t.pdf.GetStringWidth(string(runes[:mid])+marker) <= w {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 && t.pdf.GetStringWidth(marker) > w {
+		return ""
+	}
+	return string(runes[:lo]) + marker
+}
+
+// ellipsisMarker returns the text appended by the "ellipsis" overflow mode
+// in the current font. Unless set, it is "…" in UTF-8 fonts and "..." in
+// the core fonts, which would show the UTF-8 bytes of "…" as three cp1252
+// characters.
+func (t *Table) ellipsisMarker() string {
+	switch {
+	case t.ellipsis != "":
+		return t.ellipsis
+	case t.pdf.IsCurrentFontUTF8():
+		return "\u2026"
+	}
+	return "..."
+}
+
+// measureKey identifies a measurement of text in a font. Width is the
+// wrapping width for line counts and 0 for string widths.
+type measureKey struct {
+	family, style string
+	size, width   float64
+	text          string
+}
+
+// measureCache holds the text measurements made during one Render or
+// Measure call. Rows are measured for layout and again while drawing, so
+// each string is measured only once.
+type measureCache struct {
+	widths map[measureKey]float64
+	lines  map[measureKey]int
+
+	hits, misses int // lookups answered by the cache and measured by pdf
+}
+
+// newMeasureCache returns an empty cache. It is a variable so that tests
+// can inspect the caches of Render and Measure calls.
+var newMeasureCache = func() *measureCache {
+	return &measureCache{
+		widths: make(map[measureKey]float64),
+		lines:  make(map[measureKey]int),
+	}
+}
+
+// measureKey returns the cache key for text in the current font.
+func (t *Table) measureKey(text string, width float64) measureKey {
+	size, _ := t.pdf.GetFontSize()
+	return measureKey{t.pdf.GetFontFamily(), t.pdf.GetFontStyle(), size, width, text}
+}
+
+// stringWidth returns the width of s in the current font.
+func (t *Table) stringWidth(s string) float64 {
+	if t.cache == nil {
+		return t.pdf.GetStringWidth(s)
+	}
+	k := t.measureKey(s, 0)
+	w, ok := t.cache.widths[k]
+	if ok {
+		t.cache.hits++
+	} else {
+		t.cache.misses++
+		w = t.pdf.GetStringWidth(s)
+		t.cache.widths[k] = w
+	}
+	return w
+}
+
+// lineCount returns the number of lines text wraps to at width w in the
+// current font.
+func (t *Table) lineCount(text string, w float64) int {
+	if t.cache == nil {
+		return len(t.pdf.SplitLines([]byte(text), w))
+	}
+	k := t.measureKey(text, w)
+	n, ok := t.cache.lines[k]
+	if ok {
+		t.cache.hits++
+	} else {
+		t.cache.misses++
+		n = len(t.pdf.SplitLines([]byte(text), w))
+		t.cache.lines[k] = n
+	}
+	return n
+}
+
+// useFont selects the font of style, if it sets one, and returns a function
+// restoring the previous font, so that cells are measured and drawn in their
+// own font.
+func (t *Table) useFont(style CellStyle) func() {
+	if style.Font == nil {
+		return func() {}
+	}
+	family, fontStyle := t.pdf.GetFontFamily(), t.pdf.GetFontStyle()
+	size, _ := t.pdf.GetFontSize()
+	t.pdf.SetFont(style.Font.Family, style.Font.Style, style.Font.Size)
+	return func() { t.pdf.SetFont(family, fontStyle, size) }
+}
+
+// lineHeight returns the height of one line of cell text in the current font.
+func (t *Table) lineHeight() float64 {
+	_, fontSize := t.pdf.GetFontSize()
+	return fontSize * 1.5
+}
+
+// cellPadding returns the padding for a cell with the given resolved style.
+func (t *Table) cellPadding(style CellStyle) Padding {
+	if style.Padding != nil {
+		return *style.Padding
+	}
+	return t.style.CellPadding
+}
+
+// verticalOffset returns the offset from the top of a box of height boxH at
+// which content of height contentH starts for the given vertical alignment.
+func verticalOffset(valign string, boxH, contentH float64) float64 {
+	free := boxH - contentH
+	if free <= 0 {
+		return 0
+	}
+	switch valign {
+	case "M":
+		return free / 2
+	case "B":
+		return free
+	}
+	return 0
+}
+
 // resolveCellStyle determines the effective style for a cell by merging
-// table, alternate row, header, row, and cell-level styles.
-func (t *Table) resolveCellStyle(cell *Cell, row *Row, bodyIdx int, isHeader bool) CellStyle {
+// table, alternate row, header, cell function, row, and cell-level styles.
+func (t *Table) resolveCellStyle(cell *Cell, row *Row, bodyIdx, col int, isHeader bool) CellStyle {
 	var result CellStyle
 
 	// Table-level font
@@ -370,11 +1134,22 @@ func (t *Table) resolveCellStyle(cell *Cell, row *Row, bodyIdx int, isHeader boo
 	}
 
 	// Alternate row colors (only for body rows)
-	if !isHeader && t.style.AlternateRows != nil && bodyIdx >= 0 {
-		if bodyIdx%2 == 0 {
-			mergeStyle(&result, &t.style.AlternateRows.Even)
+	if alt := t.style.AlternateRows; !isHeader && alt != nil && bodyIdx >= 0 {
+		band := bodyIdx
+		if alt.GroupSize > 1 {
+			band /= alt.GroupSize
+		}
+		if band%2 == 0 {
+			mergeStyle(&result, &alt.Even)
 		} else {
-			mergeStyle(&result, &t.style.AlternateRows.Odd)
+			mergeStyle(&result, &alt.Odd)
+		}
+	}
+
+	// Cell function (only for body rows)
+	if t.cellFunc != nil && !isHeader && bodyIdx >= 0 {
+		if s := t.cellFunc(bodyIdx, col, cell.text()); s != nil {
+			mergeStyle(&result, s)
 		}
 	}
 
@@ -408,7 +1183,19 @@ func mergeStyle(dst, src *CellStyle) {
 	if src.Align != "" {
 		dst.Align = src.Align
 	}
+	if src.VerticalAlign != "" {
+		dst.VerticalAlign = src.VerticalAlign
+	}
 	if src.Padding != nil {
 		dst.Padding = src.Padding
 	}
+	if src.Borders != nil {
+		dst.Borders = src.Borders
+	}
+	if src.Overflow != "" {
+		dst.Overflow = src.Overflow
+	}
+	if src.Rotate != 0 {
+		dst.Rotate = src.Rotate
+	}
 }
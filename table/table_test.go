@@ -2,6 +2,12 @@ package table_test
 
 import (
 	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	gofpdf "github.com/lvillar/gofpdf"
@@ -109,6 +115,43 @@ func TestAlternatingRows(t *testing.T) {
 	t.Logf("Alternating rows table PDF: %d bytes", buf.Len())
 }
 
+func TestAlternatingRowGroups(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(60)
+	tb.SetStyle(table.TableStyle{
+		AlternateRows: &table.AlternateStyle{
+			Even:      table.CellStyle{FillColor: &table.RGBColor{R: 255}},
+			Odd:       table.CellStyle{FillColor: &table.RGBColor{B: 255}},
+			GroupSize: 3,
+		},
+	})
+	for i := 0; i < 7; i++ {
+		tb.AddRow().AddCellf("Item %d", i)
+	}
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	var bands string
+	for _, m := range regexp.MustCompile(`(1\.000 0\.000 0\.000|0\.000 0\.000 1\.000) rg`).FindAllSubmatch(buf.Bytes(), -1) {
+		if string(m[1]) == "1.000 0.000 0.000" {
+			bands += "E"
+		} else {
+			bands += "O"
+		}
+	}
+	if bands != "EEEOOOE" {
+		t.Errorf("row fills = %q, want %q", bands, "EEEOOOE")
+	}
+}
+
 func TestHeaderRepeatsOnPageBreak(t *testing.T) {
 	pdf := newTestPDF()
 
@@ -245,3 +288,841 @@ func TestNewDocumentWithTable(t *testing.T) {
 	}
 	t.Logf("NewDocument + Table PDF: %d bytes", buf.Len())
 }
+
+func TestQuick(t *testing.T) {
+	pdf := newTestPDF()
+
+	headers := []string{"Name", "Qty", "Price"}
+	rows := [][]string{
+		{"Widget", "10", "$5.00"},
+		{"Gadget", "5", "$12.50"},
+	}
+	style := table.TableStyle{
+		HeaderStyle: &table.CellStyle{
+			FillColor: &table.RGBColor{R: 200, G: 200, B: 200},
+		},
+	}
+	if err := table.Quick(pdf, headers, rows, style); err != nil {
+		t.Fatalf("quick: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Error("output does not start with %PDF header")
+	}
+}
+
+// shortCellY renders a two-column table whose first cell wraps over several
+// lines and returns the PDF y coordinate of the text in the second cell.
+func shortCellY(t *testing.T, valign string) float64 {
+	t.Helper()
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40, 40)
+	tb.SetStyle(table.TableStyle{
+		CellPadding: table.Padding{Top: 1, Right: 2, Bottom: 3, Left: 2},
+	})
+	r := tb.AddRow()
+	r.AddCell(strings.Repeat("long description ", 8))
+	r.AddCell("SHORT").SetStyle(table.CellStyle{VerticalAlign: valign})
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	m := regexp.MustCompile(`BT ([\d.]+) ([\d.]+) Td \(SHORT\) ?Tj`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("short cell text not found in output")
+	}
+	y, err := strconv.ParseFloat(string(m[2]), 64)
+	if err != nil {
+		t.Fatalf("parsing y: %v", err)
+	}
+	return y
+}
+
+func TestVerticalAlign(t *testing.T) {
+	top := shortCellY(t, "T")
+	middle := shortCellY(t, "M")
+	bottom := shortCellY(t, "B")
+
+	// PDF y coordinates grow upwards.
+	if !(top > middle && middle > bottom) {
+		t.Fatalf("expected top > middle > bottom, got %.2f, %.2f, %.2f", top, middle, bottom)
+	}
+	if d := (top - middle) - (middle - bottom); d > 0.02 || d < -0.02 {
+		t.Errorf("middle not centered: top=%.2f middle=%.2f bottom=%.2f", top, middle, bottom)
+	}
+	if def := shortCellY(t, ""); def != top {
+		t.Errorf("default alignment y=%.2f, want top y=%.2f", def, top)
+	}
+}
+
+func TestRowspan(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(30, 60)
+	r := tb.AddRow()
+	r.AddCell("9am").SetRowspan(3)
+	r.AddCell("Slot A")
+	tb.AddRow().AddCell("Slot B")
+	tb.AddRow().AddCell("Slot C")
+	tb.AddRow().AddCell("10am").SetColspan(2)
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+
+	textX := func(s string) float64 {
+		t.Helper()
+		m := regexp.MustCompile(`BT ([\d.]+) [\d.]+ Td \(` + s + `\) ?Tj`).FindSubmatch(out)
+		if m == nil {
+			t.Fatalf("%q not found in output", s)
+		}
+		x, _ := strconv.ParseFloat(string(m[1]), 64)
+		return x
+	}
+
+	// Rows covered by the spanning cell start in the second column.
+	a, b, c := textX("Slot A"), textX("Slot B"), textX("Slot C")
+	if a != b || b != c {
+		t.Errorf("covered rows misaligned: x = %.2f, %.2f, %.2f", a, b, c)
+	}
+	if nine, ten := textX("9am"), textX("10am"); a <= nine || ten != nine {
+		t.Errorf("unexpected x positions: 9am=%.2f slot=%.2f 10am=%.2f", nine, a, ten)
+	}
+
+	// The spanning cell's border is three rows tall.
+	var heights []float64
+	for _, m := range regexp.MustCompile(`[\d.]+ [\d.]+ [\d.]+ (-[\d.]+) re S`).FindAllSubmatch(out, -1) {
+		h, _ := strconv.ParseFloat(string(m[1]), 64)
+		heights = append(heights, -h)
+	}
+	if len(heights) != 5 {
+		t.Fatalf("expected 5 cell borders, got %d", len(heights))
+	}
+	if d := heights[0] - 3*heights[1]; d > 0.05 || d < -0.05 {
+		t.Errorf("spanning cell height %.2f, want 3 x %.2f", heights[0], heights[1])
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	pdf := newTestPDF()
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40, 0)
+	h := tb.AddHeaderRow()
+	h.AddCell("Name")
+	h.AddCell("Description")
+	for i := 0; i < 5; i++ {
+		r := tb.AddRow()
+		r.AddCellf("Item %d", i)
+		r.AddCell(strings.Repeat("wrapped description text ", i+1))
+	}
+
+	x, y := pdf.GetXY()
+	height := tb.Measure()
+	if nx, ny := pdf.GetXY(); nx != x || ny != y {
+		t.Fatalf("Measure moved the cursor from (%.2f, %.2f) to (%.2f, %.2f)", x, y, nx, ny)
+	}
+	if height <= 0 {
+		t.Fatalf("expected positive height, got %.2f", height)
+	}
+
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got := pdf.GetY() - y; got-height > 0.01 || height-got > 0.01 {
+		t.Errorf("Measure = %.2f, rendered height = %.2f", height, got)
+	}
+}
+
+func TestAutoSizeColumns(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumns(table.ColumnDef{AutoSize: true}, table.ColumnDef{AutoSize: true})
+	for _, email := range []string{"alice.anderson@example.com", "bob.brown@example.org"} {
+		r := tb.AddRow()
+		r.AddCell("OK")
+		r.AddCell(email)
+	}
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	m := regexp.MustCompile(`[\d.]+ [\d.]+ ([\d.]+) -[\d.]+ re S`).FindAllSubmatch(buf.Bytes(), 2)
+	if len(m) != 2 {
+		t.Fatalf("expected cell borders in output, got %d", len(m))
+	}
+	short, _ := strconv.ParseFloat(string(m[0][1]), 64)
+	long, _ := strconv.ParseFloat(string(m[1][1]), 64)
+	if long <= 2*short {
+		t.Errorf("long column width %.2f, short column width %.2f; want the long column much wider", long, short)
+	}
+}
+
+func TestAutoFit(t *testing.T) {
+	widths := func(autoFit bool, desc table.ColumnDef) []float64 {
+		pdf := newTestPDF()
+		pdf.SetCompression(false)
+
+		tb := table.New(pdf).SetAutoFit(autoFit)
+		tb.SetColumns(table.ColumnDef{}, desc, table.ColumnDef{})
+		r := tb.AddRow()
+		r.AddCell("12")
+		r.AddCell("A long description of the ordered item, which needs the most room")
+		r.AddCell("9.50")
+		if err := tb.Render(); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatalf("output: %v", err)
+		}
+		m := regexp.MustCompile(`[\d.]+ [\d.]+ ([\d.]+) -[\d.]+ re S`).FindAllSubmatch(buf.Bytes(), 3)
+		if len(m) != 3 {
+			t.Fatalf("expected cell borders in output, got %d", len(m))
+		}
+		var w []float64
+		for _, sub := range m {
+			v, _ := strconv.ParseFloat(string(sub[1]), 64)
+			w = append(w, v)
+		}
+		return w
+	}
+	sum := func(w []float64) float64 { return w[0] + w[1] + w[2] }
+
+	even := widths(false, table.ColumnDef{})
+	if even[0] != even[1] || even[1] != even[2] {
+		t.Errorf("default widths = %v, want an even split", even)
+	}
+
+	fit := widths(true, table.ColumnDef{})
+	if fit[1] <= 2*(fit[0]+fit[2]) {
+		t.Errorf("auto-fit widths = %v, want the description column much wider", fit)
+	}
+	if d := sum(fit) - sum(even); d > 0.1 || d < -0.1 {
+		t.Errorf("auto-fit table width = %.2f, want %.2f", sum(fit), sum(even))
+	}
+
+	// Space the clamped description column gives up goes to the others.
+	clamped := widths(true, table.ColumnDef{MaxWidth: 100})
+	if got := clamped[1] / 72 * 25.4; got > 100.1 {
+		t.Errorf("clamped description width = %.2fmm, want at most 100mm", got)
+	}
+	if d := sum(clamped) - sum(even); d > 0.1 || d < -0.1 {
+		t.Errorf("clamped table width = %.2f, want %.2f", sum(clamped), sum(even))
+	}
+}
+
+func TestBorderSides(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40, 40)
+	tb.SetStyle(table.TableStyle{
+		CellPadding: table.UniformPadding(1),
+		Border: &table.BorderStyle{
+			Sides: &table.Borders{Top: &table.BorderSide{}, Bottom: &table.BorderSide{}},
+		},
+	})
+	r := tb.AddRow()
+	r.AddCell("A")
+	r.AddCell("B")
+	r = tb.AddRow()
+	r.AddCell("C")
+	r.AddCell("D").SetStyle(table.CellStyle{
+		Borders: &table.Borders{Left: &table.BorderSide{Width: 1, Color: &table.RGBColor{R: 255}}},
+	})
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if bytes.Contains(out, []byte("re S")) {
+		t.Error("partial borders should not draw full cell boxes")
+	}
+
+	lines := regexp.MustCompile(`([\d.]+) ([\d.]+) m ([\d.]+) ([\d.]+) l S`).FindAllSubmatch(out, -1)
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 border lines, got %d", len(lines))
+	}
+	vertical := 0
+	for _, m := range lines {
+		if string(m[1]) == string(m[3]) {
+			vertical++
+		}
+	}
+	if vertical != 1 {
+		t.Errorf("expected 1 vertical line, got %d", vertical)
+	}
+	if !bytes.Contains(out, []byte("1.000 0.000 0.000 RG")) {
+		t.Error("cell border color not applied")
+	}
+}
+
+func TestFooterRows(t *testing.T) {
+	// render draws an 80 row table whose footer is either added with
+	// AddFooterRow or marked with SetFooterRows, and returns the page count,
+	// how often the footer was drawn and the last text drawn.
+	render := func(lastOnly, setFooterRows bool) (pages, totals int, lastText string) {
+		pdf := newTestPDF()
+		pdf.SetCompression(false)
+
+		tb := table.New(pdf)
+		tb.SetColumnWidths(60, 40)
+		tb.SetFooterLastPageOnly(lastOnly)
+		h := tb.AddHeaderRow()
+		h.AddCell("Item")
+		h.AddCell("Amount")
+		var footer *table.Row
+		if !setFooterRows {
+			// Added first, but still rendered after the body rows.
+			footer = tb.AddFooterRow()
+		}
+		for i := 0; i < 80; i++ {
+			r := tb.AddRow()
+			r.AddCellf("Item %d", i)
+			r.AddCell("1.00")
+		}
+		if setFooterRows {
+			footer = tb.AddRow()
+			tb.SetFooterRows(1)
+		}
+		footer.AddCell("Total")
+		footer.AddCell("80.00")
+
+		if err := tb.Render(); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatalf("output: %v", err)
+		}
+		texts := regexp.MustCompile(`\(([^)]*)\) ?Tj`).FindAllSubmatch(buf.Bytes(), -1)
+		for _, m := range texts {
+			if string(m[1]) == "Total" {
+				totals++
+			}
+		}
+		return pdf.PageCount(), totals, string(texts[len(texts)-1][1])
+	}
+
+	for _, setFooterRows := range []bool{false, true} {
+		pages, totals, last := render(false, setFooterRows)
+		if pages < 2 {
+			t.Fatalf("expected the table to span several pages, got %d", pages)
+		}
+		if totals != pages {
+			t.Errorf("footer rendered %d times on %d pages, want once per page", totals, pages)
+		}
+		if last != "80.00" {
+			t.Errorf("last text = %q, want the footer row last", last)
+		}
+
+		if _, totals, last := render(true, setFooterRows); totals != 1 || last != "80.00" {
+			t.Errorf("last page only: footer rendered %d times, last text %q", totals, last)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	const email = "alice.anderson@example.com"
+	for _, tc := range []struct {
+		overflow string
+		suffix   string
+	}{
+		{"ellipsis", "..."},
+		{"clip", ""},
+	} {
+		pdf := newTestPDF()
+		pdf.SetCompression(false)
+
+		tb := table.New(pdf)
+		tb.SetColumnWidths(25, 25)
+		tb.SetEllipsis("...")
+		r := tb.AddRow()
+		r.AddCell("Short")
+		r.AddCell(email).SetStyle(table.CellStyle{Overflow: tc.overflow})
+		if err := tb.Render(); err != nil {
+			t.Fatalf("%s: render: %v", tc.overflow, err)
+		}
+
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatalf("%s: output: %v", tc.overflow, err)
+		}
+		out := buf.Bytes()
+
+		m := regexp.MustCompile(`\((alice[^)]*)\) ?Tj`).FindAllSubmatch(out, -1)
+		if len(m) != 1 {
+			t.Fatalf("%s: expected the email drawn once on one line, got %d", tc.overflow, len(m))
+		}
+		got := string(m[0][1])
+		prefix, ok := strings.CutSuffix(got, tc.suffix)
+		if !ok || len(prefix) == 0 || len(got) >= len(email) || !strings.HasPrefix(email, prefix) {
+			t.Errorf("%s: drawn text %q, want a shortened email ending in %q", tc.overflow, got, tc.suffix)
+		}
+		if w := pdf.GetStringWidth(got); w > 25-2 {
+			t.Errorf("%s: drawn text is %.2f wide, want at most 23", tc.overflow, w)
+		}
+
+		// The row keeps the height of a single line.
+		heights := regexp.MustCompile(`[\d.]+ [\d.]+ [\d.]+ (-[\d.]+) re S`).FindAllSubmatch(out, -1)
+		if len(heights) != 2 || string(heights[0][1]) != string(heights[1][1]) {
+			t.Fatalf("%s: unexpected cell borders %q", tc.overflow, heights)
+		}
+		lineH, _ := strconv.ParseFloat(string(heights[0][1]), 64)
+		if h := -lineH / pdf.GetConversionRatio(); h > 8 {
+			t.Errorf("%s: row height %.2f, want a single line", tc.overflow, h)
+		}
+	}
+}
+
+func TestOverflowDefaultEllipsis(t *testing.T) {
+	const email = "alice.anderson@example.com"
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(25)
+	tb.AddRow().AddCell(email).SetStyle(table.CellStyle{Overflow: "ellipsis"})
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+
+	// The core fonts are cp1252, so the UTF-8 "…" must not be written.
+	m := regexp.MustCompile(`\((alice[^)]*)\) ?Tj`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("email not drawn")
+	}
+	got := string(m[1])
+	if !strings.HasSuffix(got, "...") || strings.Contains(got, "\u2026") {
+		t.Errorf("drawn text %q, want it cut with \"...\"", got)
+	}
+	if w := pdf.GetStringWidth(got); w > 25-2 {
+		t.Errorf("drawn text is %.2f wide, want at most 23", w)
+	}
+}
+
+func TestCellLinks(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	target := pdf.AddLink()
+	tb := table.New(pdf)
+	tb.SetColumnWidths(60, 40)
+	r := tb.AddRow()
+	r.AddCell("alice@example.com").SetLink("mailto:alice@example.com")
+	r.AddCell("Details").SetInternalLink(target)
+	tb.AddRow().AddCell("No link")
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	pdf.AddPage()
+	pdf.SetLink(target, 0, -1)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if n := bytes.Count(out, []byte("/Subtype /Link")); n != 2 {
+		t.Errorf("expected 2 link annotations, got %d", n)
+	}
+	if !bytes.Contains(out, []byte("(mailto:alice@example.com)")) {
+		t.Error("external link target not found")
+	}
+}
+
+func TestImageCellFitsCell(t *testing.T) {
+	// A wide 200×50 image in a 20 mm column with a 30 mm high row.
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 200, 50))); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+	tb := table.New(pdf)
+	tb.SetColumnWidths(20, 40)
+	r := tb.AddRow().SetMinHeight(30)
+	r.AddImageDataCell(img.Bytes(), "")
+	r.AddCell("Thumbnail")
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	m := regexp.MustCompile(`q ([\d.]+) 0 0 ([\d.]+) [\d.]+ [\d.]+ cm /I[0-9a-f]+ Do Q`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatal("image placement not found")
+	}
+	w, _ := strconv.ParseFloat(string(m[1]), 64)
+	h, _ := strconv.ParseFloat(string(m[2]), 64)
+	if colW := 20 * 72 / 25.4; w > colW {
+		t.Errorf("image width %.2fpt exceeds column width %.2fpt", w, colW)
+	}
+	if ratio := w / h; ratio < 3.99 || ratio > 4.01 {
+		t.Errorf("image aspect ratio not preserved: %.2f x %.2f", w, h)
+	}
+}
+
+func TestRotatedHeader(t *testing.T) {
+	const label = "Quantity Ordered"
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+	labelW := pdf.GetStringWidth(label)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(10, 10)
+	tb.SetStyle(table.TableStyle{
+		HeaderStyle: &table.CellStyle{Rotate: 90},
+		CellPadding: table.UniformPadding(1),
+	})
+	h := tb.AddHeaderRow()
+	h.AddCell(label)
+	h.AddCell("Price")
+	r := tb.AddRow()
+	r.AddCell("3")
+	r.AddCell("9.99")
+
+	startY, measured := pdf.GetY(), tb.Measure()
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got := pdf.GetY() - startY; math.Abs(got-measured) > 1e-9 {
+		t.Errorf("rendered height %.2f differs from Measure %.2f", got, measured)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.Bytes()
+	if n := bytes.Count(out, []byte("0.00000 1.00000 -1.00000 0.00000 ")); n != 2 {
+		t.Errorf("expected 2 rotated header cells, got %d", n)
+	}
+
+	// The header row is tall enough for the rotated label.
+	heights := regexp.MustCompile(`[\d.]+ [\d.]+ [\d.]+ (-[\d.]+) re S`).FindAllSubmatch(out, -1)
+	if len(heights) != 4 {
+		t.Fatalf("expected 4 cell borders, got %d", len(heights))
+	}
+	headerH, _ := strconv.ParseFloat(string(heights[0][1]), 64)
+	if hh := -headerH / pdf.GetConversionRatio(); hh < labelW+2 {
+		t.Errorf("header row height %.2f, want at least %.2f", hh, labelW+2)
+	}
+}
+
+func TestToCSV(t *testing.T) {
+	tb := table.New(newTestPDF())
+	tb.SetColumnWidths(30, 60, 30)
+	h := tb.AddHeaderRow()
+	h.AddCell("Order")
+	h.AddCell("Item")
+	h.AddCell("Price")
+	r := tb.AddRow()
+	r.AddCell("1001").SetRowspan(2)
+	r.AddCell("Paper, A4")
+	r.AddCell("4.50")
+	r = tb.AddRow()
+	r.AddImageCell("../image/logo.png")
+	r.AddCell("9.00")
+	tb.AddFooterRow().AddCell("Total").SetColspan(2)
+
+	var buf bytes.Buffer
+	if err := tb.ToCSV(&buf); err != nil {
+		t.Fatalf("ToCSV: %v", err)
+	}
+	want := "Order,Item,Price\n1001,\"Paper, A4\",4.50\n,,9.00\nTotal,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("ToCSV wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCellFunc(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	type call struct {
+		row, col int
+		text     string
+	}
+	var calls []call
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40, 40)
+	tb.SetCellFunc(func(row, col int, text string) *table.CellStyle {
+		calls = append(calls, call{row, col, text})
+		if v, err := strconv.ParseFloat(text, 64); err == nil && v < 0 {
+			return &table.CellStyle{TextColor: &table.RGBColor{R: 255}}
+		}
+		return nil
+	})
+	h := tb.AddHeaderRow()
+	h.AddCell("Account")
+	h.AddCell("Balance")
+	for _, row := range [][]string{{"Cash", "120.00"}, {"Loan", "-80.00"}} {
+		r := tb.AddRow()
+		r.AddCell(row[0])
+		r.AddCell(row[1])
+	}
+	// Row styles take precedence over the function.
+	r := tb.AddRow().SetStyle(table.CellStyle{TextColor: &table.RGBColor{G: 255}})
+	r.AddCell("Fees")
+	r.AddCell("-5.00")
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	colors := map[string]string{}
+	for _, m := range regexp.MustCompile(`(?:q ([\d.]+ [\d.]+ [\d.]+) rg )?BT [\d.]+ [\d.]+ Td \(([^)]*)\)Tj`).FindAllSubmatch(buf.Bytes(), -1) {
+		colors[string(m[2])] = string(m[1])
+	}
+	for text, want := range map[string]string{
+		"120.00": "",
+		"-80.00": "1.000 0.000 0.000",
+		"-5.00":  "0.000 1.000 0.000",
+	} {
+		if got, ok := colors[text]; !ok {
+			t.Errorf("%s not found in output", text)
+		} else if got != want {
+			t.Errorf("%s drawn in %q, want %q", text, got, want)
+		}
+	}
+
+	for _, c := range calls {
+		if c.text == "Account" || c.text == "Balance" {
+			t.Errorf("function called for header cell %q", c.text)
+		}
+		if c.text == "-80.00" && (c.row != 1 || c.col != 1) {
+			t.Errorf("-80.00 reported at row %d, column %d; want 1, 1", c.row, c.col)
+		}
+	}
+}
+
+func TestRowHeightUsesCellFont(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(36, 30)
+	tb.SetStyle(table.TableStyle{
+		CellPadding: table.UniformPadding(1),
+		HeaderStyle: &table.CellStyle{Font: &table.FontSpec{Family: "Helvetica", Style: "B", Size: 11}},
+	})
+	// At 10pt regular the header fits on two lines; in 11pt bold it needs three.
+	h := tb.AddHeaderRow()
+	h.AddCell("Quarterly revenue before adjustments")
+	h.AddCell("Q")
+	r := tb.AddRow()
+	r.AddCell("1")
+	r.AddCell("2")
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	bold := pdf.SplitLines([]byte("Quarterly revenue before adjustments"), 34)
+	pdf.SetFont("Helvetica", "", 10)
+	regular := pdf.SplitLines([]byte("Quarterly revenue before adjustments"), 34)
+	if len(bold) <= len(regular) {
+		t.Fatalf("test text wraps to %d lines in bold and %d in regular; want more in bold", len(bold), len(regular))
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	m := regexp.MustCompile(`[\d.]+ [\d.]+ [\d.]+ (-[\d.]+) re S`).FindAllSubmatch(buf.Bytes(), -1)
+	if len(m) != 4 {
+		t.Fatalf("expected 4 cell borders, got %d", len(m))
+	}
+	headerH, _ := strconv.ParseFloat(string(m[0][1]), 64)
+	bodyH, _ := strconv.ParseFloat(string(m[2][1]), 64)
+	headerH, bodyH = -headerH/pdf.GetConversionRatio(), -bodyH/pdf.GetConversionRatio()
+	lineH := 11 * 1.5 / pdf.GetConversionRatio()
+	if want := float64(len(bold))*lineH + 2; math.Abs(headerH-want) > 0.01 {
+		t.Errorf("header row height %.2f, want %.2f for %d lines of 11pt text", headerH, want, len(bold))
+	}
+
+	// The body row is drawn in the table's font, not the header's.
+	if want := 10*1.5/pdf.GetConversionRatio() + 2; math.Abs(bodyH-want) > 0.01 {
+		t.Errorf("body row height %.2f, want %.2f", bodyH, want)
+	}
+}
+
+func TestRichTextCell(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(50, 30)
+	tb.SetStyle(table.TableStyle{CellPadding: table.UniformPadding(1)})
+	r := tb.AddRow()
+	r.AddRichCell(
+		table.TextRun{Text: "Widget Pro\n", Font: &table.FontSpec{Style: "B", Size: 12}},
+		table.TextRun{Text: "Anodized aluminium housing with a two year warranty",
+			Font: &table.FontSpec{Size: 8}, Color: &table.RGBColor{R: 128, G: 128, B: 128}},
+	)
+	r.AddCell("19.99")
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	out := buf.String()
+
+	if !regexp.MustCompile(` 12\.00 Tf ET\s+BT [\d.]+ [\d.]+ Td \(Widget Pro\)Tj`).MatchString(out) {
+		t.Error("title not drawn in 12pt")
+	}
+	if !regexp.MustCompile(`q 0\.502 g BT [\d.]+ [\d.]+ Td \(Anodized`).MatchString(out) {
+		t.Error("description not drawn in gray")
+	}
+	if !strings.Contains(out, "(19.99)Tj") {
+		t.Error("plain cell missing")
+	}
+
+	// The 8pt description wraps onto a second line, below the 12pt title.
+	m := regexp.MustCompile(`BT [\d.]+ ([\d.]+) Td \((Widget Pro|Anodized[^)]*|[^)]*warranty)\)Tj`).FindAllStringSubmatch(out, -1)
+	if len(m) != 3 {
+		t.Fatalf("expected 3 lines of rich text, got %d", len(m))
+	}
+	k := pdf.GetConversionRatio()
+	title, _ := strconv.ParseFloat(m[0][1], 64)
+	desc1, _ := strconv.ParseFloat(m[1][1], 64)
+	desc2, _ := strconv.ParseFloat(m[2][1], 64)
+	if title <= desc1 || desc1 <= desc2 {
+		t.Errorf("lines at y = %.2f, %.2f, %.2f; want them from top to bottom", title, desc1, desc2)
+	}
+	if d := (desc1 - desc2) / k; math.Abs(d-8*1.5/k) > 0.01 {
+		t.Errorf("description line spacing %.2f, want %.2f", d, 8*1.5/k)
+	}
+
+	// The row is as tall as the three lines plus padding.
+	h := regexp.MustCompile(`[\d.]+ [\d.]+ [\d.]+ (-[\d.]+) re S`).FindStringSubmatch(out)
+	if h == nil {
+		t.Fatal("no cell border in output")
+	}
+	rowH, _ := strconv.ParseFloat(h[1], 64)
+	if want := (12*1.5+2*8*1.5)/k + 2; math.Abs(-rowH/k-want) > 0.01 {
+		t.Errorf("row height %.2f, want %.2f", -rowH/k, want)
+	}
+
+	var csv bytes.Buffer
+	if err := tb.ToCSV(&csv); err != nil {
+		t.Fatalf("ToCSV: %v", err)
+	}
+	if want := "\"Widget Pro\nAnodized aluminium housing with a two year warranty\",19.99\n"; csv.String() != want {
+		t.Errorf("ToCSV wrote %q, want %q", csv.String(), want)
+	}
+}
+
+// BenchmarkRenderLargeTable renders a 5000-row export. Every row is
+// measured for layout and again while drawing, and many cell values
+// repeat, so most text measurements come from the per-Render cache.
+func TestMinRowHeight(t *testing.T) {
+	pdf := newTestPDF()
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40)
+	tb.SetMinRowHeight(12)
+	for _, text := range []string{"a", "b", "c"} {
+		tb.AddRow().AddCell(text)
+	}
+	tb.AddRow().SetMinHeight(20).AddCell("d")
+
+	if got := tb.Measure(); math.Abs(got-56) > 0.01 {
+		t.Errorf("Measure = %.2f, want 3 x 12 + 20", got)
+	}
+}
+
+func TestMaxRowsPerPage(t *testing.T) {
+	pdf := newTestPDF()
+	pdf.SetCompression(false)
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(60, 60)
+	tb.SetMaxRowsPerPage(4)
+	h := tb.AddHeaderRow()
+	h.AddCell("ID")
+	h.AddCell("Name")
+	for i := 0; i < 10; i++ {
+		r := tb.AddRow()
+		r.AddCellf("%d", i+1)
+		r.AddCellf("Item %d", i+1)
+	}
+
+	if err := tb.Render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if n := pdf.PageCount(); n != 3 {
+		t.Errorf("PageCount = %d, want 3 for 10 rows at 4 per page", n)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if n := strings.Count(buf.String(), "(ID)Tj"); n != 3 {
+		t.Errorf("header drawn %d times, want once per page", n)
+	}
+}
+
+func TestOversizedRow(t *testing.T) {
+	pdf := newTestPDF()
+
+	tb := table.New(pdf)
+	tb.SetColumnWidths(40)
+	tb.AddRow().AddCell("fits")
+	tb.AddRow().AddCell(strings.Repeat("line of text\n", 200))
+	tb.AddRow().AddCell("after")
+
+	err := tb.Render()
+	if err == nil || !strings.Contains(err.Error(), "body row 2") {
+		t.Fatalf("render error = %v, want one naming body row 2", err)
+	}
+	if n := pdf.PageCount(); n != 1 {
+		t.Errorf("PageCount = %d, want no pages added", n)
+	}
+}